@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/agent"
+	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+var (
+	agentSocket      string
+	agentVaults      []string
+	agentIdleTimeout time.Duration
+	agentConfirm     bool
+)
+
+// agentCmd runs ego-agent in the foreground
+var agentCmd = &cobra.Command{
+	Use:   "agent [--vault <dir>]... [--socket <path>] [--idle-timeout <d>] [--confirm]",
+	Short: "Run the signing agent that keeps vault keys unlocked",
+	Long: `Unlock one or more vaults and serve signing requests on a local Unix socket.
+
+While the agent is running, 'ego issue', 'ego present' and 'ego auth-respond' sign
+through it instead of reading keystore.json. Keys are wiped from memory after
+--idle-timeout without requests; use 'ego agent unlock' to reload them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dirs := agentVaults
+		if len(dirs) == 0 {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			if cfg.Active == "" {
+				return fmt.Errorf("no --vault given and no active vault; use 'ego use'")
+			}
+			rootDir := cfg.RootDir
+			if rootDir == "" {
+				rootDir = "store"
+			}
+			dirs = []string{filepath.Join(rootDir, cfg.Active)}
+		}
+
+		opts := agent.Options{IdleTimeout: agentIdleTimeout}
+		if agentConfirm {
+			opts.Confirm = confirmOnTTY
+		}
+		srv := agent.NewServer(opts)
+		for _, dir := range dirs {
+			dir, err := vault.IdentityDir(dir, identityName)
+			if err != nil {
+				return err
+			}
+			v := vault.NewVault(dir)
+			encrypted, err := v.IsEncrypted()
+			if err != nil {
+				return fmt.Errorf("load vault %s: %w", dir, err)
+			}
+			var pass []byte
+			if encrypted {
+				pass, err = readPassphrase(cmd, passphraseFile, passphraseEnv, fmt.Sprintf("Passphrase for %s: ", dir))
+				if err != nil {
+					return err
+				}
+			}
+			did, err := srv.AddVault(v, pass)
+			if err != nil {
+				return fmt.Errorf("unlock vault %s: %w", dir, err)
+			}
+			cmd.Printf("Loaded %s\n", did)
+		}
+
+		path, err := resolveAgentSocket()
+		if err != nil {
+			return err
+		}
+		l, err := agent.ListenUnix(path)
+		if err != nil {
+			return err
+		}
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigs
+			srv.Close()
+		}()
+
+		cmd.Printf("Agent listening on %s\n", path)
+		return srv.Serve(l)
+	},
+}
+
+var agentStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the DIDs held by the running agent",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := dialAgent()
+		if err != nil {
+			return err
+		}
+		dids, locked, err := c.List()
+		if err != nil {
+			return err
+		}
+		if locked {
+			cmd.Println("Agent is locked")
+		} else {
+			cmd.Println("Agent is unlocked")
+		}
+		for _, did := range dids {
+			cmd.Println(did)
+		}
+		return nil
+	},
+}
+
+var agentLockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Wipe the agent's keys from memory",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := dialAgent()
+		if err != nil {
+			return err
+		}
+		if err := c.Lock(); err != nil {
+			return err
+		}
+		cmd.Println("Agent locked")
+		return nil
+	},
+}
+
+var agentUnlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Reload the agent's vault keys",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := dialAgent()
+		if err != nil {
+			return err
+		}
+		pass, err := readPassphrase(cmd, passphraseFile, passphraseEnv, "Vault passphrase: ")
+		if err != nil {
+			return err
+		}
+		if err := c.Unlock(pass); err != nil {
+			return err
+		}
+		cmd.Println("Agent unlocked")
+		return nil
+	},
+}
+
+var agentStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the running agent",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := dialAgent()
+		if err != nil {
+			return err
+		}
+		if err := c.Stop(); err != nil {
+			return err
+		}
+		cmd.Println("Agent stopped")
+		return nil
+	},
+}
+
+func resolveAgentSocket() (string, error) {
+	if agentSocket != "" {
+		return agentSocket, nil
+	}
+	return agent.DefaultSocketPath()
+}
+
+// dialAgent connects to the agent or fails if none is running.
+func dialAgent() (*agent.Client, error) {
+	path, err := resolveAgentSocket()
+	if err != nil {
+		return nil, err
+	}
+	c, err := agent.Dial(path)
+	if err != nil {
+		return nil, fmt.Errorf("no agent running on %s", path)
+	}
+	return c, nil
+}
+
+// runningAgent returns a client for a running agent, or nil when none is listening.
+func runningAgent() *agent.Client {
+	c, err := dialAgent()
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+// confirmOnTTY asks on the agent's controlling terminal before signing.
+func confirmOnTTY(req *agent.Request) bool {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	defer tty.Close()
+	fmt.Fprintf(tty, "Allow %s for %s? [y/N] ", req.Op, req.DID)
+	answer, _ := bufio.NewReader(tty).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.AddCommand(agentStatusCmd, agentLockCmd, agentUnlockCmd, agentStopCmd)
+	agentCmd.PersistentFlags().StringVar(&agentSocket, "socket", "", "Agent socket path (default $EGO_AGENT_SOCK or ~/.ego/agent.sock)")
+	agentCmd.Flags().StringSliceVar(&agentVaults, "vault", nil, "Vault directory to load; can be repeated (default: active vault)")
+	agentCmd.Flags().DurationVar(&agentIdleTimeout, "idle-timeout", 15*time.Minute, "Lock after this long without requests (0 disables)")
+	agentCmd.Flags().BoolVar(&agentConfirm, "confirm", false, "Ask on the terminal before every signing request")
+}
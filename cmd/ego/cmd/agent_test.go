@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/agent"
+	"github.com/juanpablocruz/minervaid/internal/resolver"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+)
+
+func TestIssueThroughAgent(t *testing.T) {
+	tmp := t.TempDir()
+	rootCmd.SetArgs([]string{"init", "--name", "agent", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	rootCmd.SetArgs([]string{"set", "name", "Ada", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+
+	// Start an agent holding the vault key, then remove keystore.json so only the agent can sign
+	srv := agent.NewServer(agent.Options{})
+	if _, err := srv.AddVault(vault.NewVault(identityPath(tmp)), []byte(testPassphrase)); err != nil {
+		t.Fatalf("AddVault: %v", err)
+	}
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := agent.ListenUnix(sock)
+	if err != nil {
+		t.Fatalf("ListenUnix: %v", err)
+	}
+	go srv.Serve(l)
+	defer srv.Close()
+	t.Setenv(agent.SocketEnv, sock)
+	if err := os.Remove(identityPath(tmp, "keystore.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	rootCmd.SetArgs([]string{"issue", "--out", tmp, "--id", "viaAgent"})
+	if err := Execute(); err != nil {
+		t.Fatalf("issue through agent failed: %v", err)
+	}
+	if _, err := os.Stat(identityPath(tmp, "credentials", "viaAgent.json")); err != nil {
+		t.Errorf("credential not written: %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"present", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatalf("present through agent failed: %v", err)
+	}
+
+	// a deactivated DID issues nothing, even with its key held by the agent
+	if err := vault.NewVault(identityPath(tmp)).Deactivate(); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"issue", "--out", tmp, "--id", "afterDeactivate"})
+	if err := Execute(); !errors.Is(err, resolver.ErrDeactivated) {
+		t.Errorf("issue through agent on a deactivated DID: %v", err)
+	}
+	if _, err := os.Stat(identityPath(tmp, "credentials", "afterDeactivate.json")); !os.IsNotExist(err) {
+		t.Error("credential written for a deactivated DID")
+	}
+}
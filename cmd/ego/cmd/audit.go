@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/audit"
+	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+// auditCmd groups commands for the vault's audit log
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the vault's tamper-evident audit log",
+	Long: `Every issue, present, revoke, set, key change and auth response appends a signed,
+hash-chained entry to audit.log in the vault. Entries record the operation, digests of
+its inputs and output, and when it happened. They are signed with the vault key, so
+recording one needs the passphrase or a running agent, and 'ego audit verify' checks
+them against did.json.`,
+}
+
+// auditListCmd prints the audit log entries
+var auditListCmd = &cobra.Command{
+	Use:   "list [--out <vaultDir>]",
+	Short: "List audit log entries",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vaultDir, err := auditVaultDir()
+		if err != nil {
+			return err
+		}
+		entries, err := audit.NewLog(vaultDir).Entries()
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			cmd.Printf("%d\t%s\t%-12s\tin=%s\tout=%s\n", e.Seq, e.Time.Format(time.RFC3339), e.Op, e.Inputs[:16], e.Output[:16])
+		}
+		return nil
+	},
+}
+
+// auditVerifyCmd checks the audit log's hash chain, signatures and head
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify [--out <vaultDir>]",
+	Short: "Verify that the audit log has not been edited or truncated",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vaultDir, err := auditVaultDir()
+		if err != nil {
+			return err
+		}
+		doc, err := vault.NewVault(vaultDir).Document()
+		if err != nil {
+			return err
+		}
+		entries, err := audit.NewLog(vaultDir).Verify(doc)
+		if errors.Is(err, audit.ErrNoLog) {
+			cmd.Println("No audit log yet; it starts with the next recorded operation")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		cmd.Printf("Audit log OK: %d entries\n", len(entries))
+		return nil
+	},
+}
+
+func auditVaultDir() (string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", err
+	}
+	rootDir := cfg.RootDir
+	if rootDir == "" {
+		rootDir = "store"
+	}
+	vaultDir := altVaultDir
+	if vaultDir == "" {
+		vaultDir = filepath.Join(rootDir, cfg.Active)
+	}
+	if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+		return "", err
+	}
+	return vaultDir, nil
+}
+
+// recordAudit appends op to the audit log of the vault of keys, signed with its current
+// key. inputs is marshalled to JSON before hashing; output is hashed as is.
+func recordAudit(keys *vaultKeys, op string, inputs interface{}, output []byte) error {
+	in, err := json.Marshal(inputs)
+	if err != nil {
+		return err
+	}
+	s, err := keys.Signer()
+	if err != nil {
+		return err
+	}
+	doc, err := keys.vault.Document()
+	if err != nil {
+		return err
+	}
+	vm, err := doc.CurrentMethod()
+	if err != nil {
+		return err
+	}
+	if pub, err := vm.PublicKey(); err != nil || !pub.Equal(s.PublicKey()) {
+		return fmt.Errorf("audit log: signing key does not match %s", vm.ID)
+	}
+	if _, err := audit.NewLog(keys.vault.BaseDir).Append(keys.cmd.Context(), s, vm.ID, op, in, output); err != nil {
+		return fmt.Errorf("append audit log: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditListCmd, auditVerifyCmd)
+	auditCmd.PersistentFlags().StringVar(&altVaultDir, "out", "", "Vault directory (optional, uses active)")
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	tmp := t.TempDir()
+	altVaultDir = ""
+	for _, args := range [][]string{
+		{"init", "--name", "audited", "--out", tmp},
+		{"set", "name", "Ada", "--out", tmp},
+		{"issue", "--out", tmp, "--id", "vc1"},
+		{"present", "--out", tmp},
+		{"revoke", "vc1", "--out", tmp},
+	} {
+		rootCmd.SetArgs(args)
+		if err := Execute(); err != nil {
+			t.Fatalf("%s failed: %v", args[0], err)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"audit", "list", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatalf("audit list failed: %v", err)
+	}
+	var ops []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		ops = append(ops, strings.TrimSpace(strings.Split(line, "\t")[2]))
+	}
+	if got := strings.Join(ops, ","); got != "init,set,issue,present,revoke" {
+		t.Errorf("audit ops = %s", got)
+	}
+
+	buf.Reset()
+	rootCmd.SetArgs([]string{"audit", "verify", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatalf("audit verify failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Audit log OK: 5 entries") {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+
+	// rewriting history is caught
+	logFile := identityPath(tmp, "audit.log")
+	data, _ := os.ReadFile(logFile)
+	os.WriteFile(logFile, bytes.Replace(data, []byte(`"op":"revoke"`), []byte(`"op":"issue"`), 1), 0600)
+	rootCmd.SetArgs([]string{"audit", "verify", "--out", tmp})
+	if err := Execute(); err == nil || !strings.Contains(err.Error(), "entry 5 was modified") {
+		t.Errorf("expected a modified entry error, got %v", err)
+	}
+
+	// a hand-edited digest is rejected rather than listed
+	first, rest, _ := bytes.Cut(data, []byte("\n"))
+	var e map[string]interface{}
+	json.Unmarshal(first, &e)
+	e["inputs"] = "abc"
+	first, _ = json.Marshal(e)
+	os.WriteFile(logFile, append(append(first, '\n'), rest...), 0600)
+	rootCmd.SetArgs([]string{"audit", "list", "--out", tmp})
+	if err := Execute(); err == nil || !strings.Contains(err.Error(), "malformed digest") {
+		t.Errorf("expected a malformed digest error, got %v", err)
+	}
+
+	// a vault without a log, as created before it was kept, starts one on its next operation
+	for _, f := range []string{"audit.log", "audit.head"} {
+		os.Remove(identityPath(tmp, f))
+	}
+	buf.Reset()
+	rootCmd.SetArgs([]string{"audit", "verify", "--out", tmp})
+	if err := Execute(); err != nil || !strings.Contains(buf.String(), "No audit log yet") {
+		t.Errorf("missing log: %v, %s", err, buf.String())
+	}
+	rootCmd.SetArgs([]string{"set", "name", "Grace", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	rootCmd.SetArgs([]string{"audit", "verify", "--out", tmp})
+	if err := Execute(); err != nil || !strings.Contains(buf.String(), "Audit log OK: 1 entries") {
+		t.Errorf("new log: %v, %s", err, buf.String())
+	}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+var challengeFile string
+
+// authRespondCmd signs an authentication challenge with the vault's DID
+var authRespondCmd = &cobra.Command{
+	Use:   "auth-respond --file <challenge.json> [--out <vaultDir>]",
+	Short: "Respond to an authentication challenge",
+	Long: `Sign an authentication challenge. When the challenge names a domain the response is
+signed by the vault's pairwise DID for that domain unless --no-pairwise is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(challengeFile)
+		if err != nil {
+			return fmt.Errorf("read challenge: %w", err)
+		}
+		var ch credentials.AuthenticationChallenge
+		if err := json.Unmarshal(data, &ch); err != nil {
+			return fmt.Errorf("invalid challenge JSON: %w", err)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		rootDir := cfg.RootDir
+		if rootDir == "" {
+			rootDir = "store"
+		}
+		vaultDir := altVaultDir
+		if vaultDir == "" {
+			vaultDir = filepath.Join(rootDir, cfg.Active)
+		}
+		if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+			return err
+		}
+
+		v := vault.NewVault(vaultDir)
+		did, err := v.DID()
+		if err != nil {
+			return fmt.Errorf("load vault: %w", err)
+		}
+
+		// Answer as the pairwise DID for the challenge's domain
+		domain := ""
+		if ch.Domain != "" && !noPairwise {
+			if domain = vault.NormalizeDomain(ch.Domain); domain == "" {
+				return fmt.Errorf("challenge domain %s names no relying party", ch.Domain)
+			}
+		}
+
+		// Sign through the agent when it is running
+		var resp *credentials.AuthenticationResponse
+		keys := newVaultKeys(cmd, v)
+		defer keys.Close()
+		if keys.agent != nil {
+			if resp, err = keys.agent.RespondAuthChallenge(did, domain, &ch); err != nil {
+				return fmt.Errorf("sign challenge via agent: %w", err)
+			}
+		} else {
+			var s signer.Signer
+			var vm string
+			if domain != "" {
+				s, _, vm, err = keys.Pairwise(domain)
+			} else {
+				s, vm, err = keys.SignerFor(identity.PurposeAuthentication)
+			}
+			if err != nil {
+				return err
+			}
+			if resp, err = credentials.SignAuthChallenge(cmd.Context(), vm, &ch, s); err != nil {
+				return fmt.Errorf("sign challenge: %w", err)
+			}
+		}
+		if domain != "" {
+			holder, _, _ := strings.Cut(resp.Proof.VerificationMethod, "#")
+			if err := recordPairwise(cmd, v, domain, holder); err != nil {
+				return err
+			}
+		}
+
+		out, err := json.MarshalIndent(resp, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal response: %w", err)
+		}
+		if err := recordAudit(keys, "auth-respond", &ch, out); err != nil {
+			return err
+		}
+		cmd.Println(string(out))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(authRespondCmd)
+	authRespondCmd.Flags().StringVar(&challengeFile, "file", "", "Path to challenge JSON (required)")
+	authRespondCmd.Flags().StringVar(&altVaultDir, "out", "", "Vault directory (optional, uses active)")
+	authRespondCmd.Flags().BoolVar(&noPairwise, "no-pairwise", false, "Sign with the vault DID instead of the pairwise DID for the challenge domain")
+	authRespondCmd.MarkFlagRequired("file")
+}
@@ -2,14 +2,19 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
 )
 
 func TestAuthRequestCommand(t *testing.T) {
@@ -63,59 +68,92 @@ func TestAuthRequestCommand(t *testing.T) {
 }
 
 func TestAuthVerifyCommand(t *testing.T) {
-	// 1. Initialize vault
+	t.Cleanup(func() {
+		challengeFile, authResponseFile, idTokenFile, authVerifyNonce, authVerifyClient = "", "", "", "", ""
+	})
 	tmp := t.TempDir()
 	rootCmd.SetArgs([]string{"init", "--name", "vault", "--out", tmp})
 	if err := Execute(); err != nil {
 		t.Fatalf("init failed: %v", err)
 	}
-	// 2. Create a credential and presentation
-	rootCmd.SetArgs([]string{"set", "age", "42", "--out", tmp})
-	Execute()
-	rootCmd.SetArgs([]string{"issue", "--out", tmp, "--id", "vc1"})
-	Execute()
-	rootCmd.SetArgs([]string{"present", "--out", tmp})
-	Execute()
-	// 3. Load the generated presentation
-	presDir := filepath.Join(tmp, "presentations")
-	files, err := os.ReadDir(presDir)
-	if err != nil || len(files) == 0 {
-		t.Fatalf("no presentation generated: %v", err)
-	}
-	presBytes, err := os.ReadFile(filepath.Join(presDir, files[0].Name()))
-	if err != nil {
-		t.Fatalf("read presentation failed: %v", err)
-	}
-	var pres interface{}
-	if err := json.Unmarshal(presBytes, &pres); err != nil {
-		t.Fatalf("invalid presentation JSON: %v", err)
-	}
-
-	// 4. Build a dummy JWT with alg=none, embed VP and iss
-	payload := map[string]interface{}{
-		"iss": "did:key:zDUMMY",
-		"vp":  pres,
-	}
-	plBytes, _ := json.Marshal(payload)
-	b64pl := base64.RawURLEncoding.EncodeToString(plBytes)
-	head := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
-	jwt := fmt.Sprintf("%s.%s.", head, b64pl)
-
-	jwtFile := filepath.Join(tmp, "token.jwt")
-	if err := os.WriteFile(jwtFile, []byte(jwt), 0600); err != nil {
-		t.Fatalf("write id_token file: %v", err)
+	writeJSON := func(name string, v interface{}) string {
+		data, _ := json.Marshal(v)
+		file := filepath.Join(tmp, name)
+		if err := os.WriteFile(file, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return file
 	}
 
-	// 5. Run auth-verify
+	// the holder answers the challenge with its pairwise DID for the domain
+	ch := credentials.NewAuthChallenge("rp.example", time.Minute)
+	chFile := writeJSON("challenge.json", ch)
 	buf := new(bytes.Buffer)
 	rootCmd.SetOut(buf)
-	rootCmd.SetErr(buf)
-	rootCmd.SetArgs([]string{"auth-verify", "--id-token", jwtFile})
+	rootCmd.SetArgs([]string{"auth-respond", "--file", chFile, "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatalf("auth-respond failed: %v", err)
+	}
+	var resp credentials.AuthenticationResponse
+	if err := json.Unmarshal(buf.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %s: %v", buf, err)
+	}
+	respFile := writeJSON("response.json", &resp)
+	holder, _, _ := strings.Cut(resp.Proof.VerificationMethod, "#")
+
+	buf.Reset()
+	rootCmd.SetArgs([]string{"auth-verify", "--response", respFile, "--file", chFile})
 	if err := Execute(); err != nil {
 		t.Fatalf("auth-verify failed: %v", err)
 	}
-	out := buf.String()
-	if !strings.Contains(out, "Authentication successful for DID:") {
-		t.Errorf("unexpected output: %s", out)
+	if !strings.Contains(buf.String(), "Authentication successful for DID: "+holder) {
+		t.Errorf("unexpected output: %s", buf)
+	}
+
+	// a response to another challenge, or for another domain, is refused
+	otherDomain := *ch
+	otherDomain.Domain = "evil.example"
+	for name, issued := range map[string]*credentials.AuthenticationChallenge{
+		"other.json":  credentials.NewAuthChallenge("rp.example", time.Minute),
+		"domain.json": &otherDomain,
+	} {
+		rootCmd.SetArgs([]string{"auth-verify", "--response", respFile, "--file", writeJSON(name, issued)})
+		if err := Execute(); err == nil {
+			t.Errorf("auth-verify accepted a response to %s", name)
+		}
+	}
+
+	// an id_token must be signed by its subject for the nonce and client of the request
+	authResponseFile, challengeFile = "", ""
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+	idToken := func(alg string, claims map[string]interface{}) string {
+		h, _ := json.Marshal(map[string]string{"alg": alg, "kid": did + "#keys-1"})
+		c, _ := json.Marshal(claims)
+		input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+		sig, _ := signer.NewMemory(priv).Sign(context.Background(), []byte(input))
+		file := filepath.Join(tmp, "token.jwt")
+		os.WriteFile(file, []byte(input+"."+base64.RawURLEncoding.EncodeToString(sig)), 0600)
+		return file
+	}
+	claims := map[string]interface{}{"iss": did, "sub": did, "aud": "cid123", "nonce": "n1", "exp": time.Now().Add(time.Minute).Unix()}
+	buf.Reset()
+	rootCmd.SetArgs([]string{"auth-verify", "--id-token", idToken("EdDSA", claims), "--nonce", "n1", "--client-id", "cid123"})
+	if err := Execute(); err != nil {
+		t.Fatalf("auth-verify --id-token failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Authentication successful for DID: "+did) {
+		t.Errorf("unexpected output: %s", buf)
+	}
+	for _, args := range [][]string{
+		{"--id-token", idToken("none", claims), "--nonce", "n1", "--client-id", "cid123"},
+		{"--id-token", idToken("EdDSA", claims), "--nonce", "n2", "--client-id", "cid123"},
+		{"--id-token", idToken("EdDSA", claims), "--nonce", "n1", "--client-id", "other"},
+		{"--id-token", idToken("EdDSA", map[string]interface{}{"iss": "did:key:zDUMMY", "sub": "did:key:zDUMMY", "aud": "cid123", "nonce": "n1", "exp": claims["exp"]}), "--nonce", "n1", "--client-id", "cid123"},
+	} {
+		rootCmd.SetArgs(append([]string{"auth-verify"}, args...))
+		if err := Execute(); err == nil {
+			t.Errorf("auth-verify accepted %v", args)
+		}
 	}
 }
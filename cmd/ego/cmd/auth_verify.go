@@ -1,63 +1,91 @@
 package cmd
 
 import (
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
-	"strings"
 
+	"github.com/juanpablocruz/minervaid/internal/credentials"
 	"github.com/spf13/cobra"
 )
 
-var idTokenFile string
-
-func base64URLDecode(s string) ([]byte, error) {
-	return base64.RawURLEncoding.DecodeString(s)
-}
+var (
+	idTokenFile      string
+	authResponseFile string
+	authVerifyNonce  string
+	authVerifyClient string
+)
 
-// authVerifyCmd verifies a JWT id_token and reports the authenticated DID
+// authVerifyCmd verifies an authentication response or id_token and reports the authenticated DID
 var authVerifyCmd = &cobra.Command{
-	Use:   "auth-verify --id-token <file>",
-	Short: "Verify OIDC4VP id_token JWT and extract DID",
-	Args:  cobra.NoArgs,
+	Use:   "auth-verify (--response <file> --file <challenge.json> | --id-token <file> --nonce <nonce> --client-id <id>)",
+	Short: "Verify an authentication response or OIDC4VP id_token and extract the DID",
+	Long: `Verify that a holder answered a challenge this verifier sent and print the DID they
+authenticated as.
+
+With --response, the response printed by 'ego auth-respond' must answer the challenge in
+--file: same nonce and domain, not expired, and signed by an authentication method of
+the responding DID. With --id-token, the self-issued id_token must be signed by an
+authentication method of its subject DID, carry --nonce and be addressed to --client-id,
+as sent in 'ego auth-request'.`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Read JWT from file
-		data, err := os.ReadFile(idTokenFile)
+		resolve, err := didDocResolver()
 		if err != nil {
 			return err
 		}
-		token := strings.TrimSpace(string(data))
-		parts := strings.Split(token, ".")
-		if len(parts) != 3 {
-			return fmt.Errorf("invalid JWT format")
-		}
-
-		// Decode payload
-		payload, err := base64URLDecode(parts[1])
-		if err != nil {
-			return fmt.Errorf("decode JWT payload: %w", err)
+		var did string
+		switch {
+		case authResponseFile != "" && idTokenFile == "":
+			if challengeFile == "" {
+				return fmt.Errorf("--response needs --file, the challenge that was sent")
+			}
+			var issued credentials.AuthenticationChallenge
+			if err := readJSONFile(challengeFile, &issued); err != nil {
+				return fmt.Errorf("read challenge: %w", err)
+			}
+			var resp credentials.AuthenticationResponse
+			if err := readJSONFile(authResponseFile, &resp); err != nil {
+				return fmt.Errorf("read response: %w", err)
+			}
+			if did, err = credentials.VerifyAuthResponse(&resp, &issued, resolve); err != nil {
+				return err
+			}
+		case idTokenFile != "" && authResponseFile == "":
+			if authVerifyNonce == "" || authVerifyClient == "" {
+				return fmt.Errorf("--id-token needs the --nonce and --client-id of the request")
+			}
+			token, err := os.ReadFile(idTokenFile)
+			if err != nil {
+				return err
+			}
+			if did, err = credentials.VerifyIDToken(string(token), authVerifyNonce, authVerifyClient, resolve); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("give either --response or --id-token")
 		}
 
-		// Parse claims
-		var claims map[string]interface{}
-		if err := json.Unmarshal(payload, &claims); err != nil {
-			return fmt.Errorf("invalid JWT claims: %w", err)
-		}
-
-		// Extract issuer (DID)
-		iss, ok := claims["iss"].(string)
-		if !ok || iss == "" {
-			return fmt.Errorf("iss claim missing or invalid")
-		}
-
-		cmd.Printf("Authentication successful for DID: %s\n", iss)
+		cmd.Printf("Authentication successful for DID: %s\n", did)
 		return nil
 	},
 }
 
+// readJSONFile unmarshals the JSON in file into v.
+func readJSONFile(file string, v interface{}) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
 func init() {
-	authVerifyCmd.Flags().StringVar(&idTokenFile, "id-token", "", "Path to file containing the JWT id_token (required)")
-	authVerifyCmd.MarkFlagRequired("id-token")
+	authVerifyCmd.Flags().StringVar(&authResponseFile, "response", "", "Path to the authentication response JSON")
+	authVerifyCmd.Flags().StringVar(&challengeFile, "file", "", "Path to the challenge JSON the response must answer")
+	authVerifyCmd.Flags().StringVar(&idTokenFile, "id-token", "", "Path to file containing the JWT id_token")
+	authVerifyCmd.Flags().StringVar(&authVerifyNonce, "nonce", "", "Nonce of the request the id_token answers")
+	authVerifyCmd.Flags().StringVar(&authVerifyClient, "client-id", "", "Client ID the id_token must be addressed to")
+	authVerifyCmd.Flags().StringVar(&didDocFile, "did-doc", "", "DID document or resolution result of the holder, instead of resolving it")
 	rootCmd.AddCommand(authVerifyCmd)
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/juanpablocruz/minervaid/internal/config"
 	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/vault"
 	"github.com/spf13/cobra"
 )
 
@@ -29,6 +30,33 @@ var checkRevokedCmd = &cobra.Command{
 		if vaultDir == "" {
 			vaultDir = filepath.Join(rootDir, cfg.Active)
 		}
+		if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+			return err
+		}
+		lists := &credentials.StatusLists{Dir: filepath.Join(vaultDir, "status")}
+		hasStatus, err := lists.Has(credID)
+		if err != nil {
+			return fmt.Errorf("load status lists: %w", err)
+		}
+		if hasStatus {
+			revoked, err := lists.Get(credID, credentials.StatusPurposeRevocation)
+			if err != nil {
+				return err
+			}
+			suspended, err := lists.Get(credID, credentials.StatusPurposeSuspension)
+			if err != nil {
+				return err
+			}
+			switch {
+			case revoked:
+				cmd.Printf("Credential '%s' is revoked", credID)
+			case suspended:
+				cmd.Printf("Credential '%s' is suspended", credID)
+			default:
+				cmd.Printf("Credential '%s' is not revoked", credID)
+			}
+			return nil
+		}
 		// Load revocation list
 		rl, err := credentials.NewRevocationList(filepath.Join(vaultDir, "revocations.json"))
 		if err != nil {
@@ -3,7 +3,6 @@ package cmd
 import (
 	"bytes"
 	"os"
-	"path/filepath"
 	"testing"
 )
 
@@ -15,7 +14,7 @@ func TestCheckRevokedCommand(t *testing.T) {
 		t.Fatal(err)
 	}
 	// prepare revocation list
-	rlPath := filepath.Join(tmp, "revocations.json")
+	rlPath := identityPath(tmp, "revocations.json")
 	os.WriteFile(rlPath, []byte(`["x1"]`), 0600)
 	// check revoked
 	buf := &bytes.Buffer{}
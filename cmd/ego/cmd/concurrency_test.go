@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/audit"
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+)
+
+func TestConcurrentProcesses(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", t.TempDir())
+	rootCmd.SetArgs([]string{"init", "--name", "busy", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+
+	const n = 8
+	var procs []*exec.Cmd
+	for i := 0; i < n; i++ {
+		procs = append(procs,
+			egoProcess(t, "set", fmt.Sprintf("attr%d", i), "v", "--out", tmp),
+			egoProcess(t, "revoke", fmt.Sprintf("vc%d", i), "--out", tmp))
+	}
+	for _, p := range procs {
+		if err := p.Start(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range procs {
+		if err := p.Wait(); err != nil {
+			t.Errorf("%v: %v", p.Env[len(p.Env)-1], err)
+		}
+	}
+
+	data, err := os.ReadFile(identityPath(tmp, "attributes.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attrs map[string]interface{}
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		t.Fatalf("attributes.json corrupted: %v", err)
+	}
+	if len(attrs) != n {
+		t.Errorf("attributes.json has %d attributes; want %d", len(attrs), n)
+	}
+	rl, err := credentials.NewRevocationList(identityPath(tmp, "revocations.json"))
+	if err != nil {
+		t.Fatalf("revocations.json corrupted: %v", err)
+	}
+	if got := len(rl.List()); got != n {
+		t.Errorf("%d revocations recorded; want %d", got, n)
+	}
+	doc, err := vault.NewVault(identityPath(tmp)).Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := audit.NewLog(identityPath(tmp)).Verify(doc)
+	if err != nil {
+		t.Fatalf("audit log: %v", err)
+	}
+	if len(entries) != 2*n+1 {
+		t.Errorf("audit log has %d entries; want %d", len(entries), 2*n+1)
+	}
+}
@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"path/filepath"
+
 	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/vault"
 	"github.com/spf13/cobra"
 )
 
@@ -15,9 +18,17 @@ var currentCmd = &cobra.Command{
 		}
 		if cfg.Active == "" {
 			cmd.Println("No active identity. Use 'ego use <name>' or run 'ego init'")
-		} else {
-			cmd.Printf("Active identity: %s\n", cfg.Active)
+			return nil
+		}
+		rootDir := cfg.RootDir
+		if rootDir == "" {
+			rootDir = "store"
+		}
+		ix, err := vault.OpenIndex(filepath.Join(rootDir, cfg.Active))
+		if err != nil {
+			return err
 		}
+		cmd.Printf("Active identity: %s (vault %s)\n", ix.Active, cfg.Active)
 		return nil
 	},
 }
@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/resolver"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+var (
+	didShowVersion  string
+	didShowMetadata bool
+	serviceID       string
+	serviceType     string
+	serviceEndpoint string
+	keyPurpose      string
+)
+
+// didCmd groups commands that edit the DID document of the active identity
+var didCmd = &cobra.Command{
+	Use:   "did",
+	Short: "Show and edit the DID document of the active identity",
+	Long: `Every change to did.json is a new version: the versionId is increased, the
+updated time is recorded and the previous document is kept in did-versions/.
+Only did:web documents can be edited; did:key and did:peer documents are derived
+from the DID itself. Republish did.json with 'ego remote export web' after a change.`,
+}
+
+var didShowCmd = &cobra.Command{
+	Use:   "show [--version <n>] [--metadata]",
+	Short: "Print the DID document, or one of its earlier versions",
+	Long: `Print did.json. With --metadata the output is a DID resolution result with the
+document metadata (created, updated, versionId, deactivated), which 'ego verify
+--did-doc' also accepts.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		v, _, err := didVault()
+		if err != nil {
+			return err
+		}
+		meta, err := v.Metadata()
+		if err != nil {
+			return err
+		}
+		version := didShowVersion
+		if version == "" {
+			version = meta.VersionID
+		}
+		doc, err := v.DocumentVersion(version)
+		if err != nil {
+			return err
+		}
+		var out interface{} = doc
+		if didShowMetadata {
+			if version != meta.VersionID {
+				// earlier versions are neither deactivated nor the latest update
+				meta = resolver.DocumentMetadata{Created: meta.Created, VersionID: version}
+			}
+			out = &resolver.Resolution{
+				Document:           doc,
+				ResolutionMetadata: resolver.ResolutionMetadata{ContentType: "application/did+json", Retrieved: time.Now().UTC()},
+				DocumentMetadata:   meta,
+			}
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		cmd.Println(string(data))
+		return nil
+	},
+}
+
+var didAddServiceCmd = &cobra.Command{
+	Use:   "add-service --id <#fragment> --type <type> --endpoint <url>",
+	Short: "Add a service endpoint to the DID document",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc := identity.Service{ID: serviceID, Type: serviceType, ServiceEndpoint: serviceEndpoint}
+		return editDocument(cmd, "did-add-service", svc, func(doc *identity.Document) (string, error) {
+			if err := doc.AddService(svc); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Service %s added", doc.Service[len(doc.Service)-1].ID), nil
+		})
+	},
+}
+
+var didRemoveServiceCmd = &cobra.Command{
+	Use:   "remove-service <id>",
+	Short: "Remove a service endpoint from the DID document",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return editDocument(cmd, "did-remove-service", map[string]string{"id": args[0]}, func(doc *identity.Document) (string, error) {
+			if err := doc.RemoveService(args[0]); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Service %s removed", args[0]), nil
+		})
+	},
+}
+
+var didAddControllerCmd = &cobra.Command{
+	Use:   "add-controller <did>",
+	Short: "Let another DID control the DID document",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return editDocument(cmd, "did-add-controller", map[string]string{"controller": args[0]}, func(doc *identity.Document) (string, error) {
+			if err := doc.AddController(args[0]); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Controllers: %s", strings.Join(doc.Controller, ", ")), nil
+		})
+	},
+}
+
+var didAddKeyCmd = &cobra.Command{
+	Use:   "add-key <multikey|did:key> --purpose assertionMethod|authentication|keyAgreement",
+	Short: "Add a public key to the DID document for one verification relationship",
+	Long: `Add a public key held elsewhere, such as on another device, as a verification
+method for --purpose. The key is given as a multikey (z6Mk..., or z6LS... for an X25519
+keyAgreement key) or as a did:key. The vault keeps signing with its own key.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		multikey := strings.TrimPrefix(args[0], "did:key:")
+		inputs := map[string]string{"key": multikey, "purpose": keyPurpose}
+		return editDocument(cmd, "did-add-key", inputs, func(doc *identity.Document) (string, error) {
+			vmID, err := doc.AddKey(multikey, keyPurpose, time.Now())
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Key %s added for %s", vmID, keyPurpose), nil
+		})
+	},
+}
+
+var didDeactivateCmd = &cobra.Command{
+	Use:   "deactivate",
+	Short: "Deactivate the DID; this cannot be undone",
+	Long: `Mark the DID as deactivated. Verifiers refuse proofs by a deactivated DID, the
+identity can no longer sign and its document can no longer be edited. For did:web,
+remove the hosted did.json or have the server answer 410 Gone.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		v, _, err := didVault()
+		if err != nil {
+			return err
+		}
+		doc, err := editableDocument(v)
+		if err != nil {
+			return err
+		}
+		// a deactivated vault can no longer be unlocked, so do it first
+		keys := newVaultKeys(cmd, v)
+		defer keys.Close()
+		if _, err := keys.Signer(); err != nil {
+			return err
+		}
+		if err := v.Deactivate(); err != nil {
+			return err
+		}
+		meta, err := v.Metadata()
+		if err != nil {
+			return err
+		}
+		out, _ := json.Marshal(meta)
+		if err := recordAudit(keys, "did-deactivate", map[string]string{"did": doc.ID}, out); err != nil {
+			return err
+		}
+		url, _ := resolver.WebURL(doc.ID)
+		cmd.Printf("%s deactivated at version %s\nRemove %s or serve it with status 410 Gone\n", doc.ID, meta.VersionID, url)
+		return nil
+	},
+}
+
+// editDocument applies edit to the did:web document of the active identity as a new
+// version, records op in the audit log and prints edit's message.
+func editDocument(cmd *cobra.Command, op string, inputs interface{}, edit func(doc *identity.Document) (string, error)) error {
+	v, _, err := didVault()
+	if err != nil {
+		return err
+	}
+	if _, err := editableDocument(v); err != nil {
+		return err
+	}
+	keys := newVaultKeys(cmd, v)
+	defer keys.Close()
+	if _, err := keys.Signer(); err != nil {
+		return err
+	}
+	var msg string
+	var data []byte
+	err = v.UpdateDocument(func(doc *identity.Document) error {
+		if msg, err = edit(doc); err != nil {
+			return err
+		}
+		data, err = doc.Marshal()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := recordAudit(keys, op, inputs, data); err != nil {
+		return err
+	}
+	meta, err := v.Metadata()
+	if err != nil {
+		return err
+	}
+	cmd.Printf("%s; did.json is now version %s\n", msg, meta.VersionID)
+	return nil
+}
+
+// editableDocument returns the document of v if it is a did:web document.
+func editableDocument(v *vault.Vault) (*identity.Document, error) {
+	doc, err := v.Document()
+	if err != nil {
+		return nil, fmt.Errorf("load vault: %w", err)
+	}
+	if !strings.HasPrefix(doc.ID, "did:web:") {
+		return nil, fmt.Errorf("%s is derived from its identifier and cannot be edited; use a did:web identity", doc.ID)
+	}
+	return doc, nil
+}
+
+func didVault() (*vault.Vault, string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, "", err
+	}
+	rootDir := cfg.RootDir
+	if rootDir == "" {
+		rootDir = "store"
+	}
+	vaultDir := altVaultDir
+	if vaultDir == "" {
+		vaultDir = filepath.Join(rootDir, cfg.Active)
+	}
+	if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+		return nil, "", err
+	}
+	return vault.NewVault(vaultDir), vaultDir, nil
+}
+
+func init() {
+	rootCmd.AddCommand(didCmd)
+	didCmd.AddCommand(didShowCmd, didAddServiceCmd, didRemoveServiceCmd, didAddControllerCmd, didAddKeyCmd, didDeactivateCmd)
+	didCmd.PersistentFlags().StringVar(&altVaultDir, "out", "", "Vault directory (optional, uses active)")
+	didShowCmd.Flags().StringVar(&didShowVersion, "version", "", "Version to print (default: current)")
+	didShowCmd.Flags().BoolVar(&didShowMetadata, "metadata", false, "Print the document metadata too")
+	didAddServiceCmd.Flags().StringVar(&serviceID, "id", "", "Service id, such as #messaging (required)")
+	didAddServiceCmd.Flags().StringVar(&serviceType, "type", "", "Service type, such as DIDCommMessaging or LinkedDomains (required)")
+	didAddServiceCmd.Flags().StringVar(&serviceEndpoint, "endpoint", "", "Service endpoint URL (required)")
+	didAddServiceCmd.MarkFlagRequired("id")
+	didAddServiceCmd.MarkFlagRequired("type")
+	didAddServiceCmd.MarkFlagRequired("endpoint")
+	didAddKeyCmd.Flags().StringVar(&keyPurpose, "purpose", "", "assertionMethod, authentication or keyAgreement (required)")
+	didAddKeyCmd.MarkFlagRequired("purpose")
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/resolver"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+)
+
+func TestDIDDocumentManagement(t *testing.T) {
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "web")
+	altVaultDir, vaultDir = "", ""
+	t.Cleanup(func() {
+		webDomain, altVaultDir, didDocFile = "", "", ""
+		serviceID, serviceType, serviceEndpoint, keyPurpose = "", "", "", ""
+		didShowVersion, didShowMetadata = "", false
+	})
+	device, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	deviceDID := identity.GenerateDIDForKey(device.Public())
+	for _, args := range [][]string{
+		{"init", "--name", "web", "--web", "example.com", "--out", dir},
+		{"did", "add-service", "--id", "#messaging", "--type", identity.DIDCommMessaging, "--endpoint", "https://example.com/didcomm", "--out", dir},
+		{"did", "add-key", deviceDID, "--purpose", "assertionMethod", "--out", dir},
+		{"did", "add-controller", "did:web:admin.example", "--out", dir},
+		{"did", "remove-service", "#messaging", "--out", dir},
+		{"set", "name", "Ada", "--out", dir},
+		{"issue", "--id", "vc1", "--out", dir},
+	} {
+		rootCmd.SetArgs(args)
+		if err := Execute(); err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+	}
+	webDomain = ""
+
+	v := vault.NewVault(identityPath(dir))
+	doc, _ := v.Document()
+	if len(doc.Service) != 0 || len(doc.AssertionMethod) != 2 || len(doc.Controller) != 2 {
+		t.Errorf("unexpected document %+v", doc)
+	}
+	if meta, _ := v.Metadata(); meta.VersionID != "5" {
+		t.Errorf("versionId = %s; want 5 after four edits", meta.VersionID)
+	}
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"did", "show", "--version", "2", "--out", dir})
+	if err := Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if old, err := identity.ParseDIDDocument(buf.Bytes()); err != nil || len(old.Service) != 1 {
+		t.Errorf("version 2 should hold the service: %v", err)
+	}
+	didShowVersion = ""
+
+	rootCmd.SetArgs([]string{"did", "deactivate", "--out", dir})
+	if err := Execute(); err != nil {
+		t.Fatalf("deactivate failed: %v", err)
+	}
+	for _, args := range [][]string{
+		{"issue", "--id", "vc2", "--out", dir},
+		{"did", "add-controller", "did:web:other.example", "--out", dir},
+	} {
+		rootCmd.SetArgs(args)
+		if err := Execute(); !errors.Is(err, resolver.ErrDeactivated) {
+			t.Errorf("%v on a deactivated DID: %v", args, err)
+		}
+	}
+
+	// the resolution result of 'did show --metadata' makes verifiers refuse the DID
+	buf.Reset()
+	rootCmd.SetArgs([]string{"did", "show", "--metadata", "--out", dir})
+	if err := Execute(); err != nil {
+		t.Fatal(err)
+	}
+	var res resolver.Resolution
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil || !res.DocumentMetadata.Deactivated {
+		t.Fatalf("unexpected resolution %s: %v", buf, err)
+	}
+	didDocFile = filepath.Join(tmp, "resolution.json")
+	os.WriteFile(didDocFile, buf.Bytes(), 0600)
+	resolve, err := didDocResolver()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolve(doc.ID); !errors.Is(err, resolver.ErrDeactivated) {
+		t.Errorf("expected ErrDeactivated, got %v", err)
+	}
+}
+
+func TestDIDEditRequiresWeb(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "key")
+	altVaultDir, vaultDir = "", ""
+	t.Cleanup(func() { altVaultDir = "" })
+	rootCmd.SetArgs([]string{"init", "--name", "key", "--out", dir})
+	if err := Execute(); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"did", "add-controller", "did:web:admin.example", "--out", dir})
+	if err := Execute(); err == nil {
+		t.Error("editing a did:key document must fail")
+	}
+}
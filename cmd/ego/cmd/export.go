@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleOut            string
+	bundleVaultName      string
+	bundlePassphraseFile string
+)
+
+// exportCmd writes the whole vault as one encrypted bundle
+var exportCmd = &cobra.Command{
+	Use:   "export --out <file.egovault> [--vault <name>]",
+	Short: "Export a vault as an encrypted bundle",
+	Long: `Pack did.json, keystore.json, attributes, credentials, presentations and
+revocations of a vault into a single archive encrypted with a bundle passphrase
+(--bundle-passphrase-file, $EGO_BUNDLE_PASSPHRASE or a prompt).
+Use 'ego import' on the other machine.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		rootDir := cfg.RootDir
+		if rootDir == "" {
+			rootDir = "store"
+		}
+		vaultName := bundleVaultName
+		if vaultName == "" {
+			vaultName = cfg.Active
+		}
+		if vaultName == "" {
+			return fmt.Errorf("no --vault given and no active vault; use 'ego use'")
+		}
+		v := vault.NewVault(filepath.Join(rootDir, vaultName))
+		idDir, err := vault.IdentityDir(v.BaseDir, "")
+		if err != nil {
+			return fmt.Errorf("load vault: %w", err)
+		}
+		if encrypted, err := vault.NewVault(idDir).IsEncrypted(); err != nil {
+			return fmt.Errorf("load vault: %w", err)
+		} else if !encrypted {
+			cmd.PrintErrln("Warning: keystore.json is not encrypted; anyone with the bundle passphrase can read the key")
+		}
+
+		pass, err := readNewPassphrase(cmd, bundlePassphraseFile, bundlePassphraseEnv)
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(bundleOut, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return fmt.Errorf("create bundle: %w", err)
+		}
+		m, err := v.Export(f, vaultName, pass)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(bundleOut)
+			return fmt.Errorf("export vault: %w", err)
+		}
+		cmd.Printf("Vault '%s' (%s, %d files) exported to %s\n", vaultName, m.DID, len(m.Files), bundleOut)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&bundleOut, "out", "", "Bundle file to create (required)")
+	exportCmd.Flags().StringVar(&bundleVaultName, "vault", "", "Name of the vault to export (default: active)")
+	exportCmd.Flags().StringVar(&bundlePassphraseFile, "bundle-passphrase-file", "", "File containing the bundle passphrase")
+	exportCmd.MarkFlagRequired("out")
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/config"
+)
+
+func TestExportImport(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+	t.Setenv(bundlePassphraseEnv, "bundle-secret")
+	root := filepath.Join(tmp, "vaults")
+
+	rootCmd.SetArgs([]string{"init", "--name", "alice", "--out", filepath.Join(root, "alice")})
+	if err := Execute(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	vaultDir, altVaultDir = "", ""
+	rootCmd.SetArgs([]string{"use", "alice", root})
+	if err := Execute(); err != nil {
+		t.Fatalf("use failed: %v", err)
+	}
+	rootCmd.SetArgs([]string{"set", "email", "alice@example.com"})
+	Execute()
+
+	bundle := filepath.Join(tmp, "alice.egovault")
+	rootCmd.SetArgs([]string{"export", "--out", bundle})
+	if err := Execute(); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	rootCmd.SetArgs([]string{"import", bundle, "--dry-run"})
+	if err := Execute(); err != nil {
+		t.Fatalf("import --dry-run failed: %v", err)
+	}
+	importDryRun = false
+	if !bytes.Contains(buf.Bytes(), []byte("already exists")) || !bytes.Contains(buf.Bytes(), []byte("attributes.json")) {
+		t.Errorf("unexpected dry-run output: %s", buf.String())
+	}
+
+	rootCmd.SetArgs([]string{"import", bundle})
+	if err := Execute(); err == nil {
+		t.Error("expected import to refuse overwriting an existing vault")
+	}
+
+	rootCmd.SetArgs([]string{"import", bundle, "--name", "alice2", "--use"})
+	if err := Execute(); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	importName, importUse = "", false
+
+	want, _ := os.ReadFile(identityPath(filepath.Join(root, "alice"), "attributes.json"))
+	got, err := os.ReadFile(identityPath(filepath.Join(root, "alice2"), "attributes.json"))
+	if err != nil || !bytes.Equal(got, want) {
+		t.Errorf("attributes not imported: %v", err)
+	}
+	cfg, _ := config.Load()
+	if cfg.Active != "alice2" {
+		t.Errorf("active vault = %q; want alice2", cfg.Active)
+	}
+	rootCmd.SetArgs([]string{"issue", "--id", "imported"})
+	if err := Execute(); err != nil {
+		t.Errorf("issue from imported vault failed: %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+// identityCmd groups commands that manage the identities of a vault
+var identityCmd = &cobra.Command{
+	Use:   "identity",
+	Short: "Manage the identities held by a vault",
+	Long: `A vault holds one or more named identities, each with its own DID, keys, attributes
+and credentials under identities/<name>/. Commands act on the vault's active identity
+unless --identity names another one.
+
+Vaults created before identities were supported keep their one identity at the vault
+root. They keep working as the identity 'default'; 'ego identity migrate' moves it into
+identities/default, as adding, switching or removing an identity also does.`,
+}
+
+var identityAddCmd = &cobra.Command{
+	Use:   "add <name> [--web domain.com] [--key-type <type>] [--mnemonic] [--out <vaultDir>]",
+	Short: "Create a new identity in the vault",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root, err := identityVaultRoot()
+		if err != nil {
+			return err
+		}
+		priv, words, err := newIdentityKey()
+		if err != nil {
+			return err
+		}
+		defer priv.Wipe()
+		did, _, err := createIdentity(cmd, root, args[0], priv, nil)
+		if err != nil {
+			return err
+		}
+		cmd.Printf("Identity '%s' added as %s\n", args[0], did)
+		printRecoveryWords(cmd, words)
+		return nil
+	},
+}
+
+var identityListCmd = &cobra.Command{
+	Use:   "list [--out <vaultDir>]",
+	Short: "List the identities in the vault; the active one is marked with *",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root, err := identityVaultRoot()
+		if err != nil {
+			return err
+		}
+		ix, err := vault.OpenIndex(root)
+		if err != nil {
+			return err
+		}
+		for _, e := range ix.Identities {
+			mark := " "
+			if e.Name == ix.Active {
+				mark = "*"
+			}
+			cmd.Printf("%s %s\t%s\n", mark, e.Name, e.DID)
+		}
+		return nil
+	},
+}
+
+var identityRemoveCmd = &cobra.Command{
+	Use:   "remove <name> [--out <vaultDir>]",
+	Short: "Delete an identity and all its keys and credentials",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root, err := identityVaultRoot()
+		if err != nil {
+			return err
+		}
+		if err := vault.UpdateIndex(root, func(ix *vault.Index) error { return ix.Remove(args[0]) }); err != nil {
+			return err
+		}
+		cmd.Printf("Identity '%s' removed\n", args[0])
+		return nil
+	},
+}
+
+var identitySwitchCmd = &cobra.Command{
+	Use:   "switch <name> [--out <vaultDir>]",
+	Short: "Make an identity the vault's active identity",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root, err := identityVaultRoot()
+		if err != nil {
+			return err
+		}
+		if err := vault.UpdateIndex(root, func(ix *vault.Index) error { return ix.Switch(args[0]) }); err != nil {
+			return err
+		}
+		cmd.Printf("Active identity set to '%s'\n", args[0])
+		return nil
+	},
+}
+
+// identityVaultRoot returns --out or the active vault, which must already exist.
+func identityVaultRoot() (string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", err
+	}
+	rootDir := cfg.RootDir
+	if rootDir == "" {
+		rootDir = "store"
+	}
+	root := altVaultDir
+	if root == "" {
+		root = filepath.Join(rootDir, cfg.Active)
+	}
+	if !vault.IsVault(root) {
+		return "", fmt.Errorf("%s is not a vault; create one with 'ego init'", root)
+	}
+	return root, nil
+}
+
+var identityMigrateCmd = &cobra.Command{
+	Use:   "migrate [--out <vaultDir>]",
+	Short: "Move the identity of a single-identity vault into identities/default",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root, err := identityVaultRoot()
+		if err != nil {
+			return err
+		}
+		if err := vault.Migrate(root); err != nil {
+			return err
+		}
+		cmd.Printf("Vault %s uses the multi-identity layout\n", root)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(identityCmd)
+	identityCmd.AddCommand(identityAddCmd, identityListCmd, identityRemoveCmd, identitySwitchCmd, identityMigrateCmd)
+	identityCmd.PersistentFlags().StringVar(&altVaultDir, "out", "", "Vault directory (optional, uses active)")
+	addDIDMethodFlags(identityAddCmd)
+	identityAddCmd.Flags().StringVar(&keyType, "key-type", "Ed25519", "Key type: Ed25519, secp256k1 or P-256")
+	identityAddCmd.Flags().BoolVar(&mnemonic, "mnemonic", false, "Derive the key from a new recovery mnemonic and print it once")
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/vault"
+)
+
+func TestIdentityCommands(t *testing.T) {
+	tmp := t.TempDir()
+	altVaultDir, identityName = "", ""
+	t.Cleanup(func() { identityName = "" })
+	run := func(args ...string) string {
+		t.Helper()
+		buf := new(bytes.Buffer)
+		rootCmd.SetOut(buf)
+		rootCmd.SetArgs(args)
+		if err := Execute(); err != nil {
+			t.Fatalf("%s failed: %v", strings.Join(args, " "), err)
+		}
+		return buf.String()
+	}
+
+	run("init", "--name", "wallet", "--out", tmp)
+	run("identity", "add", "work", "--key-type", "P-256", "--out", tmp)
+	keyType = "Ed25519"
+	run("set", "name", "Personal", "--out", tmp)
+	run("set", "name", "Work", "--out", tmp, "--identity", "work")
+	identityName = ""
+
+	out := run("identity", "list", "--out", tmp)
+	if !strings.Contains(out, "* default") || !strings.Contains(out, "  work") {
+		t.Errorf("unexpected list:\n%s", out)
+	}
+	ix, _ := vault.OpenIndex(tmp)
+	work, _ := ix.Get("work")
+	def, _ := ix.Get("default")
+	if work.DID == def.DID {
+		t.Error("identities share a DID")
+	}
+	if data, _ := os.ReadFile(filepath.Join(ix.Dir("work"), "attributes.json")); !bytes.Contains(data, []byte("Work")) {
+		t.Errorf("work attributes = %s", data)
+	}
+
+	run("identity", "switch", "work", "--out", tmp)
+	run("issue", "--id", "w1", "--out", tmp)
+	if _, err := os.Stat(filepath.Join(ix.Dir("work"), "credentials", "w1.json")); err != nil {
+		t.Errorf("credential not issued by the active identity: %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"identity", "remove", "work", "--out", tmp})
+	if err := Execute(); err == nil {
+		t.Error("expected removing the active identity to fail")
+	}
+	run("identity", "switch", "default", "--out", tmp)
+	run("identity", "remove", "work", "--out", tmp)
+	if out := run("identity", "list", "--out", tmp); strings.Contains(out, "work") {
+		t.Errorf("work still listed:\n%s", out)
+	}
+}
+
+func TestLegacyVault(t *testing.T) {
+	tmp := t.TempDir()
+	altVaultDir = ""
+	rootCmd.SetArgs([]string{"init", "--name", "old", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatal(err)
+	}
+	// move the identity back to the vault root, as vaults were laid out before
+	dir := identityPath(tmp)
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		os.Rename(filepath.Join(dir, e.Name()), filepath.Join(tmp, e.Name()))
+	}
+	os.RemoveAll(filepath.Join(tmp, "identities"))
+	os.Remove(filepath.Join(tmp, "identities.json"))
+
+	for _, args := range [][]string{
+		{"set", "name", "Legacy", "--out", tmp},
+		{"issue", "--id", "after", "--out", tmp},
+	} {
+		rootCmd.SetArgs(args)
+		if err := Execute(); err != nil {
+			t.Fatalf("%s on a legacy vault failed: %v", args[0], err)
+		}
+	}
+	// the vault is used in place until it is migrated explicitly
+	if _, err := os.Stat(filepath.Join(tmp, "credentials", "after.json")); err != nil {
+		t.Errorf("credential not written to the legacy vault: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "identities.json")); !os.IsNotExist(err) {
+		t.Error("using the vault migrated it")
+	}
+
+	rootCmd.SetArgs([]string{"identity", "migrate", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatalf("identity migrate failed: %v", err)
+	}
+	if _, err := os.Stat(identityPath(tmp, "credentials", "after.json")); err != nil {
+		t.Errorf("credential not moved to the migrated identity: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "did.json")); !os.IsNotExist(err) {
+		t.Error("did.json left at the vault root")
+	}
+	rootCmd.SetArgs([]string{"issue", "--id", "migrated", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatalf("issue on the migrated vault failed: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importName   string
+	importDryRun bool
+	importUse    bool
+)
+
+// importCmd unpacks a bundle written by 'ego export' into a new vault
+var importCmd = &cobra.Command{
+	Use:   "import <bundle> [--name <name>] [--dry-run] [--use]",
+	Short: "Import a vault from an encrypted bundle",
+	Long: `Decrypt and verify a bundle written by 'ego export' and unpack it as a new vault
+under the root directory. The vault keeps its exported name unless --name is given;
+an existing vault is never overwritten. --use makes the imported vault active.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		rootDir := cfg.RootDir
+		if rootDir == "" {
+			rootDir = "store"
+		}
+
+		pass, err := readPassphrase(cmd, bundlePassphraseFile, bundlePassphraseEnv, "Bundle passphrase: ")
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("open bundle: %w", err)
+		}
+		b, err := vault.OpenBundle(f, pass)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("open bundle: %w", err)
+		}
+
+		vaultName := importName
+		if vaultName == "" {
+			vaultName = b.Manifest.Name
+		}
+		if vaultName == "" || vaultName != filepath.Base(vaultName) {
+			return fmt.Errorf("invalid vault name %q; pass --name", vaultName)
+		}
+		target := filepath.Join(rootDir, vaultName)
+		_, statErr := os.Stat(target)
+		exists := statErr == nil
+
+		if importDryRun {
+			cmd.Printf("Bundle holds %s exported %s\n", b.Manifest.DID, b.Manifest.Created.Format("2006-01-02 15:04:05 MST"))
+			for _, file := range b.Manifest.Files {
+				cmd.Printf("  %s (%d bytes)\n", file.Path, file.Size)
+			}
+			if exists {
+				cmd.Printf("Vault '%s' already exists at %s; pass --name to import under another name\n", vaultName, target)
+			} else {
+				cmd.Printf("Would import as '%s' into %s\n", vaultName, target)
+			}
+			return nil
+		}
+		if exists {
+			return fmt.Errorf("vault '%s' already exists at %s; pass --name to import under another name", vaultName, target)
+		}
+		if err := b.Extract(target); err != nil {
+			return fmt.Errorf("import vault: %w", err)
+		}
+		cmd.Printf("Vault '%s' (%s) imported into %s\n", vaultName, b.Manifest.DID, target)
+
+		if importUse {
+			cfg.Active = vaultName
+			if err := cfg.Save(); err != nil {
+				return err
+			}
+			cmd.Printf("Active identity set to '%s'\n", vaultName)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVar(&importName, "name", "", "Name for the imported vault (default: exported name)")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Show what would be imported without writing anything")
+	importCmd.Flags().BoolVar(&importUse, "use", false, "Make the imported vault active in ~/.ego/config.json")
+	importCmd.Flags().StringVar(&bundlePassphraseFile, "bundle-passphrase-file", "", "File containing the bundle passphrase")
+}
@@ -5,56 +5,205 @@ import (
 	"path/filepath"
 
 	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/resolver"
 	"github.com/juanpablocruz/minervaid/internal/vault"
 	"github.com/spf13/cobra"
 )
 
 var (
-	name      string
-	webDomain string
-	vaultDir  string
+	name          string
+	webDomain     string
+	vaultDir      string
+	keyType       string
+	mnemonic      bool
+	didMethod     string
+	peerEndpoints []string
 )
 
 var initCmd = &cobra.Command{
-	Use:   "init --name <name> [--web domain.com] [--out <dir>]",
+	Use:   "init --name <name> [--web domain.com | --method peer] [--key-type <type>] [--mnemonic] [--out <dir>]",
 	Short: "Create a new identity",
+	Long: `Create a new vault holding one identity. The identity is named by the --identity
+flag, or "default"; add more with 'ego identity add'.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		pub, priv, err := identity.GenerateKeyPair()
+		priv, words, err := newIdentityKey()
 		if err != nil {
-			return fmt.Errorf("generate key pair: %w", err)
+			return err
 		}
+		defer priv.Wipe()
 
-		var did string
-		if webDomain != "" {
-			did = fmt.Sprintf("did:web:%s", webDomain)
-		} else {
-			did = identity.GenerateDID(pub)
+		did, target, err := createVault(cmd, priv, nil)
+		if err != nil {
+			return err
 		}
 
-		didDoc, err := identity.BuildDIDDocument(did, pub)
+		cmd.Printf("Identity '%s' created at %s\n", did, target)
+		printRecoveryWords(cmd, words)
+		return nil
+	},
+}
+
+// newIdentityKey generates the key of a new identity from --key-type, or derives it from
+// fresh recovery words with --mnemonic. words is empty unless --mnemonic is set.
+func newIdentityKey() (*identity.PrivateKey, string, error) {
+	kt, err := identity.ParseKeyType(keyType)
+	if err != nil {
+		return nil, "", err
+	}
+	if !mnemonic {
+		priv, err := identity.GenerateKey(kt)
 		if err != nil {
-			return fmt.Errorf("build did document: %w", err)
+			return nil, "", fmt.Errorf("generate key pair: %w", err)
 		}
+		return priv, "", nil
+	}
+	if kt != identity.KeyTypeEd25519 {
+		return nil, "", fmt.Errorf("--mnemonic only supports Ed25519 keys")
+	}
+	words, err := identity.NewMnemonic()
+	if err != nil {
+		return nil, "", fmt.Errorf("generate mnemonic: %w", err)
+	}
+	priv, err := identity.DeriveKey(words, 0)
+	if err != nil {
+		return nil, "", fmt.Errorf("generate key pair: %w", err)
+	}
+	return priv, words, nil
+}
+
+func printRecoveryWords(cmd *cobra.Command, words string) {
+	if words != "" {
+		cmd.Println("Recovery words (write them down; they will not be shown again):")
+		cmd.Println(words)
+	}
+}
 
-		target := vaultDir
-		if target == "" {
-			target = filepath.Join("store", name)
+// createVault creates the vault at --out or store/<name> holding one identity for priv,
+// named by --identity or "default".
+func createVault(cmd *cobra.Command, priv *identity.PrivateKey, didDoc []byte) (string, string, error) {
+	target := vaultDir
+	if target == "" {
+		target = filepath.Join("store", name)
+	}
+	idName := identityName
+	if idName == "" {
+		idName = vault.DefaultIdentity
+	}
+	did, _, err := createIdentity(cmd, target, idName, priv, didDoc)
+	if err != nil {
+		return "", "", err
+	}
+	return did, target, nil
+}
+
+// createIdentity writes did.json and an encrypted keystore for priv as identity idName of
+// the vault at root, and adds it to the vault's index. The document is didDoc, which must
+// list priv as its current key, or else the one chosen by newDIDDocument.
+func createIdentity(cmd *cobra.Command, root, idName string, priv *identity.PrivateKey, didDoc []byte) (string, string, error) {
+	if err := vault.ValidateIdentityName(idName); err != nil {
+		return "", "", err
+	}
+	ix, err := vault.OpenIndex(root)
+	if err != nil {
+		return "", "", err
+	}
+	if _, ok := ix.Get(idName); ok {
+		return "", "", fmt.Errorf("identity %q already exists in %s", idName, root)
+	}
+
+	pub := priv.Public()
+	var did string
+	if didDoc == nil {
+		if did, didDoc, err = newDIDDocument(priv); err != nil {
+			return "", "", err
+		}
+	} else {
+		doc, err := identity.ParseDIDDocument(didDoc)
+		if err != nil {
+			return "", "", err
 		}
+		did = doc.ID
+	}
+
+	pass, err := readNewPassphrase(cmd, passphraseFile, passphraseEnv)
+	if err != nil {
+		return "", "", err
+	}
+
+	dir := ix.Dir(idName)
+	v := vault.NewVault(dir)
+	if err := v.Init(didDoc, priv, pass); err != nil {
+		return "", "", fmt.Errorf("initialize vault: %w", err)
+	}
+	if err := vault.UpdateIndex(root, func(ix *vault.Index) error { return ix.Add(idName, did) }); err != nil {
+		return "", "", err
+	}
+	if err := recordAudit(heldKeys(cmd, v, priv), cmd.Name(), map[string]string{"did": did, "keyType": string(pub.Type)}, didDoc); err != nil {
+		return "", "", err
+	}
+	return did, dir, nil
+}
 
-		v := vault.NewVault(target)
-		if err := v.Init(didDoc, priv); err != nil {
-			return fmt.Errorf("initialize vault: %w", err)
+// newDIDDocument returns the DID and document of a new identity with key priv: the
+// did:web of --web (a domain with an optional port and path), a numalgo 2 did:peer with
+// --method peer, or else the did:key of priv.
+func newDIDDocument(priv *identity.PrivateKey) (string, []byte, error) {
+	method := didMethod
+	if method == "" {
+		method = "key"
+		if webDomain != "" {
+			method = "web"
+		}
+	}
+	if webDomain != "" && method != "web" {
+		return "", nil, fmt.Errorf("--web cannot be used with --method %s", method)
+	}
+	if len(peerEndpoints) > 0 && method != "peer" {
+		return "", nil, fmt.Errorf("--endpoint needs --method peer")
+	}
+	pub := priv.Public()
+	var did string
+	switch method {
+	case "key":
+		did = identity.GenerateDIDForKey(pub)
+	case "web":
+		if webDomain == "" {
+			return "", nil, fmt.Errorf("--method web needs --web <domain>")
+		}
+		did = resolver.WebDID(webDomain)
+	case "peer":
+		did, err := vault.PeerDID(priv, peerEndpoints)
+		if err != nil {
+			return "", nil, fmt.Errorf("build did:peer: %w", err)
+		}
+		doc, err := identity.ParsePeerDID(did)
+		if err != nil {
+			return "", nil, err
 		}
+		data, err := doc.Marshal()
+		return did, data, err
+	default:
+		return "", nil, fmt.Errorf("unsupported DID method %q; use key, web or peer", method)
+	}
+	didDoc, err := identity.BuildDIDDocument(did, pub)
+	if err != nil {
+		return "", nil, fmt.Errorf("build did document: %w", err)
+	}
+	return did, didDoc, nil
+}
 
-		// 6. Success message
-		cmd.Printf("Identity '%s' created at %s\n", did, target)
-		return nil
-	},
+// addDIDMethodFlags registers the flags that choose the DID of a new identity.
+func addDIDMethodFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&webDomain, "web", "", "Domain for did:web method (optional)")
+	cmd.Flags().StringVar(&didMethod, "method", "", "DID method: key, web or peer (default: key, or web with --web)")
+	cmd.Flags().StringSliceVar(&peerEndpoints, "endpoint", nil, "DIDComm endpoint to publish in a did:peer; can be repeated")
 }
 
 func init() {
 	initCmd.Flags().StringVar(&name, "name", "", "Name of the identity (required)")
-	initCmd.Flags().StringVar(&webDomain, "web", "", "Domain for did:web method (optional)")
+	addDIDMethodFlags(initCmd)
+	initCmd.Flags().StringVar(&keyType, "key-type", "Ed25519", "Key type: Ed25519, secp256k1 or P-256")
+	initCmd.Flags().BoolVar(&mnemonic, "mnemonic", false, "Derive the key from a new recovery mnemonic and print it once")
 	initCmd.Flags().StringVar(&vaultDir, "out", "", "Directory in which to create the identity (optional)")
 	initCmd.MarkFlagRequired("name")
 }
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"os"
-	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -30,7 +29,7 @@ func TestInitCommand(t *testing.T) {
 	}
 
 	// 4. Check did.json
-	didPath := filepath.Join(tmp, "did.json")
+	didPath := identityPath(tmp, "did.json")
 	data, err := os.ReadFile(didPath)
 	if err != nil {
 		t.Fatalf("did.json not created: %v", err)
@@ -48,20 +47,49 @@ func TestInitCommand(t *testing.T) {
 	}
 
 	// 5. Check keystore.json
-	ksPath := filepath.Join(tmp, "keystore.json")
+	ksPath := identityPath(tmp, "keystore.json")
 	data2, err := os.ReadFile(ksPath)
 	if err != nil {
 		t.Fatalf("keystore.json not created: %v", err)
 	}
-	var ks map[string]string
+	var ks map[string]interface{}
 	if err := json.Unmarshal(data2, &ks); err != nil {
 		t.Fatalf("invalid JSON in keystore.json: %v", err)
 	}
-	pk, ok := ks["privateKey"]
-	if !ok {
-		t.Fatalf("keystore.json missing \"privateKey\" field")
+	if _, ok := ks["privateKey"]; ok {
+		t.Error("keystore.json must not contain a plaintext \"privateKey\"")
+	}
+	if _, ok := ks["crypto"]; !ok {
+		t.Fatalf("keystore.json missing \"crypto\" section")
 	}
-	if len(pk) == 0 {
-		t.Error("privateKey is empty")
+}
+
+func TestInitKeyTypes(t *testing.T) {
+	for _, kt := range []string{"secp256k1", "P-256"} {
+		t.Run(kt, func(t *testing.T) {
+			tmp := t.TempDir()
+			rootCmd.SetArgs([]string{"init", "--name", "ec", "--key-type", kt, "--out", tmp})
+			if err := Execute(); err != nil {
+				t.Fatalf("init failed: %v", err)
+			}
+			keyType = "Ed25519"
+			rootCmd.SetArgs([]string{"set", "name", "Ec", "--out", tmp})
+			Execute()
+			rootCmd.SetArgs([]string{"issue", "--out", tmp, "--id", "ec"})
+			if err := Execute(); err != nil {
+				t.Fatalf("issue failed: %v", err)
+			}
+
+			buf := &bytes.Buffer{}
+			rootCmd.SetOut(buf)
+			rootCmd.SetErr(buf)
+			rootCmd.SetArgs([]string{"verify", "--file", identityPath(tmp, "credentials", "ec.json")})
+			if err := Execute(); err != nil {
+				t.Fatalf("verify failed: %v", err)
+			}
+			if !bytes.Contains(buf.Bytes(), []byte("Credential is valid")) {
+				t.Errorf("credential did not verify: %s", buf.String())
+			}
+		})
 	}
 }
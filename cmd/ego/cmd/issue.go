@@ -14,16 +14,34 @@ import (
 )
 
 var (
-	credID      string
-	altVaultDir string
+	credID          string
+	credSuite       string
+	credV2          bool
+	credName        string
+	credDescription string
+	credValidFor    string
+	credStatusURL   string
+	altVaultDir     string
 )
 
 // issueCmd issues a new Verifiable Credential based on stored attributes.json
 var issueCmd = &cobra.Command{
-	Use:   "issue [--id <id>] [--out <directory>]",
+	Use:   "issue [--id <id>] [--suite <suite>] [--v2] [--valid-for <duration>] [--status-url <url>] [--out <directory>]",
 	Short: "Issue a new verifiable credential for current attributes",
 	Long: `Issue a new Verifiable Credential whose subject is the full set of attributes
-stored in attributes.json for the active identity vault.`,
+stored in attributes.json for the active identity vault.
+
+--suite selects a W3C Data Integrity proof instead of the key's own suite:
+eddsa-jcs-2022, eddsa-rdfc-2022 or Ed25519Signature2020. The last two canonicalize
+the credential as JSON-LD, so --id must then be an absolute IRI such as a urn:uuid.
+
+--v2 issues a VC Data Model 2.0 credential, with validFrom instead of issuanceDate.
+--valid-for makes the credential expire, e.g. after 30d or 12h, or at an RFC 3339 time.
+
+--status-url gives the credential a credentialStatus, an index in the vault's revocation
+and suspension status lists, which 'ego revoke' updates. The lists are kept in the
+vault's status directory and must be published at <url>/revocation and
+<url>/suspension for verifiers to fetch them.`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Load CLI config
@@ -41,6 +59,9 @@ stored in attributes.json for the active identity vault.`,
 		if vaultDir == "" {
 			vaultDir = filepath.Join(rootDir, cfg.Active)
 		}
+		if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+			return err
+		}
 		// Read attributes.json
 		attrPath := filepath.Join(vaultDir, "attributes.json")
 		data, err := os.ReadFile(attrPath)
@@ -52,33 +73,60 @@ stored in attributes.json for the active identity vault.`,
 			return fmt.Errorf("invalid attributes.json: %w", err)
 		}
 
+		var expires time.Time
+		if credValidFor != "" {
+			if expires, err = credentials.ParseExpiration(credValidFor, time.Now()); err != nil {
+				return err
+			}
+		}
+
 		// Determine credential ID
 		id := credID
 		if id == "" {
 			id = time.Now().UTC().Format("20060102T150405Z")
 		}
 
-		// Load DID and private key
+		// Load DID
 		v := vault.NewVault(vaultDir)
-		didDoc, priv, err := v.Load()
+		did, err := v.DID()
 		if err != nil {
 			return fmt.Errorf("load vault: %w", err)
 		}
 
-		// Parse DID
-		var doc map[string]interface{}
-		if err := json.Unmarshal(didDoc, &doc); err != nil {
-			return fmt.Errorf("parse did.json: %w", err)
+		// Create and sign credential, through the agent when it is running
+		cred := credentials.NewCredential(id, did, attrs)
+		if credV2 {
+			cred = credentials.NewCredentialV2(id, did, attrs)
 		}
-		did, ok := doc["id"].(string)
-		if !ok {
-			return fmt.Errorf("did.json missing 'id'")
+		cred.Name, cred.Description = credName, credDescription
+		if !expires.IsZero() {
+			cred.SetExpiration(expires)
 		}
-
-		// Create and sign credential
-		cred := credentials.NewCredential(id, did, attrs)
-		if err := cred.SignCredential(priv, did+"#keys-1"); err != nil {
-			return fmt.Errorf("sign credential: %w", err)
+		keys := newVaultKeys(cmd, v)
+		defer keys.Close()
+		issued := false
+		if credStatusURL != "" {
+			// status lists are signed with the key's own suite, which every verifier knows
+			lists := &credentials.StatusLists{Dir: filepath.Join(vaultDir, "status")}
+			entries, err := lists.Assign(id, did, credStatusURL, func(list *credentials.Credential) error {
+				return keys.SignCredential(list, "")
+			})
+			if err != nil {
+				return fmt.Errorf("assign status index: %w", err)
+			}
+			cred.CredentialStatus = entries
+			// a credential that is not saved gives its index back
+			defer func() {
+				if issued {
+					return
+				}
+				if err := lists.Release(id); err != nil {
+					cmd.PrintErrf("Warning: status index of %s not released: %v\n", id, err)
+				}
+			}()
+		}
+		if err := keys.SignCredential(cred, credSuite); err != nil {
+			return err
 		}
 
 		// Save credential
@@ -90,6 +138,14 @@ stored in attributes.json for the active identity vault.`,
 		if err := store.Save(cred); err != nil {
 			return fmt.Errorf("save credential: %w", err)
 		}
+		issued = true
+		out, err := json.Marshal(cred)
+		if err != nil {
+			return fmt.Errorf("marshal credential: %w", err)
+		}
+		if err := recordAudit(keys, "issue", map[string]interface{}{"id": id, "attributes": attrs}, out); err != nil {
+			return err
+		}
 
 		cmd.Printf("Credential '%s' issued\n", id)
 		return nil
@@ -99,5 +155,11 @@ stored in attributes.json for the active identity vault.`,
 func init() {
 	rootCmd.AddCommand(issueCmd)
 	issueCmd.Flags().StringVar(&credID, "id", "", "Credential ID (optional)")
+	issueCmd.Flags().BoolVar(&credV2, "v2", false, "Issue a VC Data Model 2.0 credential")
+	issueCmd.Flags().StringVar(&credName, "name", "", "Name of the credential (optional)")
+	issueCmd.Flags().StringVar(&credDescription, "description", "", "Description of the credential (optional)")
+	issueCmd.Flags().StringVar(&credValidFor, "valid-for", "", "Validity period, e.g. 30d or 12h, or an RFC 3339 expiry time (default: no expiry)")
+	issueCmd.Flags().StringVar(&credStatusURL, "status-url", "", "URL the vault's status lists are published under (optional)")
+	issueCmd.Flags().StringVar(&credSuite, "suite", "", "Proof suite: eddsa-jcs-2022, eddsa-rdfc-2022 or Ed25519Signature2020 (default: the key's own)")
 	issueCmd.Flags().StringVar(&altVaultDir, "out", "", "Directory of the vault (optional, uses active if unset)")
 }
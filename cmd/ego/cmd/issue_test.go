@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"os"
-	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/credentials"
 )
 
 func TestIssueCommand_NewAttributesCredential(t *testing.T) {
@@ -20,7 +22,7 @@ func TestIssueCommand_NewAttributesCredential(t *testing.T) {
 	// 2. Write attributes.json
 	attrs := map[string]interface{}{"age": "32", "name": "Alice"}
 	attrBytes, _ := json.Marshal(attrs)
-	if err := os.WriteFile(filepath.Join(tmpDir, "attributes.json"), attrBytes, 0600); err != nil {
+	if err := os.WriteFile(identityPath(tmpDir, "attributes.json"), attrBytes, 0600); err != nil {
 		t.Fatalf("write attributes.json failed: %v", err)
 	}
 
@@ -40,7 +42,7 @@ func TestIssueCommand_NewAttributesCredential(t *testing.T) {
 	}
 
 	// 5. Check credential file
-	credFile := filepath.Join(tmpDir, "credentials", "cred123.json")
+	credFile := identityPath(tmpDir, "credentials", "cred123.json")
 	data, err := os.ReadFile(credFile)
 	if err != nil {
 		t.Fatalf("credential file not created: %v", err)
@@ -62,3 +64,87 @@ func TestIssueCommand_NewAttributesCredential(t *testing.T) {
 		}
 	}
 }
+
+func TestIssueCommand_Suite(t *testing.T) {
+	t.Cleanup(func() { credSuite = "" })
+	tmpDir := t.TempDir()
+	rootCmd.SetArgs([]string{"init", "--name", "testvault", "--out", tmpDir})
+	if err := Execute(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	if err := os.WriteFile(identityPath(tmpDir, "attributes.json"), []byte(`{"name":"Alice"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, suite := range map[string]string{
+		"cred-jcs": credentials.SuiteEddsaJcs2022,
+		"urn:uuid:0c5f1e0a-8d5b-4e0e-9b7c-3f1d2a6b8e41": credentials.SuiteEddsaRdfc2022,
+	} {
+		rootCmd.SetArgs([]string{"issue", "--out", tmpDir, "--id", id, "--suite", suite})
+		if err := Execute(); err != nil {
+			t.Fatalf("issue --suite %s failed: %v", suite, err)
+		}
+		data, err := os.ReadFile(identityPath(tmpDir, "credentials", id+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cred credentials.Credential
+		if err := json.Unmarshal(data, &cred); err != nil {
+			t.Fatal(err)
+		}
+		var proof credentials.DataIntegrityProof
+		if err := json.Unmarshal(cred.Proofs[len(cred.Proofs)-1], &proof); err != nil {
+			t.Fatal(err)
+		}
+		if proof.Type != credentials.DataIntegrityProofType || proof.Cryptosuite != suite {
+			t.Errorf("unexpected proof %+v", proof)
+		}
+		if err := credentials.VerifyCredential(&cred); err != nil {
+			t.Errorf("%s credential does not verify: %v", suite, err)
+		}
+	}
+
+	rootCmd.SetArgs([]string{"issue", "--out", tmpDir, "--id", "cred-bad", "--suite", "bogus"})
+	if err := Execute(); err == nil {
+		t.Error("expected error for an unknown suite")
+	}
+}
+
+func TestIssueCommand_ValidFor(t *testing.T) {
+	t.Cleanup(func() { credV2, credValidFor, credName = false, "", "" })
+	tmpDir := t.TempDir()
+	rootCmd.SetArgs([]string{"init", "--name", "testvault", "--out", tmpDir})
+	if err := Execute(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	if err := os.WriteFile(identityPath(tmpDir, "attributes.json"), []byte(`{"name":"Alice"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	rootCmd.SetArgs([]string{"issue", "--out", tmpDir, "--id", "cred-v2", "--v2", "--valid-for", "30d", "--name", "Profile"})
+	if err := Execute(); err != nil {
+		t.Fatalf("issue failed: %v", err)
+	}
+	data, err := os.ReadFile(identityPath(tmpDir, "credentials", "cred-v2.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cred credentials.Credential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		t.Fatal(err)
+	}
+	if !cred.IsV2() || cred.Name != "Profile" || !cred.IssuanceDate.IsZero() {
+		t.Errorf("unexpected credential %s", data)
+	}
+	if got := cred.ValidUntil.Sub(cred.ValidFrom); got < 30*24*time.Hour-time.Second || got > 30*24*time.Hour+time.Second {
+		t.Errorf("validity period %s, want 30 days", got)
+	}
+	if err := credentials.VerifyCredential(&cred); err != nil {
+		t.Errorf("credential does not verify: %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"issue", "--out", tmpDir, "--id", "cred-bad", "--valid-for", "soon"})
+	if err := Execute(); err == nil {
+		t.Error("expected error for an invalid --valid-for")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/juanpablocruz/minervaid/internal/vault"
 	"github.com/spf13/cobra"
 )
 
@@ -24,8 +25,7 @@ var listVaultCmd = &cobra.Command{
 		}
 		for _, e := range entries {
 			if e.IsDir() {
-				// check did.json inside
-				if _, err := os.Stat(filepath.Join(rootDir, e.Name(), "did.json")); err == nil {
+				if vault.IsVault(filepath.Join(rootDir, e.Name())) {
 					cmd.Println(e.Name())
 				}
 			}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/vault"
 	"github.com/spf13/cobra"
 )
 
@@ -42,7 +43,10 @@ Otherwise: list <vaultName> [<rootDir>] looks under <rootDir>/<vaultName>/creden
 		if rootDir == "" {
 			rootDir = "store"
 		}
-		target := filepath.Join(rootDir, vaultName)
+		target, err := vault.IdentityDir(filepath.Join(rootDir, vaultName), identityName)
+		if err != nil {
+			return err
+		}
 
 		// Read credential files
 		credDir := filepath.Join(target, "credentials")
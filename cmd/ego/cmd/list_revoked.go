@@ -6,6 +6,7 @@ import (
 
 	"github.com/juanpablocruz/minervaid/internal/config"
 	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/vault"
 	"github.com/spf13/cobra"
 )
 
@@ -28,6 +29,9 @@ var listRevokedCmd = &cobra.Command{
 		if vaultDir == "" {
 			vaultDir = filepath.Join(rootDir, cfg.Active)
 		}
+		if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+			return err
+		}
 		// Load revocation list
 		rl, err := credentials.NewRevocationList(filepath.Join(vaultDir, "revocations.json"))
 		if err != nil {
@@ -3,7 +3,6 @@ package cmd
 import (
 	"bytes"
 	"os"
-	"path/filepath"
 	"testing"
 )
 
@@ -19,7 +18,7 @@ func TestListRevokedCommand(t *testing.T) {
 	  "a1",
 	  "b2"
 	]`
-	os.WriteFile(filepath.Join(tmp, "revocations.json"), []byte(rl), 0600)
+	os.WriteFile(identityPath(tmp, "revocations.json"), []byte(rl), 0600)
 	// list-revoked
 	buf := &bytes.Buffer{}
 	rootCmd.SetOut(buf)
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/agent"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+)
+
+// testPassphrase unlocks every vault created by the command tests.
+const testPassphrase = "test-passphrase"
+
+// helperArgsEnv makes the test binary run one ego command, given as a JSON array of
+// arguments, so tests can start several ego processes at once.
+const helperArgsEnv = "EGO_TEST_HELPER_ARGS"
+
+func TestMain(m *testing.M) {
+	os.Setenv(passphraseEnv, testPassphrase)
+	// never talk to a real agent from tests
+	os.Setenv(agent.SocketEnv, filepath.Join(os.TempDir(), "ego-test-no-agent.sock"))
+	if args := os.Getenv(helperArgsEnv); args != "" {
+		var argv []string
+		if err := json.Unmarshal([]byte(args), &argv); err != nil {
+			os.Exit(2)
+		}
+		rootCmd.SetArgs(argv)
+		if err := Execute(); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// identityPath joins elem to the directory of the default identity of the vault at root.
+func identityPath(root string, elem ...string) string {
+	return filepath.Join(append([]string{root, "identities", vault.DefaultIdentity}, elem...)...)
+}
+
+// egoProcess returns a command that runs ego with args in a separate process.
+func egoProcess(t *testing.T, args ...string) *exec.Cmd {
+	t.Helper()
+	argv, _ := json.Marshal(args)
+	c := exec.Command(os.Args[0], "-test.run=^$")
+	c.Env = append(os.Environ(), helperArgsEnv+"="+string(argv))
+	return c
+}
@@ -0,0 +1,314 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+const migrationFilename = "migration.json"
+
+var (
+	migrateRotateKey   bool
+	migrateAlsoKnownAs bool
+)
+
+// migrateCmd clones a did:key identity into a new did:web vault
+var migrateCmd = &cobra.Command{
+	Use:   "migrate --to web --domain <domain> --name <name> [--rotate-key] [--also-known-as] [--status-url <url>] [--from <vaultDir>] [--out <dir>]",
+	Short: "Clone the active identity into a new did:web vault",
+	Long: `Create a new vault whose identity is the did:web of --domain, keeping the current
+key or, with --rotate-key, a new one. Attributes are copied, credentials the identity
+issued to itself are reissued by the new DID, and other credentials are copied as they are.
+
+Reissued credentials with a credentialStatus get an index in the new vault's status
+lists, published under --status-url, with their revocation and suspension carried over.
+
+Both vaults get migration.json, a statement signed by the old and the new DID that
+verifiers can check with 'ego verify-migration'. With --also-known-as each did.json
+lists the other DID in alsoKnownAs.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if didMethod != "web" {
+			return fmt.Errorf("--to must be web")
+		}
+		if webDomain == "" {
+			return fmt.Errorf("--domain must be provided")
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		rootDir := cfg.RootDir
+		if rootDir == "" {
+			rootDir = "store"
+		}
+		srcDir := altVaultDir
+		if srcDir == "" {
+			srcDir = filepath.Join(rootDir, cfg.Active)
+		}
+		if srcDir, err = vault.IdentityDir(srcDir, identityName); err != nil {
+			return err
+		}
+		src := vault.NewVault(srcDir)
+		srcDoc, err := src.Document()
+		if err != nil {
+			return fmt.Errorf("load vault: %w", err)
+		}
+		if strings.HasPrefix(srcDoc.ID, "did:web:") {
+			return fmt.Errorf("%s is already a did:web identity", srcDoc.ID)
+		}
+		if credStatusURL == "" {
+			id, err := statusCredential(srcDir, srcDoc.ID)
+			if err != nil {
+				return err
+			}
+			if id != "" {
+				return fmt.Errorf("credential %s has a credentialStatus; give --status-url for the new vault's status lists", id)
+			}
+		}
+		srcVM, err := srcDoc.MethodFor(identity.PurposeAuthentication)
+		if err != nil {
+			return err
+		}
+		oldKey, err := vaultKey(cmd, src)
+		if err != nil {
+			return fmt.Errorf("load vault: %w", err)
+		}
+		defer oldKey.Wipe()
+
+		newKey := &identity.PrivateKey{Type: oldKey.Type, Raw: append([]byte(nil), oldKey.Raw...)}
+		if migrateRotateKey {
+			if newKey, err = identity.GenerateKey(oldKey.Type); err != nil {
+				return fmt.Errorf("generate key pair: %w", err)
+			}
+		}
+		defer newKey.Wipe()
+		did, target, err := createVault(cmd, newKey, nil)
+		if err != nil {
+			return err
+		}
+		dstDir, err := vault.IdentityDir(target, "")
+		if err != nil {
+			return err
+		}
+		dst := vault.NewVault(dstDir)
+		dstDoc, err := dst.Document()
+		if err != nil {
+			return err
+		}
+		assertVM, err := dstDoc.MethodFor(identity.PurposeAssertionMethod)
+		if err != nil {
+			return err
+		}
+		authVM, err := dstDoc.MethodFor(identity.PurposeAuthentication)
+		if err != nil {
+			return err
+		}
+
+		if err := copyAttributes(srcDir, dstDir); err != nil {
+			return err
+		}
+		reissued, copied, err := migrateCredentials(cmd, srcDir, dstDir, srcDoc.ID, did, signer.NewMemory(newKey), assertVM.ID, credStatusURL)
+		if err != nil {
+			return err
+		}
+
+		if migrateAlsoKnownAs {
+			link := func(other string) func(doc *identity.Document) error {
+				return func(doc *identity.Document) error {
+					doc.AddAlsoKnownAs(other)
+					return nil
+				}
+			}
+			if err := dst.UpdateDocument(link(srcDoc.ID)); err != nil {
+				return err
+			}
+			if err := src.UpdateDocument(link(did)); err != nil {
+				return err
+			}
+		}
+
+		st := credentials.NewMigrationStatement(srcDoc.ID, did, migrateRotateKey)
+		if err := st.Sign(cmd.Context(), signer.NewMemory(oldKey), srcVM.ID); err != nil {
+			return fmt.Errorf("sign migration statement: %w", err)
+		}
+		if err := st.Sign(cmd.Context(), signer.NewMemory(newKey), authVM.ID); err != nil {
+			return fmt.Errorf("sign migration statement: %w", err)
+		}
+		data, err := json.MarshalIndent(st, "", "  ")
+		if err != nil {
+			return err
+		}
+		for _, dir := range []string{srcDir, dstDir} {
+			if err := fsutil.WriteFile(filepath.Join(dir, migrationFilename), data, 0644); err != nil {
+				return fmt.Errorf("write %s: %w", migrationFilename, err)
+			}
+		}
+		inputs := map[string]interface{}{"from": srcDoc.ID, "to": did, "rotateKey": migrateRotateKey, "alsoKnownAs": migrateAlsoKnownAs}
+		if err := recordAudit(heldKeys(cmd, src, oldKey), "migrate", inputs, data); err != nil {
+			return err
+		}
+
+		cmd.Printf("Identity '%s' migrated to '%s' at %s\n", srcDoc.ID, did, target)
+		cmd.Printf("Credentials reissued: %d, copied: %d\n", reissued, copied)
+		cmd.Printf("Migration statement: %s\n", filepath.Join(dstDir, migrationFilename))
+		cmd.Println("Publish the new DID document with 'ego remote export web'.")
+		return nil
+	},
+}
+
+// copyAttributes copies attributes.json, if any, from one identity directory to another.
+func copyAttributes(srcDir, dstDir string) error {
+	data, err := os.ReadFile(filepath.Join(srcDir, "attributes.json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read attributes.json: %w", err)
+	}
+	if err := fsutil.WriteFile(filepath.Join(dstDir, "attributes.json"), data, 0600); err != nil {
+		return fmt.Errorf("write attributes.json: %w", err)
+	}
+	return nil
+}
+
+// readCredentials returns the credentials of srcDir with the files they were read from.
+func readCredentials(srcDir string) ([]string, []credentials.Credential, error) {
+	files, err := filepath.Glob(filepath.Join(srcDir, "credentials", "*.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	creds := make([]credentials.Credential, len(files))
+	for i, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read credential: %w", err)
+		}
+		if err := json.Unmarshal(data, &creds[i]); err != nil {
+			return nil, nil, fmt.Errorf("parse %s: %w", filepath.Base(f), err)
+		}
+	}
+	return files, creds, nil
+}
+
+// statusCredential returns the id of a credential of srcDir issued by from with a
+// credentialStatus, or "" if there is none.
+func statusCredential(srcDir, from string) (string, error) {
+	_, creds, err := readCredentials(srcDir)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range creds {
+		if c.Issuer == from && len(c.CredentialStatus) > 0 {
+			return c.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// migrateCredentials reissues the credentials of srcDir issued by from as issued by to,
+// signed by s with verification method vm, and copies the others unchanged. A subject id
+// of from becomes to. Reissued credentials with a credentialStatus get an index in the
+// status lists of dstDir, published under statusURL, with the status they had in the
+// lists of srcDir.
+func migrateCredentials(cmd *cobra.Command, srcDir, dstDir, from, to string, s signer.Signer, vm, statusURL string) (int, int, error) {
+	files, creds, err := readCredentials(srcDir)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(files) == 0 {
+		return 0, 0, nil
+	}
+	credDir := filepath.Join(dstDir, "credentials")
+	if err := os.MkdirAll(credDir, 0700); err != nil {
+		return 0, 0, fmt.Errorf("create credentials dir: %w", err)
+	}
+	store := &credentials.FileStore{Dir: credDir}
+	oldLists := &credentials.StatusLists{Dir: filepath.Join(srcDir, "status")}
+	newLists := &credentials.StatusLists{Dir: filepath.Join(dstDir, "status")}
+	// status lists are signed with the key's own suite, as 'ego issue' signs them
+	signList := func(list *credentials.Credential) error { return list.SignCredential(cmd.Context(), s, vm) }
+	reissued, copied := 0, 0
+	for i, cred := range creds {
+		if cred.Issuer != from {
+			data, err := os.ReadFile(files[i])
+			if err != nil {
+				return 0, 0, fmt.Errorf("read credential: %w", err)
+			}
+			if err := fsutil.WriteFile(filepath.Join(credDir, filepath.Base(files[i])), data, 0644); err != nil {
+				return 0, 0, fmt.Errorf("copy credential: %w", err)
+			}
+			copied++
+			continue
+		}
+		subject := make(map[string]interface{}, len(cred.CredentialSubject))
+		for k, v := range cred.CredentialSubject {
+			subject[k] = v
+		}
+		if subject["id"] == from {
+			subject["id"] = to
+		}
+		next := cred
+		next.Issuer, next.CredentialSubject, next.Proofs = to, subject, []json.RawMessage{}
+		// the old entries point at the lists of the old DID
+		next.CredentialStatus = nil
+		if len(cred.CredentialStatus) > 0 {
+			if next.CredentialStatus, err = migrateStatus(oldLists, newLists, cred.ID, to, statusURL, signList); err != nil {
+				return 0, 0, fmt.Errorf("reissue credential %s: %w", cred.ID, err)
+			}
+		}
+		if err := next.SignCredential(cmd.Context(), s, vm); err != nil {
+			return 0, 0, fmt.Errorf("reissue credential %s: %w", cred.ID, err)
+		}
+		if err := store.Save(&next); err != nil {
+			return 0, 0, fmt.Errorf("save credential: %w", err)
+		}
+		reissued++
+	}
+	return reissued, copied, nil
+}
+
+// migrateStatus assigns credID an index in newLists, issued by to under statusURL, and
+// sets the bits that are set for it in oldLists.
+func migrateStatus(oldLists, newLists *credentials.StatusLists, credID, to, statusURL string, sign func(*credentials.Credential) error) (credentials.StatusEntries, error) {
+	entries, err := newLists.Assign(credID, to, statusURL, sign)
+	if err != nil {
+		return nil, fmt.Errorf("assign status index: %w", err)
+	}
+	for _, purpose := range []string{credentials.StatusPurposeRevocation, credentials.StatusPurposeSuspension} {
+		set, err := oldLists.Get(credID, purpose)
+		if err != nil {
+			return nil, fmt.Errorf("read %s status: %w", purpose, err)
+		}
+		if set {
+			if _, err := newLists.Set(credID, purpose, true, sign); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return entries, nil
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+	migrateCmd.Flags().StringVar(&didMethod, "to", "", "DID method to migrate to; only web is supported (required)")
+	migrateCmd.Flags().StringVar(&webDomain, "domain", "", "Domain, with optional port and path, of the new did:web (required)")
+	migrateCmd.Flags().StringVar(&name, "name", "", "Name of the new vault (required)")
+	migrateCmd.Flags().StringVar(&vaultDir, "out", "", "Directory in which to create the new vault (optional)")
+	migrateCmd.Flags().StringVar(&altVaultDir, "from", "", "Vault to migrate (optional, uses active)")
+	migrateCmd.Flags().BoolVar(&migrateRotateKey, "rotate-key", false, "Give the new identity a new key instead of reusing the current one")
+	migrateCmd.Flags().BoolVar(&migrateAlsoKnownAs, "also-known-as", false, "List each DID in the other's alsoKnownAs")
+	migrateCmd.Flags().StringVar(&credStatusURL, "status-url", "", "URL the new vault's status lists are published under; needed for reissued credentials with a credentialStatus")
+	migrateCmd.MarkFlagRequired("name")
+}
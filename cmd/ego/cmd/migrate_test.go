@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+)
+
+func TestMigrateToWeb(t *testing.T) {
+	tmp := t.TempDir()
+	src, dst := filepath.Join(tmp, "key"), filepath.Join(tmp, "web")
+	altVaultDir, vaultDir = "", ""
+	t.Cleanup(func() {
+		didMethod, webDomain, altVaultDir, vaultDir = "", "", "", ""
+		migrateRotateKey, migrateAlsoKnownAs, didDocFile = false, false, ""
+	})
+	for _, args := range [][]string{
+		{"init", "--name", "key", "--out", src},
+		{"set", "email", "ada@example.com", "--out", src},
+		{"issue", "--out", src, "--id", "vc1"},
+		{"migrate", "--to", "web", "--domain", "example.com", "--name", "web", "--out", dst, "--from", src, "--also-known-as"},
+	} {
+		rootCmd.SetArgs(args)
+		if err := Execute(); err != nil {
+			t.Fatalf("%s failed: %v", args[0], err)
+		}
+	}
+	oldDoc, _ := vault.NewVault(identityPath(src)).Document()
+	newDoc, err := vault.NewVault(identityPath(dst)).Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newDoc.ID != "did:web:example.com" {
+		t.Fatalf("new DID = %s", newDoc.ID)
+	}
+	if len(newDoc.AlsoKnownAs) != 1 || newDoc.AlsoKnownAs[0] != oldDoc.ID || len(oldDoc.AlsoKnownAs) != 1 || oldDoc.AlsoKnownAs[0] != newDoc.ID {
+		t.Errorf("alsoKnownAs not linked both ways: %v / %v", oldDoc.AlsoKnownAs, newDoc.AlsoKnownAs)
+	}
+	oldPub, _ := oldDoc.VerificationMethod[0].PublicKey()
+	newPub, _ := newDoc.VerificationMethod[0].PublicKey()
+	if !oldPub.Equal(newPub) {
+		t.Error("key was not reused without --rotate-key")
+	}
+
+	want, _ := os.ReadFile(identityPath(src, "attributes.json"))
+	if got, _ := os.ReadFile(identityPath(dst, "attributes.json")); !bytes.Equal(got, want) {
+		t.Error("attributes not copied")
+	}
+	resolve := func(did string) (*identity.Document, error) {
+		if did == newDoc.ID {
+			return newDoc, nil
+		}
+		return credentials.ResolveDocument(did)
+	}
+	data, _ := os.ReadFile(identityPath(dst, "credentials", "vc1.json"))
+	var cred credentials.Credential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		t.Fatal(err)
+	}
+	if cred.Issuer != newDoc.ID {
+		t.Errorf("credential issuer = %s; want %s", cred.Issuer, newDoc.ID)
+	}
+	if err := credentials.VerifyCredentialWith(&cred, resolve); err != nil {
+		t.Errorf("reissued credential does not verify: %v", err)
+	}
+
+	docFile := filepath.Join(tmp, "did.json")
+	docData, _ := newDoc.Marshal()
+	os.WriteFile(docFile, docData, 0600)
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"verify-migration", "--file", identityPath(src, "migration.json"), "--did-doc", docFile})
+	if err := Execute(); err != nil {
+		t.Fatalf("verify-migration failed: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(newDoc.ID)) {
+		t.Errorf("unexpected output %s", buf)
+	}
+
+	// a did:web identity cannot migrate again, and --rotate-key gives a new key
+	migrateAlsoKnownAs = false
+	rootCmd.SetArgs([]string{"migrate", "--to", "web", "--domain", "other.example", "--name", "again", "--out", filepath.Join(tmp, "again"), "--from", dst})
+	if err := Execute(); err == nil {
+		t.Error("expected migrating a did:web identity to fail")
+	}
+	rotated := filepath.Join(tmp, "rotated")
+	rootCmd.SetArgs([]string{"migrate", "--to", "web", "--domain", "rotated.example", "--name", "rotated", "--out", rotated, "--from", src, "--rotate-key"})
+	if err := Execute(); err != nil {
+		t.Fatalf("migrate --rotate-key failed: %v", err)
+	}
+	rotDoc, _ := vault.NewVault(identityPath(rotated)).Document()
+	if rotPub, _ := rotDoc.VerificationMethod[0].PublicKey(); rotPub.Equal(oldPub) {
+		t.Error("--rotate-key reused the old key")
+	}
+}
+
+func TestMigrateStatusList(t *testing.T) {
+	tmp := t.TempDir()
+	src, dst := filepath.Join(tmp, "key"), filepath.Join(tmp, "web")
+	altVaultDir, vaultDir = "", ""
+	t.Cleanup(func() {
+		didMethod, webDomain, altVaultDir, vaultDir, credStatusURL = "", "", "", "", ""
+		revokeSuspend = false
+	})
+	run := func(args ...string) error {
+		rootCmd.SetArgs(args)
+		err := Execute()
+		credStatusURL, revokeSuspend = "", false
+		return err
+	}
+	for _, args := range [][]string{
+		{"init", "--name", "key", "--out", src},
+		{"set", "email", "ada@example.com", "--out", src},
+		{"issue", "--out", src, "--id", "urn:uuid:m1", "--status-url", "https://status.example/old"},
+		{"issue", "--out", src, "--id", "urn:uuid:m2", "--status-url", "https://status.example/old"},
+		{"revoke", "urn:uuid:m2", "--out", src},
+		{"issue", "--out", src, "--id", "urn:uuid:m3", "--status-url", "https://status.example/old"},
+		{"revoke", "urn:uuid:m3", "--suspend", "--out", src},
+	} {
+		if err := run(args...); err != nil {
+			t.Fatalf("%s failed: %v", args[0], err)
+		}
+	}
+	migrate := []string{"migrate", "--to", "web", "--domain", "example.com", "--name", "web", "--out", dst, "--from", src}
+	if err := run(migrate...); err == nil {
+		t.Fatal("expected error migrating credentials with a status without --status-url")
+	}
+	if _, err := os.Stat(dst); err == nil {
+		t.Error("vault created before --status-url was checked")
+	}
+	if err := run(append(migrate, "--status-url", "https://status.example/new")...); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+
+	newDoc, err := vault.NewVault(identityPath(dst)).Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolve := func(did string) (*identity.Document, error) {
+		if did == newDoc.ID {
+			return newDoc, nil
+		}
+		return credentials.ResolveDocument(did)
+	}
+	fetch, err := credentials.StatusListFiles(identityPath(dst, "status", "revocation.json"), identityPath(dst, "status", "suspension.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]error{"urn:uuid:m1": nil, "urn:uuid:m2": credentials.ErrRevoked, "urn:uuid:m3": credentials.ErrSuspended} {
+		data, _ := os.ReadFile(identityPath(dst, "credentials", id+".json"))
+		var cred credentials.Credential
+		if err := json.Unmarshal(data, &cred); err != nil {
+			t.Fatal(err)
+		}
+		if len(cred.CredentialStatus) != 2 || cred.CredentialStatus[0].StatusListCredential != "https://status.example/new/revocation" {
+			t.Errorf("%s: credentialStatus not moved to the new lists: %+v", id, cred.CredentialStatus)
+		}
+		opts := credentials.DefaultVerifyOptions()
+		opts.Resolve, opts.FetchStatusList = resolve, fetch
+		if err := credentials.VerifyCredentialWithOptions(&cred, opts); !errors.Is(err, want) || (want == nil) != (err == nil) {
+			t.Errorf("%s: got %v, want %v", id, err, want)
+		}
+	}
+}
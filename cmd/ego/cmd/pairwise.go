@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+// pairwiseCmd groups commands for the pairwise DID registry
+var pairwiseCmd = &cobra.Command{
+	Use:   "pairwise",
+	Short: "Inspect the pairwise DIDs used with relying parties",
+	Long: `'ego present --audience' and 'ego auth-respond' sign with a DID derived from the
+vault key for each relying party. The vault records which DID was shown to which domain.`,
+}
+
+// pairwiseListCmd prints the pairwise DID registry
+var pairwiseListCmd = &cobra.Command{
+	Use:   "list [--out <vaultDir>]",
+	Short: "List relying parties and the pairwise DID used with each",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		rootDir := cfg.RootDir
+		if rootDir == "" {
+			rootDir = "store"
+		}
+		vaultDir := altVaultDir
+		if vaultDir == "" {
+			vaultDir = filepath.Join(rootDir, cfg.Active)
+		}
+		if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+			return err
+		}
+
+		entries, err := vault.NewVault(vaultDir).PairwiseRegistry()
+		if err != nil {
+			return fmt.Errorf("load pairwise registry: %w", err)
+		}
+		for _, e := range entries {
+			cmd.Printf("%s\t%s\t%s\n", e.Domain, e.DID, e.LastUsed.Format(time.RFC3339))
+		}
+		return nil
+	},
+}
+
+// pairwisePeerCmd creates the did:peer used with a wallet-to-wallet contact
+var pairwisePeerCmd = &cobra.Command{
+	Use:   "peer <contact> [--endpoint <url>] [--out <vaultDir>]",
+	Short: "Create the pairwise did:peer for a contact",
+	Long: `Derive a numalgo 2 did:peer for a contact from the vault's pairwise seed, with its
+own signing and key agreement keys and a DIDComm service for each --endpoint, and record
+it in the pairwise registry as peer:<contact>. Share the printed DID with the contact, and sign
+for them with --audience peer:<contact>.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		rootDir := cfg.RootDir
+		if rootDir == "" {
+			rootDir = "store"
+		}
+		vaultDir := altVaultDir
+		if vaultDir == "" {
+			vaultDir = filepath.Join(rootDir, cfg.Active)
+		}
+		if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+			return err
+		}
+
+		v := vault.NewVault(vaultDir)
+		priv, seed, err := vaultPairwise(cmd, v)
+		if err != nil {
+			return fmt.Errorf("load vault: %w", err)
+		}
+		defer priv.Wipe()
+		defer clear(seed)
+		if seed == nil {
+			return vault.ErrNoPairwiseSeed
+		}
+		contact := vault.PeerContact(args[0])
+		did, err := vault.PeerContactDID(seed, contact, peerEndpoints)
+		if err != nil {
+			return err
+		}
+		if err := recordPairwise(cmd, v, contact, did); err != nil {
+			return err
+		}
+		if err := recordAudit(heldKeys(cmd, v, priv), "pairwise-peer", map[string]interface{}{"contact": contact, "endpoints": peerEndpoints}, []byte(did)); err != nil {
+			return err
+		}
+		cmd.Println(did)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pairwiseCmd)
+	pairwiseCmd.AddCommand(pairwiseListCmd, pairwisePeerCmd)
+	pairwiseListCmd.Flags().StringVar(&altVaultDir, "out", "", "Vault directory (optional, uses active)")
+	pairwisePeerCmd.Flags().StringVar(&altVaultDir, "out", "", "Vault directory (optional, uses active)")
+	pairwisePeerCmd.Flags().StringSliceVar(&peerEndpoints, "endpoint", nil, "DIDComm endpoint to publish in the did:peer; can be repeated")
+}
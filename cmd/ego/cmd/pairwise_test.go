@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+)
+
+func TestPresentPairwise(t *testing.T) {
+	tmp := t.TempDir()
+	altVaultDir = ""
+	t.Cleanup(func() { presentAudience, noPairwise = "", false })
+	for _, args := range [][]string{
+		{"init", "--name", "pw", "--out", tmp},
+		{"set", "name", "Ada", "--out", tmp},
+		{"issue", "--out", tmp, "--id", "vc1"},
+		{"present", "--out", tmp, "--audience", "https://shop.example/checkout"},
+	} {
+		rootCmd.SetArgs(args)
+		if err := Execute(); err != nil {
+			t.Fatalf("%s failed: %v", args[0], err)
+		}
+	}
+	did, _ := vault.NewVault(identityPath(tmp)).DID()
+
+	files, _ := os.ReadDir(identityPath(tmp, "presentations"))
+	data, err := os.ReadFile(identityPath(tmp, "presentations", files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pres credentials.Presentation
+	if err := json.Unmarshal(data, &pres); err != nil {
+		t.Fatal(err)
+	}
+	if pres.Holder == did {
+		t.Fatal("presentation holder is the vault DID; expected a pairwise DID")
+	}
+	if err := credentials.VerifyPresentation(&pres); err != nil {
+		t.Errorf("pairwise presentation does not verify: %v", err)
+	}
+
+	// auth-respond picks the same DID from the challenge domain, and a new one for another party
+	respond := func(domain string) string {
+		t.Helper()
+		chFile := filepath.Join(tmp, "challenge.json")
+		chData, _ := json.Marshal(credentials.NewAuthChallenge(domain, time.Minute))
+		os.WriteFile(chFile, chData, 0600)
+		buf := new(bytes.Buffer)
+		rootCmd.SetOut(buf)
+		rootCmd.SetErr(new(bytes.Buffer))
+		rootCmd.SetArgs([]string{"auth-respond", "--file", chFile, "--out", tmp})
+		if err := Execute(); err != nil {
+			t.Fatalf("auth-respond failed: %v", err)
+		}
+		var resp credentials.AuthenticationResponse
+		if err := json.Unmarshal(buf.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		holder, _, _ := strings.Cut(resp.Proof.VerificationMethod, "#")
+		return holder
+	}
+	if got := respond("shop.example"); got != pres.Holder {
+		t.Errorf("auth-respond used %s; want %s", got, pres.Holder)
+	}
+	other := respond("bank.example")
+	if other == pres.Holder || other == did {
+		t.Errorf("bank.example got a linkable DID %s", other)
+	}
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"pairwise", "list", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatalf("pairwise list failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "shop.example\t"+pres.Holder) || !strings.Contains(out, "bank.example\t"+other) {
+		t.Errorf("unexpected registry:\n%s", out)
+	}
+}
+
+func TestPeerIdentity(t *testing.T) {
+	tmp := t.TempDir()
+	altVaultDir = ""
+	t.Cleanup(func() { didMethod, peerEndpoints, presentAudience, noPairwise = "", nil, "", false })
+	for _, args := range [][]string{
+		{"init", "--name", "peer", "--method", "peer", "--endpoint", "https://alice.example/didcomm", "--out", tmp},
+		{"set", "name", "Ada", "--out", tmp},
+		{"issue", "--out", tmp, "--id", "vc1"},
+	} {
+		rootCmd.SetArgs(args)
+		if err := Execute(); err != nil {
+			t.Fatalf("%s failed: %v", args[0], err)
+		}
+	}
+	didMethod, peerEndpoints = "", nil
+	doc, err := vault.NewVault(identityPath(tmp)).Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(doc.ID, "did:peer:2.") || len(doc.KeyAgreement) != 1 || len(doc.Service) != 1 {
+		t.Fatalf("unexpected peer document %+v", doc)
+	}
+	data, _ := os.ReadFile(identityPath(tmp, "credentials", "vc1.json"))
+	var cred credentials.Credential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		t.Fatal(err)
+	}
+	if err := credentials.VerifyCredential(&cred); err != nil {
+		t.Errorf("credential of a did:peer issuer does not verify: %v", err)
+	}
+	var proof credentials.SignatureProof
+	json.Unmarshal(cred.Proofs[len(cred.Proofs)-1], &proof)
+	if proof.VerificationMethod != doc.ID+"#key-2" {
+		t.Errorf("credential signed with %s; want the assertion key #key-2", proof.VerificationMethod)
+	}
+
+	// presenting as the peer DID itself signs with its authentication key
+	rootCmd.SetArgs([]string{"present", "--out", tmp, "--no-pairwise"})
+	if err := Execute(); err != nil {
+		t.Fatalf("present --no-pairwise failed: %v", err)
+	}
+	noPairwise = false
+	files, _ := os.ReadDir(identityPath(tmp, "presentations"))
+	data, _ = os.ReadFile(identityPath(tmp, "presentations", files[0].Name()))
+	var own credentials.Presentation
+	if err := json.Unmarshal(data, &own); err != nil {
+		t.Fatal(err)
+	}
+	if err := credentials.VerifyPresentation(&own); err != nil {
+		t.Errorf("presentation by the did:peer holder does not verify: %v", err)
+	}
+	os.RemoveAll(identityPath(tmp, "presentations"))
+
+	// a pairwise did:peer for a contact, used to present to them
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"pairwise", "peer", "Bob", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatalf("pairwise peer failed: %v", err)
+	}
+	bob := strings.TrimSpace(buf.String())
+	if !strings.HasPrefix(bob, "did:peer:2.") || bob == doc.ID {
+		t.Fatalf("unexpected contact DID %q", bob)
+	}
+	rootCmd.SetArgs([]string{"present", "--out", tmp, "--audience", "peer:bob"})
+	if err := Execute(); err != nil {
+		t.Fatalf("present failed: %v", err)
+	}
+	files, _ = os.ReadDir(identityPath(tmp, "presentations"))
+	data, _ = os.ReadFile(identityPath(tmp, "presentations", files[0].Name()))
+	var pres credentials.Presentation
+	if err := json.Unmarshal(data, &pres); err != nil {
+		t.Fatal(err)
+	}
+	if pres.Holder != bob {
+		t.Errorf("presentation holder = %s; want %s", pres.Holder, bob)
+	}
+	if err := credentials.VerifyPresentation(&pres); err != nil {
+		t.Errorf("presentation to a peer contact does not verify: %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"rotate-key", "--out", tmp})
+	if err := Execute(); err == nil {
+		t.Error("expected rotate-key to refuse a did:peer identity")
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+const (
+	passphraseEnv    = "EGO_PASSPHRASE"
+	newPassphraseEnv = "EGO_NEW_PASSPHRASE"
+	// bundlePassphraseEnv protects vault export bundles
+	bundlePassphraseEnv = "EGO_BUNDLE_PASSPHRASE"
+)
+
+var passphraseFile string
+
+// readPassphrase resolves the vault passphrase from --passphrase-file, $EGO_PASSPHRASE
+// or an interactive prompt, in that order.
+func readPassphrase(cmd *cobra.Command, file, env, prompt string) ([]byte, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read passphrase file: %w", err)
+		}
+		return bytes.TrimRight(data, "\r\n"), nil
+	}
+	if p, ok := os.LookupEnv(env); ok {
+		return []byte(p), nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("no passphrase available: use --passphrase-file or $%s", env)
+	}
+	fmt.Fprint(cmd.ErrOrStderr(), prompt)
+	p, err := term.ReadPassword(fd)
+	fmt.Fprintln(cmd.ErrOrStderr())
+	if err != nil {
+		return nil, fmt.Errorf("read passphrase: %w", err)
+	}
+	return p, nil
+}
+
+// readNewPassphrase is like readPassphrase but asks twice when prompting interactively.
+func readNewPassphrase(cmd *cobra.Command, file, env string) ([]byte, error) {
+	_, fromEnv := os.LookupEnv(env)
+	p, err := readPassphrase(cmd, file, env, "New passphrase: ")
+	if err != nil || file != "" || fromEnv {
+		return p, err
+	}
+	confirm, err := readPassphrase(cmd, "", env, "Repeat passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(p, confirm) {
+		return nil, fmt.Errorf("passphrases do not match")
+	}
+	return p, nil
+}
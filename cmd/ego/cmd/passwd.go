@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+var newPassphraseFile string
+
+// passwdCmd changes the passphrase protecting keystore.json, or encrypts a legacy plaintext keystore
+var passwdCmd = &cobra.Command{
+	Use:   "passwd [--out <vaultDir>] [--new-passphrase-file <file>]",
+	Short: "Change the vault passphrase",
+	Long: `Re-encrypt keystore.json under a new passphrase.
+
+The current passphrase is read from --passphrase-file, $EGO_PASSPHRASE or a prompt;
+the new one from --new-passphrase-file, $EGO_NEW_PASSPHRASE or a prompt.
+A vault with a plaintext keystore is upgraded in place.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		rootDir := cfg.RootDir
+		if rootDir == "" {
+			rootDir = "store"
+		}
+		vaultDir := altVaultDir
+		if vaultDir == "" {
+			vaultDir = filepath.Join(rootDir, cfg.Active)
+		}
+		if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+			return err
+		}
+
+		v := vault.NewVault(vaultDir)
+		encrypted, err := v.IsEncrypted()
+		if err != nil {
+			return fmt.Errorf("load vault: %w", err)
+		}
+		var oldPass []byte
+		if encrypted {
+			oldPass, err = readPassphrase(cmd, passphraseFile, passphraseEnv, "Current passphrase: ")
+			if err != nil {
+				return err
+			}
+		}
+		newPass, err := readNewPassphrase(cmd, newPassphraseFile, newPassphraseEnv)
+		if err != nil {
+			return err
+		}
+		if err := v.ChangePassphrase(oldPass, newPass); err != nil {
+			return fmt.Errorf("change passphrase: %w", err)
+		}
+		_, priv, err := v.Load(newPass)
+		if err != nil {
+			return err
+		}
+		defer priv.Wipe()
+		if err := recordAudit(heldKeys(cmd, v, priv), "passwd", map[string]bool{"encrypted": encrypted}, nil); err != nil {
+			return err
+		}
+
+		if encrypted {
+			cmd.Println("Passphrase changed")
+		} else {
+			cmd.Println("Keystore encrypted")
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(passwdCmd)
+	passwdCmd.Flags().StringVar(&altVaultDir, "out", "", "Vault directory (optional, uses active)")
+	passwdCmd.Flags().StringVar(&newPassphraseFile, "new-passphrase-file", "", "File containing the new passphrase")
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPasswdCommand(t *testing.T) {
+	tmp := t.TempDir()
+	rootCmd.SetArgs([]string{"init", "--name", "pw", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+
+	newFile := filepath.Join(tmp, "new.txt")
+	os.WriteFile(newFile, []byte("changed\n"), 0600)
+	buf := &bytes.Buffer{}
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	rootCmd.SetArgs([]string{"passwd", "--out", tmp, "--new-passphrase-file", newFile})
+	if err := Execute(); err != nil {
+		t.Fatalf("passwd failed: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("Passphrase changed")) {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+
+	// old passphrase (from env) must no longer unlock the vault
+	rootCmd.SetArgs([]string{"set", "age", "1", "--out", tmp})
+	Execute()
+	rootCmd.SetArgs([]string{"issue", "--out", tmp, "--id", "pw1"})
+	if err := Execute(); err == nil {
+		t.Error("expected issue to fail with old passphrase")
+	}
+	rootCmd.SetArgs([]string{"issue", "--out", tmp, "--id", "pw1", "--passphrase-file", newFile})
+	if err := Execute(); err != nil {
+		t.Errorf("issue with new passphrase failed: %v", err)
+	}
+	// reset persistent flag for subsequent tests
+	passphraseFile = ""
+}
@@ -11,6 +11,8 @@ import (
 
 	"github.com/juanpablocruz/minervaid/internal/config"
 	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
+	"github.com/juanpablocruz/minervaid/internal/identity"
 	"github.com/juanpablocruz/minervaid/internal/vault"
 	"github.com/spf13/cobra"
 )
@@ -20,14 +22,22 @@ var (
 	revealFlag    string
 	outVault      string
 	zkpChallenges []string
+
+	presentAudience string
+	noPairwise      bool
 )
 
 // presentCmd creates a Verifiable Presentation from existing credentials
 var presentCmd = &cobra.Command{
-	Use:   "present [--creds <id,id,...>] [--reveal <field,field,...>] [--out <directory>]",
+	Use:   "present [--creds <id,id,...>] [--reveal <field,field,...>] [--audience <domain>] [--out <directory>]",
 	Short: "Create a Verifiable Presentation",
 	Long: `Load one or more VCs from vault credentials, optionally apply selective disclosure,
-and sign a Verifiable Presentation.`,
+and sign a Verifiable Presentation.
+
+With --audience the presentation is signed by the vault's pairwise DID for that relying
+party, so different verifiers cannot correlate the holder. The audience may be a domain,
+a URL or an openid4vp:// request; a request stands for the relying party it names, any
+other URL for its own host.`,
 	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Load CLI config
@@ -45,19 +55,16 @@ and sign a Verifiable Presentation.`,
 		if vaultDir == "" {
 			vaultDir = filepath.Join(rootDir, cfg.Active)
 		}
+		if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+			return err
+		}
 
-		// Load DID and key
+		// Load DID
 		v := vault.NewVault(vaultDir)
-		didDoc, priv, err := v.Load()
+		did, err := v.DID()
 		if err != nil {
 			return fmt.Errorf("load vault: %w", err)
 		}
-		// Parse DID
-		var didDocMap map[string]interface{}
-		if err := json.Unmarshal(didDoc, &didDocMap); err != nil {
-			return fmt.Errorf("parse did.json: %w", err)
-		}
-		did, _ := didDocMap["id"].(string)
 
 		// Determine credential IDs
 		var ids []string
@@ -137,10 +144,42 @@ and sign a Verifiable Presentation.`,
 			}
 		}
 
-		// Build presentation
+		// Build presentation, as the pairwise DID when an audience is given
+		domain := ""
+		if presentAudience != "" && !noPairwise {
+			if domain = vault.NormalizeDomain(presentAudience); domain == "" {
+				return fmt.Errorf("--audience %s names no relying party", presentAudience)
+			}
+		}
 		pres := credentials.NewPresentation(credsList, did)
-		if err := pres.SignPresentation(priv, did+"#keys-1"); err != nil {
-			return fmt.Errorf("sign presentation: %w", err)
+		keys := newVaultKeys(cmd, v)
+		defer keys.Close()
+		if keys.agent != nil {
+			if pres, err = keys.agent.SignPresentation(did, domain, pres); err != nil {
+				return fmt.Errorf("sign presentation via agent: %w", err)
+			}
+		} else if domain != "" {
+			s, holder, vm, err := keys.Pairwise(domain)
+			if err != nil {
+				return err
+			}
+			pres.Holder = holder
+			if err := pres.SignPresentation(cmd.Context(), s, vm); err != nil {
+				return fmt.Errorf("sign presentation: %w", err)
+			}
+		} else {
+			s, vm, err := keys.SignerFor(identity.PurposeAuthentication)
+			if err != nil {
+				return err
+			}
+			if err := pres.SignPresentation(cmd.Context(), s, vm); err != nil {
+				return fmt.Errorf("sign presentation: %w", err)
+			}
+		}
+		if domain != "" {
+			if err := recordPairwise(cmd, v, domain, pres.Holder); err != nil {
+				return err
+			}
 		}
 
 		// Save presentation
@@ -154,9 +193,13 @@ and sign a Verifiable Presentation.`,
 		if err != nil {
 			return fmt.Errorf("marshal presentation: %w", err)
 		}
-		if err := os.WriteFile(outFile, data, 0600); err != nil {
+		if err := fsutil.WriteFile(outFile, data, 0600); err != nil {
 			return fmt.Errorf("write presentation: %w", err)
 		}
+		inputs := map[string]interface{}{"creds": ids, "reveal": revealFlag, "zkp": zkpChallenges, "audience": domain}
+		if err := recordAudit(keys, "present", inputs, data); err != nil {
+			return err
+		}
 
 		cmd.Printf("Presentation '%s' created\n", presID)
 		return nil
@@ -171,4 +214,6 @@ func init() {
 	presentCmd.Flags().
 		StringSliceVar(&zkpChallenges, "zkp", nil,
 			"Add a zero-knowledge proof from `<type>:<field>:<param>`; can be repeated")
+	presentCmd.Flags().StringVar(&presentAudience, "audience", "", "Relying party domain or URL; signs with its pairwise DID")
+	presentCmd.Flags().BoolVar(&noPairwise, "no-pairwise", false, "Sign with the vault DID even when an audience is given")
 }
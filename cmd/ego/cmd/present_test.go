@@ -55,7 +55,7 @@ func TestPresentCommand_AllCredentials(t *testing.T) {
 	}
 
 	// Find the generated presentation file
-	presDir := filepath.Join(tmpDir, "presentations")
+	presDir := identityPath(tmpDir, "presentations")
 	files, err := os.ReadDir(presDir)
 	if err != nil || len(files) != 1 {
 		t.Fatalf("expected one presentation file, got %v, err=%v", files, err)
@@ -115,8 +115,8 @@ func TestPresentCommand_SelectiveDisclosure(t *testing.T) {
 	}
 
 	// Load the presentation file
-	files, _ := os.ReadDir(filepath.Join(tmpDir, "presentations"))
-	data, _ := os.ReadFile(filepath.Join(tmpDir, "presentations", files[0].Name()))
+	files, _ := os.ReadDir(identityPath(tmpDir, "presentations"))
+	data, _ := os.ReadFile(identityPath(tmpDir, "presentations", files[0].Name()))
 	var pres map[string]interface{}
 	json.Unmarshal(data, &pres)
 	vcs := pres["verifiableCredential"].([]interface{})
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+var shareFiles []string
+
+// recoverCmd rebuilds keystore.json from Shamir shares
+var recoverCmd = &cobra.Command{
+	Use:   "recover --share <file>... [--out <vaultDir>]",
+	Short: "Rebuild the vault key from recovery shares",
+	Long: `Combine shares written by 'ego split-key' and write a new keystore.json, sealed
+under a new passphrase. The vault's did.json must still be present: the recovered
+key is checked against its current verification method.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		rootDir := cfg.RootDir
+		if rootDir == "" {
+			rootDir = "store"
+		}
+		vaultDir := altVaultDir
+		if vaultDir == "" {
+			vaultDir = filepath.Join(rootDir, cfg.Active)
+		}
+		if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+			return err
+		}
+
+		shares := make([]*vault.KeyShare, 0, len(shareFiles))
+		for _, f := range shareFiles {
+			data, err := os.ReadFile(f)
+			if err != nil {
+				return fmt.Errorf("read share: %w", err)
+			}
+			var s vault.KeyShare
+			if err := json.Unmarshal(data, &s); err != nil {
+				return fmt.Errorf("invalid share %s: %w", f, err)
+			}
+			shares = append(shares, &s)
+		}
+
+		v := vault.NewVault(vaultDir)
+		doc, err := v.Document()
+		if err != nil {
+			return fmt.Errorf("load vault: %w", err)
+		}
+		vm, err := doc.CurrentMethod()
+		if err != nil {
+			return err
+		}
+		pub, err := vm.PublicKey()
+		if err != nil {
+			return err
+		}
+		priv, err := vault.CombineKeyShares(shares, pub)
+		if err != nil {
+			return fmt.Errorf("recover key: %w", err)
+		}
+		defer priv.Wipe()
+
+		pass, err := readNewPassphrase(cmd, passphraseFile, passphraseEnv)
+		if err != nil {
+			return err
+		}
+		if err := v.RecoverKey(priv, pass); err != nil {
+			return fmt.Errorf("recover key: %w", err)
+		}
+		if err := recordAudit(heldKeys(cmd, v, priv), "recover", map[string]int{"shares": len(shares)}, []byte(vault.KeyFingerprint(pub))); err != nil {
+			return err
+		}
+		cmd.Printf("Key for %s recovered into %s\n", doc.ID, vaultDir)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(recoverCmd)
+	recoverCmd.Flags().StringSliceVar(&shareFiles, "share", nil, "Share file; repeat for each share (required)")
+	recoverCmd.Flags().StringVar(&altVaultDir, "out", "", "Vault directory (optional, uses active)")
+	recoverCmd.MarkFlagRequired("share")
+}
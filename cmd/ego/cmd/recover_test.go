@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitKeyAndRecover(t *testing.T) {
+	tmp := t.TempDir()
+	vaultPath := filepath.Join(tmp, "team")
+	rootCmd.SetArgs([]string{"init", "--name", "team", "--out", vaultPath})
+	if err := Execute(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	shares := filepath.Join(tmp, "shares")
+	rootCmd.SetArgs([]string{"split-key", "--shares", "5", "--threshold", "3", "--dir", shares, "--out", vaultPath})
+	if err := Execute(); err != nil {
+		t.Fatalf("split-key failed: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(shares, "share-*.json"))
+	if len(files) != 5 {
+		t.Fatalf("expected 5 share files, got %v", files)
+	}
+
+	// lose the keystore, then rebuild it from three shares
+	os.Remove(identityPath(vaultPath, "keystore.json"))
+	rootCmd.SetArgs([]string{"recover", "--share", files[0], "--share", files[2], "--share", files[4], "--out", vaultPath})
+	if err := Execute(); err != nil {
+		t.Fatalf("recover failed: %v", err)
+	}
+	shareFiles = nil
+	rootCmd.SetArgs([]string{"set", "name", "Team", "--out", vaultPath})
+	Execute()
+	rootCmd.SetArgs([]string{"issue", "--out", vaultPath, "--id", "after-recovery"})
+	if err := Execute(); err != nil {
+		t.Fatalf("issue with recovered key failed: %v", err)
+	}
+
+	// two shares are not enough
+	buf.Reset()
+	rootCmd.SetArgs([]string{"recover", "--share", files[0], "--share", files[1], "--out", vaultPath})
+	err := Execute()
+	shareFiles = nil
+	if err == nil || !strings.Contains(err.Error(), "not enough shares") {
+		t.Errorf("expected not-enough-shares error, got %v", err)
+	}
+}
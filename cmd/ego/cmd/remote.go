@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
+	"github.com/juanpablocruz/minervaid/internal/resolver"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+var (
+	remoteOut       string
+	remoteVaultName string
+)
+
+// remoteCmd groups commands for publishing DID documents
+var remoteCmd = &cobra.Command{
+	Use:   "remote",
+	Short: "Publish the DID document of an identity",
+}
+
+// remoteExportCmd writes the did:web document to be hosted
+var remoteExportCmd = &cobra.Command{
+	Use:   "export web [--out <did.json>] [--vault <name>]",
+	Short: "Write the DID document to host for a did:web identity",
+	Long: `Write the did.json of the identity so it can be uploaded to the URL that
+did:web resolution fetches: https://<domain>/.well-known/did.json, or
+https://<domain>/<path>/did.json for a DID with a path. Without --out the
+document is printed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if args[0] != "web" {
+			return fmt.Errorf("unsupported remote %q; only 'web' is supported", args[0])
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		rootDir := cfg.RootDir
+		if rootDir == "" {
+			rootDir = "store"
+		}
+		vaultName := remoteVaultName
+		if vaultName == "" {
+			vaultName = cfg.Active
+		}
+		vaultDir, err := vault.IdentityDir(filepath.Join(rootDir, vaultName), identityName)
+		if err != nil {
+			return err
+		}
+
+		v := vault.NewVault(vaultDir)
+		doc, err := v.Document()
+		if err != nil {
+			return fmt.Errorf("load vault: %w", err)
+		}
+		if !strings.HasPrefix(doc.ID, "did:web:") {
+			return fmt.Errorf("%s is not a did:web identity; create one with 'ego init --web <domain>'", doc.ID)
+		}
+		url, err := resolver.WebURL(doc.ID)
+		if err != nil {
+			return err
+		}
+		if err := v.CheckActive(); err != nil {
+			return fmt.Errorf("%w; remove %s or serve it with status 410 Gone", err, url)
+		}
+		data, err := doc.Marshal()
+		if err != nil {
+			return err
+		}
+		if remoteOut == "" {
+			cmd.Println(string(data))
+			return nil
+		}
+		if err := fsutil.WriteFile(remoteOut, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("write DID document: %w", err)
+		}
+		cmd.Printf("DID document for %s written to %s\nHost it at %s\n", doc.ID, remoteOut, url)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(remoteCmd)
+	remoteCmd.AddCommand(remoteExportCmd)
+	remoteExportCmd.Flags().StringVar(&remoteOut, "out", "", "File to write the DID document to (default: stdout)")
+	remoteExportCmd.Flags().StringVar(&remoteVaultName, "vault", "", "Name of the vault (default: active)")
+}
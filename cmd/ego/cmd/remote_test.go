@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/resolver"
+)
+
+func TestRemoteExportWeb(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+	root := filepath.Join(tmp, "vaults")
+	hosted := filepath.Join(tmp, "did.json")
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/did.json" {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, hosted)
+	}))
+	defer srv.Close()
+	saved := credentials.DefaultResolver
+	credentials.DefaultResolver = resolver.NewRegistry(srv.Client())
+	defer func() { credentials.DefaultResolver = saved }()
+	domain := strings.TrimPrefix(srv.URL, "https://")
+
+	altVaultDir, vaultDir = "", ""
+	t.Cleanup(func() { webDomain, remoteOut = "", "" })
+	for _, args := range [][]string{
+		{"init", "--name", "web", "--web", domain, "--out", filepath.Join(root, "web")},
+		{"use", "web", root},
+		{"set", "name", "Ada"},
+		{"issue", "--id", "vc1"},
+		{"remote", "export", "web", "--out", hosted},
+	} {
+		rootCmd.SetArgs(args)
+		if err := Execute(); err != nil {
+			t.Fatalf("%s failed: %v", args[0], err)
+		}
+	}
+	webDomain = ""
+
+	data, err := os.ReadFile(hosted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := resolver.WebDID(domain); !bytes.Contains(data, []byte(`"id": "`+want+`"`)) {
+		t.Fatalf("exported document is not for %s: %s", want, data)
+	}
+
+	// the credential verifies against the hosted document
+	data, err = os.ReadFile(identityPath(filepath.Join(root, "web"), "credentials", "vc1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cred credentials.Credential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		t.Fatal(err)
+	}
+	if err := credentials.VerifyCredential(&cred); err != nil {
+		t.Errorf("did:web credential does not verify: %v", err)
+	}
+
+	// a did:key identity has nothing to publish
+	rootCmd.SetArgs([]string{"init", "--name", "key", "--out", filepath.Join(root, "key")})
+	if err := Execute(); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"remote", "export", "web", "--vault", "key"})
+	if err := Execute(); err == nil {
+		t.Error("expected remote export to refuse a did:key identity")
+	}
+	remoteVaultName = ""
+}
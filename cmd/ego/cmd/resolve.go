@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/juanpablocruz/minervaid/internal/resolver"
+	"github.com/spf13/cobra"
+)
+
+var resolveMetadata bool
+
+// resolveCmd prints the DID Document of any supported DID
+var resolveCmd = &cobra.Command{
+	Use:   "resolve <did> [--metadata]",
+	Short: "Resolve a DID and print its DID Document",
+	Long: `Resolve did:key, did:jwk and did:peer DIDs from the identifier and fetch did:web
+documents over HTTPS. With --metadata the output is the full resolution result:
+didDocument, didResolutionMetadata and didDocumentMetadata.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		res, err := resolver.NewRegistry(nil).Resolve(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		var out interface{} = res.Document
+		if resolveMetadata {
+			out = res
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		cmd.Println(string(data))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resolveCmd)
+	resolveCmd.Flags().BoolVar(&resolveMetadata, "metadata", false, "Print the resolution and document metadata too")
+}
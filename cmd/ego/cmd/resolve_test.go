@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/resolver"
+)
+
+func TestResolve(t *testing.T) {
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+	t.Cleanup(func() { resolveMetadata = false })
+
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"resolve", did, "--metadata"})
+	if err := Execute(); err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	var res resolver.Resolution
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatalf("invalid output %s: %v", buf, err)
+	}
+	if res.Document == nil || res.Document.ID != did || res.ResolutionMetadata.Retrieved.IsZero() {
+		t.Errorf("unexpected resolution %s", buf)
+	}
+
+	rootCmd.SetArgs([]string{"resolve", "did:example:123"})
+	if err := Execute(); err == nil {
+		t.Error("expected an unsupported method to fail")
+	}
+}
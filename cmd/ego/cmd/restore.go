@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mnemonicFile   string
+	restoreIndex   uint32
+	restoreDocFile string
+)
+
+// restoreCmd rebuilds a vault from its recovery mnemonic
+var restoreCmd = &cobra.Command{
+	Use:   "restore --name <name> --mnemonic-file <file> [--web domain.com | --did-doc <did.json>] [--index <n>] [--out <dir>]",
+	Short: "Rebuild an identity from its recovery mnemonic",
+	Long: `Derive the key of an identity created with 'ego init --mnemonic' and write a new
+did.json and keystore.json. Pass the same --web domain used at creation to get
+back a did:web identity; --index selects a key other than the first for a did:key.
+
+The document of a did:web identity whose key was rotated with 'ego rotate-key
+--mnemonic-file' cannot be derived: it also lists the retired keys. Pass its
+published did.json with --did-doc instead; the key of its current #keys-N method is
+derived at index N-1 and the document is restored as is.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		words, err := os.ReadFile(mnemonicFile)
+		if err != nil {
+			return fmt.Errorf("read mnemonic: %w", err)
+		}
+		index := restoreIndex
+		var didDoc []byte
+		if restoreDocFile != "" {
+			if webDomain != "" {
+				return fmt.Errorf("--web cannot be used with --did-doc")
+			}
+			if didDoc, err = os.ReadFile(restoreDocFile); err != nil {
+				return fmt.Errorf("read DID document: %w", err)
+			}
+			n, err := currentKeyIndex(didDoc)
+			if err != nil {
+				return err
+			}
+			if restoreIndex == 0 {
+				index = n
+			}
+		} else if webDomain != "" && restoreIndex > 0 {
+			return fmt.Errorf("the document of a rotated identity cannot be derived; pass its did.json with --did-doc")
+		}
+		priv, err := identity.DeriveKey(string(words), index)
+		if err != nil {
+			return err
+		}
+		defer priv.Wipe()
+		if didDoc != nil {
+			if err := checkCurrentKey(didDoc, priv.Public()); err != nil {
+				return err
+			}
+		}
+
+		target := vaultDir
+		if target == "" {
+			target = filepath.Join("store", name)
+		}
+		if vault.IsVault(target) {
+			return fmt.Errorf("%s already holds a vault; choose another --name or --out", target)
+		}
+
+		did, target, err := createVault(cmd, priv, didDoc)
+		if err != nil {
+			return err
+		}
+		cmd.Printf("Identity '%s' restored at %s\n", did, target)
+		return nil
+	},
+}
+
+// currentKeyIndex returns the derivation index of the current #keys-N method of the DID
+// Document data: N-1, as 'ego rotate-key --mnemonic-file' derives it.
+func currentKeyIndex(data []byte) (uint32, error) {
+	doc, err := identity.ParseDIDDocument(data)
+	if err != nil {
+		return 0, err
+	}
+	vm, err := doc.CurrentMethod()
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(vm.ID, doc.ID+"#keys-"))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("current method %s is not a #keys-N key derived from a mnemonic", vm.ID)
+	}
+	return uint32(n - 1), nil
+}
+
+// checkCurrentKey checks that pub is the key of the current method of the DID Document data.
+func checkCurrentKey(data []byte, pub *identity.PublicKey) error {
+	doc, err := identity.ParseDIDDocument(data)
+	if err != nil {
+		return err
+	}
+	vm, err := doc.CurrentMethod()
+	if err != nil {
+		return err
+	}
+	cur, err := vm.PublicKey()
+	if err != nil {
+		return err
+	}
+	if !cur.Equal(pub) {
+		return fmt.Errorf("the mnemonic does not derive the key of %s", vm.ID)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.Flags().StringVar(&name, "name", "", "Name of the identity (required)")
+	restoreCmd.Flags().StringVar(&mnemonicFile, "mnemonic-file", "", "File containing the recovery words (required)")
+	restoreCmd.Flags().StringVar(&webDomain, "web", "", "Domain for did:web method (optional)")
+	restoreCmd.Flags().StringVar(&restoreDocFile, "did-doc", "", "Published did.json of the identity, for did:web identities with rotated keys")
+	restoreCmd.Flags().Uint32Var(&restoreIndex, "index", 0, "Derivation index of the key (default 0)")
+	restoreCmd.Flags().StringVar(&vaultDir, "out", "", "Directory in which to restore the identity (optional)")
+	restoreCmd.MarkFlagRequired("name")
+	restoreCmd.MarkFlagRequired("mnemonic-file")
+}
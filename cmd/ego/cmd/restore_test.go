@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+)
+
+func TestInitMnemonicAndRestore(t *testing.T) {
+	tmp := t.TempDir()
+	orig := filepath.Join(tmp, "orig")
+	buf := &bytes.Buffer{}
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	rootCmd.SetArgs([]string{"init", "--name", "m", "--mnemonic", "--web", "restore.example", "--out", orig})
+	if err := Execute(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	mnemonic = false
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	words := lines[len(lines)-1]
+	if n := len(strings.Fields(words)); n != 24 {
+		t.Fatalf("expected 24 recovery words, got %d: %q", n, words)
+	}
+	wordsFile := filepath.Join(tmp, "words.txt")
+	os.WriteFile(wordsFile, []byte(words+"\n"), 0600)
+
+	restored := filepath.Join(tmp, "restored")
+	rootCmd.SetArgs([]string{"restore", "--name", "m", "--mnemonic-file", wordsFile, "--web", "restore.example", "--out", restored})
+	if err := Execute(); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+
+	want, _ := os.ReadFile(identityPath(orig, "did.json"))
+	got, _ := os.ReadFile(identityPath(restored, "did.json"))
+	if !bytes.Equal(want, got) {
+		t.Errorf("restored did.json differs:\n%s\nvs\n%s", got, want)
+	}
+	_, k1, err := vault.NewVault(identityPath(orig)).Load([]byte(testPassphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, k2, err := vault.NewVault(identityPath(restored)).Load([]byte(testPassphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(k1.Raw, k2.Raw) {
+		t.Error("restored key differs from the original")
+	}
+
+	// restoring over an existing vault is refused
+	rootCmd.SetArgs([]string{"restore", "--name", "m", "--mnemonic-file", wordsFile, "--out", restored})
+	if err := Execute(); err == nil {
+		t.Error("expected restore to refuse an existing vault")
+	}
+	webDomain = ""
+}
+
+func TestRestoreRotated(t *testing.T) {
+	t.Cleanup(func() {
+		mnemonic, webDomain, rotateMnemonic, rotateKeyType, restoreDocFile, restoreIndex = false, "", "", "", "", 0
+	})
+	tmp := t.TempDir()
+	orig := filepath.Join(tmp, "orig")
+	buf := &bytes.Buffer{}
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	rootCmd.SetArgs([]string{"init", "--name", "m", "--mnemonic", "--web", "rotated.example", "--out", orig})
+	if err := Execute(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	mnemonic, webDomain = false, ""
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	wordsFile := filepath.Join(tmp, "words.txt")
+	os.WriteFile(wordsFile, []byte(lines[len(lines)-1]+"\n"), 0600)
+
+	// the words of another identity, or a key type, are refused
+	other, _ := identity.NewMnemonic()
+	otherFile := filepath.Join(tmp, "other.txt")
+	os.WriteFile(otherFile, []byte(other+"\n"), 0600)
+	rootCmd.SetArgs([]string{"rotate-key", "--mnemonic-file", otherFile, "--out", orig})
+	if err := Execute(); err == nil || !strings.Contains(err.Error(), "does not derive") {
+		t.Errorf("expected rotate-key to refuse another mnemonic, got %v", err)
+	}
+	rootCmd.SetArgs([]string{"rotate-key", "--mnemonic-file", wordsFile, "--key-type", "secp256k1", "--out", orig})
+	if err := Execute(); err == nil {
+		t.Error("expected rotate-key to refuse --key-type with --mnemonic-file")
+	}
+	rotateKeyType = ""
+	rotateKeyCmd.Flags().Lookup("key-type").Changed = false
+	if doc, _ := vault.NewVault(identityPath(orig)).Document(); doc.NextKeyIndex() != 2 {
+		t.Fatalf("a refused rotation changed did.json")
+	}
+
+	rootCmd.SetArgs([]string{"rotate-key", "--mnemonic-file", wordsFile, "--out", orig})
+	if err := Execute(); err != nil {
+		t.Fatalf("rotate-key failed: %v", err)
+	}
+	rotateMnemonic = ""
+
+	// the document cannot be derived once rotated
+	rootCmd.SetArgs([]string{"restore", "--name", "m", "--mnemonic-file", wordsFile, "--web", "rotated.example", "--index", "1", "--out", filepath.Join(tmp, "derived")})
+	if err := Execute(); err == nil {
+		t.Error("expected restore to refuse deriving a rotated did:web document")
+	}
+	webDomain, restoreIndex = "", 0
+
+	restored := filepath.Join(tmp, "restored")
+	rootCmd.SetArgs([]string{"restore", "--name", "m", "--mnemonic-file", wordsFile, "--did-doc", identityPath(orig, "did.json"), "--out", restored})
+	if err := Execute(); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	want, _ := os.ReadFile(identityPath(orig, "did.json"))
+	got, _ := os.ReadFile(identityPath(restored, "did.json"))
+	if !bytes.Equal(want, got) {
+		t.Errorf("restored did.json differs:\n%s\nvs\n%s", got, want)
+	}
+	_, k1, err := vault.NewVault(identityPath(orig)).Load([]byte(testPassphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, k2, err := vault.NewVault(identityPath(restored)).Load([]byte(testPassphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(k1.Raw, k2.Raw) {
+		t.Error("restored key is not the rotated key")
+	}
+}
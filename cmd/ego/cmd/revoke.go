@@ -1,19 +1,32 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 
 	"github.com/juanpablocruz/minervaid/internal/config"
 	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/vault"
 	"github.com/spf13/cobra"
 )
 
+var (
+	revokeSuspend   bool
+	revokeUnsuspend bool
+)
+
 // revokeCmd marks a credential as revoked in the vault
 var revokeCmd = &cobra.Command{
-	Use:   "revoke <credID> [--out <vaultDir>]",
+	Use:   "revoke <credID> [--suspend | --unsuspend] [--out <vaultDir>]",
 	Short: "Revoke a credential",
-	Args:  cobra.ExactArgs(1),
+	Long: `Revoke a credential. For a credential issued with --status-url, set its bit in the
+vault's signed revocation status list, or with --suspend or --unsuspend in its
+suspension list, and republish the list for verifiers to see the change.
+
+Credentials without a credentialStatus are only added to the vault's local
+revocations.json, which verifiers cannot see.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		credID := args[0]
 		// Load config
@@ -29,6 +42,31 @@ var revokeCmd = &cobra.Command{
 		if vaultDir == "" {
 			vaultDir = filepath.Join(rootDir, cfg.Active)
 		}
+		if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+			return err
+		}
+		if revokeSuspend && revokeUnsuspend {
+			return fmt.Errorf("--suspend and --unsuspend cannot be combined")
+		}
+		lists := &credentials.StatusLists{Dir: filepath.Join(vaultDir, "status")}
+		hasStatus, err := lists.Has(credID)
+		if err != nil {
+			return fmt.Errorf("load status lists: %w", err)
+		}
+		if hasStatus {
+			return setStatus(cmd, vaultDir, lists, credID)
+		}
+		if revokeSuspend || revokeUnsuspend {
+			return fmt.Errorf("credential '%s' has no credentialStatus and cannot be suspended", credID)
+		}
+
+		// unlock the key for the audit entry before changing anything
+		keys := newVaultKeys(cmd, vault.NewVault(vaultDir))
+		defer keys.Close()
+		if _, err := keys.Signer(); err != nil {
+			return err
+		}
+
 		// Initialize revocation list
 		rl, err := credentials.NewRevocationList(filepath.Join(vaultDir, "revocations.json"))
 		if err != nil {
@@ -37,12 +75,50 @@ var revokeCmd = &cobra.Command{
 		if err := rl.Revoke(credID); err != nil {
 			return fmt.Errorf("revoke credential: %w", err)
 		}
+		out, err := json.Marshal(rl.List())
+		if err != nil {
+			return err
+		}
+		if err := recordAudit(keys, "revoke", map[string]string{"id": credID}, out); err != nil {
+			return err
+		}
 		cmd.Printf("Credential '%s' revoked\n", credID)
 		return nil
 	},
 }
 
+// setStatus revokes, suspends or reinstates credID in the vault's status lists.
+func setStatus(cmd *cobra.Command, vaultDir string, lists *credentials.StatusLists, credID string) error {
+	purpose, value, done := credentials.StatusPurposeRevocation, true, "revoked"
+	if revokeSuspend {
+		purpose, done = credentials.StatusPurposeSuspension, "suspended"
+	} else if revokeUnsuspend {
+		purpose, value, done = credentials.StatusPurposeSuspension, false, "reinstated"
+	}
+	keys := newVaultKeys(cmd, vault.NewVault(vaultDir))
+	defer keys.Close()
+	list, err := lists.Set(credID, purpose, value, func(list *credentials.Credential) error {
+		return keys.SignCredential(list, "")
+	})
+	if err != nil {
+		return fmt.Errorf("update status list: %w", err)
+	}
+	out, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	inputs := map[string]interface{}{"id": credID, "statusPurpose": purpose, "value": value}
+	if err := recordAudit(keys, "revoke", inputs, out); err != nil {
+		return err
+	}
+	cmd.Printf("Credential '%s' %s\n", credID, done)
+	cmd.Printf("Publish %s at %s\n", filepath.Join(lists.Dir, purpose+".json"), list.ID)
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(revokeCmd)
+	revokeCmd.Flags().BoolVar(&revokeSuspend, "suspend", false, "Suspend the credential instead of revoking it")
+	revokeCmd.Flags().BoolVar(&revokeUnsuspend, "unsuspend", false, "Reinstate a suspended credential")
 	revokeCmd.Flags().StringVar(&altVaultDir, "out", "", "Vault directory (optional, uses active)")
 }
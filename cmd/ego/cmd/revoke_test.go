@@ -2,9 +2,14 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/credentials"
 )
 
 func TestRevokeCommand(t *testing.T) {
@@ -15,7 +20,7 @@ func TestRevokeCommand(t *testing.T) {
 		t.Fatal(err)
 	}
 	// create a dummy VC file
-	credDir := filepath.Join(tmp, "credentials")
+	credDir := identityPath(tmp, "credentials")
 	os.MkdirAll(credDir, 0700)
 	dummy := []byte(`{"id":"c1"}`)
 	os.WriteFile(filepath.Join(credDir, "c1.json"), dummy, 0600)
@@ -31,3 +36,87 @@ func TestRevokeCommand(t *testing.T) {
 		t.Errorf("unexpected output %s", buf.String())
 	}
 }
+
+func TestRevokeCommand_StatusList(t *testing.T) {
+	t.Cleanup(func() { credStatusURL, credSuite, revokeSuspend, revokeUnsuspend = "", "", false, false })
+	tmp := t.TempDir()
+	rootCmd.SetArgs([]string{"init", "--name", "v", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(identityPath(tmp, "attributes.json"), []byte(`{"name":"Alice"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// a credential that fails to sign gives its status index back
+	rootCmd.SetArgs([]string{"issue", "--out", tmp, "--id", "urn:uuid:c2", "--status-url", "https://status.example/v", "--suite", "bogus-2024"})
+	if err := Execute(); err == nil {
+		t.Fatal("expected error for an unknown suite")
+	}
+	credSuite = ""
+	rootCmd.SetArgs([]string{"issue", "--out", tmp, "--id", "urn:uuid:c2", "--status-url", "https://status.example/v"})
+	if err := Execute(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(identityPath(tmp, "credentials", "urn:uuid:c2.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cred credentials.Credential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		t.Fatal(err)
+	}
+	if len(cred.CredentialStatus) != 2 {
+		t.Fatalf("unexpected credentialStatus in %s", data)
+	}
+	verify := func() error {
+		fetch, err := credentials.StatusListFiles(identityPath(tmp, "status", "revocation.json"), identityPath(tmp, "status", "suspension.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return credentials.CheckStatus(&cred, fetch, credentials.ResolveDocument)
+	}
+	if err := verify(); err != nil {
+		t.Fatalf("fresh credential: %v", err)
+	}
+
+	run := func(args ...string) string {
+		t.Helper()
+		buf := &bytes.Buffer{}
+		rootCmd.SetOut(buf)
+		rootCmd.SetErr(buf)
+		rootCmd.SetArgs(append(args, "--out", tmp))
+		if err := Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		revokeSuspend, revokeUnsuspend = false, false
+		return buf.String()
+	}
+	if out := run("revoke", "urn:uuid:c2", "--suspend"); !strings.Contains(out, "Credential 'urn:uuid:c2' suspended") {
+		t.Errorf("unexpected output %s", out)
+	}
+	if err := verify(); !errors.Is(err, credentials.ErrSuspended) {
+		t.Errorf("suspended credential: got %v", err)
+	}
+	if out := run("check-revoked", "urn:uuid:c2"); !strings.Contains(out, "is suspended") {
+		t.Errorf("unexpected output %s", out)
+	}
+	run("revoke", "urn:uuid:c2", "--unsuspend")
+	if err := verify(); err != nil {
+		t.Errorf("reinstated credential: %v", err)
+	}
+	if out := run("revoke", "urn:uuid:c2"); !strings.Contains(out, "Credential 'urn:uuid:c2' revoked") {
+		t.Errorf("unexpected output %s", out)
+	}
+	if err := verify(); !errors.Is(err, credentials.ErrRevoked) {
+		t.Errorf("revoked credential: got %v", err)
+	}
+	// the local revocations.json is not used for credentials with a status
+	if _, err := os.Stat(identityPath(tmp, "revocations.json")); err == nil {
+		t.Error("revocations.json written for a credential with a status")
+	}
+
+	rootCmd.SetArgs([]string{"revoke", "c3", "--suspend", "--out", tmp})
+	if err := Execute(); err == nil {
+		t.Error("expected error suspending a credential without a status")
+	}
+}
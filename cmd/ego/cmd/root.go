@@ -2,6 +2,9 @@ package cmd
 
 import "github.com/spf13/cobra"
 
+// identityName selects an identity of the vault other than its active one.
+var identityName string
+
 var rootCmd = &cobra.Command{
 	Use:   "ego",
 	Short: "Ego - CLI for SSI",
@@ -16,4 +19,7 @@ func init() {
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(issueCmd)
 	rootCmd.AddCommand(verifyCmd)
+	rootCmd.PersistentFlags().StringVar(&passphraseFile, "passphrase-file", "", "File containing the vault passphrase (default: $EGO_PASSPHRASE or prompt)")
+	rootCmd.PersistentFlags().StringVar(&identityName, "identity", "", "Identity of the vault to use (default: the vault's active identity)")
+	rootCmd.PersistentFlags().StringVar(&signerCommand, "signer-command", "", "External signer command that holds the vault key (default: $EGO_SIGNER_COMMAND)")
 }
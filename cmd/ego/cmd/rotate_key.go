@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+var (
+	retireAt       string
+	rotateKeyType  string
+	rotateMnemonic string
+)
+
+// rotateKeyCmd replaces the vault key and records the new verification method in did.json
+var rotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key [--retire-at <RFC3339>] [--out <vaultDir>]",
+	Short: "Rotate the signing key of the active identity",
+	Long: `Generate a new key, add it to did.json as #keys-N and mark the previous method as
+retired from --retire-at (default: now). Old public keys stay in the document so
+credentials signed before the rotation keep verifying.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		rootDir := cfg.RootDir
+		if rootDir == "" {
+			rootDir = "store"
+		}
+		vaultDir := altVaultDir
+		if vaultDir == "" {
+			vaultDir = filepath.Join(rootDir, cfg.Active)
+		}
+		if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+		retire := now
+		if retireAt != "" {
+			if retire, err = time.Parse(time.RFC3339, retireAt); err != nil {
+				return fmt.Errorf("invalid --retire-at: %w", err)
+			}
+		}
+
+		v := vault.NewVault(vaultDir)
+		doc, err := v.Document()
+		if err != nil {
+			return fmt.Errorf("load vault: %w", err)
+		}
+		if !strings.HasPrefix(doc.ID, "did:web:") {
+			return fmt.Errorf("%s is derived from its key and cannot rotate; use a did:web identity", doc.ID)
+		}
+		encrypted, err := v.IsEncrypted()
+		if err != nil {
+			return fmt.Errorf("load vault: %w", err)
+		}
+		var pass []byte
+		if encrypted {
+			pass, err = readPassphrase(cmd, passphraseFile, passphraseEnv, "Vault passphrase: ")
+		} else {
+			// a plaintext keystore gets sealed as part of the rotation
+			pass, err = readNewPassphrase(cmd, passphraseFile, passphraseEnv)
+		}
+		if err != nil {
+			return err
+		}
+
+		var words []byte
+		if rotateMnemonic != "" {
+			if words, err = os.ReadFile(rotateMnemonic); err != nil {
+				return fmt.Errorf("read mnemonic: %w", err)
+			}
+		}
+		// the document is read and updated under the vault lock
+		var vmID string
+		var newKey *identity.PrivateKey
+		didDoc, err := v.Rotate(pass, func(doc *identity.Document) (*identity.PrivateKey, error) {
+			kt := identity.KeyTypeEd25519
+			if words != nil {
+				if err := checkMnemonic(doc, string(words)); err != nil {
+					return nil, err
+				}
+				// #keys-N is derived at index N-1, so a restore can recreate every key
+				priv, err := identity.DeriveKey(string(words), uint32(doc.NextKeyIndex()-1))
+				if err != nil {
+					return nil, err
+				}
+				vmID, newKey = doc.RotateKey(priv.Public(), now, retire), priv
+				return priv, nil
+			}
+			if rotateKeyType != "" {
+				var err error
+				if kt, err = identity.ParseKeyType(rotateKeyType); err != nil {
+					return nil, err
+				}
+			} else if cur, err := doc.CurrentMethod(); err == nil {
+				// keep the algorithm of the key being replaced
+				if pub, err := cur.PublicKey(); err == nil {
+					kt = pub.Type
+				}
+			}
+			priv, err := identity.GenerateKey(kt)
+			if err != nil {
+				return nil, fmt.Errorf("generate key pair: %w", err)
+			}
+			vmID, newKey = doc.RotateKey(priv.Public(), now, retire), priv
+			return priv, nil
+		})
+		if newKey != nil {
+			defer newKey.Wipe()
+		}
+		if err != nil {
+			return fmt.Errorf("rotate key: %w", err)
+		}
+		// the entry is signed by the new key, which did.json now lists as current
+		if err := recordAudit(heldKeys(cmd, v, newKey), "rotate-key", map[string]string{"verificationMethod": vmID}, didDoc); err != nil {
+			return err
+		}
+
+		cmd.Printf("Key rotated; now signing with %s\n", vmID)
+		if runningAgent() != nil {
+			cmd.Println("An agent is running; run 'ego agent lock' and 'ego agent unlock' to load the new key")
+		}
+		return nil
+	},
+}
+
+// checkMnemonic refuses words that do not derive #keys-1 of doc, the first key of the
+// identity, so a rotation cannot switch the identity to keys of another mnemonic.
+func checkMnemonic(doc *identity.Document, words string) error {
+	first, err := doc.Method(doc.ID + "#keys-1")
+	if err != nil {
+		return fmt.Errorf("%s has no #keys-1 to check the mnemonic against", doc.ID)
+	}
+	pub, err := first.PublicKey()
+	if err != nil {
+		return err
+	}
+	priv, err := identity.DeriveKey(words, 0)
+	if err != nil {
+		return err
+	}
+	defer priv.Wipe()
+	if !priv.Public().Equal(pub) {
+		return fmt.Errorf("the mnemonic does not derive %s; it belongs to another identity", first.ID)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(rotateKeyCmd)
+	rotateKeyCmd.Flags().StringVar(&retireAt, "retire-at", "", "Time from which the old key is retired (RFC3339, default now)")
+	rotateKeyCmd.Flags().StringVar(&rotateKeyType, "key-type", "", "Key type of the new key (default: same as the current key)")
+	rotateKeyCmd.Flags().StringVar(&rotateMnemonic, "mnemonic-file", "", "Derive the new key from this recovery mnemonic instead of generating one")
+	rotateKeyCmd.Flags().StringVar(&altVaultDir, "out", "", "Vault directory (optional, uses active)")
+	// keys derived from a mnemonic are always Ed25519
+	rotateKeyCmd.MarkFlagsMutuallyExclusive("key-type", "mnemonic-file")
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRotateKeyCommand(t *testing.T) {
+	tmp := t.TempDir()
+	rootCmd.SetArgs([]string{"init", "--name", "web", "--web", "rotate.example", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	webDomain = ""
+	rootCmd.SetArgs([]string{"set", "name", "Rot", "--out", tmp})
+	Execute()
+	rootCmd.SetArgs([]string{"issue", "--out", tmp, "--id", "before"})
+	if err := Execute(); err != nil {
+		t.Fatalf("issue before rotation failed: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	rootCmd.SetOut(buf)
+	rootCmd.SetErr(buf)
+	rootCmd.SetArgs([]string{"rotate-key", "--out", tmp, "--retire-at", "2999-01-01T00:00:00Z"})
+	if err := Execute(); err != nil {
+		t.Fatalf("rotate-key failed: %v", err)
+	}
+	retireAt = ""
+	if !bytes.Contains(buf.Bytes(), []byte("#keys-2")) {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+
+	rootCmd.SetArgs([]string{"issue", "--out", tmp, "--id", "after"})
+	if err := Execute(); err != nil {
+		t.Fatalf("issue after rotation failed: %v", err)
+	}
+
+	didDoc := identityPath(tmp, "did.json")
+	for _, id := range []string{"before", "after"} {
+		buf.Reset()
+		rootCmd.SetArgs([]string{"verify", "--file", identityPath(tmp, "credentials", id+".json"), "--did-doc", didDoc})
+		if err := Execute(); err != nil {
+			t.Fatalf("verify %s failed: %v", id, err)
+		}
+		if !bytes.Contains(buf.Bytes(), []byte("Credential is valid")) {
+			t.Errorf("credential %s did not verify: %s", id, buf.String())
+		}
+	}
+	didDocFile = ""
+}
+
+func TestRotateKeyRejectsDidKey(t *testing.T) {
+	tmp := t.TempDir()
+	rootCmd.SetArgs([]string{"init", "--name", "k", "--out", tmp})
+	if err := Execute(); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+	rootCmd.SetArgs([]string{"rotate-key", "--out", tmp})
+	if err := Execute(); err == nil {
+		t.Error("expected rotate-key to refuse a did:key identity")
+	}
+}
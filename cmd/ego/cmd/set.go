@@ -3,11 +3,12 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"io/fs"
 	"os"
 	"path/filepath"
 
 	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
+	"github.com/juanpablocruz/minervaid/internal/vault"
 	"github.com/spf13/cobra"
 )
 
@@ -35,34 +36,27 @@ var setCmd = &cobra.Command{
 		if vaultDir == "" {
 			vaultDir = filepath.Join(rootDir, cfg.Active)
 		}
+		if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+			return err
+		}
 
 		// Ensure vault exists
 		if _, err := os.Stat(filepath.Join(vaultDir, "did.json")); err != nil {
 			return fmt.Errorf("vault '%s' not found: %w", cfg.Active, err)
 		}
 
-		// Load existing attributes
-		attrFile := filepath.Join(vaultDir, "attributes.json")
-		attrs := make(map[string]interface{})
-		if data, err := os.ReadFile(attrFile); err == nil {
-			if err := json.Unmarshal(data, &attrs); err != nil {
-				return fmt.Errorf("invalid attributes.json: %w", err)
-			}
-		}
-
-		// Update attribute map
-		attrs[key] = val
-
-		// Save updated attributes.json
-		if err := os.MkdirAll(vaultDir, fs.FileMode(0700)); err != nil {
-			return fmt.Errorf("create vault dir: %w", err)
+		// unlock the key for the audit entry before changing anything
+		keys := newVaultKeys(cmd, vault.NewVault(vaultDir))
+		defer keys.Close()
+		if _, err := keys.Signer(); err != nil {
+			return err
 		}
-		outData, err := json.MarshalIndent(attrs, "", "  ")
+		outData, err := setAttribute(vaultDir, key, val)
 		if err != nil {
-			return fmt.Errorf("marshal attributes: %w", err)
+			return err
 		}
-		if err := os.WriteFile(attrFile, outData, fs.FileMode(0600)); err != nil {
-			return fmt.Errorf("write attributes.json: %w", err)
+		if err := recordAudit(keys, "set", map[string]string{"key": key, "value": val}, outData); err != nil {
+			return err
 		}
 
 		cmd.Printf("Attribute '%s' set to '%s'\n", key, val)
@@ -70,6 +64,33 @@ var setCmd = &cobra.Command{
 	},
 }
 
+// setAttribute updates attributes.json under the vault lock so concurrent `ego set` runs
+// do not drop each other's attributes. It returns the new file contents.
+func setAttribute(vaultDir, key, val string) ([]byte, error) {
+	lock, err := vault.NewVault(vaultDir).Lock()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	attrFile := filepath.Join(vaultDir, "attributes.json")
+	attrs := make(map[string]interface{})
+	if data, err := os.ReadFile(attrFile); err == nil {
+		if err := json.Unmarshal(data, &attrs); err != nil {
+			return nil, fmt.Errorf("invalid attributes.json: %w", err)
+		}
+	}
+	attrs[key] = val
+	data, err := json.MarshalIndent(attrs, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal attributes: %w", err)
+	}
+	if err := fsutil.WriteFile(attrFile, data, 0600); err != nil {
+		return nil, fmt.Errorf("write attributes.json: %w", err)
+	}
+	return data, nil
+}
+
 func init() {
 	rootCmd.AddCommand(setCmd)
 	setCmd.Flags().StringVar(&altVaultDir, "out", "", "Directory of the vault (optional, overrides active)")
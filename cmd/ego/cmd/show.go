@@ -8,6 +8,7 @@ import (
 
 	"github.com/juanpablocruz/minervaid/internal/config"
 	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/vault"
 	"github.com/spf13/cobra"
 )
 
@@ -23,7 +24,10 @@ var showCmd = &cobra.Command{
 		if rootDir == "" {
 			rootDir = "store"
 		}
-		vaultDir := filepath.Join(rootDir, cfg.Active)
+		vaultDir, err := vault.IdentityDir(filepath.Join(rootDir, cfg.Active), identityName)
+		if err != nil {
+			return err
+		}
 
 		// Print DID document
 		didData, err := os.ReadFile(filepath.Join(vaultDir, "did.json"))
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/juanpablocruz/minervaid/internal/agent"
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+const signerCommandEnv = "EGO_SIGNER_COMMAND"
+
+var signerCommand string
+
+// vaultSigner returns the signer for the vault's current key and the verification method
+// of that key listed under purpose. An external --signer-command takes precedence over
+// keystore.json; otherwise the passphrase is asked for only if the keystore is encrypted.
+func vaultSigner(cmd *cobra.Command, v *vault.Vault, purpose string) (signer.Signer, string, error) {
+	if err := v.CheckActive(); err != nil {
+		return nil, "", err
+	}
+	doc, err := v.Document()
+	if err != nil {
+		return nil, "", err
+	}
+	vm, err := doc.MethodFor(purpose)
+	if err != nil {
+		return nil, "", err
+	}
+
+	command := signerCommand
+	if command == "" {
+		command = os.Getenv(signerCommandEnv)
+	}
+	if command != "" {
+		s, err := signer.NewProcess(cmd.Context(), strings.Fields(command))
+		if err != nil {
+			return nil, "", err
+		}
+		pub, err := vm.PublicKey()
+		if err != nil {
+			return nil, "", err
+		}
+		if !s.PublicKey().Equal(pub) {
+			return nil, "", fmt.Errorf("signer command key does not match %s", vm.ID)
+		}
+		return s, vm.ID, nil
+	}
+
+	encrypted, err := v.IsEncrypted()
+	if err != nil {
+		return nil, "", err
+	}
+	var pass []byte
+	if encrypted {
+		if pass, err = readPassphrase(cmd, passphraseFile, passphraseEnv, "Vault passphrase: "); err != nil {
+			return nil, "", err
+		}
+	} else {
+		cmd.PrintErrln("Warning: keystore.json is not encrypted; run 'ego passwd' to protect it")
+	}
+	s, err := signer.NewVaultFile(v, pass)
+	if err != nil {
+		return nil, "", err
+	}
+	return s, vm.ID, nil
+}
+
+// closeSigner wipes the key that s holds in memory, if any.
+func closeSigner(s signer.Signer) {
+	if c, ok := s.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// vaultKeys signs with the vault's current key for the length of a command: through the
+// agent when it is running and otherwise with vaultSigner, which is set up on first use so
+// the passphrase is asked for at most once. Close wipes the key.
+type vaultKeys struct {
+	cmd   *cobra.Command
+	vault *vault.Vault
+	agent *agent.Client
+	s     signer.Signer
+	// priv and seed are the vault key and pairwise seed unlocked by Pairwise
+	priv *identity.PrivateKey
+	seed []byte
+}
+
+func newVaultKeys(cmd *cobra.Command, v *vault.Vault) *vaultKeys {
+	return &vaultKeys{cmd: cmd, vault: v, agent: runningAgent()}
+}
+
+// heldKeys returns vaultKeys for priv, the current key of v already unlocked by the
+// command, which stays responsible for wiping it.
+func heldKeys(cmd *cobra.Command, v *vault.Vault, priv *identity.PrivateKey) *vaultKeys {
+	return &vaultKeys{cmd: cmd, vault: v, s: signer.NewMemory(priv)}
+}
+
+// Signer returns the signer of the vault's current key.
+func (k *vaultKeys) Signer() (signer.Signer, error) {
+	s, _, err := k.SignerFor(identity.PurposeAuthentication)
+	return s, err
+}
+
+// SignerFor returns the signer of the vault's current key and its verification method
+// listed under purpose.
+func (k *vaultKeys) SignerFor(purpose string) (signer.Signer, string, error) {
+	if k.s == nil && k.agent == nil {
+		s, vm, err := vaultSigner(k.cmd, k.vault, purpose)
+		if err != nil {
+			return nil, "", fmt.Errorf("load vault: %w", err)
+		}
+		k.s = s
+		return s, vm, nil
+	}
+	if k.s == nil {
+		s, err := newAgentSigner(k.agent, k.vault)
+		if err != nil {
+			return nil, "", err
+		}
+		k.s = s
+	}
+	doc, err := k.vault.Document()
+	if err != nil {
+		return nil, "", err
+	}
+	vm, err := doc.MethodFor(purpose)
+	if err != nil {
+		return nil, "", err
+	}
+	return k.s, vm.ID, nil
+}
+
+// SignCredential signs cred, issued by the vault, with a proof of suite. A deactivated
+// DID issues nothing, whether the agent or the keystore holds its key.
+func (k *vaultKeys) SignCredential(cred *credentials.Credential, suite string) error {
+	if err := k.vault.CheckActive(); err != nil {
+		return err
+	}
+	if k.agent != nil {
+		did, err := k.vault.DID()
+		if err != nil {
+			return err
+		}
+		signed, err := k.agent.SignCredential(did, suite, cred)
+		if err != nil {
+			return fmt.Errorf("sign credential via agent: %w", err)
+		}
+		*cred = *signed
+		return nil
+	}
+	s, vm, err := k.SignerFor(identity.PurposeAssertionMethod)
+	if err != nil {
+		return err
+	}
+	if err := cred.SignCredentialSuite(k.cmd.Context(), s, vm, suite); err != nil {
+		return fmt.Errorf("sign credential: %w", err)
+	}
+	return nil
+}
+
+// Pairwise returns a signer for the vault's pairwise key with domain, the pairwise DID and
+// its verification method. Pairwise keys are derived from the pairwise seed in the
+// keystore, so an external signer command cannot produce them. The vault key stays
+// unlocked for Signer.
+func (k *vaultKeys) Pairwise(domain string) (signer.Signer, string, string, error) {
+	if signerCommand != "" || os.Getenv(signerCommandEnv) != "" {
+		return nil, "", "", fmt.Errorf("pairwise DIDs need the vault keystore; pass --no-pairwise to sign with the external signer")
+	}
+	if k.priv == nil {
+		priv, seed, err := vaultPairwise(k.cmd, k.vault)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("load vault: %w", err)
+		}
+		k.Close()
+		k.priv, k.seed, k.s = priv, seed, signer.NewMemory(priv)
+	}
+	pk, did, vm, err := k.vault.PairwiseIdentity(k.seed, domain)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return signer.NewMemory(pk), did, vm, nil
+}
+
+// Close wipes the key held in memory, if any.
+func (k *vaultKeys) Close() {
+	if k.s != nil {
+		closeSigner(k.s)
+	}
+	if k.priv != nil {
+		k.priv.Wipe()
+		k.priv = nil
+	}
+	clear(k.seed)
+	k.seed = nil
+}
+
+// agentSigner signs bytes with the key the agent holds for a vault.
+type agentSigner struct {
+	c   *agent.Client
+	did string
+	pub *identity.PublicKey
+}
+
+func newAgentSigner(c *agent.Client, v *vault.Vault) (*agentSigner, error) {
+	doc, err := v.Document()
+	if err != nil {
+		return nil, err
+	}
+	vm, err := doc.CurrentMethod()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := vm.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return &agentSigner{c: c, did: doc.ID, pub: pub}, nil
+}
+
+func (a *agentSigner) PublicKey() *identity.PublicKey { return a.pub }
+
+func (a *agentSigner) Algorithm() string { return a.pub.Type.JWSAlgorithm() }
+
+func (a *agentSigner) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	sig, err := a.c.SignBytes(a.did, data)
+	if err != nil {
+		return nil, fmt.Errorf("sign via agent: %w", err)
+	}
+	return sig, nil
+}
+
+// vaultKey unlocks the vault key itself, for keys derived from it. It refuses when an
+// external signer command holds the key.
+func vaultKey(cmd *cobra.Command, v *vault.Vault) (*identity.PrivateKey, error) {
+	pass, err := keystorePassphrase(cmd, v)
+	if err != nil {
+		return nil, err
+	}
+	_, priv, err := v.Load(pass)
+	return priv, err
+}
+
+// vaultPairwise unlocks the vault key and the pairwise seed that pairwise keys are
+// derived from, as vaultKey does.
+func vaultPairwise(cmd *cobra.Command, v *vault.Vault) (*identity.PrivateKey, []byte, error) {
+	pass, err := keystorePassphrase(cmd, v)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v.LoadPairwise(pass)
+}
+
+// keystorePassphrase asks for the passphrase of keystore.json, which is nil when the
+// keystore is not encrypted.
+func keystorePassphrase(cmd *cobra.Command, v *vault.Vault) ([]byte, error) {
+	if signerCommand != "" || os.Getenv(signerCommandEnv) != "" {
+		return nil, fmt.Errorf("%s needs the vault key and cannot use an external signer", cmd.CommandPath())
+	}
+	if err := v.CheckActive(); err != nil {
+		return nil, err
+	}
+	encrypted, err := v.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if !encrypted {
+		cmd.PrintErrln("Warning: keystore.json is not encrypted; run 'ego passwd' to protect it")
+		return nil, nil
+	}
+	return readPassphrase(cmd, passphraseFile, passphraseEnv, "Vault passphrase: ")
+}
+
+// recordPairwise adds did to the vault's pairwise registry and warns when a peer contact
+// was given a different DID before.
+func recordPairwise(cmd *cobra.Command, v *vault.Vault, domain, did string) error {
+	previous, err := v.RecordPairwise(domain, did)
+	if err != nil {
+		return err
+	}
+	if previous != "" {
+		cmd.PrintErrf("Warning: %s was given %s before; share the new DID with them\n", vault.PeerContact(domain), previous)
+	}
+	return nil
+}
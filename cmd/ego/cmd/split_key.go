@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/juanpablocruz/minervaid/internal/config"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/spf13/cobra"
+)
+
+var (
+	shareCount     int
+	shareThreshold int
+	shareDir       string
+)
+
+// splitKeyCmd splits the vault key into Shamir shares for social recovery
+var splitKeyCmd = &cobra.Command{
+	Use:   "split-key --shares <n> --threshold <m> --dir <dir> [--out <vaultDir>]",
+	Short: "Split the vault key into recovery shares",
+	Long: `Split the private key into --shares files of which any --threshold rebuild it
+with 'ego recover'. Give each file to a different person; fewer than --threshold
+shares reveal nothing about the key.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		rootDir := cfg.RootDir
+		if rootDir == "" {
+			rootDir = "store"
+		}
+		vaultDir := altVaultDir
+		if vaultDir == "" {
+			vaultDir = filepath.Join(rootDir, cfg.Active)
+		}
+		if vaultDir, err = vault.IdentityDir(vaultDir, identityName); err != nil {
+			return err
+		}
+
+		v := vault.NewVault(vaultDir)
+		encrypted, err := v.IsEncrypted()
+		if err != nil {
+			return fmt.Errorf("load vault: %w", err)
+		}
+		var pass []byte
+		if encrypted {
+			if pass, err = readPassphrase(cmd, passphraseFile, passphraseEnv, "Vault passphrase: "); err != nil {
+				return err
+			}
+		}
+		didDoc, priv, err := v.Load(pass)
+		if err != nil {
+			return fmt.Errorf("load vault: %w", err)
+		}
+		defer priv.Wipe()
+		did, err := vault.DIDFromDocument(didDoc)
+		if err != nil {
+			return err
+		}
+
+		shares, err := vault.SplitKey(did, priv, shareCount, shareThreshold)
+		if err != nil {
+			return fmt.Errorf("split key: %w", err)
+		}
+		if err := os.MkdirAll(shareDir, 0700); err != nil {
+			return fmt.Errorf("create share dir: %w", err)
+		}
+		for _, s := range shares {
+			data, err := json.MarshalIndent(s, "", "  ")
+			if err != nil {
+				return err
+			}
+			path := filepath.Join(shareDir, fmt.Sprintf("share-%s-%d.json", s.Fingerprint, s.Index))
+			if err := os.WriteFile(path, data, 0600); err != nil {
+				return fmt.Errorf("write share: %w", err)
+			}
+			cmd.Println(path)
+		}
+		cmd.Printf("Key split into %d shares; %d are needed to recover it\n", shareCount, shareThreshold)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(splitKeyCmd)
+	splitKeyCmd.Flags().IntVar(&shareCount, "shares", 0, "Number of shares to create (required)")
+	splitKeyCmd.Flags().IntVar(&shareThreshold, "threshold", 0, "Number of shares needed to recover (required)")
+	splitKeyCmd.Flags().StringVar(&shareDir, "dir", "", "Directory to write the share files to (required)")
+	splitKeyCmd.Flags().StringVar(&altVaultDir, "out", "", "Vault directory (optional, uses active)")
+	splitKeyCmd.MarkFlagRequired("shares")
+	splitKeyCmd.MarkFlagRequired("threshold")
+	splitKeyCmd.MarkFlagRequired("dir")
+}
@@ -1,20 +1,34 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/resolver"
 	"github.com/spf13/cobra"
 )
 
-var credentialFile string
+var (
+	credentialFile string
+	didDocFile     string
+	schemasDir     string
+	statusFiles    []string
+	clockSkew      time.Duration
+)
 
 var verifyCmd = &cobra.Command{
 	Use:   "verify --file <credential.json>",
 	Short: "Verify a verifiable credential",
-	Long:  "Verify the signature of a credential JSON file.",
+	Long: `Verify the signature of a credential JSON file, and that the credential is valid now:
+not before its validFrom or issuanceDate, and not after its validUntil or expirationDate.
+
+A credential with a credentialStatus is checked against its issuer's status lists,
+fetched from their URLs or, with --status-list, read from files.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		file := credentialFile
 		if file == "" {
@@ -28,16 +42,61 @@ var verifyCmd = &cobra.Command{
 		if err := json.Unmarshal(data, &cred); err != nil {
 			return fmt.Errorf("invalid credential JSON: %w", err)
 		}
-		if err := credentials.VerifyCredential(&cred); err != nil {
+		opts := credentials.DefaultVerifyOptions()
+		if opts.Resolve, err = didDocResolver(); err != nil {
+			return err
+		}
+		if schemasDir != "" {
+			opts.Schemas = &credentials.SchemaRegistry{Dir: schemasDir}
+		}
+		if len(statusFiles) > 0 {
+			if opts.FetchStatusList, err = credentials.StatusListFiles(statusFiles...); err != nil {
+				return err
+			}
+		}
+		opts.ClockSkew = clockSkew
+		if err := credentials.VerifyCredentialWithOptions(&cred, opts); err != nil {
 			cmd.Printf("Credential verification failed: %v\n", err)
 			os.Exit(1)
 		}
+		if credentials.LegacyProof(cred.Proofs) {
+			cmd.PrintErrln("Warning: the proof holds a hex signature from an older version of ego; other verifiers cannot check it, so reissue the credential")
+		}
 		cmd.Println("Credential is valid ✅")
 		return nil
 	},
 }
 
+// didDocResolver returns the default DocumentResolver, answering with the document of
+// --did-doc for its DID. --did-doc holds a DID document or a resolution result, as
+// printed by 'ego resolve --metadata', whose metadata may mark the DID deactivated.
+func didDocResolver() (credentials.DocumentResolver, error) {
+	if didDocFile == "" {
+		return credentials.ResolveDocument, nil
+	}
+	docData, err := os.ReadFile(didDocFile)
+	if err != nil {
+		return nil, fmt.Errorf("read DID document: %w", err)
+	}
+	var res resolver.Resolution
+	if err := json.Unmarshal(docData, &res); err != nil || res.Document == nil {
+		if res.Document, err = identity.ParseDIDDocument(docData); err != nil {
+			return nil, err
+		}
+	}
+	return credentials.ResolveWith(resolver.Func(func(ctx context.Context, did string) (*resolver.Resolution, error) {
+		if did == res.Document.ID {
+			return &res, nil
+		}
+		return credentials.DefaultResolver.Resolve(ctx, did)
+	})), nil
+}
+
 func init() {
 	verifyCmd.Flags().StringVar(&credentialFile, "file", "", "Path to credential JSON file (required)")
+	verifyCmd.Flags().StringVar(&didDocFile, "did-doc", "", "DID document or resolution result of the issuer, instead of resolving it")
+	verifyCmd.Flags().StringVar(&schemasDir, "schemas", "", "Directory of schemas to check the credential's credentialSchema against (optional)")
+	verifyCmd.Flags().StringArrayVar(&statusFiles, "status-list", nil, "Status list credential file to check credentialStatus against, instead of fetching it; can be repeated")
+	verifyCmd.Flags().DurationVar(&clockSkew, "clock-skew", credentials.DefaultClockSkew, "How far the issuer's clock may be off when checking validFrom and validUntil")
 	verifyCmd.MarkFlagRequired("file")
 }
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/spf13/cobra"
+)
+
+var migrationFile string
+
+// verifyMigrationCmd checks a statement written by 'ego migrate'
+var verifyMigrationCmd = &cobra.Command{
+	Use:   "verify-migration --file <migration.json> [--did-doc <did.json>]",
+	Short: "Verify that two DIDs belong to the same migrated identity",
+	Long: `Check that a migration statement is signed by both its old and its new DID. The new
+did:web document is fetched from its domain unless --did-doc supplies it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(migrationFile)
+		if err != nil {
+			return fmt.Errorf("read migration statement: %w", err)
+		}
+		var st credentials.MigrationStatement
+		if err := json.Unmarshal(data, &st); err != nil {
+			return fmt.Errorf("invalid migration statement JSON: %w", err)
+		}
+		resolve, err := didDocResolver()
+		if err != nil {
+			return err
+		}
+		if err := credentials.VerifyMigrationStatement(&st, resolve); err != nil {
+			return fmt.Errorf("migration statement verification failed: %w", err)
+		}
+		if credentials.LegacyProof(st.Proofs[:1]) || credentials.LegacyProof(st.Proofs) {
+			cmd.PrintErrln("Warning: the statement holds hex signatures from an older version of ego")
+		}
+		cmd.Printf("%s migrated to %s ✅\n", st.From, st.To)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyMigrationCmd)
+	verifyMigrationCmd.Flags().StringVar(&migrationFile, "file", "", "Path to the migration statement (required)")
+	verifyMigrationCmd.Flags().StringVar(&didDocFile, "did-doc", "", "DID document of the new DID, instead of resolving it")
+	verifyMigrationCmd.MarkFlagRequired("file")
+}
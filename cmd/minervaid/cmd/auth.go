@@ -11,10 +11,11 @@ import (
 )
 
 var (
-	authDomain string
-	authExpiry time.Duration
-	authDid    string
-	authFile   string
+	authDomain   string
+	authExpiry   time.Duration
+	authDid      string
+	authFile     string
+	authResponse string
 )
 
 var authChallengeCmd = &cobra.Command{
@@ -23,7 +24,7 @@ var authChallengeCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ch := credentials.NewAuthChallenge(authDomain, authExpiry)
 		data, _ := json.MarshalIndent(ch, "", "  ")
-		fmt.Println(string(data))
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
 		return nil
 	},
 }
@@ -45,15 +46,52 @@ var authRespondCmd = &cobra.Command{
 			return err
 		}
 		out, _ := json.MarshalIndent(resp, "", "  ")
-		fmt.Println(string(out))
+		fmt.Fprintln(cmd.OutOrStdout(), string(out))
 		return nil
 	},
 }
 
+var authVerifyCmd = &cobra.Command{
+	Use:   "auth-verify --response <file> --file <challenge.json>",
+	Short: "Verify a response to an authentication challenge",
+	Long: `Check that the response answers the challenge in --file, the one that was sent: same
+nonce and domain, not expired, and signed by an authentication method of the responding
+DID. Prints the authenticated DID.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var ch credentials.AuthenticationChallenge
+		if err := readJSON(authFile, &ch); err != nil {
+			return fmt.Errorf("read challenge: %w", err)
+		}
+		var resp credentials.AuthenticationResponse
+		if err := readJSON(authResponse, &resp); err != nil {
+			return fmt.Errorf("read response: %w", err)
+		}
+		did, err := credentials.VerifyAuthResponse(&resp, &ch, credentials.ResolveDocument)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), did)
+		return nil
+	},
+}
+
+// readJSON unmarshals the JSON in file into v.
+func readJSON(file string, v interface{}) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
 func init() {
 	authChallengeCmd.Flags().StringVar(&authDomain, "domain", "", "Challenge domain")
 	authChallengeCmd.Flags().DurationVar(&authExpiry, "expiry", 5*time.Minute, "Challenge expiry duration")
 	authRespondCmd.Flags().StringVar(&authDid, "did", "", "Holder DID")
 	authRespondCmd.Flags().StringVar(&authFile, "file", "", "Path to challenge JSON")
-	rootCmd.AddCommand(authChallengeCmd, authRespondCmd)
+	authVerifyCmd.Flags().StringVar(&authResponse, "response", "", "Path to the authentication response JSON (required)")
+	authVerifyCmd.Flags().StringVar(&authFile, "file", "", "Path to the challenge JSON that was sent (required)")
+	authVerifyCmd.MarkFlagRequired("response")
+	authVerifyCmd.MarkFlagRequired("file")
+	rootCmd.AddCommand(authChallengeCmd, authRespondCmd, authVerifyCmd)
 }
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/store"
+)
+
+func TestAuthVerify(t *testing.T) {
+	tmp := t.TempDir()
+	t.Cleanup(func() { storeDir, authDid, authFile, authResponse = "./store", "", "", "" })
+	rootCmd.SetArgs([]string{"new-did", "--store", tmp})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("new-did failed: %v", err)
+	}
+	ks, err := store.Open(filepath.Join(tmp, "keystore.json"))
+	if err != nil || len(ks.DIDs()) != 1 {
+		t.Fatalf("keystore: %v", err)
+	}
+	did := ks.DIDs()[0]
+
+	writeJSON := func(name string, v interface{}) string {
+		data, _ := json.Marshal(v)
+		file := filepath.Join(tmp, name)
+		if err := os.WriteFile(file, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	chFile := writeJSON("challenge.json", credentials.NewAuthChallenge("rp.example", time.Minute))
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	rootCmd.SetArgs([]string{"auth-respond", "--did", did, "--file", chFile, "--store", tmp})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("auth-respond failed: %v", err)
+	}
+	respFile := filepath.Join(tmp, "response.json")
+	os.WriteFile(respFile, buf.Bytes(), 0600)
+
+	buf.Reset()
+	rootCmd.SetArgs([]string{"auth-verify", "--response", respFile, "--file", chFile})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("auth-verify failed: %v", err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != did {
+		t.Errorf("auth-verify printed %q; want %s", got, did)
+	}
+
+	// a response is only good for the challenge it answers
+	other := writeJSON("other.json", credentials.NewAuthChallenge("rp.example", time.Minute))
+	rootCmd.SetArgs([]string{"auth-verify", "--response", respFile, "--file", other})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "answers challenge") {
+		t.Errorf("expected a challenge mismatch, got %v", err)
+	}
+}
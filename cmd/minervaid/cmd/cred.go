@@ -8,7 +8,7 @@ import (
 	"time"
 
 	"github.com/juanpablocruz/minervaid/internal/credentials"
-	"github.com/mr-tron/base58"
+	"github.com/juanpablocruz/minervaid/internal/identity"
 	"github.com/spf13/cobra"
 )
 
@@ -17,11 +17,14 @@ var (
 	credSubject string
 	credID      string
 	zkpMinAge   uint64
+	credV2      bool
+	credExpires string
+	credSchema  string
 )
 
 // newCredCmd issues a new Verifiable Credential, optionally attaching a range proof.
 var newCredCmd = &cobra.Command{
-	Use:   "new-cred --did <did> --subject <json|@file> [--id <id>] [--zkp-min-age <n>]",
+	Use:   "new-cred --did <did> --subject <json|@file> [--id <id>] [--v2] [--expires <time|duration>] [--schema <id>] [--zkp-min-age <n>]",
 	Short: "Issue a new Verifiable Credential",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if credDid == "" || credSubject == "" {
@@ -33,14 +36,9 @@ var newCredCmd = &cobra.Command{
 		}
 
 		// Load issuer private key
-		ks := loadKeyStore(filepath.Join(storeDir, "keystore.json"))
-		privEnc, ok := ks[credDid]
-		if !ok {
-			return fmt.Errorf("unknown DID: %s", credDid)
-		}
-		privBytes, err := base58.Decode(privEnc)
+		keySigner, err := loadSigner(credDid, identity.PurposeAssertionMethod)
 		if err != nil {
-			return fmt.Errorf("decoding private key: %w", err)
+			return err
 		}
 
 		// Parse subject JSON
@@ -64,6 +62,27 @@ var newCredCmd = &cobra.Command{
 
 		// Create credential
 		cred := credentials.NewCredential(id, credDid, subj)
+		if credV2 {
+			cred = credentials.NewCredentialV2(id, credDid, subj)
+		}
+		if credExpires != "" {
+			expires, err := credentials.ParseExpiration(credExpires, time.Now())
+			if err != nil {
+				return err
+			}
+			cred.SetExpiration(expires)
+		}
+		if credSchema != "" {
+			reg := schemaRegistry()
+			_, ref, err := reg.Lookup(credSchema)
+			if err != nil {
+				return err
+			}
+			cred.CredentialSchema = credentials.CredentialSchemas{ref}
+			if err := credentials.ValidateCredentialSchema(cred, reg, credentials.ResolveDocument); err != nil {
+				return err
+			}
+		}
 
 		// Optionally attach a range proof via generic challenge
 		if zkpMinAge > 0 {
@@ -81,7 +100,7 @@ var newCredCmd = &cobra.Command{
 		}
 
 		// Sign credential
-		if err := cred.SignCredential(privBytes, credDid+"#keys-1"); err != nil {
+		if err := cred.SignCredential(cmd.Context(), keySigner, credDid+"#keys-1"); err != nil {
 			return fmt.Errorf("signing credential: %w", err)
 		}
 
@@ -104,6 +123,9 @@ func init() {
 	newCredCmd.Flags().StringVar(&credDid, "did", "", "Issuer DID (required)")
 	newCredCmd.Flags().StringVar(&credSubject, "subject", "", "Subject JSON or @file (required)")
 	newCredCmd.Flags().StringVar(&credID, "id", "", "Credential ID (optional)")
+	newCredCmd.Flags().BoolVar(&credV2, "v2", false, "Issue a VC Data Model 2.0 credential")
+	newCredCmd.Flags().StringVar(&credExpires, "expires", "", "Expiry, as an RFC 3339 time or a period such as 30d (optional)")
+	newCredCmd.Flags().StringVar(&credSchema, "schema", "", "Id of a schema from add-schema that the credential must match (optional)")
 	newCredCmd.Flags().Uint64Var(&zkpMinAge, "zkp-min-age", 0, "Generate ZKP proof for age >= this value")
 	newCredCmd.Flags().StringVar(&storeDir, "store", "./store", "Directory for storing data")
 	_ = newCredCmd.MarkFlagRequired("did")
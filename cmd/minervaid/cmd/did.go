@@ -1,32 +1,72 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/resolver"
+	"github.com/juanpablocruz/minervaid/internal/store"
+	"github.com/mr-tron/base58"
 	"github.com/spf13/cobra"
 )
 
+var (
+	didLabel        string
+	didPurpose      string
+	didKeyType      string
+	targetDid       string
+	resolveMetadata bool
+)
+
 var newDidCmd = &cobra.Command{
-	Use:   "new-did",
+	Use:   "new-did [--label <label>] [--purpose <p,p,...>] [--key-type <type>]",
 	Short: "Generate a new DID",
+	Long: `Generate a new DID and store its private key in the keystore.
+
+--purpose lists the verification relationships the key may be used for:
+assertionMethod to issue credentials, authentication to sign presentations and answer
+challenges, keyAgreement. A key is refused for anything else.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		var purposes []string
+		if didPurpose != "" {
+			var err error
+			if purposes, err = store.ParsePurposes(didPurpose); err != nil {
+				return err
+			}
+		}
 		if err := os.MkdirAll(storeDir, 0755); err != nil {
 			return err
 		}
-		pub, priv, err := identity.GenerateKeyPair()
+		ks, err := store.Open(keystorePath())
 		if err != nil {
 			return err
 		}
-		did := identity.GenerateDID(pub)
-		privEnc := identity.EncodePrivateKey(priv)
-		ksPath := filepath.Join(storeDir, "keystore.json")
-		ks := loadKeyStore(ksPath)
-		ks[did] = privEnc
-		saveKeyStore(ksPath, ks)
-		fmt.Println(did)
+		kt, err := identity.ParseKeyType(didKeyType)
+		if err != nil {
+			return err
+		}
+		priv, err := identity.GenerateKey(kt)
+		if err != nil {
+			return err
+		}
+		did := identity.GenerateDIDForKey(priv.Public())
+		entry := store.Entry{
+			KeyType:    kt,
+			PrivateKey: base58.Encode(priv.Raw),
+			Label:      didLabel,
+			Purpose:    purposes,
+		}
+		if err := ks.Add(did, entry); err != nil {
+			return err
+		}
+		if err := ks.Save(); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), did)
 		return nil
 	},
 }
@@ -35,15 +75,118 @@ var listDidsCmd = &cobra.Command{
 	Use:   "list-dids",
 	Short: "List all DIDs",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		ksPath := filepath.Join(storeDir, "keystore.json")
-		ks := loadKeyStore(ksPath)
-		for did := range ks {
-			fmt.Println(did)
+		ks, err := store.Open(keystorePath())
+		if err != nil {
+			return err
+		}
+		for _, did := range ks.DIDs() {
+			fmt.Fprintln(cmd.OutOrStdout(), did)
+		}
+		return nil
+	},
+}
+
+var deleteDidCmd = &cobra.Command{
+	Use:   "delete-did --did <did>",
+	Short: "Delete a DID and its private key from the keystore",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ks, err := store.Open(keystorePath())
+		if err != nil {
+			return err
+		}
+		if err := ks.Delete(targetDid); err != nil {
+			return err
+		}
+		if err := ks.Save(); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "DID %s deleted\n", targetDid)
+		return nil
+	},
+}
+
+var renameDidCmd = &cobra.Command{
+	Use:   "rename-did --did <did> --label <label>",
+	Short: "Change the label of a DID in the keystore",
+	Long: `Change the human-readable label of a DID in the keystore. The DID itself is derived
+from its key and stays the same.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ks, err := store.Open(keystorePath())
+		if err != nil {
+			return err
+		}
+		if err := ks.Rename(targetDid, didLabel); err != nil {
+			return err
+		}
+		if err := ks.Save(); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "DID %s labelled %q\n", targetDid, didLabel)
+		return nil
+	},
+}
+
+var describeDidCmd = &cobra.Command{
+	Use:   "describe-did --did <did>",
+	Short: "Show the keystore metadata of a DID",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ks, err := store.Open(keystorePath())
+		if err != nil {
+			return err
+		}
+		e, err := ks.Get(targetDid)
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "DID:       %s\n", targetDid)
+		fmt.Fprintf(out, "Label:     %s\n", e.Label)
+		fmt.Fprintf(out, "Key type:  %s\n", e.KeyType)
+		if priv, err := e.Key(); err == nil {
+			fmt.Fprintf(out, "Public:    %s\n", base58.Encode(priv.Public().Raw))
+		}
+		if !e.Created.IsZero() {
+			fmt.Fprintf(out, "Created:   %s\n", e.Created.Format(time.RFC3339))
+		}
+		fmt.Fprintf(out, "Purpose:   %s\n", strings.Join(e.Purpose, ", "))
+		return nil
+	},
+}
+
+var resolveCmd = &cobra.Command{
+	Use:   "resolve <did> [--metadata]",
+	Short: "Resolve a DID and print its DID Document",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		res, err := resolver.NewRegistry(nil).Resolve(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		var out interface{} = res.Document
+		if resolveMetadata {
+			out = res
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
 		}
+		fmt.Println(string(data))
 		return nil
 	},
 }
 
 func init() {
-	rootCmd.AddCommand(newDidCmd, listDidsCmd)
+	resolveCmd.Flags().BoolVar(&resolveMetadata, "metadata", false, "Print the resolution and document metadata too")
+	newDidCmd.Flags().StringVar(&didLabel, "label", "", "Human-readable label for the DID")
+	newDidCmd.Flags().StringVar(&didKeyType, "key-type", "Ed25519", "Key type: Ed25519, secp256k1 or P-256")
+	newDidCmd.Flags().StringVar(&didPurpose, "purpose", "", "Comma-separated verification relationships the key may be used for: assertionMethod, authentication, keyAgreement (default assertionMethod,authentication)")
+	deleteDidCmd.Flags().StringVar(&targetDid, "did", "", "DID to delete (required)")
+	describeDidCmd.Flags().StringVar(&targetDid, "did", "", "DID to describe (required)")
+	renameDidCmd.Flags().StringVar(&targetDid, "did", "", "DID to relabel (required)")
+	renameDidCmd.Flags().StringVar(&didLabel, "label", "", "New label for the DID (required)")
+	_ = deleteDidCmd.MarkFlagRequired("did")
+	_ = describeDidCmd.MarkFlagRequired("did")
+	_ = renameDidCmd.MarkFlagRequired("did")
+	_ = renameDidCmd.MarkFlagRequired("label")
+	rootCmd.AddCommand(newDidCmd, listDidsCmd, deleteDidCmd, renameDidCmd, describeDidCmd, resolveCmd)
 }
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/store"
+)
+
+func TestDIDCommands(t *testing.T) {
+	tmp := t.TempDir()
+	t.Cleanup(func() { storeDir, targetDid, didLabel, didPurpose = "./store", "", "", "" })
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+	run := func(args ...string) (string, error) {
+		buf.Reset()
+		rootCmd.SetArgs(append(args, "--store", tmp))
+		err := rootCmd.Execute()
+		return strings.TrimSpace(buf.String()), err
+	}
+
+	did, err := run("new-did", "--label", "main")
+	if err != nil {
+		t.Fatalf("new-did failed: %v", err)
+	}
+	out, err := run("describe-did", "--did", did)
+	if err != nil {
+		t.Fatalf("describe-did failed: %v", err)
+	}
+	for _, want := range []string{"DID:       " + did, "Label:     main", "Key type:  Ed25519", "Purpose:   assertionMethod, authentication"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("describe-did output lacks %q:\n%s", want, out)
+		}
+	}
+
+	if _, err := run("rename-did", "--did", did, "--label", "work"); err != nil {
+		t.Fatalf("rename-did failed: %v", err)
+	}
+	if out, _ := run("describe-did", "--did", did); !strings.Contains(out, "Label:     work") {
+		t.Errorf("label not changed:\n%s", out)
+	}
+
+	if _, err := run("delete-did", "--did", did); err != nil {
+		t.Fatalf("delete-did failed: %v", err)
+	}
+	if _, err := run("describe-did", "--did", did); !errors.Is(err, store.ErrUnknownDID) {
+		t.Errorf("expected ErrUnknownDID after delete, got %v", err)
+	}
+	if _, err := run("delete-did", "--did", did); !errors.Is(err, store.ErrUnknownDID) {
+		t.Errorf("expected ErrUnknownDID deleting twice, got %v", err)
+	}
+}
+
+func TestNewDIDPurpose(t *testing.T) {
+	tmp := t.TempDir()
+	t.Cleanup(func() { storeDir, didLabel, didPurpose, credDid, credSubject, authDid, authFile = "./store", "", "", "", "", "", "" })
+	buf := new(bytes.Buffer)
+	rootCmd.SetOut(buf)
+
+	rootCmd.SetArgs([]string{"new-did", "--purpose", "authentication,signing", "--store", tmp})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), `unknown key purpose "signing"`) {
+		t.Errorf("expected an unknown purpose error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "keystore.json")); !os.IsNotExist(err) {
+		t.Errorf("keystore written for a rejected DID: %v", err)
+	}
+
+	// an authentication key answers challenges but does not issue credentials
+	buf.Reset()
+	rootCmd.SetArgs([]string{"new-did", "--purpose", "authentication", "--store", tmp})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("new-did failed: %v", err)
+	}
+	did := strings.TrimSpace(buf.String())
+	ks, _ := store.Open(filepath.Join(tmp, "keystore.json"))
+	if e, err := ks.Get(did); err != nil || len(e.Purpose) != 1 || e.Purpose[0] != "authentication" {
+		t.Fatalf("purpose not stored: %+v, %v", e, err)
+	}
+
+	rootCmd.SetArgs([]string{"new-cred", "--did", did, "--subject", `{"name":"Alice"}`, "--store", tmp})
+	if err := rootCmd.Execute(); !errors.Is(err, store.ErrPurpose) {
+		t.Errorf("expected ErrPurpose issuing with an authentication key, got %v", err)
+	}
+	data, _ := json.Marshal(credentials.NewAuthChallenge("rp.example", time.Minute))
+	chFile := filepath.Join(tmp, "challenge.json")
+	if err := os.WriteFile(chFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"auth-respond", "--did", did, "--file", chFile, "--store", tmp})
+	if err := rootCmd.Execute(); err != nil {
+		t.Errorf("auth-respond failed: %v", err)
+	}
+}
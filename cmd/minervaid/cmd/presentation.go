@@ -8,7 +8,7 @@ import (
 	"time"
 
 	"github.com/juanpablocruz/minervaid/internal/credentials"
-	"github.com/mr-tron/base58"
+	"github.com/juanpablocruz/minervaid/internal/identity"
 	"github.com/spf13/cobra"
 )
 
@@ -28,12 +28,7 @@ var newPresentationCmd = &cobra.Command{
 		if err := os.MkdirAll(storeDir, 0755); err != nil {
 			return err
 		}
-		ks := loadKeyStore(filepath.Join(storeDir, "keystore.json"))
-		privEnc, ok := ks[presDid]
-		if !ok {
-			return fmt.Errorf("unknown DID: %s", presDid)
-		}
-		privBytes, err := base58.Decode(privEnc)
+		keySigner, err := loadSigner(presDid, identity.PurposeAuthentication)
 		if err != nil {
 			return err
 		}
@@ -60,7 +55,7 @@ var newPresentationCmd = &cobra.Command{
 			}
 		}
 		pres := credentials.NewPresentation(creds, presDid)
-		if err := pres.SignPresentation(privBytes, presDid+"#keys-1"); err != nil {
+		if err := pres.SignPresentation(cmd.Context(), keySigner, presDid+"#keys-1"); err != nil {
 			return err
 		}
 		outDir := filepath.Join(storeDir, "presentations")
@@ -3,17 +3,26 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
+	"github.com/juanpablocruz/minervaid/internal/signer"
 	"github.com/juanpablocruz/minervaid/internal/store"
 	"github.com/spf13/cobra"
 )
 
-func loadKeyStore(path string) map[string]string {
-	return store.LoadKeyStore(path)
+// keystorePath returns the location of the multi-DID keystore inside storeDir.
+func keystorePath() string {
+	return filepath.Join(storeDir, "keystore.json")
 }
 
-func saveKeyStore(path string, ks map[string]string) {
-	store.SaveKeyStore(path, ks)
+// loadSigner returns a signer for the private key stored for did, which must be
+// registered for purpose.
+func loadSigner(did, purpose string) (signer.Signer, error) {
+	priv, err := store.LoadKey(keystorePath(), did, purpose)
+	if err != nil {
+		return nil, err
+	}
+	return signer.NewMemory(priv), nil
 }
 
 var (
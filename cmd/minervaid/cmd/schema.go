@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/spf13/cobra"
+)
+
+var schemaFile string
+
+// schemaRegistry returns the registry of credential schemas in the store.
+func schemaRegistry() *credentials.SchemaRegistry {
+	return &credentials.SchemaRegistry{Dir: filepath.Join(storeDir, "schemas")}
+}
+
+// addSchemaCmd adds a JSON Schema or JsonSchemaCredential to the schema registry.
+var addSchemaCmd = &cobra.Command{
+	Use:   "add-schema --file <schema.json>",
+	Short: "Add a credential schema to the local registry",
+	Long: `Add a JSON Schema, identified by its $id, or a JsonSchemaCredential, identified by its
+id, to the schema registry in <store>/schemas. new-cred --schema refers to it by that id.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(schemaFile)
+		if err != nil {
+			return fmt.Errorf("reading schema file: %w", err)
+		}
+		ref, err := schemaRegistry().Add(data)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s %s\n", ref.Type, ref.ID)
+		return nil
+	},
+}
+
+func init() {
+	addSchemaCmd.Flags().StringVar(&schemaFile, "file", "", "JSON Schema or JsonSchemaCredential file (required)")
+	_ = addSchemaCmd.MarkFlagRequired("file")
+	rootCmd.AddCommand(addSchemaCmd)
+}
@@ -4,8 +4,15 @@ go 1.24.0
 
 require (
 	github.com/0xdecaf/zkrp v0.0.0-20201019075642-eed3acf37c78
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.3.0
+	github.com/gofrs/flock v0.12.1
+	github.com/gowebpki/jcs v1.0.1
 	github.com/mr-tron/base58 v1.2.0
+	github.com/santhosh-tekuri/jsonschema/v6 v6.0.2
 	github.com/spf13/cobra v1.9.1
+	github.com/tyler-smith/go-bip39 v1.1.0
+	golang.org/x/crypto v0.38.0
+	golang.org/x/term v0.32.0
 )
 
 require (
@@ -13,4 +20,6 @@ require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/ing-bank/zkrp v0.0.0-20211018091920-bc4eff1b3466 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.25.0 // indirect
 )
@@ -0,0 +1,267 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+)
+
+var testPass = []byte("agent-pass")
+
+// startAgent creates a vault, loads it into a new agent and serves it on a temp socket.
+func startAgent(t *testing.T, opts Options) (*Server, *Client, string) {
+	t.Helper()
+	dir := t.TempDir()
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+	doc, _ := identity.BuildDIDDocument(did, priv.Public())
+	v := vault.NewVault(filepath.Join(dir, "vault"))
+	v.KDF = vault.KDFParams{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	if err := v.Init(doc, priv, testPass); err != nil {
+		t.Fatalf("vault init: %v", err)
+	}
+
+	srv := NewServer(opts)
+	if _, err := srv.AddVault(v, testPass); err != nil {
+		t.Fatalf("AddVault: %v", err)
+	}
+	sock := filepath.Join(dir, "agent.sock")
+	l, err := ListenUnix(sock)
+	if err != nil {
+		t.Fatalf("ListenUnix: %v", err)
+	}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+
+	c, err := Dial(sock)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	return srv, c, did
+}
+
+func TestAgentSignCredentialAndPresentation(t *testing.T) {
+	_, c, did := startAgent(t, Options{})
+
+	cred := credentials.NewCredential("vc1", did, map[string]interface{}{"id": did, "age": 30})
+	signed, err := c.SignCredential(did, "", cred)
+	if err != nil {
+		t.Fatalf("SignCredential: %v", err)
+	}
+	if err := credentials.VerifyCredential(signed); err != nil {
+		t.Errorf("credential signed by agent does not verify: %v", err)
+	}
+	jcsCred := credentials.NewCredential("vc2", did, map[string]interface{}{"id": did})
+	if jcsCred, err = c.SignCredential(did, credentials.SuiteEddsaJcs2022, jcsCred); err != nil {
+		t.Fatalf("SignCredential %s: %v", credentials.SuiteEddsaJcs2022, err)
+	}
+	if err := credentials.VerifyCredential(jcsCred); err != nil {
+		t.Errorf("%s credential signed by agent does not verify: %v", credentials.SuiteEddsaJcs2022, err)
+	}
+
+	pres := credentials.NewPresentation([]credentials.Credential{*signed}, did)
+	signedPres, err := c.SignPresentation(did, "", pres)
+	if err != nil {
+		t.Fatalf("SignPresentation: %v", err)
+	}
+	if err := credentials.VerifyPresentation(signedPres); err != nil {
+		t.Errorf("presentation signed by agent does not verify: %v", err)
+	}
+
+	ch := credentials.NewAuthChallenge("example.com", time.Minute)
+	ar, err := c.RespondAuthChallenge(did, "", ch)
+	if err != nil {
+		t.Fatalf("RespondAuthChallenge: %v", err)
+	}
+	if ar.Proof == nil || ar.Proof.ProofPurpose != "authentication" {
+		t.Errorf("unexpected auth response: %+v", ar)
+	}
+
+	if _, err := c.SignBytes("did:key:zUnknown", []byte("x")); err == nil {
+		t.Error("expected error for unknown DID")
+	}
+}
+
+func TestAgentPairwise(t *testing.T) {
+	_, c, did := startAgent(t, Options{})
+
+	pres := credentials.NewPresentation(nil, did)
+	signed, err := c.SignPresentation(did, "verifier.example", pres)
+	if err != nil {
+		t.Fatalf("SignPresentation: %v", err)
+	}
+	if signed.Holder == did || !strings.HasPrefix(signed.Holder, "did:key:") {
+		t.Fatalf("expected a pairwise holder, got %s", signed.Holder)
+	}
+	if err := credentials.VerifyPresentation(signed); err != nil {
+		t.Errorf("pairwise presentation does not verify: %v", err)
+	}
+
+	ar, err := c.RespondAuthChallenge(did, "verifier.example", credentials.NewAuthChallenge("verifier.example", time.Minute))
+	if err != nil {
+		t.Fatalf("RespondAuthChallenge: %v", err)
+	}
+	if ar.Proof.VerificationMethod != signed.Holder+"#keys-1" {
+		t.Errorf("auth response signed by %s; want the pairwise DID %s", ar.Proof.VerificationMethod, signed.Holder)
+	}
+}
+
+func TestAgentLockUnlock(t *testing.T) {
+	_, c, did := startAgent(t, Options{})
+	if err := c.Lock(); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	if _, err := c.SignBytes(did, []byte("x")); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+	if err := c.Unlock([]byte("wrong")); err == nil {
+		t.Error("expected unlock with wrong passphrase to fail")
+	}
+	if err := c.Unlock(testPass); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if _, err := c.SignBytes(did, []byte("x")); err != nil {
+		t.Errorf("SignBytes after unlock: %v", err)
+	}
+}
+
+func TestAgentFollowsVault(t *testing.T) {
+	srv, c, did := startAgent(t, Options{})
+	v := srv.keys[did].vault
+
+	// a rotated key is not used under the new verification method
+	next, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	if _, err := v.Rotate(testPass, func(doc *identity.Document) (*identity.PrivateKey, error) {
+		now := time.Now()
+		doc.RotateKey(next.Public(), now, now)
+		return next, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	cred := credentials.NewCredential("vc1", did, map[string]interface{}{"id": did})
+	if _, err := c.SignCredential(did, "", cred); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected a key mismatch after rotation, got %v", err)
+	}
+	c.Lock()
+	if err := c.Unlock(testPass); err != nil {
+		t.Fatal(err)
+	}
+	signed, err := c.SignCredential(did, "", cred)
+	if err != nil {
+		t.Fatalf("SignCredential after unlock: %v", err)
+	}
+	var proof struct {
+		VerificationMethod string `json:"verificationMethod"`
+	}
+	json.Unmarshal(signed.Proofs[0], &proof)
+	if proof.VerificationMethod != did+"#keys-2" {
+		t.Errorf("signed by %s after rotation", proof.VerificationMethod)
+	}
+
+	// nothing is signed for a deactivated DID
+	if err := v.Deactivate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SignBytes(did, []byte("x")); err == nil || !strings.Contains(err.Error(), "deactivated") {
+		t.Errorf("SignBytes after deactivation: %v", err)
+	}
+	if _, err := c.SignCredential(did, "", credentials.NewCredential("vc2", did, nil)); err == nil {
+		t.Error("SignCredential after deactivation succeeded")
+	}
+}
+
+func TestAgentIdleTimeout(t *testing.T) {
+	_, c, did := startAgent(t, Options{IdleTimeout: 50 * time.Millisecond})
+	time.Sleep(150 * time.Millisecond)
+	if _, locked, _ := c.List(); !locked {
+		t.Fatal("agent should lock after idle timeout")
+	}
+	if _, err := c.SignBytes(did, []byte("x")); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+}
+
+func TestAgentConfirm(t *testing.T) {
+	var asked []string
+	_, c, did := startAgent(t, Options{Confirm: func(req *Request) bool {
+		asked = append(asked, req.Op)
+		return req.Op != OpSignBytes
+	}})
+	if _, err := c.SignBytes(did, []byte("x")); err == nil || err.Error() != ErrDenied.Error() {
+		t.Errorf("expected denial, got %v", err)
+	}
+	ch := credentials.NewAuthChallenge("", time.Minute)
+	if _, err := c.RespondAuthChallenge(did, "", ch); err != nil {
+		t.Errorf("confirmed request failed: %v", err)
+	}
+	if len(asked) != 2 {
+		t.Errorf("expected 2 confirmations, got %v", asked)
+	}
+}
+
+func TestAgentStop(t *testing.T) {
+	_, c, _ := startAgent(t, Options{})
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, _, err := c.List(); err == nil {
+		t.Error("agent should not answer after stop")
+	}
+}
+
+func TestAgentConfirmDoesNotBlock(t *testing.T) {
+	asking := make(chan struct{})
+	answer := make(chan bool)
+	_, c, did := startAgent(t, Options{Confirm: func(req *Request) bool {
+		close(asking)
+		return <-answer
+	}})
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.SignBytes(did, []byte("x"))
+		errc <- err
+	}()
+	<-asking
+
+	// the agent answers and locks while the user is asked
+	if _, _, err := c.List(); err != nil {
+		t.Fatalf("List while confirming: %v", err)
+	}
+	if err := c.Lock(); err != nil {
+		t.Fatalf("Lock while confirming: %v", err)
+	}
+	answer <- true
+	if err := <-errc; !errors.Is(err, ErrLocked) {
+		t.Errorf("request confirmed after lock: got %v, want ErrLocked", err)
+	}
+}
+
+func TestAgentIdleConnection(t *testing.T) {
+	orig := requestTimeout
+	requestTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { requestTimeout = orig })
+	_, c, _ := startAgent(t, Options{})
+
+	conn, err := net.Dial("unix", c.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		t.Fatalf("agent did not answer an idle connection: %v", err)
+	}
+	if resp.Error == "" {
+		t.Error("expected an error for a connection without a request")
+	}
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+)
+
+// Client talks to a running agent over its Unix socket.
+type Client struct {
+	path string
+}
+
+// Dial checks that an agent is listening on path and returns a client for it.
+func Dial(path string) (*Client, error) {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return nil, err
+	}
+	conn.Close()
+	return &Client{path: path}, nil
+}
+
+func (c *Client) call(req *Request) (*Response, error) {
+	conn, err := net.Dial("unix", c.path)
+	if err != nil {
+		return nil, fmt.Errorf("connect to agent: %w", err)
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.Error != "" {
+		if resp.Locked {
+			return &resp, fmt.Errorf("%w: run 'ego agent unlock'", ErrLocked)
+		}
+		return &resp, errors.New(resp.Error)
+	}
+	return &resp, nil
+}
+
+// SignBytes returns a raw Ed25519 signature over data by did's key.
+func (c *Client) SignBytes(did string, data []byte) ([]byte, error) {
+	resp, err := c.call(&Request{Op: OpSignBytes, DID: did, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Signature, nil
+}
+
+// SignCredential has the agent append a signature proof of suite to cred, as
+// Credential.SignCredentialSuite does.
+func (c *Client) SignCredential(did, suite string, cred *credentials.Credential) (*credentials.Credential, error) {
+	resp, err := c.call(&Request{Op: OpSignCredential, DID: did, Suite: suite, Credential: cred})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Credential, nil
+}
+
+// SignPresentation has the agent append a signature proof to pres. With a non-empty
+// audience the agent signs as did's pairwise DID for that relying party and sets it as holder.
+func (c *Client) SignPresentation(did, audience string, pres *credentials.Presentation) (*credentials.Presentation, error) {
+	resp, err := c.call(&Request{Op: OpSignPresentation, DID: did, Audience: audience, Presentation: pres})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Presentation, nil
+}
+
+// RespondAuthChallenge has the agent sign an authentication challenge for did, or for its
+// pairwise DID when audience is set.
+func (c *Client) RespondAuthChallenge(did, audience string, ch *credentials.AuthenticationChallenge) (*credentials.AuthenticationResponse, error) {
+	resp, err := c.call(&Request{Op: OpAuthRespond, DID: did, Audience: audience, Challenge: ch})
+	if err != nil {
+		return nil, err
+	}
+	return resp.AuthResponse, nil
+}
+
+// List returns the DIDs held by the agent and whether it is locked.
+func (c *Client) List() ([]string, bool, error) {
+	resp, err := c.call(&Request{Op: OpList})
+	if err != nil {
+		return nil, false, err
+	}
+	return resp.DIDs, resp.Locked, nil
+}
+
+// Lock wipes the agent's keys from memory.
+func (c *Client) Lock() error {
+	_, err := c.call(&Request{Op: OpLock})
+	return err
+}
+
+// Unlock reloads the agent's vaults with passphrase.
+func (c *Client) Unlock(passphrase []byte) error {
+	resp, err := c.call(&Request{Op: OpUnlock, Passphrase: passphrase})
+	if err != nil && resp != nil {
+		return errors.New(resp.Error)
+	}
+	return err
+}
+
+// Stop shuts the agent down.
+func (c *Client) Stop() error {
+	_, err := c.call(&Request{Op: OpStop})
+	return err
+}
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+)
+
+// Operations understood by the agent. One JSON Request is sent per connection
+// and answered with one JSON Response.
+const (
+	OpSignBytes        = "sign"
+	OpSignCredential   = "sign-credential"
+	OpSignPresentation = "sign-presentation"
+	OpAuthRespond      = "auth-respond"
+	OpList             = "list"
+	OpLock             = "lock"
+	OpUnlock           = "unlock"
+	OpStop             = "stop"
+)
+
+// SocketEnv overrides the default agent socket location.
+const SocketEnv = "EGO_AGENT_SOCK"
+
+// Request is a single call to the agent.
+type Request struct {
+	Op           string                               `json:"op"`
+	DID          string                               `json:"did,omitempty"`
+	Data         []byte                               `json:"data,omitempty"`
+	Credential   *credentials.Credential              `json:"credential,omitempty"`
+	Presentation *credentials.Presentation            `json:"presentation,omitempty"`
+	Challenge    *credentials.AuthenticationChallenge `json:"challenge,omitempty"`
+	// Audience, if set, signs presentations and auth responses with the pairwise DID for that relying party.
+	Audience string `json:"audience,omitempty"`
+	// Suite selects the proof suite of credentials, such as eddsa-jcs-2022; empty is the key's own.
+	Suite      string `json:"suite,omitempty"`
+	Passphrase []byte `json:"passphrase,omitempty"`
+}
+
+// Response carries the result of a Request; Error is set on failure.
+type Response struct {
+	Error        string                              `json:"error,omitempty"`
+	Signature    []byte                              `json:"signature,omitempty"`
+	Credential   *credentials.Credential             `json:"credential,omitempty"`
+	Presentation *credentials.Presentation           `json:"presentation,omitempty"`
+	AuthResponse *credentials.AuthenticationResponse `json:"authResponse,omitempty"`
+	DIDs         []string                            `json:"dids,omitempty"`
+	Locked       bool                                `json:"locked,omitempty"`
+}
+
+// DefaultSocketPath returns $EGO_AGENT_SOCK or ~/.ego/agent.sock.
+func DefaultSocketPath() (string, error) {
+	if p := os.Getenv(SocketEnv); p != "" {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot get home directory: %w", err)
+	}
+	return filepath.Join(home, ".ego", "agent.sock"), nil
+}
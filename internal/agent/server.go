@@ -0,0 +1,356 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/credentials"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+)
+
+var (
+	// ErrLocked is returned while the agent holds no unlocked keys.
+	ErrLocked = errors.New("agent is locked")
+	// ErrDenied is returned when the confirmation callback rejects a request.
+	ErrDenied = errors.New("request denied")
+)
+
+// requestTimeout bounds how long a client may take to send its request, so an idle
+// connection does not hold a goroutine forever.
+var requestTimeout = 10 * time.Second
+
+// Options configure a Server.
+type Options struct {
+	// IdleTimeout locks the agent after this long without a signing request (0 disables).
+	IdleTimeout time.Duration
+	// Confirm, if set, is asked before every signing request.
+	Confirm func(req *Request) bool
+}
+
+type heldKey struct {
+	vault *vault.Vault
+	priv  *identity.PrivateKey
+	// seed is the vault's pairwise seed, nil for a plaintext keystore
+	seed []byte
+}
+
+// Server keeps vault keys unlocked in memory and signs on behalf of local clients.
+type Server struct {
+	mu     sync.Mutex
+	opts   Options
+	keys   map[string]*heldKey
+	locked bool
+	idle   *time.Timer
+
+	listener net.Listener
+	done     chan struct{}
+}
+
+// NewServer creates an agent with no vaults loaded.
+func NewServer(opts Options) *Server {
+	return &Server{opts: opts, keys: make(map[string]*heldKey), done: make(chan struct{})}
+}
+
+// AddVault unlocks v with passphrase and holds its key. It returns the vault's DID.
+func (s *Server) AddVault(v *vault.Vault, passphrase []byte) (string, error) {
+	if err := v.CheckActive(); err != nil {
+		return "", err
+	}
+	priv, seed, err := v.LoadPairwise(passphrase)
+	if err != nil {
+		return "", err
+	}
+	doc, err := v.Document()
+	if err != nil {
+		return "", err
+	}
+	if _, err := doc.CurrentMethod(); err != nil {
+		return "", err
+	}
+	did := doc.ID
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[did] = &heldKey{vault: v, priv: priv, seed: seed}
+	s.locked = false
+	return did, nil
+}
+
+// Lock wipes all private keys from memory. Vaults stay registered so Unlock can reload them.
+func (s *Server) Lock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lockLocked()
+}
+
+func (s *Server) lockLocked() {
+	for _, k := range s.keys {
+		if k.priv != nil {
+			k.priv.Wipe()
+			k.priv = nil
+		}
+		clear(k.seed)
+		k.seed = nil
+	}
+	s.locked = true
+}
+
+// Unlock reloads every registered vault that opens with passphrase.
+func (s *Server) Unlock(passphrase []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlocked := 0
+	for _, k := range s.keys {
+		if k.priv != nil {
+			unlocked++
+			continue
+		}
+		priv, seed, err := k.vault.LoadPairwise(passphrase)
+		if err != nil {
+			continue
+		}
+		k.priv, k.seed = priv, seed
+		unlocked++
+	}
+	if unlocked == 0 {
+		return vault.ErrWrongPassphrase
+	}
+	s.locked = false
+	s.touchLocked()
+	return nil
+}
+
+// ListenUnix removes a stale socket at path and listens on it with owner-only permissions.
+func ListenUnix(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("create socket dir: %w", err)
+	}
+	if c, err := net.Dial("unix", path); err == nil {
+		c.Close()
+		return nil, fmt.Errorf("an agent is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("remove stale socket: %w", err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("chmod socket: %w", err)
+	}
+	return l, nil
+}
+
+// Serve accepts connections on l until Close is called or a stop request arrives.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	s.listener = l
+	if s.opts.IdleTimeout > 0 {
+		s.idle = time.AfterFunc(s.opts.IdleTimeout, s.Lock)
+	}
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close stops the server and wipes all keys.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lockLocked()
+	if s.idle != nil {
+		s.idle.Stop()
+	}
+	select {
+	case <-s.done:
+		return nil
+	default:
+		close(s.done)
+	}
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(requestTimeout))
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(Response{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	resp := s.dispatch(&req)
+	json.NewEncoder(conn).Encode(resp)
+	if req.Op == OpStop && resp.Error == "" {
+		s.Close()
+	}
+}
+
+func (s *Server) dispatch(req *Request) *Response {
+	switch req.Op {
+	case OpList:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		dids := make([]string, 0, len(s.keys))
+		for did := range s.keys {
+			dids = append(dids, did)
+		}
+		sort.Strings(dids)
+		return &Response{DIDs: dids, Locked: s.locked}
+	case OpLock:
+		s.Lock()
+		return &Response{Locked: true}
+	case OpUnlock:
+		if err := s.Unlock(req.Passphrase); err != nil {
+			return &Response{Error: err.Error(), Locked: true}
+		}
+		return &Response{}
+	case OpStop:
+		return &Response{}
+	}
+
+	resp, err := s.sign(req)
+	if err != nil {
+		return &Response{Error: err.Error(), Locked: errors.Is(err, ErrLocked)}
+	}
+	return resp
+}
+
+func (s *Server) sign(req *Request) (*Response, error) {
+	s.mu.Lock()
+	_, err := s.keyLocked(req.DID)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	// ask without holding s.mu: the user may take a while, and meanwhile the agent must
+	// still answer other requests and lock when idle
+	if s.opts.Confirm != nil && !s.opts.Confirm(req) {
+		return nil, ErrDenied
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the agent may have been locked while the user was asked
+	k, err := s.keyLocked(req.DID)
+	if err != nil {
+		return nil, err
+	}
+	s.touchLocked()
+
+	// the DID may have been deactivated or its key rotated since the vault was unlocked
+	if err := k.vault.CheckActive(); err != nil {
+		return nil, err
+	}
+	doc, err := k.vault.Document()
+	if err != nil {
+		return nil, err
+	}
+	purpose := identity.PurposeAuthentication
+	if req.Op == OpSignCredential {
+		purpose = identity.PurposeAssertionMethod
+	}
+	method, err := doc.MethodFor(purpose)
+	if err != nil {
+		return nil, err
+	}
+	if pub, err := method.PublicKey(); err != nil || !pub.Equal(k.priv.Public()) {
+		return nil, fmt.Errorf("agent key for %s does not match %s; lock and unlock the agent to load the current key", req.DID, method.ID)
+	}
+	vm := method.ID
+	ctx := context.Background()
+	ks := signer.NewMemory(k.priv)
+	holder := ""
+	if req.Audience != "" && (req.Op == OpSignPresentation || req.Op == OpAuthRespond) {
+		pk, did, pvm, err := k.vault.PairwiseIdentity(k.seed, req.Audience)
+		if err != nil {
+			return nil, err
+		}
+		holder, vm = did, pvm
+		ks = signer.NewMemory(pk)
+	}
+	switch req.Op {
+	case OpSignBytes:
+		sig, err := ks.Sign(ctx, req.Data)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Signature: sig}, nil
+	case OpSignCredential:
+		if req.Credential == nil {
+			return nil, fmt.Errorf("missing credential")
+		}
+		if err := req.Credential.SignCredentialSuite(ctx, ks, vm, req.Suite); err != nil {
+			return nil, err
+		}
+		return &Response{Credential: req.Credential}, nil
+	case OpSignPresentation:
+		if req.Presentation == nil {
+			return nil, fmt.Errorf("missing presentation")
+		}
+		if holder != "" {
+			req.Presentation.Holder = holder
+		}
+		if err := req.Presentation.SignPresentation(ctx, ks, vm); err != nil {
+			return nil, err
+		}
+		return &Response{Presentation: req.Presentation}, nil
+	case OpAuthRespond:
+		if req.Challenge == nil {
+			return nil, fmt.Errorf("missing challenge")
+		}
+		ar, err := credentials.SignAuthChallenge(ctx, vm, req.Challenge, ks)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{AuthResponse: ar}, nil
+	default:
+		return nil, fmt.Errorf("unknown operation %q", req.Op)
+	}
+}
+
+// keyLocked returns the unlocked key held for did; s.mu must be held.
+func (s *Server) keyLocked(did string) (*heldKey, error) {
+	if s.locked {
+		return nil, ErrLocked
+	}
+	k, ok := s.keys[did]
+	if !ok {
+		return nil, fmt.Errorf("agent holds no key for %s", did)
+	}
+	if k.priv == nil {
+		return nil, ErrLocked
+	}
+	return k, nil
+}
+
+// touchLocked restarts the idle timer; s.mu must be held.
+func (s *Server) touchLocked() {
+	if s.idle != nil {
+		s.idle.Reset(s.opts.IdleTimeout)
+	}
+}
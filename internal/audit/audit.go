@@ -0,0 +1,261 @@
+// Package audit keeps a tamper-evident log of the operations performed on a vault.
+//
+// Every entry records the operation, a digest of its inputs and output, a timestamp and
+// the hash of the previous entry, and is signed with the vault's own key. Signatures are
+// checked against the vault's DID Document, so the log cannot be rewritten without that
+// key. A separate signed head file records the last entry so that truncating the log is
+// detected too.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+)
+
+const (
+	logFilename  = "audit.log"
+	headFilename = "audit.head"
+)
+
+var (
+	// ErrTampered is returned by Verify when the log does not match its hashes, signatures or head.
+	ErrTampered = errors.New("audit log has been tampered with")
+	// ErrNoLog is returned by Verify for a vault that has not recorded an operation yet.
+	ErrNoLog = errors.New("no audit log yet")
+)
+
+// Entry is one line of audit.log.
+type Entry struct {
+	Seq    int       `json:"seq"`
+	Time   time.Time `json:"time"`
+	Op     string    `json:"op"`
+	Inputs string    `json:"inputs"`
+	Output string    `json:"output"`
+	Prev   string    `json:"prev"`
+	// VM is the verification method of the vault key that signed the entry.
+	VM   string `json:"vm,omitempty"`
+	Hash string `json:"hash"`
+	Sig  []byte `json:"sig"`
+}
+
+// head is the signed pointer to the last entry, kept in audit.head.
+type head struct {
+	Seq  int    `json:"seq"`
+	Hash string `json:"hash"`
+	VM   string `json:"vm,omitempty"`
+	Sig  []byte `json:"sig"`
+}
+
+// Log is the audit log of the vault in Dir.
+type Log struct {
+	Dir string
+}
+
+// NewLog returns the audit log of the vault at dir.
+func NewLog(dir string) *Log {
+	return &Log{Dir: dir}
+}
+
+// Digest returns the hex SHA-256 of data, as recorded in entries.
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Append records op with the digests of its inputs and output, signed by s, the vault key
+// of verification method vm.
+func (l *Log) Append(ctx context.Context, s signer.Signer, vm, op string, inputs, output []byte) (*Entry, error) {
+	lock, err := fsutil.LockDir(l.Dir)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	entries, err := l.Entries()
+	if err != nil {
+		return nil, err
+	}
+	e := &Entry{
+		Seq:    1,
+		Time:   time.Now().UTC(),
+		Op:     op,
+		Inputs: Digest(inputs),
+		Output: Digest(output),
+		VM:     vm,
+	}
+	if n := len(entries); n > 0 {
+		last := entries[n-1]
+		e.Seq, e.Prev = last.Seq+1, last.Hash
+	}
+	e.Hash = entryHash(e)
+	if e.Sig, err = s.Sign(ctx, []byte(e.Hash)); err != nil {
+		return nil, fmt.Errorf("sign audit entry: %w", err)
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(l.Dir, logFilename), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", logFilename, err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("write %s: %w", logFilename, err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	h := head{Seq: e.Seq, Hash: e.Hash, VM: vm}
+	if h.Sig, err = s.Sign(ctx, headMessage(h)); err != nil {
+		return nil, fmt.Errorf("sign audit head: %w", err)
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	if err := fsutil.WriteFile(filepath.Join(l.Dir, headFilename), data, 0600); err != nil {
+		return nil, fmt.Errorf("write %s: %w", headFilename, err)
+	}
+	return e, nil
+}
+
+// Entries parses audit.log without checking its hashes or signatures. A vault without a
+// log has no entries.
+func (l *Log) Entries() ([]*Entry, error) {
+	data, err := os.ReadFile(filepath.Join(l.Dir, logFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", logFilename, err)
+	}
+	var entries []*Entry
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrTampered, n, err)
+		}
+		if !isDigest(e.Inputs) || !isDigest(e.Output) {
+			return nil, fmt.Errorf("%w: line %d: malformed digest", ErrTampered, n)
+		}
+		entries = append(entries, &e)
+	}
+	return entries, sc.Err()
+}
+
+// Verify checks every entry's hash, signature and link to its predecessor, and that the
+// last entry is the one recorded in the signed head. Signatures must be made by a
+// verification method of doc, the vault's DID Document. It returns the verified entries,
+// or ErrNoLog when neither the log nor its head exists, as in vaults created before the
+// log was kept; their log starts with the next Append.
+func (l *Log) Verify(doc *identity.Document) ([]*Entry, error) {
+	entries, err := l.Entries()
+	if err != nil {
+		return nil, err
+	}
+	hdata, err := os.ReadFile(filepath.Join(l.Dir, headFilename))
+	if os.IsNotExist(err) {
+		if len(entries) == 0 {
+			return nil, ErrNoLog
+		}
+		return nil, fmt.Errorf("%w: %s is missing", ErrTampered, headFilename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", headFilename, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: log is empty but %s exists", ErrTampered, headFilename)
+	}
+
+	prev := ""
+	for i, e := range entries {
+		if e.Seq != i+1 {
+			return nil, fmt.Errorf("%w: entry %d has sequence number %d", ErrTampered, i+1, e.Seq)
+		}
+		if e.Prev != prev {
+			return nil, fmt.Errorf("%w: entry %d does not link to entry %d", ErrTampered, e.Seq, e.Seq-1)
+		}
+		if entryHash(e) != e.Hash {
+			return nil, fmt.Errorf("%w: entry %d was modified", ErrTampered, e.Seq)
+		}
+		if e.VM == "" {
+			return nil, fmt.Errorf("%w: entry %d is not signed by the vault key", ErrTampered, e.Seq)
+		}
+		if err := verifySig(doc, e.VM, []byte(e.Hash), e.Sig); err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %v", ErrTampered, e.Seq, err)
+		}
+		prev = e.Hash
+	}
+
+	var h head
+	if err := json.Unmarshal(hdata, &h); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrTampered, headFilename, err)
+	}
+	if h.VM == "" {
+		return nil, fmt.Errorf("%w: %s is not signed by the vault key", ErrTampered, headFilename)
+	}
+	if err := verifySig(doc, h.VM, headMessage(h), h.Sig); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrTampered, headFilename, err)
+	}
+	last := entries[len(entries)-1]
+	if h.Seq > last.Seq {
+		return nil, fmt.Errorf("%w: log ends at entry %d but %d entries were written", ErrTampered, last.Seq, h.Seq)
+	}
+	if h.Seq != last.Seq || h.Hash != last.Hash {
+		return nil, fmt.Errorf("%w: entry %d is not the recorded head", ErrTampered, last.Seq)
+	}
+	return entries, nil
+}
+
+// verifySig checks sig over msg against the key of verification method vm of doc.
+func verifySig(doc *identity.Document, vm string, msg, sig []byte) error {
+	method, err := doc.Method(vm)
+	if err != nil {
+		return err
+	}
+	pub, err := method.PublicKey()
+	if err != nil {
+		return err
+	}
+	if !pub.Verify(msg, sig) {
+		return fmt.Errorf("invalid signature by %s", vm)
+	}
+	return nil
+}
+
+// isDigest reports whether s is a hex SHA-256 digest, as Digest returns.
+func isDigest(s string) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == sha256.Size
+}
+
+// entryHash hashes e without its hash and signature.
+func entryHash(e *Entry) string {
+	c := *e
+	c.Hash, c.Sig = "", nil
+	data, _ := json.Marshal(c)
+	return Digest(data)
+}
+
+func headMessage(h head) []byte {
+	return []byte(fmt.Sprintf("audit-head:%d:%s", h.Seq, h.Hash))
+}
@@ -0,0 +1,148 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+)
+
+// newVault returns the signer and DID Document of a fresh vault key.
+func newVault(t *testing.T) (signer.Signer, *identity.Document) {
+	t.Helper()
+	priv, err := identity.GenerateKey(identity.KeyTypeEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := priv.Public()
+	return signer.NewMemory(priv), identity.NewDocument(identity.GenerateDIDForKey(pub), pub)
+}
+
+func appendN(t *testing.T, l *Log, s signer.Signer, doc *identity.Document, n int) {
+	t.Helper()
+	vm, _ := doc.CurrentMethod()
+	for i := 0; i < n; i++ {
+		if _, err := l.Append(context.Background(), s, vm.ID, "issue", []byte{byte(i)}, []byte("out")); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+}
+
+// writeLog appends n entries and returns the log, its vault's document and its raw lines.
+func writeLog(t *testing.T, n int) (*Log, *identity.Document, [][]byte) {
+	t.Helper()
+	l := NewLog(t.TempDir())
+	s, doc := newVault(t)
+	appendN(t, l, s, doc, n)
+	data, _ := os.ReadFile(filepath.Join(l.Dir, logFilename))
+	return l, doc, bytes.SplitAfter(bytes.TrimSpace(data), []byte("\n"))
+}
+
+func rewrite(t *testing.T, l *Log, lines [][]byte) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(l.Dir, logFilename), bytes.Join(lines, nil), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppendVerify(t *testing.T) {
+	l, doc, _ := writeLog(t, 3)
+	entries, err := l.Verify(doc)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if len(entries) != 3 || entries[2].Seq != 3 || entries[2].Prev != entries[1].Hash {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+	if entries[0].Inputs != Digest([]byte{0}) || entries[0].Output != Digest([]byte("out")) {
+		t.Error("digests not recorded")
+	}
+
+	// a vault without a log has not recorded anything yet; its first Append starts the chain
+	empty := NewLog(t.TempDir())
+	if _, err := empty.Verify(doc); !errors.Is(err, ErrNoLog) {
+		t.Errorf("missing log: expected ErrNoLog, got %v", err)
+	}
+	os.Remove(filepath.Join(l.Dir, headFilename))
+	if _, err := l.Verify(doc); !errors.Is(err, ErrTampered) {
+		t.Errorf("missing head: expected ErrTampered, got %v", err)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	cases := map[string]func(lines [][]byte) [][]byte{
+		"edited entry": func(lines [][]byte) [][]byte {
+			lines[1] = bytes.Replace(lines[1], []byte(`"op":"issue"`), []byte(`"op":"revoke"`), 1)
+			return lines
+		},
+		"removed entry": func(lines [][]byte) [][]byte {
+			return append(lines[:1:1], lines[2:]...)
+		},
+		"reordered entries": func(lines [][]byte) [][]byte {
+			lines[1], lines[2] = lines[2], lines[1]
+			return lines
+		},
+		"truncated log": func(lines [][]byte) [][]byte {
+			return lines[:3]
+		},
+		"emptied log": func(lines [][]byte) [][]byte {
+			return nil
+		},
+	}
+	for name, tamper := range cases {
+		t.Run(name, func(t *testing.T) {
+			l, doc, lines := writeLog(t, 4)
+			rewrite(t, l, tamper(lines))
+			if _, err := l.Verify(doc); !errors.Is(err, ErrTampered) {
+				t.Errorf("expected ErrTampered, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyDetectsResignedHead(t *testing.T) {
+	l, doc, lines := writeLog(t, 3)
+	// a log rebuilt under a different key does not match the original head
+	other, _, _ := writeLog(t, 2)
+	data, _ := os.ReadFile(filepath.Join(other.Dir, headFilename))
+	os.WriteFile(filepath.Join(l.Dir, headFilename), data, 0600)
+	rewrite(t, l, lines)
+	if _, err := l.Verify(doc); !errors.Is(err, ErrTampered) {
+		t.Errorf("expected ErrTampered for a foreign head, got %v", err)
+	}
+}
+
+func TestVerifyRejectsForeignKey(t *testing.T) {
+	// a log and head written from scratch by someone without the vault key
+	l, doc, _ := writeLog(t, 3)
+	mallory, _ := newVault(t)
+	vm, _ := doc.CurrentMethod()
+	os.Remove(filepath.Join(l.Dir, logFilename))
+	if _, err := l.Append(context.Background(), mallory, vm.ID, "issue", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Verify(doc); !errors.Is(err, ErrTampered) {
+		t.Errorf("expected ErrTampered for a log signed by another key, got %v", err)
+	}
+}
+
+func TestEntriesRejectsMalformedDigest(t *testing.T) {
+	l, _, lines := writeLog(t, 2)
+	lines[1] = bytes.Replace(lines[1], []byte(`"inputs":"`), []byte(`"inputs":"ab`), 1)
+	var e Entry
+	json.Unmarshal(lines[0], &e)
+	e.Output = "ab"
+	lines[0], _ = json.Marshal(e)
+	lines[0] = append(lines[0], '\n')
+	rewrite(t, l, lines)
+	if _, err := l.Entries(); !errors.Is(err, ErrTampered) || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected ErrTampered for a short digest, got %v", err)
+	}
+}
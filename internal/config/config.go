@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
 )
 
 const (
@@ -51,7 +53,7 @@ func (c *Config) Save() error {
 	if err != nil {
 		return fmt.Errorf("marshal config: %w", err)
 	}
-	if err := os.WriteFile(cfgPath, data, 0600); err != nil {
+	if err := fsutil.WriteFile(cfgPath, data, 0600); err != nil {
 		return fmt.Errorf("write config: %w", err)
 	}
 	return nil
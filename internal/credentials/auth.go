@@ -1,13 +1,17 @@
 package credentials
 
 import (
-	"crypto/ed25519"
+	"context"
 	crand "crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
 	"github.com/juanpablocruz/minervaid/internal/store"
 	"github.com/mr-tron/base58"
 )
@@ -47,29 +51,138 @@ func NewAuthChallenge(domain string, validity time.Duration) *AuthenticationChal
 // RespondAuthChallenge loads the holder's private key from storeDir/keystore.json,
 // signs the serialized challenge, and returns a response containing the proof.
 func RespondAuthChallenge(did string, ch *AuthenticationChallenge, storeDir string) (*AuthenticationResponse, error) {
-	ksPath := filepath.Join(storeDir, "keystore.json")
-	ks := store.LoadKeyStore(ksPath)
-	privEnc, ok := ks[did]
-	if !ok {
-		return nil, fmt.Errorf("unknown DID: %s", did)
-	}
-	privBytes, err := base58.Decode(privEnc)
+	priv, err := store.LoadKey(filepath.Join(storeDir, "keystore.json"), did, identity.PurposeAuthentication)
 	if err != nil {
-		return nil, fmt.Errorf("decoding private key: %w", err)
+		return nil, err
 	}
+	defer priv.Wipe()
+	return SignAuthChallenge(context.Background(), did+"#keys-1", ch, signer.NewMemory(priv))
+}
+
+// SignAuthChallenge signs the serialized challenge with s, the key of verificationMethod.
+func SignAuthChallenge(ctx context.Context, verificationMethod string, ch *AuthenticationChallenge, s signer.Signer) (*AuthenticationResponse, error) {
 	// Serialize challenge
 	data, err := json.Marshal(ch)
 	if err != nil {
 		return nil, fmt.Errorf("marshaling challenge: %w", err)
 	}
-	// Sign with Ed25519
-	sig := ed25519.Sign(privBytes, data)
-	proof := &SignatureProof{
-		Type:               "Ed25519Signature2018",
-		Created:            time.Now().UTC().Format(time.RFC3339),
-		ProofPurpose:       "authentication",
-		VerificationMethod: did + "#keys-1",
-		JWS:                fmt.Sprintf("%x", sig),
+	proof, err := newSignatureProof(ctx, s, verificationMethod, identity.PurposeAuthentication, data)
+	if err != nil {
+		return nil, err
 	}
 	return &AuthenticationResponse{Challenge: ch, Proof: proof}, nil
 }
+
+// VerifyAuthResponse checks that resp answers issued, the challenge the verifier sent:
+// its nonce and domain must be those of issued, issued must not have expired, and the
+// challenge must be signed with an authentication method of the DID its verification
+// method belongs to. It returns the authenticated DID.
+func VerifyAuthResponse(resp *AuthenticationResponse, issued *AuthenticationChallenge, resolve DocumentResolver) (string, error) {
+	if resp.Challenge == nil || resp.Proof == nil {
+		return "", fmt.Errorf("authentication response needs a challenge and a proof")
+	}
+	if resp.Challenge.Challenge != issued.Challenge {
+		return "", fmt.Errorf("response answers challenge %q, not %q", resp.Challenge.Challenge, issued.Challenge)
+	}
+	if resp.Challenge.Domain != issued.Domain {
+		return "", fmt.Errorf("response is for domain %q, not %q", resp.Challenge.Domain, issued.Domain)
+	}
+	if time.Now().After(issued.ExpiresAt) {
+		return "", fmt.Errorf("challenge expired at %s", issued.ExpiresAt.Format(time.RFC3339))
+	}
+	did, _, _ := strings.Cut(resp.Proof.VerificationMethod, "#")
+	data, err := json.Marshal(resp.Challenge)
+	if err != nil {
+		return "", err
+	}
+	if err := verifyProof(resp.Proof, data, did, identity.PurposeAuthentication, resolve); err != nil {
+		return "", fmt.Errorf("invalid authentication response: %w", err)
+	}
+	return did, nil
+}
+
+// idTokenClaims are the claims of a self-issued id_token that VerifyIDToken checks.
+type idTokenClaims struct {
+	Iss   string      `json:"iss"`
+	Sub   string      `json:"sub"`
+	Aud   interface{} `json:"aud"`
+	Nonce string      `json:"nonce"`
+	Exp   int64       `json:"exp"`
+}
+
+// VerifyIDToken checks a self-issued OIDC id_token: a compact JWS whose kid is an
+// authentication method of the DID in sub, which must equal iss, for the nonce and
+// client_id the verifier sent in its request. It returns the authenticated DID.
+func VerifyIDToken(token, nonce, clientID string, resolve DocumentResolver) (string, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("id_token is not a compact JWS")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	var claims idTokenClaims
+	for i, v := range []interface{}{&header, &claims} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			return "", fmt.Errorf("decode id_token: %w", err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return "", fmt.Errorf("invalid id_token: %w", err)
+		}
+	}
+	did := claims.Sub
+	if did == "" {
+		did = claims.Iss
+	}
+	if claims.Iss != did {
+		return "", fmt.Errorf("self-issued id_token has issuer %q, not its subject %q", claims.Iss, did)
+	}
+	if !strings.HasPrefix(did, "did:") {
+		return "", fmt.Errorf("id_token subject %q is not a DID", did)
+	}
+	if claims.Nonce != nonce {
+		return "", fmt.Errorf("id_token nonce %q does not match %q", claims.Nonce, nonce)
+	}
+	if !audienceIncludes(claims.Aud, clientID) {
+		return "", fmt.Errorf("id_token is not for client %q", clientID)
+	}
+	if claims.Exp == 0 || time.Now().After(time.Unix(claims.Exp, 0)) {
+		return "", fmt.Errorf("id_token has expired")
+	}
+	vm, err := proofMethod(header.Kid, time.Now(), did, identity.PurposeAuthentication, resolve)
+	if err != nil {
+		return "", fmt.Errorf("invalid id_token: %w", err)
+	}
+	pub, err := vm.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	if header.Alg != pub.Type.JWSAlgorithm() {
+		return "", fmt.Errorf("id_token alg %q does not match %s key", header.Alg, pub.Type)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("decode id_token signature: %w", err)
+	}
+	if !pub.Verify([]byte(parts[0]+"."+parts[1]), sig) {
+		return "", fmt.Errorf("invalid id_token: invalid signature")
+	}
+	return did, nil
+}
+
+// audienceIncludes reports whether aud, a string or an array of strings, names clientID.
+func audienceIncludes(aud interface{}, clientID string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == clientID
+	case []interface{}:
+		for _, v := range a {
+			if v == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
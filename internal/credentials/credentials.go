@@ -1,25 +1,102 @@
 package credentials
 
 import (
-	"crypto/ed25519"
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
 )
 
-// Credential represents a W3C Verifiable Credential.
+// Credential represents a W3C Verifiable Credential, of the 1.1 data model with
+// IssuanceDate and ExpirationDate or of the 2.0 data model with ValidFrom and ValidUntil.
 type Credential struct {
-	Context           []string               `json:"@context"`
+	Context           []interface{}          `json:"@context"`
 	ID                string                 `json:"id"`
 	Type              []string               `json:"type"`
+	Name              string                 `json:"name,omitempty"`
+	Description       string                 `json:"description,omitempty"`
 	Issuer            string                 `json:"issuer"`
-	IssuanceDate      time.Time              `json:"issuanceDate"`
+	IssuanceDate      time.Time              `json:"issuanceDate,omitzero"`
+	ExpirationDate    time.Time              `json:"expirationDate,omitzero"`
+	ValidFrom         time.Time              `json:"validFrom,omitzero"`
+	ValidUntil        time.Time              `json:"validUntil,omitzero"`
 	CredentialSubject map[string]interface{} `json:"credentialSubject"`
-	Proofs            []json.RawMessage      `json:"proof"`
+	CredentialSchema  CredentialSchemas      `json:"credentialSchema,omitempty"`
+	CredentialStatus  StatusEntries          `json:"credentialStatus,omitempty"`
+	Proofs            ProofSet               `json:"proof"`
+
+	// raw is the JSON the credential was read from and read the JSON of its fields then;
+	// MarshalJSON writes raw for as long as the fields still encode to read.
+	raw, read []byte
 }
 
-// SignatureProof is the Ed25519 signature proof.
+// credentialFields is Credential without its JSON methods.
+type credentialFields Credential
+
+// UnmarshalJSON reads a credential and keeps data, so that an unchanged credential
+// encodes to it again, with the properties and formatting that Credential drops. An
+// issuer may be a URL or an object with an id.
+func (c *Credential) UnmarshalJSON(data []byte) error {
+	*c = Credential{}
+	v := struct {
+		*credentialFields
+		Issuer json.RawMessage `json:"issuer"`
+	}{credentialFields: (*credentialFields)(c)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if issuer := bytes.TrimSpace(v.Issuer); len(issuer) > 0 && issuer[0] == '{' {
+		var obj struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(issuer, &obj); err != nil {
+			return fmt.Errorf("issuer: %w", err)
+		}
+		c.Issuer = obj.ID
+	} else if len(issuer) > 0 {
+		if err := json.Unmarshal(issuer, &c.Issuer); err != nil {
+			return fmt.Errorf("issuer: %w", err)
+		}
+	}
+	read, err := json.Marshal((*credentialFields)(c))
+	if err != nil {
+		return err
+	}
+	c.raw, c.read = bytes.Clone(data), read
+	return nil
+}
+
+// MarshalJSON writes the JSON the credential was read from if its fields are unchanged.
+func (c Credential) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(credentialFields(c))
+	if err != nil {
+		return nil, err
+	}
+	if c.raw != nil && bytes.Equal(data, c.read) {
+		return c.raw, nil
+	}
+	return data, nil
+}
+
+// ProofSet holds the proofs of a credential or presentation, as a single proof object
+// when there is one and as an array otherwise.
+type ProofSet []json.RawMessage
+
+func (p ProofSet) MarshalJSON() ([]byte, error) { return marshalOneOrMany(p) }
+
+func (p *ProofSet) UnmarshalJSON(data []byte) error {
+	return unmarshalOneOrMany(data, (*[]json.RawMessage)(p))
+}
+
+// SignatureProof is the proof of the legacy suites named after the key type, such as
+// Ed25519Signature2018. JWS is a detached JWS with an unencoded payload (RFC 7797) over
+// the JSON encoding of the signed document.
 type SignatureProof struct {
 	Type               string `json:"type"`
 	Created            string `json:"created"`
@@ -34,10 +111,10 @@ type Challenge struct {
 	Params map[string]interface{} `json:"params"`
 }
 
-// NewCredential builds a new Verifiable Credential.
+// NewCredential builds a new Verifiable Credential of the 1.1 data model.
 func NewCredential(id, issuer string, subject map[string]interface{}) *Credential {
 	return &Credential{
-		Context:           []string{"https://www.w3.org/2018/credentials/v1"},
+		Context:           []interface{}{credentialsV1Context},
 		ID:                id,
 		Type:              []string{"VerifiableCredential"},
 		Issuer:            issuer,
@@ -47,6 +124,56 @@ func NewCredential(id, issuer string, subject map[string]interface{}) *Credentia
 	}
 }
 
+// NewCredentialV2 builds a new Verifiable Credential of the 2.0 data model, valid from now.
+func NewCredentialV2(id, issuer string, subject map[string]interface{}) *Credential {
+	return &Credential{
+		Context:           []interface{}{credentialsV2Context},
+		ID:                id,
+		Type:              []string{"VerifiableCredential"},
+		Issuer:            issuer,
+		ValidFrom:         time.Now().UTC().Truncate(time.Second),
+		CredentialSubject: subject,
+		Proofs:            []json.RawMessage{},
+	}
+}
+
+// IsV2 reports whether the credential uses the 2.0 data model.
+func (c *Credential) IsV2() bool {
+	return len(c.Context) > 0 && c.Context[0] == credentialsV2Context
+}
+
+// SetExpiration makes the credential expire at t: it sets ValidUntil for 2.0 credentials
+// and ExpirationDate for 1.1 ones. Sign the credential afterwards.
+func (c *Credential) SetExpiration(t time.Time) {
+	t = t.UTC().Truncate(time.Second)
+	if c.IsV2() {
+		c.ValidUntil = t
+	} else {
+		c.ExpirationDate = t
+	}
+}
+
+// ParseExpiration parses when a credential expires: an RFC 3339 time, or a period after
+// now such as 30d, a number of days, or a Go duration such as 12h.
+func ParseExpiration(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	var d time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil || d <= 0 {
+		return time.Time{}, fmt.Errorf("invalid expiry %q: use an RFC 3339 time or a period such as 30d or 12h", s)
+	}
+	return now.Add(d), nil
+}
+
 // AttachProof applies a zero-knowledge proof based on a generic Challenge JSON.
 // Currently supports proof type "range" to generate a bulletproof range proof.
 func (c *Credential) AttachProof(challengeJSON []byte) error {
@@ -134,8 +261,10 @@ func (c *Credential) AttachProof(challengeJSON []byte) error {
 	return nil
 }
 
-// SignCredential signs the credential with Ed25519 and appends a signature proof.
-func (c *Credential) SignCredential(priv ed25519.PrivateKey, verificationMethod string) error {
+// SignCredential signs the credential with s and appends a signature proof of the key's
+// legacy suite, over the JSON encoding of the credential. Use SignCredentialSuite for
+// Data Integrity proofs.
+func (c *Credential) SignCredential(ctx context.Context, s signer.Signer, verificationMethod string) error {
 	// clone without proofs
 	tmp := *c
 	tmp.Proofs = nil
@@ -146,13 +275,9 @@ func (c *Credential) SignCredential(priv ed25519.PrivateKey, verificationMethod
 		return err
 	}
 
-	sig := ed25519.Sign(priv, data)
-	sp := SignatureProof{
-		Type:               "Ed25519Signature2018",
-		Created:            time.Now().UTC().Format(time.RFC3339),
-		ProofPurpose:       "assertionMethod",
-		VerificationMethod: verificationMethod,
-		JWS:                fmt.Sprintf("%x", sig),
+	sp, err := newSignatureProof(ctx, s, verificationMethod, identity.PurposeAssertionMethod, data)
+	if err != nil {
+		return err
 	}
 	b, err := json.Marshal(sp)
 	if err != nil {
@@ -1,9 +1,12 @@
 package credentials
 
 import (
-	"crypto/ed25519"
+	"context"
 	"encoding/json"
 	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer/signertest"
 )
 
 func TestCredentialAttachRangeProof(t *testing.T) {
@@ -39,11 +42,11 @@ func TestCredentialAttachRangeProof(t *testing.T) {
 func TestSignCredential(t *testing.T) {
 	subj := map[string]interface{}{"id": "did:example:holder"}
 	cred := NewCredential("cred2", "did:example:issuer", subj)
-	_, priv, _ := ed25519.GenerateKey(nil)
+	fake, _ := signertest.New(identity.KeyTypeEd25519)
 	// append dummy proof
 	cred.Proofs = append(cred.Proofs, json.RawMessage(`{"type":"Dummy"}`))
 
-	if err := cred.SignCredential(priv, "did:example:issuer#key-1"); err != nil {
+	if err := cred.SignCredential(context.Background(), fake, "did:example:issuer#key-1"); err != nil {
 		t.Fatalf("SignCredential failed: %v", err)
 	}
 	if len(cred.Proofs) != 2 {
@@ -0,0 +1,363 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gowebpki/jcs"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/jsonld"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+	"github.com/mr-tron/base58"
+)
+
+// Data Integrity suites. Unlike the legacy suites named after the key type, such as
+// Ed25519Signature2018, which sign the JSON encoding of the Go structs, they sign a
+// canonical form of the document, so other VC implementations can verify them: the RDF
+// dataset for SuiteEddsaRdfc2022 and SuiteEd25519Signature2020, and the RFC 8785 JSON
+// Canonicalization Scheme for SuiteEddsaJcs2022, which needs no JSON-LD processing.
+const (
+	SuiteEddsaRdfc2022        = "eddsa-rdfc-2022"
+	SuiteEddsaJcs2022         = "eddsa-jcs-2022"
+	SuiteEd25519Signature2020 = "Ed25519Signature2020"
+)
+
+// DataIntegrityProofType is the proof type of the cryptosuite-based suites.
+const DataIntegrityProofType = "DataIntegrityProof"
+
+const (
+	credentialsV1Context        = "https://www.w3.org/2018/credentials/v1"
+	credentialsV2Context        = "https://www.w3.org/ns/credentials/v2"
+	dataIntegrityContext        = "https://w3id.org/security/data-integrity/v2"
+	ed25519Signature2020Context = "https://w3id.org/security/suites/ed25519-2020/v1"
+	// issuerDependentVocab gives claims that no context defines an IRI, as the VCDM 2.0
+	// context does.
+	issuerDependentVocab = "https://www.w3.org/ns/credentials/issuer-dependent#"
+)
+
+// ContextLoader supplies the JSON-LD contexts that Data Integrity proofs are computed
+// with. It holds the W3C credentials, DID and security contexts; add the contexts of
+// other credential types to it. Contexts are never fetched from the network.
+var ContextLoader = jsonld.NewOfflineLoader()
+
+// DataIntegrityProof is a W3C Data Integrity proof. ProofValue is the multibase
+// (base58btc) signature over the hashes of the canonical proof options and document.
+type DataIntegrityProof struct {
+	Type               string `json:"type"`
+	Cryptosuite        string `json:"cryptosuite,omitempty"`
+	Created            string `json:"created,omitempty"`
+	VerificationMethod string `json:"verificationMethod"`
+	ProofPurpose       string `json:"proofPurpose"`
+	Challenge          string `json:"challenge,omitempty"`
+	Domain             string `json:"domain,omitempty"`
+	ProofValue         string `json:"proofValue,omitempty"`
+}
+
+// dataIntegritySuite describes how a suite appears in proofs.
+type dataIntegritySuite struct {
+	proofType   string
+	cryptosuite string
+	// context defines proofType for documents whose own contexts do not
+	context string
+	// jcs selects the JSON Canonicalization Scheme instead of RDFC-1.0
+	jcs bool
+}
+
+var dataIntegritySuites = map[string]dataIntegritySuite{
+	SuiteEddsaRdfc2022:        {DataIntegrityProofType, SuiteEddsaRdfc2022, dataIntegrityContext, false},
+	SuiteEddsaJcs2022:         {DataIntegrityProofType, SuiteEddsaJcs2022, dataIntegrityContext, true},
+	SuiteEd25519Signature2020: {SuiteEd25519Signature2020, "", ed25519Signature2020Context, false},
+}
+
+// suiteOf returns the Data Integrity suite of the proof raw, if it is one.
+func suiteOf(raw json.RawMessage) (dataIntegritySuite, bool) {
+	var p DataIntegrityProof
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return dataIntegritySuite{}, false
+	}
+	for _, s := range dataIntegritySuites {
+		if s.proofType == p.Type && s.cryptosuite == p.Cryptosuite {
+			return s, true
+		}
+	}
+	return dataIntegritySuite{}, false
+}
+
+// SignCredentialSuite signs the credential with s and appends a proof of suite: a Data
+// Integrity suite such as SuiteEddsaRdfc2022, or the key's legacy suite. Before a Data
+// Integrity proof is added, the contexts that define its terms are added to the
+// credential, including a vocabulary for claims that the v1 context leaves undefined.
+func (c *Credential) SignCredentialSuite(ctx context.Context, s signer.Signer, verificationMethod, suite string) error {
+	if suite == "" || suite == s.PublicKey().Type.SignatureSuite() {
+		return c.SignCredential(ctx, s, verificationMethod)
+	}
+	di, err := lookupSuite(suite, s.PublicKey().Type)
+	if err != nil {
+		return err
+	}
+	c.Context = addContexts(c.Context, di)
+	proof, err := dataIntegrityProof(ctx, c, s, verificationMethod, identity.PurposeAssertionMethod, di)
+	if err != nil {
+		return err
+	}
+	c.Proofs = append(c.Proofs, proof)
+	return nil
+}
+
+// SignPresentationSuite is SignCredentialSuite for presentations.
+func (p *Presentation) SignPresentationSuite(ctx context.Context, s signer.Signer, verificationMethod, suite string) error {
+	if suite == "" || suite == s.PublicKey().Type.SignatureSuite() {
+		return p.SignPresentation(ctx, s, verificationMethod)
+	}
+	di, err := lookupSuite(suite, s.PublicKey().Type)
+	if err != nil {
+		return err
+	}
+	p.Context = addContexts(p.Context, di)
+	proof, err := dataIntegrityProof(ctx, p, s, verificationMethod, identity.PurposeAuthentication, di)
+	if err != nil {
+		return err
+	}
+	p.Proofs = append(p.Proofs, proof)
+	return nil
+}
+
+// lookupSuite returns the Data Integrity suite named suite, if keys of type kt sign it.
+func lookupSuite(suite string, kt identity.KeyType) (dataIntegritySuite, error) {
+	di, ok := dataIntegritySuites[suite]
+	if !ok {
+		return dataIntegritySuite{}, fmt.Errorf("unknown proof suite %q", suite)
+	}
+	if kt != identity.KeyTypeEd25519 {
+		return dataIntegritySuite{}, fmt.Errorf("proof suite %s needs an Ed25519 key, not %s", suite, kt)
+	}
+	return di, nil
+}
+
+// dataIntegrityProof returns a proof of suite di by s over v, a credential or
+// presentation, leaving out its proofs.
+func dataIntegrityProof(ctx context.Context, v interface{}, s signer.Signer, verificationMethod, purpose string, di dataIntegritySuite) (json.RawMessage, error) {
+	doc, err := documentOf(v)
+	if err != nil {
+		return nil, err
+	}
+	proof := &DataIntegrityProof{
+		Type:               di.proofType,
+		Cryptosuite:        di.cryptosuite,
+		Created:            time.Now().UTC().Format(time.RFC3339),
+		VerificationMethod: verificationMethod,
+		ProofPurpose:       purpose,
+	}
+	if err := signDataIntegrity(ctx, doc, s, proof); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(proof)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling signature proof: %w", err)
+	}
+	return b, nil
+}
+
+// addContexts returns contexts with what a credential needs for a proof of suite.
+func addContexts(contexts []interface{}, suite dataIntegritySuite) []interface{} {
+	has := func(url string) bool {
+		for _, c := range contexts {
+			if c == url {
+				return true
+			}
+		}
+		return false
+	}
+	out := append([]interface{}(nil), contexts...)
+	if !has(suite.context) && !(suite.proofType == DataIntegrityProofType && has(credentialsV2Context)) {
+		out = append(out, suite.context)
+	}
+	if !suite.jcs && has(credentialsV1Context) && !hasVocab(contexts) {
+		out = append(out, map[string]interface{}{"@vocab": issuerDependentVocab})
+	}
+	return out
+}
+
+func hasVocab(contexts []interface{}) bool {
+	for _, c := range contexts {
+		if m, ok := c.(map[string]interface{}); ok {
+			if _, ok := m["@vocab"]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// documentOf returns v as generic JSON, without its proof.
+func documentOf(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	delete(doc, "proof")
+	return doc, nil
+}
+
+// signDataIntegrity sets the proofValue of proof to the signature by s over doc.
+func signDataIntegrity(ctx context.Context, doc map[string]interface{}, s signer.Signer, proof *DataIntegrityProof) error {
+	proof.ProofValue = ""
+	opts, err := documentOf(proof)
+	if err != nil {
+		return err
+	}
+	data, err := dataIntegrityInput(doc, opts, proof.Cryptosuite == SuiteEddsaJcs2022)
+	if err != nil {
+		return err
+	}
+	sig, err := s.Sign(ctx, data)
+	if err != nil {
+		return err
+	}
+	proof.ProofValue = "z" + base58.Encode(sig)
+	return nil
+}
+
+// dataIntegrityInput returns the signed bytes of a Data Integrity proof: the SHA-256 of
+// the canonical proof options, the proof without its proofValue, which take the
+// document's @context, followed by the SHA-256 of the canonical document.
+func dataIntegrityInput(doc, options map[string]interface{}, useJCS bool) ([]byte, error) {
+	opts := make(map[string]interface{}, len(options)+1)
+	for k, v := range options {
+		opts[k] = v
+	}
+	delete(opts, "@context")
+	if c, ok := doc["@context"]; ok {
+		opts["@context"] = c
+	}
+	canonicalOpts, err := canonicalize(opts, useJCS)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalize proof options: %w", err)
+	}
+	canonicalDoc, err := canonicalize(doc, useJCS)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalize document: %w", err)
+	}
+	optsHash := sha256.Sum256(canonicalOpts)
+	docHash := sha256.Sum256(canonicalDoc)
+	return append(optsHash[:], docHash[:]...), nil
+}
+
+// canonicalize returns doc in RFC 8785 JSON canonical form if useJCS is set, and as
+// canonical N-Quads otherwise.
+func canonicalize(doc map[string]interface{}, useJCS bool) ([]byte, error) {
+	if useJCS {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		return jcs.Transform(data)
+	}
+	nquads, err := jsonld.Canonicalize(doc, ContextLoader)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(nquads), nil
+}
+
+// verifyDataIntegrity checks that raw is a Data Integrity proof for purpose over doc,
+// with the same checks of its verification method as verifyProof. The proof options are
+// raw as received without its proofValue, so properties DataIntegrityProof does not
+// model, such as nonce or previousProof, stay signed. A proof with its own @context must
+// list the first contexts of doc, and doc is canonicalized with those.
+func verifyDataIntegrity(doc map[string]interface{}, raw json.RawMessage, controller, purpose string, resolve DocumentResolver) error {
+	var proof DataIntegrityProof
+	if err := json.Unmarshal(raw, &proof); err != nil {
+		return fmt.Errorf("unmarshal data integrity proof: %w", err)
+	}
+	var opts map[string]interface{}
+	if err := json.Unmarshal(raw, &opts); err != nil {
+		return fmt.Errorf("unmarshal data integrity proof: %w", err)
+	}
+	delete(opts, "proofValue")
+	if proof.ProofPurpose != purpose {
+		return fmt.Errorf("proof purpose is %q, want %q", proof.ProofPurpose, purpose)
+	}
+	if len(proof.ProofValue) < 2 || proof.ProofValue[0] != 'z' {
+		return fmt.Errorf("proofValue is not base58btc multibase")
+	}
+	sig, err := base58.Decode(proof.ProofValue[1:])
+	if err != nil {
+		return fmt.Errorf("decode proofValue: %w", err)
+	}
+	if c, ok := opts["@context"]; ok {
+		if doc, err = withProofContext(doc, c); err != nil {
+			return err
+		}
+	}
+	created := time.Now()
+	if proof.Created != "" {
+		if created, err = time.Parse(time.RFC3339, proof.Created); err != nil {
+			return fmt.Errorf("invalid proof created time: %w", err)
+		}
+	}
+	if expires, ok := opts["expires"].(string); ok {
+		t, err := time.Parse(time.RFC3339, expires)
+		if err != nil {
+			return fmt.Errorf("invalid proof expires time: %w", err)
+		}
+		if time.Now().After(t) {
+			return fmt.Errorf("proof expired at %s", expires)
+		}
+	}
+	vm, err := proofMethod(proof.VerificationMethod, created, controller, purpose, resolve)
+	if err != nil {
+		return err
+	}
+	pub, err := vm.PublicKey()
+	if err != nil {
+		return err
+	}
+	if pub.Type != identity.KeyTypeEd25519 {
+		return fmt.Errorf("proof type %s does not match %s key %s", proof.Type, pub.Type, vm.ID)
+	}
+	data, err := dataIntegrityInput(doc, opts, proof.Cryptosuite == SuiteEddsaJcs2022)
+	if err != nil {
+		return err
+	}
+	if !pub.Verify(data, sig) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// withProofContext returns doc with @context replaced by c, the @context of its proof,
+// which must repeat the first contexts of doc in order.
+func withProofContext(doc map[string]interface{}, c interface{}) (map[string]interface{}, error) {
+	list := func(v interface{}) []interface{} {
+		if l, ok := v.([]interface{}); ok {
+			return l
+		}
+		return []interface{}{v}
+	}
+	docContexts, proofContexts := list(doc["@context"]), list(c)
+	if len(proofContexts) > len(docContexts) {
+		return nil, fmt.Errorf("proof @context is not a prefix of the document @context")
+	}
+	for i, pc := range proofContexts {
+		a, _ := json.Marshal(pc)
+		b, _ := json.Marshal(docContexts[i])
+		if !bytes.Equal(a, b) {
+			return nil, fmt.Errorf("proof @context is not a prefix of the document @context")
+		}
+	}
+	out := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		out[k] = v
+	}
+	out["@context"] = c
+	return out, nil
+}
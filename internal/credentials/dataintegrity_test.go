@@ -0,0 +1,348 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/jsonld"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+	"github.com/mr-tron/base58"
+)
+
+// The eddsa-rdfc-2022 test vector of the W3C Data Integrity EdDSA Cryptosuites
+// specification.
+const (
+	vectorSecretKey  = "z3u2en7t5LR2WtQH5PfFqMqwVHBeXouLzo6haApm8XHqvjxq"
+	vectorDID        = "did:key:z6MkrJVnaZkeFzdQyMZu1cgjg7k1pZZ6pvBQ7XJPt4swbTQ2"
+	vectorProofValue = "z2YwC8z3ap7yx1nZYCg4L3j3ApHsF8kgPdSb5xoS1VR7vPG3F561B52hYnQF9iseabecm3ijx4K1FBTQsCZahKZme"
+	vectorCredential = `{
+  "@context": [
+    "https://www.w3.org/ns/credentials/v2",
+    "https://www.w3.org/ns/credentials/examples/v2"
+  ],
+  "id": "urn:uuid:58172aac-d8ba-11ed-83dd-0b3aef56cc33",
+  "type": ["VerifiableCredential", "AlumniCredential"],
+  "name": "Alumni Credential",
+  "description": "A minimum viable example of an Alumni Credential.",
+  "issuer": "https://vc.example/issuers/5678",
+  "validFrom": "2023-01-01T00:00:00Z",
+  "credentialSubject": {
+    "id": "did:example:abcdefgh",
+    "alumniOf": "The School of Examples"
+  }
+}`
+)
+
+func vectorKey(t *testing.T) *identity.PrivateKey {
+	t.Helper()
+	raw, err := base58.Decode(vectorSecretKey[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// multicodec ed25519-priv (0x1300) followed by the seed
+	if len(raw) != 34 || raw[0] != 0x80 || raw[1] != 0x26 {
+		t.Fatalf("unexpected secret key encoding %x", raw[:2])
+	}
+	return identity.FromEd25519(ed25519.NewKeyFromSeed(raw[2:]))
+}
+
+func TestDataIntegrityTestVector(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(vectorCredential), &doc); err != nil {
+		t.Fatal(err)
+	}
+	proof := &DataIntegrityProof{
+		Type:               DataIntegrityProofType,
+		Cryptosuite:        SuiteEddsaRdfc2022,
+		Created:            "2023-02-24T23:36:38Z",
+		VerificationMethod: vectorDID + "#" + vectorDID[len("did:key:"):],
+		ProofPurpose:       identity.PurposeAssertionMethod,
+	}
+	if err := signDataIntegrity(context.Background(), doc, signer.NewMemory(vectorKey(t)), proof); err != nil {
+		t.Fatal(err)
+	}
+	if proof.ProofValue != vectorProofValue {
+		t.Fatalf("proofValue = %s, want %s", proof.ProofValue, vectorProofValue)
+	}
+
+	raw, _ := json.Marshal(proof)
+	if err := verifyDataIntegrity(doc, raw, vectorDID, identity.PurposeAssertionMethod, ResolveDocument); err != nil {
+		t.Errorf("test vector did not verify: %v", err)
+	}
+	doc["credentialSubject"].(map[string]interface{})["alumniOf"] = "Another School"
+	if err := verifyDataIntegrity(doc, raw, vectorDID, identity.PurposeAssertionMethod, ResolveDocument); err == nil {
+		t.Error("expected failure for a changed claim")
+	}
+}
+
+// vectorIssuer resolves the issuer of the test vector, an https URL, to a document that
+// lists the vector's did:key method for assertionMethod.
+func vectorIssuer(t *testing.T) DocumentResolver {
+	t.Helper()
+	keyDoc, err := ResolveDocument(vectorDID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const issuer = "https://vc.example/issuers/5678"
+	vm := keyDoc.VerificationMethod[0]
+	vm.ID, vm.Controller = vectorDID+"#"+vectorDID[len("did:key:"):], issuer
+	doc := &identity.Document{
+		ID:                 issuer,
+		VerificationMethod: []identity.VerificationMethod{vm},
+		AssertionMethod:    []string{vm.ID},
+	}
+	return func(did string) (*identity.Document, error) { return doc, nil }
+}
+
+func TestVerifyTestVectorJSON(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(vectorCredential), &doc); err != nil {
+		t.Fatal(err)
+	}
+	// the signed credential of the specification, with a single proof object
+	doc["proof"] = map[string]interface{}{
+		"type":               DataIntegrityProofType,
+		"cryptosuite":        SuiteEddsaRdfc2022,
+		"created":            "2023-02-24T23:36:38Z",
+		"verificationMethod": vectorDID + "#" + vectorDID[len("did:key:"):],
+		"proofPurpose":       identity.PurposeAssertionMethod,
+		"proofValue":         vectorProofValue,
+	}
+	signed, _ := json.MarshalIndent(doc, "", "  ")
+
+	var cred Credential
+	if err := json.Unmarshal(signed, &cred); err != nil {
+		t.Fatal(err)
+	}
+	if len(cred.Proofs) != 1 {
+		t.Fatalf("got %d proofs, want 1", len(cred.Proofs))
+	}
+	if err := VerifyCredentialWith(&cred, vectorIssuer(t)); err != nil {
+		t.Errorf("signed test vector did not verify: %v", err)
+	}
+	out, _ := json.Marshal(&cred)
+	if !bytes.Contains(out, []byte(`"proof":{`)) {
+		t.Errorf("single proof not written as an object: %s", out)
+	}
+
+	cred.Name = "Another Credential"
+	if err := VerifyCredentialWith(&cred, vectorIssuer(t)); err == nil {
+		t.Error("expected failure for a changed name")
+	}
+}
+
+func TestVerifyReceivedCredential(t *testing.T) {
+	ctx := context.Background()
+	// properties Credential does not model, a validFrom with milliseconds and an issuer
+	// object must all reach the canonical form unchanged
+	doc := map[string]interface{}{
+		"@context":  []interface{}{credentialsV2Context, "https://www.w3.org/ns/credentials/examples/v2"},
+		"id":        "urn:uuid:0b6c1c0e-5d0a-4a8e-9f0b-3c2f3f0e7a11",
+		"type":      []interface{}{"VerifiableCredential", "AlumniCredential"},
+		"issuer":    map[string]interface{}{"id": vectorDID, "name": "The School of Examples"},
+		"validFrom": "2023-01-01T00:00:00.250Z",
+		"credentialSubject": map[string]interface{}{
+			"id":       "did:example:abcdefgh",
+			"alumniOf": "The School of Examples",
+		},
+		"evidence": []interface{}{map[string]interface{}{
+			"type":     []interface{}{"ExampleEvidence"},
+			"verifier": "https://vc.example/verifiers/1",
+		}},
+	}
+	proof := &DataIntegrityProof{
+		Type:               DataIntegrityProofType,
+		Cryptosuite:        SuiteEddsaRdfc2022,
+		Created:            "2023-02-24T23:36:38Z",
+		VerificationMethod: vectorDID + "#" + vectorDID[len("did:key:"):],
+		ProofPurpose:       identity.PurposeAssertionMethod,
+	}
+	if err := signDataIntegrity(ctx, doc, signer.NewMemory(vectorKey(t)), proof); err != nil {
+		t.Fatal(err)
+	}
+	doc["proof"] = []interface{}{proof}
+	signed, _ := json.Marshal(doc)
+
+	var cred Credential
+	if err := json.Unmarshal(signed, &cred); err != nil {
+		t.Fatal(err)
+	}
+	if cred.Issuer != vectorDID {
+		t.Errorf("issuer = %q, want %q", cred.Issuer, vectorDID)
+	}
+	if err := VerifyCredential(&cred); err != nil {
+		t.Errorf("received credential did not verify: %v", err)
+	}
+	// unchanged credentials are written as they were read
+	out, _ := json.Marshal(&cred)
+	if !bytes.Equal(out, signed) {
+		t.Errorf("round trip changed the credential:\n%s\n%s", out, signed)
+	}
+}
+
+func TestVerifyExtraProofProperties(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(vectorCredential), &doc); err != nil {
+		t.Fatal(err)
+	}
+	// a proof with properties DataIntegrityProof does not model, as other issuers write
+	proof := map[string]interface{}{
+		"@context":           doc["@context"].([]interface{})[:1],
+		"id":                 "urn:uuid:26329423-bec9-4b2e-88cb-a7c7d9dc4544",
+		"type":               DataIntegrityProofType,
+		"cryptosuite":        SuiteEddsaRdfc2022,
+		"created":            "2023-02-24T23:36:38Z",
+		"expires":            "2123-02-24T23:36:38Z",
+		"nonce":              "1234567890",
+		"previousProof":      "urn:uuid:60102d04-b51e-11ed-acfe-2fcd717666a7",
+		"verificationMethod": vectorDID + "#" + vectorDID[len("did:key:"):],
+		"proofPurpose":       identity.PurposeAssertionMethod,
+	}
+	sign := func(proof map[string]interface{}) json.RawMessage {
+		t.Helper()
+		signed := map[string]interface{}{}
+		for k, v := range doc {
+			signed[k] = v
+		}
+		signed["@context"] = proof["@context"]
+		data, err := dataIntegrityInput(signed, proof, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, _ := signer.NewMemory(vectorKey(t)).Sign(context.Background(), data)
+		out := map[string]interface{}{"proofValue": "z" + base58.Encode(sig)}
+		for k, v := range proof {
+			out[k] = v
+		}
+		raw, _ := json.Marshal(out)
+		return raw
+	}
+	raw := sign(proof)
+	if err := verifyDataIntegrity(doc, raw, vectorDID, identity.PurposeAssertionMethod, ResolveDocument); err != nil {
+		t.Fatalf("proof with extra properties did not verify: %v", err)
+	}
+
+	// each of them is signed
+	for _, prop := range []string{"id", "expires", "nonce", "previousProof"} {
+		var m map[string]interface{}
+		json.Unmarshal(raw, &m)
+		delete(m, prop)
+		changed, _ := json.Marshal(m)
+		if err := verifyDataIntegrity(doc, changed, vectorDID, identity.PurposeAssertionMethod, ResolveDocument); err == nil {
+			t.Errorf("proof without %s still verified", prop)
+		}
+	}
+	proof["expires"] = "2023-02-25T23:36:38Z"
+	if err := verifyDataIntegrity(doc, sign(proof), vectorDID, identity.PurposeAssertionMethod, ResolveDocument); err == nil {
+		t.Error("expired proof verified")
+	}
+	proof["expires"] = "2123-02-24T23:36:38Z"
+	proof["@context"] = []interface{}{"https://www.w3.org/ns/credentials/examples/v2"}
+	if err := verifyDataIntegrity(doc, sign(proof), vectorDID, identity.PurposeAssertionMethod, ResolveDocument); err == nil {
+		t.Error("proof @context that does not start the document's verified")
+	}
+}
+
+func TestSignCredentialSuite(t *testing.T) {
+	ctx := context.Background()
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+	for _, suite := range []string{SuiteEddsaRdfc2022, SuiteEddsaJcs2022, SuiteEd25519Signature2020} {
+		cred := NewCredential("urn:uuid:6a7c1f0e-2b1d-4c55-9a4f-0d6e3f7b9c21", did, map[string]interface{}{
+			"id":     "did:example:holder",
+			"degree": map[string]interface{}{"type": "BachelorDegree", "name": "Computer Science"},
+		})
+		if err := cred.SignCredentialSuite(ctx, signer.NewMemory(priv), did+"#keys-1", suite); err != nil {
+			t.Fatalf("%s: %v", suite, err)
+		}
+		var proof DataIntegrityProof
+		if err := json.Unmarshal(cred.Proofs[len(cred.Proofs)-1], &proof); err != nil {
+			t.Fatal(err)
+		}
+		if proof.ProofValue == "" || proof.ProofValue[0] != 'z' {
+			t.Errorf("%s: proofValue %q is not multibase", suite, proof.ProofValue)
+		}
+
+		// the proof survives a JSON round trip
+		data, _ := json.Marshal(cred)
+		var decoded Credential
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifyCredential(&decoded); err != nil {
+			t.Errorf("%s: credential failed: %v", suite, err)
+		}
+
+		decoded.CredentialSubject["degree"].(map[string]interface{})["name"] = "Law"
+		if err := VerifyCredential(&decoded); err == nil {
+			t.Errorf("%s: expected failure for a changed claim", suite)
+		}
+	}
+}
+
+func TestSignCredentialSuiteErrors(t *testing.T) {
+	ctx := context.Background()
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+
+	// relative ids cannot be canonicalized
+	cred := NewCredential("vc1", did, map[string]interface{}{"id": did})
+	err := cred.SignCredentialSuite(ctx, signer.NewMemory(priv), did+"#keys-1", SuiteEddsaRdfc2022)
+	if !errors.Is(err, jsonld.ErrUndefinedTerm) {
+		t.Errorf("relative id: got %v, want ErrUndefinedTerm", err)
+	}
+
+	cred = NewCredential("urn:uuid:1", did, map[string]interface{}{"id": did})
+	if err := cred.SignCredentialSuite(ctx, signer.NewMemory(priv), did+"#keys-1", "bogus-2024"); err == nil {
+		t.Error("expected error for an unknown suite")
+	}
+
+	k1, _ := identity.GenerateKey(identity.KeyTypeSecp256k1)
+	k1DID := identity.GenerateDIDForKey(k1.Public())
+	cred = NewCredential("urn:uuid:2", k1DID, map[string]interface{}{"id": k1DID})
+	if err := cred.SignCredentialSuite(ctx, signer.NewMemory(k1), k1DID+"#keys-1", SuiteEddsaRdfc2022); err == nil {
+		t.Error("expected error for a secp256k1 key")
+	}
+	// the key's own suite is the legacy proof
+	if err := cred.SignCredentialSuite(ctx, signer.NewMemory(k1), k1DID+"#keys-1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyCredential(cred); err != nil {
+		t.Errorf("legacy proof failed: %v", err)
+	}
+}
+
+func TestSignCredentialSuiteJCS(t *testing.T) {
+	ctx := context.Background()
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+
+	// JCS needs no JSON-LD, so short ids and undefined claims are fine
+	cred := NewCredential("vc1", did, map[string]interface{}{"id": did, "age": 30})
+	if err := cred.SignCredentialSuite(ctx, signer.NewMemory(priv), did+"#keys-1", SuiteEddsaJcs2022); err != nil {
+		t.Fatal(err)
+	}
+	if len(cred.Context) != 2 || cred.Context[1] != dataIntegrityContext {
+		t.Errorf("unexpected @context %v", cred.Context)
+	}
+	if err := VerifyCredential(cred); err != nil {
+		t.Errorf("credential failed: %v", err)
+	}
+
+	pres := NewPresentation([]Credential{*cred}, did)
+	if err := pres.SignPresentationSuite(ctx, signer.NewMemory(priv), did+"#keys-1", SuiteEddsaJcs2022); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyPresentation(pres); err != nil {
+		t.Errorf("presentation failed: %v", err)
+	}
+	pres.Type = append(pres.Type, "ExtraPresentation")
+	if err := VerifyPresentation(pres); err == nil {
+		t.Error("expected failure for a changed type")
+	}
+}
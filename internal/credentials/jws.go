@@ -0,0 +1,114 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+)
+
+// jwsHeader is the protected header of the detached JWS in SignatureProof: the payload,
+// the signed document, is neither base64url-encoded nor included in the JWS (RFC 7797).
+type jwsHeader struct {
+	Alg  string   `json:"alg"`
+	B64  bool     `json:"b64"`
+	Crit []string `json:"crit"`
+}
+
+// newSignatureProof signs data with s and returns the proof of the key's legacy suite for
+// verificationMethod and purpose.
+func newSignatureProof(ctx context.Context, s signer.Signer, verificationMethod, purpose string, data []byte) (*SignatureProof, error) {
+	jws, err := signDetachedJWS(ctx, s, data)
+	if err != nil {
+		return nil, err
+	}
+	return &SignatureProof{
+		Type:               s.PublicKey().Type.SignatureSuite(),
+		Created:            time.Now().UTC().Format(time.RFC3339),
+		ProofPurpose:       purpose,
+		VerificationMethod: verificationMethod,
+		JWS:                jws,
+	}, nil
+}
+
+// signDetachedJWS returns the compact JWS of payload by s with the payload left out:
+// "<protected header>..<signature>".
+func signDetachedJWS(ctx context.Context, s signer.Signer, payload []byte) (string, error) {
+	header, err := json.Marshal(jwsHeader{Alg: s.Algorithm(), B64: false, Crit: []string{"b64"}})
+	if err != nil {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(header)
+	sig, err := s.Sign(ctx, jwsSigningInput(protected, payload))
+	if err != nil {
+		return "", err
+	}
+	return protected + ".." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// verifyDetachedJWS checks that jws, as written by signDetachedJWS, signs payload with pub.
+func verifyDetachedJWS(jws string, payload []byte, pub *identity.PublicKey) error {
+	protected, sig, ok := strings.Cut(jws, "..")
+	if !ok || strings.Contains(sig, ".") {
+		return fmt.Errorf("jws is not a detached compact JWS")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(protected)
+	if err != nil {
+		return fmt.Errorf("decode JWS header: %w", err)
+	}
+	var h jwsHeader
+	if err := json.Unmarshal(data, &h); err != nil {
+		return fmt.Errorf("invalid JWS header: %w", err)
+	}
+	if h.Alg != pub.Type.JWSAlgorithm() {
+		return fmt.Errorf("JWS alg %q does not match %s key", h.Alg, pub.Type)
+	}
+	if h.B64 || len(h.Crit) != 1 || h.Crit[0] != "b64" {
+		return fmt.Errorf("JWS header must have b64 false and crit [\"b64\"]")
+	}
+	sigBytes, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("decode JWS signature: %w", err)
+	}
+	if !pub.Verify(jwsSigningInput(protected, payload), sigBytes) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+func jwsSigningInput(protected string, payload []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(protected)
+	b.WriteByte('.')
+	b.Write(payload)
+	return b.Bytes()
+}
+
+// Legacy reports whether the proof holds a bare hex-encoded signature, as written before
+// proofs carried a detached JWS. Such proofs still verify.
+func (sp *SignatureProof) Legacy() bool {
+	if sp.JWS == "" || strings.Contains(sp.JWS, ".") {
+		return false
+	}
+	_, err := hex.DecodeString(sp.JWS)
+	return err == nil
+}
+
+// LegacyProof reports whether the signature proof of proofs, the last one, is Legacy.
+func LegacyProof(proofs []json.RawMessage) bool {
+	if len(proofs) == 0 {
+		return false
+	}
+	var sp SignatureProof
+	if err := json.Unmarshal(proofs[len(proofs)-1], &sp); err != nil {
+		return false
+	}
+	return sp.Legacy()
+}
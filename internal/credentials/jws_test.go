@@ -0,0 +1,105 @@
+package credentials
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+)
+
+func TestSignatureProofDetachedJWS(t *testing.T) {
+	ctx := context.Background()
+	for kt, alg := range map[identity.KeyType]string{
+		identity.KeyTypeEd25519:   "EdDSA",
+		identity.KeyTypeSecp256k1: "ES256K",
+		identity.KeyTypeP256:      "ES256",
+	} {
+		priv, _ := identity.GenerateKey(kt)
+		did := identity.GenerateDIDForKey(priv.Public())
+		cred := NewCredential("vc1", did, map[string]interface{}{"id": did})
+		if err := cred.SignCredential(ctx, signer.NewMemory(priv), did+"#keys-1"); err != nil {
+			t.Fatal(err)
+		}
+		var sp SignatureProof
+		json.Unmarshal(cred.Proofs[0], &sp)
+		protected, sig, ok := strings.Cut(sp.JWS, "..")
+		if !ok || sig == "" {
+			t.Fatalf("%s: jws %q is not detached", kt, sp.JWS)
+		}
+		header, _ := base64.RawURLEncoding.DecodeString(protected)
+		if want := fmt.Sprintf(`{"alg":%q,"b64":false,"crit":["b64"]}`, alg); string(header) != want {
+			t.Errorf("%s: header %s, want %s", kt, header, want)
+		}
+		if sp.Legacy() || LegacyProof(cred.Proofs) {
+			t.Errorf("%s: new proof reported as legacy", kt)
+		}
+		if err := VerifyCredential(cred); err != nil {
+			t.Errorf("%s: credential failed: %v", kt, err)
+		}
+	}
+}
+
+func TestVerifyLegacyHexProof(t *testing.T) {
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+	cred := NewCredential("vc1", did, map[string]interface{}{"id": did})
+
+	// a proof as written before detached JWS
+	unsigned := *cred
+	unsigned.Proofs = nil
+	data, _ := json.Marshal(&unsigned)
+	sig, _ := priv.Sign(data)
+	proof, _ := json.Marshal(SignatureProof{
+		Type:               identity.KeyTypeEd25519.SignatureSuite(),
+		Created:            time.Now().UTC().Format(time.RFC3339),
+		ProofPurpose:       identity.PurposeAssertionMethod,
+		VerificationMethod: did + "#keys-1",
+		JWS:                fmt.Sprintf("%x", sig),
+	})
+	cred.Proofs = append(cred.Proofs, proof)
+	if !LegacyProof(cred.Proofs) {
+		t.Error("hex proof not reported as legacy")
+	}
+	if err := VerifyCredential(cred); err != nil {
+		t.Errorf("legacy credential failed: %v", err)
+	}
+	cred.CredentialSubject["id"] = "did:example:other"
+	if err := VerifyCredential(cred); err == nil {
+		t.Error("expected failure for a changed legacy credential")
+	}
+}
+
+func TestVerifyDetachedJWSErrors(t *testing.T) {
+	ctx := context.Background()
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	payload := []byte(`{"id":"vc1"}`)
+	jws, err := signDetachedJWS(ctx, signer.NewMemory(priv), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyDetachedJWS(jws, payload, priv.Public()); err != nil {
+		t.Fatalf("valid JWS failed: %v", err)
+	}
+	_, sig, _ := strings.Cut(jws, "..")
+	enc := base64.RawURLEncoding.EncodeToString
+	for name, bad := range map[string]string{
+		"changed payload": jws,
+		"encoded payload": enc([]byte(`{"alg":"EdDSA"}`)) + ".." + sig,
+		"wrong alg":       enc([]byte(`{"alg":"ES256K","b64":false,"crit":["b64"]}`)) + ".." + sig,
+		"attached":        strings.Replace(jws, "..", "."+enc(payload)+".", 1),
+	} {
+		p := payload
+		if name == "changed payload" {
+			p = []byte(`{"id":"vc2"}`)
+		}
+		if err := verifyDetachedJWS(bad, p, priv.Public()); err == nil {
+			t.Errorf("%s: expected failure", name)
+		}
+	}
+}
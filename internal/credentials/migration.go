@@ -0,0 +1,88 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+)
+
+// MigrationStatementType is the type of statements written by 'ego migrate'.
+const MigrationStatementType = "DIDMigrationStatement"
+
+// MigrationStatement records that the holder of From moved to To. It carries a proof from
+// each DID, so a verifier who trusts one identifier can connect it to the other.
+type MigrationStatement struct {
+	Type       string            `json:"type"`
+	From       string            `json:"from"`
+	To         string            `json:"to"`
+	Created    time.Time         `json:"created"`
+	KeyRotated bool              `json:"keyRotated"`
+	Proofs     []json.RawMessage `json:"proof"`
+}
+
+// NewMigrationStatement builds an unsigned statement that from moved to to.
+func NewMigrationStatement(from, to string, keyRotated bool) *MigrationStatement {
+	return &MigrationStatement{
+		Type:       MigrationStatementType,
+		From:       from,
+		To:         to,
+		Created:    time.Now().UTC().Truncate(time.Second),
+		KeyRotated: keyRotated,
+		Proofs:     []json.RawMessage{},
+	}
+}
+
+// Sign appends a proof by s, the key of verificationMethod. The statement must be signed
+// first by the From DID and then by the To DID.
+func (m *MigrationStatement) Sign(ctx context.Context, s signer.Signer, verificationMethod string) error {
+	data, err := m.signingInput()
+	if err != nil {
+		return err
+	}
+	sp, err := newSignatureProof(ctx, s, verificationMethod, identity.PurposeAuthentication, data)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(sp)
+	if err != nil {
+		return fmt.Errorf("marshaling signature proof: %w", err)
+	}
+	m.Proofs = append(m.Proofs, b)
+	return nil
+}
+
+// VerifyMigrationStatement checks that m is signed by both From and To, resolving their
+// DID Documents with resolve.
+func VerifyMigrationStatement(m *MigrationStatement, resolve DocumentResolver) error {
+	if m.Type != MigrationStatementType {
+		return fmt.Errorf("not a migration statement: type %q", m.Type)
+	}
+	if len(m.Proofs) != 2 {
+		return fmt.Errorf("migration statement needs proofs from both DIDs, has %d", len(m.Proofs))
+	}
+	data, err := m.signingInput()
+	if err != nil {
+		return err
+	}
+	for i, controller := range []string{m.From, m.To} {
+		var sp SignatureProof
+		if err := json.Unmarshal(m.Proofs[i], &sp); err != nil {
+			return fmt.Errorf("unmarshal signature proof: %w", err)
+		}
+		if err := verifyProof(&sp, data, controller, identity.PurposeAuthentication, resolve); err != nil {
+			return fmt.Errorf("invalid signature of %s: %w", controller, err)
+		}
+	}
+	return nil
+}
+
+// signingInput serializes the statement without its proofs.
+func (m *MigrationStatement) signingInput() ([]byte, error) {
+	tmp := *m
+	tmp.Proofs = nil
+	return json.Marshal(tmp)
+}
@@ -0,0 +1,61 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+)
+
+func TestMigrationStatement(t *testing.T) {
+	ctx := context.Background()
+	oldKey, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	newKey, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	from := identity.GenerateDIDForKey(oldKey.Public())
+	doc := identity.NewDocument("did:web:example.com", newKey.Public())
+	resolve := func(did string) (*identity.Document, error) {
+		if did == doc.ID {
+			return doc, nil
+		}
+		return ResolveDocument(did)
+	}
+
+	st := NewMigrationStatement(from, doc.ID, true)
+	if err := st.Sign(ctx, signer.NewMemory(oldKey), from+"#keys-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyMigrationStatement(st, resolve); err == nil {
+		t.Error("a statement signed by one DID only must fail")
+	}
+	if err := st.Sign(ctx, signer.NewMemory(newKey), doc.ID+"#keys-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyMigrationStatement(st, resolve); err != nil {
+		t.Fatalf("VerifyMigrationStatement: %v", err)
+	}
+
+	tampered := *st
+	tampered.To = "did:web:attacker.example"
+	if err := VerifyMigrationStatement(&tampered, resolve); err == nil {
+		t.Error("a statement with a changed target must fail")
+	}
+
+	// the new DID cannot vouch for the old one on its own
+	forged := NewMigrationStatement(from, doc.ID, true)
+	forged.Sign(ctx, signer.NewMemory(newKey), from+"#keys-1")
+	forged.Sign(ctx, signer.NewMemory(newKey), doc.ID+"#keys-1")
+	if err := VerifyMigrationStatement(forged, resolve); err == nil {
+		t.Error("a statement not signed by the old key must fail")
+	}
+
+	data, _ := json.Marshal(st)
+	var parsed MigrationStatement
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyMigrationStatement(&parsed, resolve); err != nil {
+		t.Errorf("statement does not survive a JSON round trip: %v", err)
+	}
+}
@@ -1,23 +1,25 @@
 package credentials
 
 import (
-	"crypto/ed25519"
+	"context"
 	"encoding/json"
 	"fmt"
-	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
 )
 
 type Presentation struct {
-	Context              []string          `json:"@context"`
-	Type                 []string          `json:"type"`
-	VerifiableCredential []Credential      `json:"verifiableCredential"`
-	Holder               string            `json:"holder,omitempty"`
-	Proofs               []json.RawMessage `json:"proof"`
+	Context              []interface{} `json:"@context"`
+	Type                 []string      `json:"type"`
+	VerifiableCredential []Credential  `json:"verifiableCredential"`
+	Holder               string        `json:"holder,omitempty"`
+	Proofs               ProofSet      `json:"proof"`
 }
 
 func NewPresentation(creds []Credential, holder string) *Presentation {
 	return &Presentation{
-		Context:              []string{"https://www.w3.org/2018/credentials/v1"},
+		Context:              []interface{}{credentialsV1Context},
 		Type:                 []string{"VerifiablePresentation"},
 		VerifiableCredential: creds,
 		Holder:               holder,
@@ -25,7 +27,7 @@ func NewPresentation(creds []Credential, holder string) *Presentation {
 	}
 }
 
-func (p *Presentation) SignPresentation(priv ed25519.PrivateKey, verificationMethod string) error {
+func (p *Presentation) SignPresentation(ctx context.Context, s signer.Signer, verificationMethod string) error {
 	// Serialize presentation without existing signature proofs
 	tmp := *p
 	tmp.Proofs = nil
@@ -33,13 +35,9 @@ func (p *Presentation) SignPresentation(priv ed25519.PrivateKey, verificationMet
 	if err != nil {
 		return err
 	}
-	sig := ed25519.Sign(priv, data)
-	sp := SignatureProof{
-		Type:               "Ed25519Signature2018",
-		Created:            time.Now().UTC().Format(time.RFC3339),
-		ProofPurpose:       "authentication",
-		VerificationMethod: verificationMethod,
-		JWS:                fmt.Sprintf("%x", sig),
+	sp, err := newSignatureProof(ctx, s, verificationMethod, identity.PurposeAuthentication, data)
+	if err != nil {
+		return err
 	}
 	proofBytes, err := json.Marshal(sp)
 	if err != nil {
@@ -1,9 +1,12 @@
 package credentials
 
 import (
-	"crypto/ed25519"
+	"context"
 	"encoding/json"
 	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer/signertest"
 )
 
 func TestPresentationSign(t *testing.T) {
@@ -12,12 +15,12 @@ func TestPresentationSign(t *testing.T) {
 	subj := map[string]interface{}{"id": holder}
 	cred := *NewCredential("cred1", "did:example:issuer", subj)
 	// Sign the credential so VC contains a signature proof
-	_, privIssuer, _ := ed25519.GenerateKey(nil)
-	cred.SignCredential(privIssuer, "did:example:issuer#key-1")
+	issuer, _ := signertest.New(identity.KeyTypeEd25519)
+	cred.SignCredential(context.Background(), issuer, "did:example:issuer#key-1")
 
 	pres := NewPresentation([]Credential{cred}, holder)
-	_, privHolder, _ := ed25519.GenerateKey(nil)
-	if err := pres.SignPresentation(privHolder, holder+"#key-1"); err != nil {
+	holderKey, _ := signertest.New(identity.KeyTypeEd25519)
+	if err := pres.SignPresentation(context.Background(), holderKey, holder+"#key-1"); err != nil {
 		t.Fatalf("SignPresentation failed: %v", err)
 	}
 	if len(pres.Proofs) != 1 {
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
+
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
 )
 
 type RevocationList struct {
@@ -14,26 +17,26 @@ type RevocationList struct {
 }
 
 func NewRevocationList(path string) (*RevocationList, error) {
-	rl := &RevocationList{store: path, revoked: make(map[string]struct{})}
-	data, err := os.ReadFile(path)
-	if err != nil && !os.IsNotExist(err) {
+	rl := &RevocationList{store: path}
+	if err := rl.load(); err != nil {
 		return nil, err
 	}
-	if len(data) > 0 {
-		var list []string
-		if err := json.Unmarshal(data, &list); err != nil {
-			return nil, err
-		}
-		for _, id := range list {
-			rl.revoked[id] = struct{}{}
-		}
-	}
 	return rl, nil
 }
 
+// Revoke adds id to the list. It holds the vault lock while it reloads the file and writes
+// it back, so revocations made by other processes in the meantime are not lost.
 func (rl *RevocationList) Revoke(id string) error {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
+	lock, err := fsutil.LockDir(filepath.Dir(rl.store))
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	if err := rl.load(); err != nil {
+		return err
+	}
 	if _, exists := rl.revoked[id]; exists {
 		return fmt.Errorf("credential %s already revoked", id)
 	}
@@ -68,5 +71,25 @@ func (rl *RevocationList) save() error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(rl.store, data, 0644)
+	return fsutil.WriteFile(rl.store, data, 0644)
+}
+
+// load replaces the in-memory list with the contents of the file.
+func (rl *RevocationList) load() error {
+	revoked := make(map[string]struct{})
+	data, err := os.ReadFile(rl.store)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		var list []string
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		for _, id := range list {
+			revoked[id] = struct{}{}
+		}
+	}
+	rl.revoked = revoked
+	return nil
 }
@@ -1,10 +1,32 @@
 package credentials
 
 import (
+	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 )
 
+// helperRevokeEnv makes the test binary revoke ids in a revocation list, as another process would.
+const helperRevokeEnv = "CREDENTIALS_TEST_HELPER_REVOKE"
+
+func TestMain(m *testing.M) {
+	if path := os.Getenv(helperRevokeEnv); path != "" {
+		rl, err := NewRevocationList(path)
+		if err != nil {
+			os.Exit(2)
+		}
+		for i := 0; i < 5; i++ {
+			if err := rl.Revoke(fmt.Sprintf("%s-%d", os.Getenv(helperRevokeEnv+"_ID"), i)); err != nil {
+				os.Exit(1)
+			}
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
 func TestRevocationList(t *testing.T) {
 	tmp, err := os.CreateTemp("", "revoke*.json")
 	if err != nil {
@@ -36,3 +58,29 @@ func TestRevocationList(t *testing.T) {
 		t.Error("expected error on double revoke")
 	}
 }
+
+func TestRevocationListConcurrentProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revocations.json")
+	const procs = 4
+	var cmds []*exec.Cmd
+	for i := 0; i < procs; i++ {
+		c := exec.Command(os.Args[0], "-test.run=^$")
+		c.Env = append(os.Environ(), helperRevokeEnv+"="+path, fmt.Sprintf("%s_ID=p%d", helperRevokeEnv, i))
+		if err := c.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, c)
+	}
+	for _, c := range cmds {
+		if err := c.Wait(); err != nil {
+			t.Fatalf("helper failed: %v", err)
+		}
+	}
+	rl, err := NewRevocationList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(rl.List()); got != procs*5 {
+		t.Errorf("%d revocations; want %d", got, procs*5)
+	}
+}
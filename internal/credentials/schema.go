@@ -0,0 +1,242 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// Types of credentialSchema entries, from the W3C VC JSON Schema specification.
+const (
+	// SchemaTypeJsonSchema refers to a JSON Schema by its $id.
+	SchemaTypeJsonSchema = "JsonSchema"
+	// SchemaTypeJsonSchemaCredential refers to a credential whose subject holds the JSON
+	// Schema, so the schema itself is signed by its author.
+	SchemaTypeJsonSchemaCredential = "JsonSchemaCredential"
+)
+
+// ErrSchemaNotFound is returned by SchemaRegistry for schemas it does not hold.
+var ErrSchemaNotFound = errors.New("schema not found in registry")
+
+// CredentialSchema is an entry of a credential's credentialSchema.
+type CredentialSchema struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// CredentialSchemas is the credentialSchema of a credential. A single schema is written
+// as an object, several as an array; both forms are read.
+type CredentialSchemas []CredentialSchema
+
+func (s CredentialSchemas) MarshalJSON() ([]byte, error) { return marshalOneOrMany(s) }
+
+func (s *CredentialSchemas) UnmarshalJSON(data []byte) error {
+	return unmarshalOneOrMany(data, (*[]CredentialSchema)(s))
+}
+
+// marshalOneOrMany writes a single element as itself and any other number as an array.
+func marshalOneOrMany[T any](s []T) ([]byte, error) {
+	if len(s) == 1 {
+		return json.Marshal(s[0])
+	}
+	return json.Marshal(s)
+}
+
+// unmarshalOneOrMany reads an object as a one-element slice, or an array.
+func unmarshalOneOrMany[T any](data []byte, s *[]T) error {
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '{' {
+		var one T
+		if err := json.Unmarshal(data, &one); err != nil {
+			return err
+		}
+		*s = []T{one}
+		return nil
+	}
+	return json.Unmarshal(data, s)
+}
+
+// SchemaRegistry holds the schemas that credentials refer to as JSON files in Dir: JSON
+// Schemas, found by their $id, and JsonSchemaCredentials, found by their id. Schemas are
+// never fetched from the network.
+type SchemaRegistry struct {
+	Dir string
+}
+
+// Add stores the JSON Schema or JsonSchemaCredential data in the registry and returns its
+// id and credentialSchema type. JSON Schemas must compile.
+func (r *SchemaRegistry) Add(data []byte) (CredentialSchema, error) {
+	ref, err := schemaRef(data)
+	if err != nil {
+		return CredentialSchema{}, err
+	}
+	if ref.Type == SchemaTypeJsonSchema {
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+		if err != nil {
+			return CredentialSchema{}, err
+		}
+		c := r.compiler()
+		if err := c.AddResource(ref.ID, doc); err != nil {
+			return CredentialSchema{}, fmt.Errorf("invalid schema %s: %w", ref.ID, err)
+		}
+		if _, err := c.Compile(ref.ID); err != nil {
+			return CredentialSchema{}, fmt.Errorf("invalid schema %s: %w", ref.ID, err)
+		}
+	}
+	if err := os.MkdirAll(r.Dir, 0o755); err != nil {
+		return CredentialSchema{}, err
+	}
+	sum := sha256.Sum256([]byte(ref.ID))
+	path := filepath.Join(r.Dir, hex.EncodeToString(sum[:8])+".json")
+	if err := fsutil.WriteFile(path, data, 0o644); err != nil {
+		return CredentialSchema{}, err
+	}
+	return ref, nil
+}
+
+// Lookup returns the raw JSON of the schema or schema credential id.
+func (r *SchemaRegistry) Lookup(id string) ([]byte, CredentialSchema, error) {
+	files, err := filepath.Glob(filepath.Join(r.Dir, "*.json"))
+	if err != nil {
+		return nil, CredentialSchema{}, err
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, CredentialSchema{}, err
+		}
+		if ref, err := schemaRef(data); err == nil && ref.ID == id {
+			return data, ref, nil
+		}
+	}
+	return nil, CredentialSchema{}, fmt.Errorf("%w: %s", ErrSchemaNotFound, id)
+}
+
+// Load returns the JSON Schema id for the JSON Schema compiler, which loads schemas
+// referenced with $ref through it.
+func (r *SchemaRegistry) Load(id string) (any, error) {
+	data, ref, err := r.Lookup(strings.TrimSuffix(id, "#"))
+	if err != nil {
+		return nil, err
+	}
+	if ref.Type != SchemaTypeJsonSchema {
+		return nil, fmt.Errorf("%s is a %s, not a JSON Schema", id, ref.Type)
+	}
+	return jsonschema.UnmarshalJSON(bytes.NewReader(data))
+}
+
+func (r *SchemaRegistry) compiler() *jsonschema.Compiler {
+	c := jsonschema.NewCompiler()
+	c.UseLoader(r)
+	return c
+}
+
+// ValidateCredentialSchema validates cred against each entry of its credentialSchema,
+// resolved from schemas. As the VC JSON Schema specification defines, the schema applies
+// to the whole credential, and so to the subject through its credentialSubject property.
+// The proof of a JsonSchemaCredential is verified with resolve.
+func ValidateCredentialSchema(cred *Credential, schemas *SchemaRegistry, resolve DocumentResolver) error {
+	if len(cred.CredentialSchema) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for _, cs := range cred.CredentialSchema {
+		sch, err := schemas.schemaFor(cs, resolve)
+		if err != nil {
+			return err
+		}
+		if err := sch.Validate(instance); err != nil {
+			return fmt.Errorf("credential does not match schema %s: %w", cs.ID, err)
+		}
+	}
+	return nil
+}
+
+// schemaFor compiles the JSON Schema that cs refers to.
+func (r *SchemaRegistry) schemaFor(cs CredentialSchema, resolve DocumentResolver) (*jsonschema.Schema, error) {
+	data, ref, err := r.Lookup(cs.ID)
+	if err != nil {
+		return nil, err
+	}
+	if ref.Type != cs.Type {
+		return nil, fmt.Errorf("schema %s is a %s, not a %s", cs.ID, ref.Type, cs.Type)
+	}
+	switch cs.Type {
+	case SchemaTypeJsonSchema:
+		sch, err := r.compiler().Compile(cs.ID)
+		if err != nil {
+			return nil, fmt.Errorf("compile schema %s: %w", cs.ID, err)
+		}
+		return sch, nil
+	case SchemaTypeJsonSchemaCredential:
+		var sc Credential
+		if err := json.Unmarshal(data, &sc); err != nil {
+			return nil, fmt.Errorf("invalid schema credential %s: %w", cs.ID, err)
+		}
+		if err := VerifyCredentialWith(&sc, resolve); err != nil {
+			return nil, fmt.Errorf("schema credential %s: %w", cs.ID, err)
+		}
+		schema, err := json.Marshal(sc.CredentialSubject["jsonSchema"])
+		if err != nil {
+			return nil, err
+		}
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+		if err != nil {
+			return nil, err
+		}
+		c := r.compiler()
+		if err := c.AddResource(cs.ID, doc); err != nil {
+			return nil, err
+		}
+		sch, err := c.Compile(cs.ID)
+		if err != nil {
+			return nil, fmt.Errorf("compile schema of %s: %w", cs.ID, err)
+		}
+		return sch, nil
+	default:
+		return nil, fmt.Errorf("unsupported credentialSchema type %q", cs.Type)
+	}
+}
+
+// schemaRef returns the id and credentialSchema type of a JSON Schema or
+// JsonSchemaCredential document.
+func schemaRef(data []byte) (CredentialSchema, error) {
+	var doc struct {
+		SchemaID string          `json:"$id"`
+		ID       string          `json:"id"`
+		Type     json.RawMessage `json:"type"`
+		Subject  struct {
+			Type       string      `json:"type"`
+			JSONSchema interface{} `json:"jsonSchema"`
+		} `json:"credentialSubject"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return CredentialSchema{}, fmt.Errorf("invalid schema JSON: %w", err)
+	}
+	if doc.SchemaID != "" {
+		return CredentialSchema{ID: doc.SchemaID, Type: SchemaTypeJsonSchema}, nil
+	}
+	var types []string
+	json.Unmarshal(doc.Type, &types)
+	for _, t := range types {
+		if t == SchemaTypeJsonSchemaCredential && doc.ID != "" && doc.Subject.Type == SchemaTypeJsonSchema && doc.Subject.JSONSchema != nil {
+			return CredentialSchema{ID: doc.ID, Type: SchemaTypeJsonSchemaCredential}, nil
+		}
+	}
+	return CredentialSchema{}, fmt.Errorf("not a JSON Schema with an $id or a JsonSchemaCredential")
+}
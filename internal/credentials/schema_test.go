@@ -0,0 +1,135 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+)
+
+const emailSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://schemas.example/email.json",
+  "type": "object",
+  "properties": {
+    "credentialSubject": {
+      "type": "object",
+      "properties": {"email": {"type": "string", "pattern": "^[^@]+@[^@]+$"}},
+      "required": ["email"]
+    }
+  },
+  "required": ["credentialSubject"]
+}`
+
+func TestSchemaRegistry(t *testing.T) {
+	reg := &SchemaRegistry{Dir: t.TempDir()}
+	ref, err := reg.Add([]byte(emailSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref != (CredentialSchema{ID: "https://schemas.example/email.json", Type: SchemaTypeJsonSchema}) {
+		t.Errorf("unexpected reference %+v", ref)
+	}
+	if _, _, err := reg.Lookup(ref.ID); err != nil {
+		t.Error(err)
+	}
+	if _, _, err := reg.Lookup("https://schemas.example/other.json"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("got %v, want ErrSchemaNotFound", err)
+	}
+	for name, data := range map[string]string{
+		"no $id":         `{"type": "object"}`,
+		"invalid schema": `{"$id": "https://schemas.example/bad.json", "type": 5}`,
+		"not JSON":       `{`,
+	} {
+		if _, err := reg.Add([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestValidateCredentialSchema(t *testing.T) {
+	reg := &SchemaRegistry{Dir: t.TempDir()}
+	ref, err := reg.Add([]byte(emailSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred := NewCredential("vc1", "did:example:issuer", map[string]interface{}{"email": "alice@example.com"})
+	cred.CredentialSchema = CredentialSchemas{ref}
+	if err := ValidateCredentialSchema(cred, reg, ResolveDocument); err != nil {
+		t.Errorf("valid subject rejected: %v", err)
+	}
+	cred.CredentialSubject["email"] = "not an address"
+	if err := ValidateCredentialSchema(cred, reg, ResolveDocument); err == nil {
+		t.Error("expected failure for a malformed subject")
+	}
+	cred.CredentialSchema[0].Type = SchemaTypeJsonSchemaCredential
+	if err := ValidateCredentialSchema(cred, reg, ResolveDocument); err == nil {
+		t.Error("expected failure for the wrong schema type")
+	}
+}
+
+func TestJsonSchemaCredential(t *testing.T) {
+	ctx := context.Background()
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+	reg := &SchemaRegistry{Dir: t.TempDir()}
+
+	var schema map[string]interface{}
+	json.Unmarshal([]byte(emailSchema), &schema)
+	sc := NewCredential("https://schemas.example/email-credential.json", did, map[string]interface{}{
+		"id":         "https://schemas.example/email.json",
+		"type":       SchemaTypeJsonSchema,
+		"jsonSchema": schema,
+	})
+	sc.Type = append(sc.Type, SchemaTypeJsonSchemaCredential)
+	if err := sc.SignCredential(ctx, signer.NewMemory(priv), did+"#keys-1"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(sc)
+	ref, err := reg.Add(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Type != SchemaTypeJsonSchemaCredential || ref.ID != sc.ID {
+		t.Errorf("unexpected reference %+v", ref)
+	}
+
+	cred := NewCredential("vc1", did, map[string]interface{}{"id": did, "email": "alice@example.com"})
+	cred.CredentialSchema = CredentialSchemas{ref}
+	if err := cred.SignCredential(ctx, signer.NewMemory(priv), did+"#keys-1"); err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultVerifyOptions()
+	opts.Schemas = reg
+	if err := VerifyCredentialWithOptions(cred, opts); err != nil {
+		t.Errorf("credential failed: %v", err)
+	}
+
+	// a schema credential whose schema was changed no longer verifies
+	files, _ := filepath.Glob(filepath.Join(reg.Dir, "*.json"))
+	os.WriteFile(files[0], []byte(strings.Replace(string(data), `^[^@]+@[^@]+$`, `.*`, 1)), 0644)
+	if err := VerifyCredentialWithOptions(cred, opts); err == nil {
+		t.Error("expected failure for a tampered schema credential")
+	}
+}
+
+func TestCredentialSchemasJSON(t *testing.T) {
+	one := CredentialSchemas{{ID: "https://schemas.example/a.json", Type: SchemaTypeJsonSchema}}
+	data, _ := json.Marshal(one)
+	if string(data) != `{"id":"https://schemas.example/a.json","type":"JsonSchema"}` {
+		t.Errorf("single schema marshaled as %s", data)
+	}
+	var got CredentialSchemas
+	if err := json.Unmarshal([]byte(`[{"id":"a","type":"JsonSchema"},{"id":"b","type":"JsonSchema"}]`), &got); err != nil || len(got) != 2 {
+		t.Errorf("array: %v %v", got, err)
+	}
+	if err := json.Unmarshal(data, &got); err != nil || len(got) != 1 || got[0] != one[0] {
+		t.Errorf("object: %v %v", got, err)
+	}
+}
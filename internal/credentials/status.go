@@ -0,0 +1,521 @@
+package credentials
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
+)
+
+// Status purposes of the W3C Bitstring Status List. A revoked credential stays revoked; a
+// suspended one can be reinstated.
+const (
+	StatusPurposeRevocation = "revocation"
+	StatusPurposeSuspension = "suspension"
+)
+
+// Types of the W3C Bitstring Status List.
+const (
+	StatusListEntryType      = "BitstringStatusListEntry"
+	StatusListType           = "BitstringStatusList"
+	StatusListCredentialType = "BitstringStatusListCredential"
+)
+
+// StatusListSize is the number of entries in a status list: 16KB, the minimum the
+// specification sets so that a list does not reveal how many credentials it covers.
+const StatusListSize = 131072
+
+var (
+	// ErrRevoked is returned for credentials whose revocation bit is set.
+	ErrRevoked = errors.New("credential has been revoked")
+	// ErrSuspended is returned for credentials whose suspension bit is set.
+	ErrSuspended = errors.New("credential is suspended")
+)
+
+// StatusEntry is a credentialStatus entry pointing at a bit of a status list credential.
+type StatusEntry struct {
+	ID                   string `json:"id,omitempty"`
+	Type                 string `json:"type"`
+	StatusPurpose        string `json:"statusPurpose"`
+	StatusListIndex      string `json:"statusListIndex"`
+	StatusListCredential string `json:"statusListCredential"`
+}
+
+// StatusEntries is the credentialStatus of a credential. A single entry is written as an
+// object, several as an array; both forms are read.
+type StatusEntries []StatusEntry
+
+func (s StatusEntries) MarshalJSON() ([]byte, error) { return marshalOneOrMany(s) }
+
+func (s *StatusEntries) UnmarshalJSON(data []byte) error {
+	return unmarshalOneOrMany(data, (*[]StatusEntry)(s))
+}
+
+// Bitstring is a status list. Bit 0 is the most significant bit of the first byte.
+type Bitstring []byte
+
+// NewBitstring returns a bitstring of size bits, all clear.
+func NewBitstring(size int) Bitstring {
+	return make(Bitstring, (size+7)/8)
+}
+
+// Len returns the number of bits in b.
+func (b Bitstring) Len() int { return len(b) * 8 }
+
+// Get returns bit i.
+func (b Bitstring) Get(i int) bool {
+	return b[i/8]&(0x80>>(i%8)) != 0
+}
+
+// Set sets bit i to v.
+func (b Bitstring) Set(i int, v bool) {
+	if v {
+		b[i/8] |= 0x80 >> (i % 8)
+	} else {
+		b[i/8] &^= 0x80 >> (i % 8)
+	}
+}
+
+// Encode returns the encodedList of b: the GZIP-compressed bits in multibase base64url.
+func (b Bitstring) Encode() (string, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return "u" + base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecodeBitstring parses an encodedList as written by Encode.
+func DecodeBitstring(encoded string) (Bitstring, error) {
+	if !strings.HasPrefix(encoded, "u") {
+		return nil, fmt.Errorf("encodedList is not multibase base64url")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded[1:])
+	if err != nil {
+		return nil, fmt.Errorf("decode encodedList: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompress encodedList: %w", err)
+	}
+	// a list far larger than the minimum is suspicious; do not inflate it without bound
+	bits, err := io.ReadAll(io.LimitReader(zr, 64*StatusListSize/8+1))
+	if err != nil {
+		return nil, fmt.Errorf("decompress encodedList: %w", err)
+	}
+	if len(bits) > 64*StatusListSize/8 {
+		return nil, fmt.Errorf("encodedList is too large")
+	}
+	return Bitstring(bits), nil
+}
+
+// NewStatusListCredential returns an unsigned status list credential with id listURL,
+// issued by issuer for purpose, holding bits.
+func NewStatusListCredential(listURL, issuer, purpose string, bits Bitstring) (*Credential, error) {
+	encoded, err := bits.Encode()
+	if err != nil {
+		return nil, err
+	}
+	c := NewCredentialV2(listURL, issuer, map[string]interface{}{
+		"id":            listURL + "#list",
+		"type":          StatusListType,
+		"statusPurpose": purpose,
+		"encodedList":   encoded,
+	})
+	c.Type = append(c.Type, StatusListCredentialType)
+	return c, nil
+}
+
+// statusListBits returns the purpose and bits of a status list credential.
+func statusListBits(list *Credential) (string, Bitstring, error) {
+	subject := list.CredentialSubject
+	if subject["type"] != StatusListType {
+		return "", nil, fmt.Errorf("status list %s has subject type %v, want %s", list.ID, subject["type"], StatusListType)
+	}
+	purpose, _ := subject["statusPurpose"].(string)
+	encoded, _ := subject["encodedList"].(string)
+	bits, err := DecodeBitstring(encoded)
+	if err != nil {
+		return "", nil, fmt.Errorf("status list %s: %w", list.ID, err)
+	}
+	return purpose, bits, nil
+}
+
+// StatusFetcher returns the status list credential published at url.
+type StatusFetcher func(url string) ([]byte, error)
+
+// httpStatusFetcher fetches the status lists that VerifyCredential checks
+// credentialStatus entries against.
+var httpStatusFetcher = HTTPStatusFetcher(&http.Client{Timeout: 10 * time.Second})
+
+// HTTPStatusFetcher returns a StatusFetcher that GETs status lists with client.
+func HTTPStatusFetcher(client *http.Client) StatusFetcher {
+	return func(url string) ([]byte, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetch status list %s: %s", url, resp.Status)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	}
+}
+
+// StatusListFiles returns a StatusFetcher that serves the status list credentials in
+// files, by their id, and fails for any other URL.
+func StatusListFiles(files ...string) (StatusFetcher, error) {
+	lists := make(map[string][]byte)
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var c struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+			return nil, fmt.Errorf("%s is not a status list credential", f)
+		}
+		lists[c.ID] = data
+	}
+	return func(url string) ([]byte, error) {
+		data, ok := lists[url]
+		if !ok {
+			return nil, fmt.Errorf("no status list file for %s", url)
+		}
+		return data, nil
+	}, nil
+}
+
+// CheckStatus checks each BitstringStatusListEntry of cred against its status list,
+// fetched with fetch. The list must be a valid credential from the same issuer, verified
+// with resolve. It returns ErrRevoked or ErrSuspended if the credential's bit is set.
+func CheckStatus(cred *Credential, fetch StatusFetcher, resolve DocumentResolver) error {
+	return checkStatus(cred, VerifyOptions{Resolve: resolve, FetchStatusList: fetch, ClockSkew: DefaultClockSkew})
+}
+
+// checkStatus is CheckStatus with the fetcher, resolver and clock skew of opts.
+func checkStatus(cred *Credential, opts VerifyOptions) error {
+	for _, entry := range cred.CredentialStatus {
+		if entry.Type != StatusListEntryType {
+			return fmt.Errorf("unsupported credentialStatus type %q", entry.Type)
+		}
+		index, err := strconv.Atoi(entry.StatusListIndex)
+		if err != nil || index < 0 {
+			return fmt.Errorf("invalid statusListIndex %q", entry.StatusListIndex)
+		}
+		data, err := opts.FetchStatusList(entry.StatusListCredential)
+		if err != nil {
+			return fmt.Errorf("fetch status list: %w", err)
+		}
+		var list Credential
+		if err := json.Unmarshal(data, &list); err != nil {
+			return fmt.Errorf("invalid status list %s: %w", entry.StatusListCredential, err)
+		}
+		if list.ID != entry.StatusListCredential {
+			return fmt.Errorf("status list at %s has id %s", entry.StatusListCredential, list.ID)
+		}
+		if list.Issuer != cred.Issuer {
+			return fmt.Errorf("status list %s is issued by %s, not %s", list.ID, list.Issuer, cred.Issuer)
+		}
+		// the list's own status would be checked against itself
+		if len(list.CredentialStatus) > 0 {
+			return fmt.Errorf("status list %s has a credentialStatus", list.ID)
+		}
+		listOpts := opts
+		listOpts.Schemas = nil
+		if err := VerifyCredentialWithOptions(&list, listOpts); err != nil {
+			return fmt.Errorf("status list %s: %w", list.ID, err)
+		}
+		purpose, bits, err := statusListBits(&list)
+		if err != nil {
+			return err
+		}
+		if purpose != entry.StatusPurpose {
+			return fmt.Errorf("status list %s is for %s, not %s", list.ID, purpose, entry.StatusPurpose)
+		}
+		if index >= bits.Len() {
+			return fmt.Errorf("statusListIndex %d is outside status list %s", index, list.ID)
+		}
+		if !bits.Get(index) {
+			continue
+		}
+		switch entry.StatusPurpose {
+		case StatusPurposeRevocation:
+			return ErrRevoked
+		case StatusPurposeSuspension:
+			return ErrSuspended
+		default:
+			return fmt.Errorf("credential has status %s", entry.StatusPurpose)
+		}
+	}
+	return nil
+}
+
+// StatusLists keeps an issuer's status lists in Dir: the signed status list credential of
+// each purpose in <purpose>.json, to be published at its id, and in status.json the URL
+// of the lists and the index given to each credential. Indexes are picked at random, so
+// they reveal nothing about when or in which order credentials were issued.
+type StatusLists struct {
+	Dir string
+}
+
+// statusState is the content of status.json.
+type statusState struct {
+	URL     string         `json:"url"`
+	Indexes map[string]int `json:"indexes"`
+}
+
+// ListURL returns the id of the status list for purpose under baseURL.
+func ListURL(baseURL, purpose string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/" + purpose
+}
+
+// Assign gives the credential credID an index in the revocation and suspension lists
+// published under baseURL, creating them signed with sign if needed, and returns the
+// credentialStatus entries to embed in the credential before it is signed.
+func (sl *StatusLists) Assign(credID, issuer, baseURL string, sign func(*Credential) error) (StatusEntries, error) {
+	if err := os.MkdirAll(sl.Dir, 0o700); err != nil {
+		return nil, err
+	}
+	lock, err := fsutil.LockDir(sl.Dir)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	st, err := sl.load()
+	if err != nil {
+		return nil, err
+	}
+	if st.URL == "" {
+		st.URL = baseURL
+		for _, purpose := range []string{StatusPurposeRevocation, StatusPurposeSuspension} {
+			list, err := NewStatusListCredential(ListURL(baseURL, purpose), issuer, purpose, NewBitstring(StatusListSize))
+			if err != nil {
+				return nil, err
+			}
+			if err := sl.saveList(list, purpose, sign); err != nil {
+				return nil, err
+			}
+		}
+	} else if st.URL != baseURL {
+		return nil, fmt.Errorf("status lists are published under %s, not %s", st.URL, baseURL)
+	}
+	if _, ok := st.Indexes[credID]; ok {
+		return nil, fmt.Errorf("credential %s already has a status index", credID)
+	}
+	if len(st.Indexes) >= StatusListSize {
+		return nil, fmt.Errorf("status lists are full")
+	}
+	used := make(map[int]bool, len(st.Indexes))
+	for _, i := range st.Indexes {
+		used[i] = true
+	}
+	var index int
+	for {
+		n, err := rand.Int(rand.Reader, big.NewInt(StatusListSize))
+		if err != nil {
+			return nil, err
+		}
+		if index = int(n.Int64()); !used[index] {
+			break
+		}
+	}
+	st.Indexes[credID] = index
+	if err := sl.save(st); err != nil {
+		return nil, err
+	}
+	var entries StatusEntries
+	for _, purpose := range []string{StatusPurposeRevocation, StatusPurposeSuspension} {
+		listURL := ListURL(st.URL, purpose)
+		entries = append(entries, StatusEntry{
+			ID:                   fmt.Sprintf("%s#%d", listURL, index),
+			Type:                 StatusListEntryType,
+			StatusPurpose:        purpose,
+			StatusListIndex:      strconv.Itoa(index),
+			StatusListCredential: listURL,
+		})
+	}
+	return entries, nil
+}
+
+// Release takes back the status index of credID, for a credential that was given one by
+// Assign but never issued. Its bits must still be clear.
+func (sl *StatusLists) Release(credID string) error {
+	lock, err := fsutil.LockDir(sl.Dir)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	st, err := sl.load()
+	if err != nil {
+		return err
+	}
+	index, ok := st.Indexes[credID]
+	if !ok {
+		return fmt.Errorf("credential %s has no status index", credID)
+	}
+	for _, purpose := range []string{StatusPurposeRevocation, StatusPurposeSuspension} {
+		list, err := sl.List(purpose)
+		if err != nil {
+			return err
+		}
+		_, bits, err := statusListBits(list)
+		if err != nil {
+			return err
+		}
+		if bits.Get(index) {
+			return fmt.Errorf("credential %s has a %s status, its index cannot be released", credID, purpose)
+		}
+	}
+	delete(st.Indexes, credID)
+	return sl.save(st)
+}
+
+// Has reports whether credID was given a status index.
+func (sl *StatusLists) Has(credID string) (bool, error) {
+	st, err := sl.load()
+	if err != nil {
+		return false, err
+	}
+	_, ok := st.Indexes[credID]
+	return ok, nil
+}
+
+// Get returns the bit of credID in the list for purpose.
+func (sl *StatusLists) Get(credID, purpose string) (bool, error) {
+	st, err := sl.load()
+	if err != nil {
+		return false, err
+	}
+	index, ok := st.Indexes[credID]
+	if !ok {
+		return false, fmt.Errorf("credential %s has no status index", credID)
+	}
+	list, err := sl.List(purpose)
+	if err != nil {
+		return false, err
+	}
+	_, bits, err := statusListBits(list)
+	if err != nil {
+		return false, err
+	}
+	return bits.Get(index), nil
+}
+
+// Set sets the bit of credID in the list for purpose to v and signs the list again with
+// sign. Revocation cannot be undone.
+func (sl *StatusLists) Set(credID, purpose string, v bool, sign func(*Credential) error) (*Credential, error) {
+	lock, err := fsutil.LockDir(sl.Dir)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	st, err := sl.load()
+	if err != nil {
+		return nil, err
+	}
+	index, ok := st.Indexes[credID]
+	if !ok {
+		return nil, fmt.Errorf("credential %s has no status index", credID)
+	}
+	list, err := sl.List(purpose)
+	if err != nil {
+		return nil, err
+	}
+	_, bits, err := statusListBits(list)
+	if err != nil {
+		return nil, err
+	}
+	if purpose == StatusPurposeRevocation && !v {
+		return nil, fmt.Errorf("revocation of %s cannot be undone", credID)
+	}
+	if bits.Get(index) == v {
+		if purpose == StatusPurposeRevocation {
+			return nil, fmt.Errorf("credential %s already revoked", credID)
+		}
+		return nil, fmt.Errorf("credential %s is already %s", credID, map[bool]string{true: "suspended", false: "not suspended"}[v])
+	}
+	bits.Set(index, v)
+	next, err := NewStatusListCredential(list.ID, list.Issuer, purpose, bits)
+	if err != nil {
+		return nil, err
+	}
+	if err := sl.saveList(next, purpose, sign); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// List returns the signed status list credential for purpose.
+func (sl *StatusLists) List(purpose string) (*Credential, error) {
+	data, err := os.ReadFile(sl.listPath(purpose))
+	if err != nil {
+		return nil, err
+	}
+	var c Credential
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid status list %s: %w", purpose, err)
+	}
+	return &c, nil
+}
+
+func (sl *StatusLists) listPath(purpose string) string {
+	return filepath.Join(sl.Dir, purpose+".json")
+}
+
+func (sl *StatusLists) saveList(list *Credential, purpose string, sign func(*Credential) error) error {
+	if err := sign(list); err != nil {
+		return fmt.Errorf("sign status list: %w", err)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFile(sl.listPath(purpose), data, 0o644)
+}
+
+func (sl *StatusLists) load() (*statusState, error) {
+	st := &statusState{Indexes: make(map[string]int)}
+	data, err := os.ReadFile(filepath.Join(sl.Dir, "status.json"))
+	if os.IsNotExist(err) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("invalid status.json: %w", err)
+	}
+	if st.Indexes == nil {
+		st.Indexes = make(map[string]int)
+	}
+	return st, nil
+}
+
+func (sl *StatusLists) save(st *statusState) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFile(filepath.Join(sl.Dir, "status.json"), data, 0o600)
+}
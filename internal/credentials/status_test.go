@@ -0,0 +1,267 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+)
+
+func TestBitstring(t *testing.T) {
+	b := NewBitstring(StatusListSize)
+	if b.Len() != StatusListSize {
+		t.Fatalf("Len = %d", b.Len())
+	}
+	b.Set(0, true)
+	b.Set(13, true)
+	b.Set(StatusListSize-1, true)
+	if b[0] != 0x80 || b[1] != 0x04 || b[len(b)-1] != 0x01 {
+		t.Errorf("unexpected bit layout %x %x %x", b[0], b[1], b[len(b)-1])
+	}
+	b.Set(13, false)
+
+	encoded, err := b.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoded[0] != 'u' {
+		t.Errorf("encodedList %q is not multibase base64url", encoded[:8])
+	}
+	decoded, err := DecodeBitstring(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Get(0) || decoded.Get(13) || !decoded.Get(StatusListSize-1) || decoded.Get(1) {
+		t.Error("bits did not survive encoding")
+	}
+
+	if _, err := DecodeBitstring("z" + encoded[1:]); err == nil {
+		t.Error("expected error for a non base64url multibase")
+	}
+	if _, err := DecodeBitstring("uAAAA"); err == nil {
+		t.Error("expected error for data that is not gzip")
+	}
+}
+
+// statusIssuer issues credentials with credentialStatus from lists kept in a temporary
+// directory.
+type statusIssuer struct {
+	did   string
+	priv  *identity.PrivateKey
+	lists *StatusLists
+	opts  VerifyOptions
+}
+
+func newStatusIssuer(t *testing.T) *statusIssuer {
+	t.Helper()
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	return &statusIssuer{
+		did:   identity.GenerateDIDForKey(priv.Public()),
+		priv:  priv,
+		lists: &StatusLists{Dir: filepath.Join(t.TempDir(), "status")},
+	}
+}
+
+func (si *statusIssuer) sign(c *Credential) error {
+	return c.SignCredentialSuite(context.Background(), signer.NewMemory(si.priv), si.did+"#keys-1", SuiteEddsaJcs2022)
+}
+
+func (si *statusIssuer) issue(t *testing.T, id, baseURL string) *Credential {
+	t.Helper()
+	cred := NewCredentialV2(id, si.did, map[string]interface{}{"id": "did:example:holder"})
+	entries, err := si.lists.Assign(id, si.did, baseURL, si.sign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred.CredentialStatus = entries
+	if err := si.sign(cred); err != nil {
+		t.Fatal(err)
+	}
+	return cred
+}
+
+// serve publishes the lists of si on a local HTTP server, which si.opts fetches them
+// from, and returns its URL.
+func (si *statusIssuer) serve(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join(si.lists.Dir, strings.TrimPrefix(r.URL.Path, "/")+".json"))
+	}))
+	t.Cleanup(srv.Close)
+	si.opts = DefaultVerifyOptions()
+	si.opts.FetchStatusList = HTTPStatusFetcher(srv.Client())
+	return srv.URL
+}
+
+func TestStatusListRevocation(t *testing.T) {
+	si := newStatusIssuer(t)
+	baseURL := si.serve(t)
+	cred := si.issue(t, "urn:uuid:status-1", baseURL)
+	other := si.issue(t, "urn:uuid:status-2", baseURL)
+
+	if len(cred.CredentialStatus) != 2 || cred.CredentialStatus[0].StatusPurpose != StatusPurposeRevocation ||
+		cred.CredentialStatus[1].StatusListCredential != baseURL+"/suspension" {
+		t.Fatalf("unexpected credentialStatus %+v", cred.CredentialStatus)
+	}
+	if cred.CredentialStatus[0].StatusListIndex == other.CredentialStatus[0].StatusListIndex {
+		t.Error("credentials share a status index")
+	}
+
+	// the credentialStatus survives a JSON round trip
+	data, _ := json.Marshal(cred)
+	var decoded Credential
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyCredentialWithOptions(&decoded, si.opts); err != nil {
+		t.Fatalf("fresh credential failed: %v", err)
+	}
+
+	if _, err := si.lists.Set(cred.ID, StatusPurposeSuspension, true, si.sign); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyCredentialWithOptions(cred, si.opts); !errors.Is(err, ErrSuspended) {
+		t.Errorf("suspended credential: got %v, want ErrSuspended", err)
+	}
+	if _, err := si.lists.Set(cred.ID, StatusPurposeSuspension, false, si.sign); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyCredentialWithOptions(cred, si.opts); err != nil {
+		t.Errorf("reinstated credential failed: %v", err)
+	}
+
+	if _, err := si.lists.Set(cred.ID, StatusPurposeRevocation, true, si.sign); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyCredentialWithOptions(cred, si.opts); !errors.Is(err, ErrRevoked) {
+		t.Errorf("revoked credential: got %v, want ErrRevoked", err)
+	}
+	if revoked, _ := si.lists.Get(cred.ID, StatusPurposeRevocation); !revoked {
+		t.Error("Get did not report the revocation")
+	}
+	if _, err := si.lists.Set(cred.ID, StatusPurposeRevocation, false, si.sign); err == nil || !strings.Contains(err.Error(), "cannot be undone") {
+		t.Errorf("undoing a revocation: got %v", err)
+	}
+	// clearing a revocation bit that is not set is refused the same way
+	if _, err := si.lists.Set(other.ID, StatusPurposeRevocation, false, si.sign); err == nil || !strings.Contains(err.Error(), "cannot be undone") {
+		t.Errorf("clearing an unset revocation: got %v", err)
+	}
+	if err := VerifyCredentialWithOptions(other, si.opts); err != nil {
+		t.Errorf("other credential failed: %v", err)
+	}
+}
+
+func TestStatusListFiles(t *testing.T) {
+	si := newStatusIssuer(t)
+	baseURL := "https://status.example/lists"
+	cred := si.issue(t, "urn:uuid:status-3", baseURL)
+	if _, err := si.lists.Assign("urn:uuid:status-4", si.did, "https://elsewhere.example", si.sign); err == nil {
+		t.Error("expected error for a different base URL")
+	}
+
+	fetch, err := StatusListFiles(filepath.Join(si.lists.Dir, "revocation.json"), filepath.Join(si.lists.Dir, "suspension.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckStatus(cred, fetch, ResolveDocument); err != nil {
+		t.Errorf("status check failed: %v", err)
+	}
+	if _, err := si.lists.Set(cred.ID, StatusPurposeRevocation, true, si.sign); err != nil {
+		t.Fatal(err)
+	}
+	// the fetcher read the files when it was made
+	if err := CheckStatus(cred, fetch, ResolveDocument); err != nil {
+		t.Errorf("status check failed: %v", err)
+	}
+	fetch, _ = StatusListFiles(filepath.Join(si.lists.Dir, "revocation.json"), filepath.Join(si.lists.Dir, "suspension.json"))
+	if err := CheckStatus(cred, fetch, ResolveDocument); !errors.Is(err, ErrRevoked) {
+		t.Errorf("got %v, want ErrRevoked", err)
+	}
+
+	// without the list the status cannot be checked, and the credential is not accepted
+	fetch, _ = StatusListFiles()
+	if err := CheckStatus(cred, fetch, ResolveDocument); err == nil {
+		t.Error("expected error for a missing status list")
+	}
+}
+
+func TestStatusListRelease(t *testing.T) {
+	si := newStatusIssuer(t)
+	baseURL := "https://status.example/lists"
+	if _, err := si.lists.Assign("urn:uuid:status-6", si.did, baseURL, si.sign); err != nil {
+		t.Fatal(err)
+	}
+	if err := si.lists.Release("urn:uuid:status-6"); err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := si.lists.Has("urn:uuid:status-6"); has {
+		t.Error("released index still assigned")
+	}
+	if err := si.lists.Release("urn:uuid:status-6"); err == nil {
+		t.Error("expected error releasing an unassigned index")
+	}
+	// the id can be given an index again
+	cred := si.issue(t, "urn:uuid:status-6", baseURL)
+
+	// an index whose bit was set stays taken
+	if _, err := si.lists.Set(cred.ID, StatusPurposeSuspension, true, si.sign); err != nil {
+		t.Fatal(err)
+	}
+	if err := si.lists.Release(cred.ID); err == nil {
+		t.Error("expected error releasing the index of a suspended credential")
+	}
+}
+
+func TestCheckStatusRejectsForeignLists(t *testing.T) {
+	si := newStatusIssuer(t)
+	baseURL := "https://status.example/lists"
+	cred := si.issue(t, "urn:uuid:status-5", baseURL)
+	revocationURL := baseURL + "/revocation"
+	revocation, _ := os.ReadFile(filepath.Join(si.lists.Dir, "revocation.json"))
+	suspension, _ := os.ReadFile(filepath.Join(si.lists.Dir, "suspension.json"))
+	fetchWith := func(list []byte) StatusFetcher {
+		return func(url string) ([]byte, error) {
+			if url == revocationURL {
+				return list, nil
+			}
+			return suspension, nil
+		}
+	}
+
+	// a list signed by someone else
+	mallory := newStatusIssuer(t)
+	forged, _ := NewStatusListCredential(revocationURL, mallory.did, StatusPurposeRevocation, NewBitstring(StatusListSize))
+	mallory.sign(forged)
+	data, _ := json.Marshal(forged)
+	if err := CheckStatus(cred, fetchWith(data), ResolveDocument); err == nil || !strings.Contains(err.Error(), "issued by") {
+		t.Errorf("list from another issuer: got %v", err)
+	}
+
+	// a list changed after signing
+	var list Credential
+	json.Unmarshal(revocation, &list)
+	bits := NewBitstring(StatusListSize)
+	list.CredentialSubject["encodedList"], _ = bits.Encode()
+	list.CredentialSubject["statusPurpose"] = StatusPurposeSuspension
+	data, _ = json.Marshal(list)
+	if err := CheckStatus(cred, fetchWith(data), ResolveDocument); err == nil {
+		t.Error("expected error for a tampered list")
+	}
+
+	// a list for the wrong purpose
+	if err := CheckStatus(cred, fetchWith(suspension), ResolveDocument); err == nil {
+		t.Error("expected error for a list of another purpose")
+	}
+
+	if err := CheckStatus(cred, fetchWith(revocation), ResolveDocument); err != nil {
+		t.Errorf("genuine list failed: %v", err)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"os"
 	"sync"
+
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
 )
 
 type InMemoryStore struct {
@@ -57,7 +59,7 @@ func (fs *FileStore) Save(cred *Credential) error {
 	}
 
 	filename := fs.Dir + "/" + cred.ID + ".json"
-	return os.WriteFile(filename, data, 0644)
+	return fsutil.WriteFile(filename, data, 0644)
 }
 
 func (fs *FileStore) Get(id string) (*Credential, error) {
@@ -0,0 +1,41 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultClockSkew is how far VerifyCredential lets the clocks of issuers and verifiers
+// be apart.
+const DefaultClockSkew = 5 * time.Minute
+
+var (
+	// ErrNotYetValid is returned for credentials whose validity period has not started.
+	ErrNotYetValid = errors.New("credential is not yet valid")
+	// ErrExpired is returned for credentials whose validity period has ended.
+	ErrExpired = errors.New("credential has expired")
+)
+
+// CheckValidity checks that cred is valid at now, give or take skew. A credential is valid
+// from its validFrom, or issuanceDate for the 1.1 data model, until its validUntil or
+// expirationDate; either end may be missing.
+func CheckValidity(cred *Credential, now time.Time, skew time.Duration) error {
+	from := cred.ValidFrom
+	if from.IsZero() {
+		from = cred.IssuanceDate
+	}
+	if !from.IsZero() && now.Add(skew).Before(from) {
+		return fmt.Errorf("%w: valid from %s", ErrNotYetValid, from.Format(time.RFC3339))
+	}
+	if !cred.ValidFrom.IsZero() && !cred.ValidUntil.IsZero() && cred.ValidUntil.Before(cred.ValidFrom) {
+		return fmt.Errorf("credential validUntil %s is before validFrom %s",
+			cred.ValidUntil.Format(time.RFC3339), cred.ValidFrom.Format(time.RFC3339))
+	}
+	for _, until := range []time.Time{cred.ValidUntil, cred.ExpirationDate} {
+		if !until.IsZero() && now.Add(-skew).After(until) {
+			return fmt.Errorf("%w: valid until %s", ErrExpired, until.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+)
+
+func TestCheckValidity(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	skew := time.Minute
+	tests := []struct {
+		name string
+		cred Credential
+		want error
+	}{
+		{"open ended", Credential{}, nil},
+		{"v1 issued", Credential{IssuanceDate: now.Add(-time.Hour)}, nil},
+		{"v1 issued in the future", Credential{IssuanceDate: now.Add(time.Hour)}, ErrNotYetValid},
+		{"v1 expired", Credential{IssuanceDate: now.Add(-time.Hour), ExpirationDate: now.Add(-2 * time.Minute)}, ErrExpired},
+		{"v2 valid", Credential{ValidFrom: now.Add(-time.Hour), ValidUntil: now.Add(time.Hour)}, nil},
+		{"v2 not yet valid", Credential{ValidFrom: now.Add(2 * time.Minute)}, ErrNotYetValid},
+		{"v2 expired", Credential{ValidFrom: now.Add(-time.Hour), ValidUntil: now.Add(-2 * time.Minute)}, ErrExpired},
+		{"within skew of start", Credential{ValidFrom: now.Add(30 * time.Second)}, nil},
+		{"within skew of end", Credential{ValidUntil: now.Add(-30 * time.Second)}, nil},
+	}
+	for _, tt := range tests {
+		if err := CheckValidity(&tt.cred, now, skew); !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	reversed := Credential{ValidFrom: now.Add(-time.Hour), ValidUntil: now.Add(-2 * time.Hour)}
+	if err := CheckValidity(&reversed, now, skew); err == nil {
+		t.Error("expected error for validUntil before validFrom")
+	}
+}
+
+func TestCredentialV2(t *testing.T) {
+	ctx := context.Background()
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+
+	cred := NewCredentialV2("urn:uuid:4f3c2b1a-0e9d-4c8b-a7f6-5e4d3c2b1a09", did, map[string]interface{}{"id": did})
+	cred.Name, cred.Description = "Membership", "Member of the example club."
+	cred.SetExpiration(time.Now().Add(24 * time.Hour))
+	for _, suite := range []string{"", SuiteEddsaRdfc2022, SuiteEddsaJcs2022, SuiteEd25519Signature2020} {
+		c := *cred
+		c.Context = append([]interface{}(nil), cred.Context...)
+		c.Proofs = nil
+		if err := c.SignCredentialSuite(ctx, signer.NewMemory(priv), did+"#keys-1", suite); err != nil {
+			t.Fatalf("%q: %v", suite, err)
+		}
+		if err := VerifyCredential(&c); err != nil {
+			t.Errorf("%q: credential failed: %v", suite, err)
+		}
+	}
+
+	data, _ := json.Marshal(cred)
+	var m map[string]interface{}
+	json.Unmarshal(data, &m)
+	if _, ok := m["issuanceDate"]; ok {
+		t.Error("2.0 credential has issuanceDate")
+	}
+	if m["validFrom"] == nil || m["validUntil"] == nil || m["name"] != "Membership" {
+		t.Errorf("unexpected credential %s", data)
+	}
+
+	expired := NewCredentialV2("urn:uuid:1", did, map[string]interface{}{"id": did})
+	expired.ValidFrom = time.Now().Add(-48 * time.Hour).UTC()
+	expired.SetExpiration(time.Now().Add(-24 * time.Hour))
+	expired.SignCredential(ctx, signer.NewMemory(priv), did+"#keys-1")
+	if err := VerifyCredential(expired); !errors.Is(err, ErrExpired) {
+		t.Errorf("expired credential: got %v, want ErrExpired", err)
+	}
+	opts := DefaultVerifyOptions()
+	opts.ClockSkew = 25 * time.Hour
+	if err := VerifyCredentialWithOptions(expired, opts); err != nil {
+		t.Errorf("expired credential within clock skew: %v", err)
+	}
+
+	v1 := NewCredential("vc1", did, map[string]interface{}{"id": did})
+	v1.SetExpiration(time.Now().Add(time.Hour))
+	if v1.ExpirationDate.IsZero() || !v1.ValidUntil.IsZero() {
+		t.Errorf("1.1 credential expiry not in expirationDate: %+v", v1)
+	}
+}
+
+func TestParseExpiration(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for in, want := range map[string]time.Time{
+		"30d":                  now.Add(30 * 24 * time.Hour),
+		"12h":                  now.Add(12 * time.Hour),
+		"90m":                  now.Add(90 * time.Minute),
+		"2027-06-01T00:00:00Z": time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		if got, err := ParseExpiration(in, now); err != nil || !got.Equal(want) {
+			t.Errorf("ParseExpiration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "d", "-1d", "0h", "1w", "soon"} {
+		if _, err := ParseExpiration(in, now); err == nil {
+			t.Errorf("ParseExpiration(%q): expected error", in)
+		}
+	}
+}
@@ -1,94 +1,246 @@
 package credentials
 
 import (
-	"crypto/ed25519"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/mr-tron/base58"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/resolver"
 )
 
-func ResolveDidKeyPub(did string) (ed25519.PublicKey, error) {
-	const prefix = "did:key:z"
-	if !strings.HasPrefix(did, prefix) {
-		return nil, fmt.Errorf("unsupported DID method")
+func ResolveDidKeyPub(did string) (*identity.PublicKey, error) {
+	return identity.ParseDIDKey(did)
+}
+
+// DocumentResolver returns the DID Document that controls did.
+type DocumentResolver func(did string) (*identity.Document, error)
+
+// DefaultResolver resolves DIDs for ResolveDocument, and so for VerifyCredential and
+// VerifyPresentation. It resolves did:key, did:web, did:jwk and did:peer, and caches
+// documents for resolver.DefaultTTL.
+var DefaultResolver resolver.Resolver = resolver.NewCache(resolver.NewRegistry(nil), resolver.DefaultTTL)
+
+// ResolveWith returns a DocumentResolver that resolves DIDs with r. Deactivated DIDs
+// fail with resolver.ErrDeactivated, so their proofs are refused.
+func ResolveWith(r resolver.Resolver) DocumentResolver {
+	return func(did string) (*identity.Document, error) {
+		res, err := r.Resolve(context.Background(), did)
+		if err != nil {
+			return nil, err
+		}
+		if res.DocumentMetadata.Deactivated {
+			return nil, fmt.Errorf("%w: %s", resolver.ErrDeactivated, did)
+		}
+		return res.Document, nil
+	}
+}
+
+// ResolveDocument is the default DocumentResolver; it resolves did with DefaultResolver.
+func ResolveDocument(did string) (*identity.Document, error) {
+	return ResolveWith(DefaultResolver)(did)
+}
+
+// verifyProof checks that sp is a proof for purpose that signs data with the key its
+// verificationMethod points to, as checked by proofMethod. Its jws is a detached JWS, or
+// a Legacy hex signature.
+func verifyProof(sp *SignatureProof, data []byte, controller, purpose string, resolve DocumentResolver) error {
+	if sp.ProofPurpose != purpose {
+		return fmt.Errorf("proof purpose is %q, want %q", sp.ProofPurpose, purpose)
+	}
+	created, err := time.Parse(time.RFC3339, sp.Created)
+	if err != nil {
+		return fmt.Errorf("invalid proof created time: %w", err)
+	}
+	vm, err := proofMethod(sp.VerificationMethod, created, controller, purpose, resolve)
+	if err != nil {
+		return err
 	}
-	data := did[len(prefix):]
-	raw, err := base58.Decode(data)
+	pub, err := vm.PublicKey()
+	if err != nil {
+		return err
+	}
+	if sp.Type != pub.Type.SignatureSuite() {
+		return fmt.Errorf("proof type %s does not match %s key %s", sp.Type, pub.Type, vm.ID)
+	}
+	if !sp.Legacy() {
+		return verifyDetachedJWS(sp.JWS, data, pub)
+	}
+	sigBytes, err := hex.DecodeString(sp.JWS)
+	if err != nil {
+		return err
+	}
+	if !pub.Verify(data, sigBytes) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// proofMethod returns the verification method vmID of a proof created at created. The
+// method must be controlled by controller and listed under purpose in the controller's
+// DID Document, and must have been valid when the proof was created.
+func proofMethod(vmID string, created time.Time, controller, purpose string, resolve DocumentResolver) (*identity.VerificationMethod, error) {
+	doc, err := resolve(controller)
 	if err != nil {
 		return nil, err
 	}
-	// strip multicodec prefix 0xed01
-	if len(raw) < 2 || raw[0] != 0xed || raw[1] != 0x01 {
-		return nil, fmt.Errorf("invalid multicodec prefix")
+	vm, err := dereference(doc, vmID, resolve)
+	if err != nil {
+		return nil, err
+	}
+	if vm.Controller != controller {
+		return nil, fmt.Errorf("verification method %s is controlled by %s, not %s", vm.ID, vm.Controller, controller)
 	}
-	return ed25519.PublicKey(raw[2:]), nil
+	if !doc.HasRelationship(purpose, vm.ID) {
+		return nil, fmt.Errorf("verification method %s is not listed under %s in %s", vm.ID, purpose, controller)
+	}
+	if err := vm.CheckValidAt(created); err != nil {
+		return nil, err
+	}
+	return vm, nil
 }
 
-func VerifyCredential(cred *Credential) error {
-	if len(cred.Proofs) == 0 {
-		return fmt.Errorf("no proof present in credential")
+// verifyLastProof verifies the last of proofs, the signature proof, of doc: a credential
+// or presentation as received. Data Integrity proofs sign the canonical form of doc
+// without its proofs; legacy proofs sign the JSON encoding of stripped, doc with its
+// proofs removed.
+func verifyLastProof(doc, stripped interface{}, proofs []json.RawMessage, controller, purpose string, resolve DocumentResolver) error {
+	last := proofs[len(proofs)-1]
+	if _, ok := suiteOf(last); ok {
+		m, err := documentOf(doc)
+		if err != nil {
+			return err
+		}
+		return verifyDataIntegrity(m, last, controller, purpose, resolve)
 	}
-	// signature proof is the last proof in the array
-	last := cred.Proofs[len(cred.Proofs)-1]
 	var sp SignatureProof
 	if err := json.Unmarshal(last, &sp); err != nil {
 		return fmt.Errorf("unmarshal signature proof: %w", err)
 	}
-	sigBytes, err := hex.DecodeString(sp.JWS)
+	data, err := json.Marshal(stripped)
 	if err != nil {
 		return err
 	}
-	// serialize credential without proofs
+	return verifyProof(&sp, data, controller, purpose, resolve)
+}
+
+// dereference returns the verification method vmID, from doc or from the document of the
+// DID that vmID belongs to.
+func dereference(doc *identity.Document, vmID string, resolve DocumentResolver) (*identity.VerificationMethod, error) {
+	did, _, ok := strings.Cut(vmID, "#")
+	if !ok {
+		return nil, fmt.Errorf("verification method %q is not a DID URL with a fragment", vmID)
+	}
+	if did != doc.ID {
+		other, err := resolve(did)
+		if err != nil {
+			return nil, err
+		}
+		doc = other
+	}
+	return doc.Method(vmID)
+}
+
+// VerifyOptions configure how VerifyCredentialWithOptions and
+// VerifyPresentationWithOptions check credentials. Start from DefaultVerifyOptions.
+type VerifyOptions struct {
+	// Resolve resolves the DID Documents of issuers and holders (ResolveDocument if nil).
+	Resolve DocumentResolver
+	// Schemas, if set, validates credentials against their credentialSchema.
+	Schemas *SchemaRegistry
+	// FetchStatusList fetches the status lists that credentialStatus entries point to
+	// (over HTTP if nil).
+	FetchStatusList StatusFetcher
+	// ClockSkew is how far the clocks of issuers and verifiers may be apart: credentials
+	// are accepted up to ClockSkew before they become valid and after they expire.
+	ClockSkew time.Duration
+}
+
+// DefaultVerifyOptions returns the options of VerifyCredential and VerifyPresentation.
+func DefaultVerifyOptions() VerifyOptions {
+	return VerifyOptions{Resolve: ResolveDocument, FetchStatusList: httpStatusFetcher, ClockSkew: DefaultClockSkew}
+}
+
+func VerifyCredential(cred *Credential) error {
+	return VerifyCredentialWithOptions(cred, DefaultVerifyOptions())
+}
+
+// VerifyCredentialWith verifies cred with the default options, resolving the issuer's
+// DID Document with resolve.
+func VerifyCredentialWith(cred *Credential, resolve DocumentResolver) error {
+	opts := DefaultVerifyOptions()
+	opts.Resolve = resolve
+	return VerifyCredentialWithOptions(cred, opts)
+}
+
+// VerifyCredentialWithOptions verifies the signature of cred, checks that it is valid now
+// as CheckValidity does and, if it has a credentialStatus, that it is not revoked or
+// suspended. With opts.Schemas set, cred must also match its credentialSchema.
+func VerifyCredentialWithOptions(cred *Credential, opts VerifyOptions) error {
+	opts = opts.withDefaults()
+	if len(cred.Proofs) == 0 {
+		return fmt.Errorf("no proof present in credential")
+	}
+	// signature proof is the last proof in the array
 	tmp := *cred
 	tmp.Proofs = nil
-	data, err := json.Marshal(tmp)
-	if err != nil {
-		return err
+	if err := verifyLastProof(cred, &tmp, cred.Proofs, cred.Issuer, identity.PurposeAssertionMethod, opts.Resolve); err != nil {
+		return fmt.Errorf("invalid credential signature: %w", err)
 	}
-	pub, err := ResolveDidKeyPub(cred.Issuer)
-	if err != nil {
+	if err := CheckValidity(cred, time.Now(), opts.ClockSkew); err != nil {
 		return err
 	}
-	if !ed25519.Verify(pub, data, sigBytes) {
-		return fmt.Errorf("invalid credential signature")
+	if len(cred.CredentialStatus) > 0 {
+		if err := checkStatus(cred, opts); err != nil {
+			return err
+		}
+	}
+	if opts.Schemas != nil {
+		return ValidateCredentialSchema(cred, opts.Schemas, opts.Resolve)
 	}
 	return nil
 }
 
+func (o VerifyOptions) withDefaults() VerifyOptions {
+	if o.Resolve == nil {
+		o.Resolve = ResolveDocument
+	}
+	if o.FetchStatusList == nil {
+		o.FetchStatusList = httpStatusFetcher
+	}
+	return o
+}
+
 func VerifyPresentation(pres *Presentation) error {
+	return VerifyPresentationWithOptions(pres, DefaultVerifyOptions())
+}
+
+// VerifyPresentationWith verifies pres and its embedded credentials, resolving DID Documents with resolve.
+func VerifyPresentationWith(pres *Presentation, resolve DocumentResolver) error {
+	opts := DefaultVerifyOptions()
+	opts.Resolve = resolve
+	return VerifyPresentationWithOptions(pres, opts)
+}
+
+// VerifyPresentationWithOptions verifies pres, and its embedded credentials as
+// VerifyCredentialWithOptions does.
+func VerifyPresentationWithOptions(pres *Presentation, opts VerifyOptions) error {
+	opts = opts.withDefaults()
 	if len(pres.Proofs) == 0 {
 		return fmt.Errorf("no proof present in presentation")
 	}
 	// signature proof is the last proof in the array
-	last := pres.Proofs[len(pres.Proofs)-1]
-	var sp SignatureProof
-	if err := json.Unmarshal(last, &sp); err != nil {
-		return fmt.Errorf("unmarshal signature proof: %w", err)
-	}
-	sigBytes, err := hex.DecodeString(sp.JWS)
-	if err != nil {
-		return err
-	}
-	// serialize presentation without proofs
 	tmp := *pres
 	tmp.Proofs = nil
-	data, err := json.Marshal(tmp)
-	if err != nil {
-		return err
-	}
-	pub, err := ResolveDidKeyPub(pres.Holder)
-	if err != nil {
-		return err
-	}
-	if !ed25519.Verify(pub, data, sigBytes) {
-		return fmt.Errorf("invalid presentation signature")
+	if err := verifyLastProof(pres, &tmp, pres.Proofs, pres.Holder, identity.PurposeAuthentication, opts.Resolve); err != nil {
+		return fmt.Errorf("invalid presentation signature: %w", err)
 	}
 	// verify all embedded credentials
 	for _, vc := range pres.VerifiableCredential {
-		if err := VerifyCredential(&vc); err != nil {
+		if err := VerifyCredentialWithOptions(&vc, opts); err != nil {
 			return fmt.Errorf("embedded credential %s failed: %w", vc.ID, err)
 		}
 	}
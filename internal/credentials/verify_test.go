@@ -0,0 +1,242 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/resolver"
+	"github.com/juanpablocruz/minervaid/internal/signer"
+)
+
+// rotatedIssuer returns a did:web document whose #keys-1 is retired at retireAt in favour of #keys-2.
+func rotatedIssuer(t *testing.T, retireAt time.Time) (*identity.Document, DocumentResolver, [2]*identity.PrivateKey) {
+	t.Helper()
+	priv1, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	priv2, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	doc := identity.NewDocument("did:web:issuer.example", priv1.Public())
+	doc.RotateKey(priv2.Public(), time.Now().Add(-time.Minute), retireAt)
+	resolve := func(did string) (*identity.Document, error) {
+		if did == doc.ID {
+			return doc, nil
+		}
+		return ResolveDocument(did)
+	}
+	return doc, resolve, [2]*identity.PrivateKey{priv1, priv2}
+}
+
+func TestVerifyCredentialAfterRotation(t *testing.T) {
+	doc, resolve, keys := rotatedIssuer(t, time.Now().Add(-time.Second))
+	ctx := context.Background()
+
+	// new key verifies
+	cred := NewCredential("vc-new", doc.ID, map[string]interface{}{"id": "did:example:holder"})
+	if err := cred.SignCredential(ctx, signer.NewMemory(keys[1]), doc.ID+"#keys-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyCredentialWith(cred, resolve); err != nil {
+		t.Errorf("credential signed with current key failed: %v", err)
+	}
+
+	// retired key used after retirement is rejected
+	old := NewCredential("vc-old", doc.ID, map[string]interface{}{"id": "did:example:holder"})
+	old.SignCredential(ctx, signer.NewMemory(keys[0]), doc.ID+"#keys-1")
+	if err := VerifyCredentialWith(old, resolve); err == nil {
+		t.Error("expected failure for proof created after key retirement")
+	}
+
+	// proof pointing at the wrong method is rejected
+	wrong := NewCredential("vc-wrong", doc.ID, map[string]interface{}{"id": "did:example:holder"})
+	wrong.SignCredential(ctx, signer.NewMemory(keys[0]), doc.ID+"#keys-2")
+	if err := VerifyCredentialWith(wrong, resolve); err == nil {
+		t.Error("expected failure when verificationMethod does not match the signing key")
+	}
+}
+
+func TestVerifyCredentialBeforeRetirement(t *testing.T) {
+	doc, resolve, keys := rotatedIssuer(t, time.Now().Add(time.Hour))
+	ctx := context.Background()
+	cred := NewCredential("vc-grace", doc.ID, map[string]interface{}{"id": "did:example:holder"})
+	cred.SignCredential(ctx, signer.NewMemory(keys[0]), doc.ID+"#keys-1")
+	if err := VerifyCredentialWith(cred, resolve); err != nil {
+		t.Errorf("old key should verify until retirement: %v", err)
+	}
+}
+
+func TestVerifyCredentialDidKey(t *testing.T) {
+	ctx := context.Background()
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+	cred := NewCredential("vc-key", did, map[string]interface{}{"id": did})
+	cred.SignCredential(ctx, signer.NewMemory(priv), did+"#keys-1")
+	if err := VerifyCredential(cred); err != nil {
+		t.Errorf("did:key credential failed: %v", err)
+	}
+}
+
+func TestVerifyCredentialDidJWK(t *testing.T) {
+	ctx := context.Background()
+	priv, _ := identity.GenerateKey(identity.KeyTypeP256)
+	did, err := identity.GenerateDIDJWK(priv.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred := NewCredential("vc-jwk", did, map[string]interface{}{"id": did})
+	cred.SignCredential(ctx, signer.NewMemory(priv), did+"#0")
+	if err := VerifyCredential(cred); err != nil {
+		t.Errorf("did:jwk credential failed: %v", err)
+	}
+}
+
+func TestVerifyCredentialDidWeb(t *testing.T) {
+	ctx := context.Background()
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	var doc []byte
+	gone := false
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gone {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.Write(doc)
+	}))
+	defer srv.Close()
+	did := resolver.WebDID(strings.TrimPrefix(srv.URL, "https://"))
+	doc, _ = identity.NewDocument(did, priv.Public()).Marshal()
+
+	saved := DefaultResolver
+	DefaultResolver = resolver.NewRegistry(srv.Client())
+	defer func() { DefaultResolver = saved }()
+
+	cred := NewCredential("vc-web", did, map[string]interface{}{"id": "did:example:holder"})
+	cred.SignCredential(ctx, signer.NewMemory(priv), did+"#keys-1")
+	if err := VerifyCredential(cred); err != nil {
+		t.Errorf("did:web credential failed: %v", err)
+	}
+
+	other, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	forged := NewCredential("vc-forged", did, map[string]interface{}{"id": "did:example:holder"})
+	forged.SignCredential(ctx, signer.NewMemory(other), did+"#keys-1")
+	if err := VerifyCredential(forged); err == nil {
+		t.Error("credential signed with a key not in the hosted document must fail")
+	}
+
+	gone = true
+	if err := VerifyCredential(cred); !errors.Is(err, resolver.ErrDeactivated) {
+		t.Errorf("expected ErrDeactivated once the host answers 410, got %v", err)
+	}
+}
+
+func TestVerifyCredentialDeactivated(t *testing.T) {
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	doc := identity.NewDocument("did:web:issuer.example", priv.Public())
+	res := &resolver.Resolution{Document: doc}
+	resolve := ResolveWith(resolver.Func(func(ctx context.Context, did string) (*resolver.Resolution, error) {
+		return res, nil
+	}))
+
+	cred := NewCredential("vc1", doc.ID, map[string]interface{}{"id": "did:example:holder"})
+	cred.SignCredential(context.Background(), signer.NewMemory(priv), doc.ID+"#keys-1")
+	if err := VerifyCredentialWith(cred, resolve); err != nil {
+		t.Fatalf("VerifyCredentialWith: %v", err)
+	}
+	res.DocumentMetadata.Deactivated = true
+	if err := VerifyCredentialWith(cred, resolve); !errors.Is(err, resolver.ErrDeactivated) {
+		t.Errorf("expected ErrDeactivated, got %v", err)
+	}
+}
+
+func TestVerifyProofRelationships(t *testing.T) {
+	ctx := context.Background()
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	doc := identity.NewDocument("did:web:issuer.example", priv.Public())
+	resolve := func(did string) (*identity.Document, error) {
+		if did == doc.ID {
+			return doc, nil
+		}
+		return ResolveDocument(did)
+	}
+	vm := doc.ID + "#keys-1"
+	subject := map[string]interface{}{"id": "did:example:holder"}
+
+	cred := NewCredential("vc1", doc.ID, subject)
+	cred.SignCredential(ctx, signer.NewMemory(priv), vm)
+	if err := VerifyCredentialWith(cred, resolve); err != nil {
+		t.Fatalf("VerifyCredentialWith: %v", err)
+	}
+
+	// the purpose is not covered by the signature, so it must be checked on its own
+	var sp SignatureProof
+	json.Unmarshal(cred.Proofs[0], &sp)
+	sp.ProofPurpose = identity.PurposeAuthentication
+	relabelled := *cred
+	relabelled.Proofs = []json.RawMessage{mustMarshal(t, sp)}
+	if err := VerifyCredentialWith(&relabelled, resolve); err == nil {
+		t.Error("a credential proof claiming authentication must fail")
+	}
+
+	// a key of another DID cannot sign for the issuer
+	attacker, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	attackerDID := identity.GenerateDIDForKey(attacker.Public())
+	forged := NewCredential("vc2", doc.ID, subject)
+	forged.SignCredential(ctx, signer.NewMemory(attacker), attackerDID+"#keys-1")
+	if err := VerifyCredentialWith(forged, resolve); err == nil || !strings.Contains(err.Error(), "controlled by") {
+		t.Errorf("a proof by another DID's key must fail the controller check, got %v", err)
+	}
+
+	// a key listed only under authentication cannot issue, and vice versa
+	doc.AssertionMethod = nil
+	if err := VerifyCredentialWith(cred, resolve); err == nil {
+		t.Error("a credential signed by an authentication-only key must fail")
+	}
+	doc.AssertionMethod, doc.Authentication = []string{"#keys-1"}, nil
+	if err := VerifyCredentialWith(cred, resolve); err != nil {
+		t.Errorf("relative method references must match: %v", err)
+	}
+	pres := NewPresentation([]Credential{*cred}, doc.ID)
+	pres.SignPresentation(ctx, signer.NewMemory(priv), vm)
+	if err := VerifyPresentationWith(pres, resolve); err == nil {
+		t.Error("a presentation signed by an assertion-only key must fail")
+	}
+	issued := NewAuthChallenge("rp.example", time.Minute)
+	ar, _ := SignAuthChallenge(ctx, vm, issued, signer.NewMemory(priv))
+	if _, err := VerifyAuthResponse(ar, issued, resolve); err == nil {
+		t.Error("an auth response signed by an assertion-only key must fail")
+	}
+
+	doc.Authentication = []string{vm}
+	if err := VerifyPresentationWith(pres, resolve); err != nil {
+		t.Errorf("VerifyPresentationWith: %v", err)
+	}
+	if did, err := VerifyAuthResponse(ar, issued, resolve); err != nil || did != doc.ID {
+		t.Errorf("VerifyAuthResponse = %s, %v", did, err)
+	}
+	other := *issued
+	other.Domain = "evil.example"
+	if _, err := VerifyAuthResponse(ar, &other, resolve); err == nil {
+		t.Error("a response for another domain must fail")
+	}
+	if _, err := VerifyAuthResponse(ar, NewAuthChallenge("rp.example", time.Minute), resolve); err == nil {
+		t.Error("a response to another challenge must fail")
+	}
+	expiredCh := NewAuthChallenge("rp.example", -time.Minute)
+	expired, _ := SignAuthChallenge(ctx, vm, expiredCh, signer.NewMemory(priv))
+	if _, err := VerifyAuthResponse(expired, expiredCh, resolve); err == nil {
+		t.Error("an expired challenge must fail")
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
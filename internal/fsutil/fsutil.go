@@ -0,0 +1,67 @@
+// Package fsutil provides crash-safe file writes and the advisory lock that serialises
+// ego processes working on the same vault.
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gofrs/flock"
+)
+
+// LockFilename is the advisory lock file kept in each vault directory.
+const LockFilename = ".lock"
+
+// WriteFile writes data to a temporary file next to path and renames it into place, so
+// readers see either the old or the new contents and never a partial write.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Lock is an exclusive advisory lock on a directory, held across processes.
+type Lock struct {
+	f *flock.Flock
+}
+
+// LockDir blocks until it holds the lock on dir, creating dir if needed. Locks are not
+// reentrant: a process must release the lock before taking it again.
+func LockDir(dir string) (*Lock, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	f := flock.New(filepath.Join(dir, LockFilename))
+	if err := f.Lock(); err != nil {
+		return nil, fmt.Errorf("lock %s: %w", dir, err)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock() error {
+	return l.f.Unlock()
+}
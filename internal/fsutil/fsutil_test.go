@@ -0,0 +1,79 @@
+package fsutil
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// helperDirEnv makes the test binary increment a counter in the given directory under the lock.
+const helperDirEnv = "FSUTIL_TEST_HELPER_DIR"
+
+func TestMain(m *testing.M) {
+	if dir := os.Getenv(helperDirEnv); dir != "" {
+		for i := 0; i < 10; i++ {
+			lock, err := LockDir(dir)
+			if err != nil {
+				os.Exit(2)
+			}
+			path := filepath.Join(dir, "counter")
+			data, _ := os.ReadFile(path)
+			n, _ := strconv.Atoi(string(data))
+			time.Sleep(time.Millisecond)
+			if err := WriteFile(path, []byte(strconv.Itoa(n+1)), 0600); err != nil {
+				os.Exit(2)
+			}
+			lock.Unlock()
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		data, _ := os.ReadFile(path)
+		if string(data) != content {
+			t.Errorf("read %q; want %q", data, content)
+		}
+	}
+	fi, _ := os.Stat(path)
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v; want 0600", fi.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestLockDirAcrossProcesses(t *testing.T) {
+	dir := t.TempDir()
+	const procs = 4
+	var cmds []*exec.Cmd
+	for i := 0; i < procs; i++ {
+		c := exec.Command(os.Args[0], "-test.run=^$")
+		c.Env = append(os.Environ(), helperDirEnv+"="+dir)
+		if err := c.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, c)
+	}
+	for _, c := range cmds {
+		if err := c.Wait(); err != nil {
+			t.Fatalf("helper failed: %v", err)
+		}
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "counter"))
+	if string(data) != strconv.Itoa(procs*10) {
+		t.Errorf("counter = %s; want %d", data, procs*10)
+	}
+}
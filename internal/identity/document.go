@@ -0,0 +1,395 @@
+package identity
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mr-tron/base58"
+)
+
+const didContext = "https://www.w3.org/ns/did/v1"
+
+// VerificationMethod is a public key entry of a DID Document.
+// Created and Retired bound the period in which the key may be used for new proofs.
+type VerificationMethod struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`
+	Controller      string `json:"controller"`
+	PublicKeyBase58 string `json:"publicKeyBase58,omitempty"`
+	PublicKeyJwk    *JWK   `json:"publicKeyJwk,omitempty"`
+	Created         string `json:"created,omitempty"`
+	Retired         string `json:"retired,omitempty"`
+}
+
+// PublicKey decodes the method's public key.
+func (vm *VerificationMethod) PublicKey() (*PublicKey, error) {
+	if vm.PublicKeyJwk != nil {
+		return vm.PublicKeyJwk.PublicKey()
+	}
+	kt, err := keyTypeForVerificationMethod(vm.Type)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base58.Decode(vm.PublicKeyBase58)
+	if err != nil {
+		return nil, fmt.Errorf("decode public key: %w", err)
+	}
+	return ParsePublicKey(kt, raw)
+}
+
+// CheckValidAt returns an error if the method was not usable at t.
+func (vm *VerificationMethod) CheckValidAt(t time.Time) error {
+	if vm.Created != "" {
+		created, err := time.Parse(time.RFC3339, vm.Created)
+		if err != nil {
+			return fmt.Errorf("invalid created time on %s: %w", vm.ID, err)
+		}
+		if t.Before(created) {
+			return fmt.Errorf("verification method %s not valid before %s", vm.ID, vm.Created)
+		}
+	}
+	if vm.Retired != "" {
+		retired, err := time.Parse(time.RFC3339, vm.Retired)
+		if err != nil {
+			return fmt.Errorf("invalid retired time on %s: %w", vm.ID, err)
+		}
+		if !t.Before(retired) {
+			return fmt.Errorf("verification method %s retired at %s", vm.ID, vm.Retired)
+		}
+	}
+	return nil
+}
+
+// Service is a service endpoint of a DID Document. ServiceEndpoint is a URL string, or
+// the map or list forms that DID Core also allows.
+type Service struct {
+	ID              string      `json:"id"`
+	Type            string      `json:"type"`
+	ServiceEndpoint interface{} `json:"serviceEndpoint"`
+	RoutingKeys     []string    `json:"routingKeys,omitempty"`
+	Accept          []string    `json:"accept,omitempty"`
+}
+
+// Controllers is the controller property of a DID Document: one DID, serialized as a
+// string, or a set of them.
+type Controllers []string
+
+// MarshalJSON writes a single controller as a string.
+func (c Controllers) MarshalJSON() ([]byte, error) {
+	if len(c) == 1 {
+		return json.Marshal(c[0])
+	}
+	return json.Marshal([]string(c))
+}
+
+// UnmarshalJSON accepts both the string and the set form.
+func (c *Controllers) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*c = Controllers{one}
+		return nil
+	}
+	var set []string
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("controller must be a DID or a set of DIDs")
+	}
+	*c = set
+	return nil
+}
+
+// Verification relationships of a DID Document.
+const (
+	PurposeAuthentication  = "authentication"
+	PurposeAssertionMethod = "assertionMethod"
+	PurposeKeyAgreement    = "keyAgreement"
+)
+
+// Document is a DID Document as stored in did.json.
+type Document struct {
+	Context            []interface{}        `json:"@context"`
+	ID                 string               `json:"id"`
+	AlsoKnownAs        []string             `json:"alsoKnownAs,omitempty"`
+	Controller         Controllers          `json:"controller,omitempty"`
+	VerificationMethod []VerificationMethod `json:"verificationMethod"`
+	Authentication     []string             `json:"authentication"`
+	AssertionMethod    []string             `json:"assertionMethod,omitempty"`
+	KeyAgreement       []string             `json:"keyAgreement,omitempty"`
+	Service            []Service            `json:"service,omitempty"`
+}
+
+// NewDocument builds a DID Document with pub as its first key, #keys-1, for both
+// authentication and assertionMethod.
+func NewDocument(did string, pub *PublicKey) *Document {
+	vmID := did + "#keys-1"
+	return &Document{
+		Context: []interface{}{didContext},
+		ID:      did,
+		VerificationMethod: []VerificationMethod{{
+			ID:              vmID,
+			Type:            pub.Type.VerificationMethodType(),
+			Controller:      did,
+			PublicKeyBase58: base58.Encode(pub.Raw),
+		}},
+		Authentication:  []string{vmID},
+		AssertionMethod: []string{vmID},
+	}
+}
+
+// ParseDIDDocument decodes a serialized DID Document.
+func ParseDIDDocument(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse DID document: %w", err)
+	}
+	if doc.ID == "" {
+		return nil, fmt.Errorf("DID document missing 'id'")
+	}
+	return &doc, nil
+}
+
+// AddAlsoKnownAs records that the subject of d is also identified by id.
+func (d *Document) AddAlsoKnownAs(id string) {
+	if !contains(d.AlsoKnownAs, id) {
+		d.AlsoKnownAs = append(d.AlsoKnownAs, id)
+	}
+}
+
+// Marshal serializes the document as indented JSON.
+func (d *Document) Marshal() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// Method returns the verification method with the given id.
+func (d *Document) Method(id string) (*VerificationMethod, error) {
+	for i := range d.VerificationMethod {
+		if d.VerificationMethod[i].ID == id {
+			return &d.VerificationMethod[i], nil
+		}
+	}
+	return nil, fmt.Errorf("verification method %s not found in %s", id, d.ID)
+}
+
+// CurrentMethod returns the signing method of the document's own key: the #keys-N method
+// with the highest N that has not been retired. Documents without #keys-N methods fall
+// back to the most recently added signing method. Key agreement keys are skipped.
+func (d *Document) CurrentMethod() (*VerificationMethod, error) {
+	var current *VerificationMethod
+	for i := range d.VerificationMethod {
+		vm := &d.VerificationMethod[i]
+		if vm.Retired == "" && keyIndex(d.ID, vm.ID) > 0 && (current == nil || keyIndex(d.ID, vm.ID) > keyIndex(d.ID, current.ID)) {
+			current = vm
+		}
+	}
+	if current != nil {
+		return current, nil
+	}
+	for i := len(d.VerificationMethod) - 1; i >= 0; i-- {
+		vm := &d.VerificationMethod[i]
+		if vm.Retired == "" && !contains(d.KeyAgreement, vm.ID) {
+			return vm, nil
+		}
+	}
+	return nil, fmt.Errorf("DID document %s has no active verification method", d.ID)
+}
+
+// MethodFor returns the method to sign with for purpose: CurrentMethod if it is listed
+// under purpose, or else an active method of the same key that is.
+func (d *Document) MethodFor(purpose string) (*VerificationMethod, error) {
+	cur, err := d.CurrentMethod()
+	if err != nil {
+		return nil, err
+	}
+	if d.HasRelationship(purpose, cur.ID) {
+		return cur, nil
+	}
+	if pub, err := cur.PublicKey(); err == nil {
+		for i := range d.VerificationMethod {
+			vm := &d.VerificationMethod[i]
+			if vm.Retired != "" || !d.HasRelationship(purpose, vm.ID) {
+				continue
+			}
+			if other, err := vm.PublicKey(); err == nil && other.Equal(pub) {
+				return vm, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s does not list its current key %s under %s", d.ID, cur.ID, purpose)
+}
+
+// Relationship returns the method IDs listed under purpose.
+func (d *Document) Relationship(purpose string) []string {
+	switch purpose {
+	case PurposeAuthentication:
+		return d.Authentication
+	case PurposeAssertionMethod:
+		return d.AssertionMethod
+	case PurposeKeyAgreement:
+		return d.KeyAgreement
+	}
+	return nil
+}
+
+// HasRelationship reports whether the method vmID is listed under purpose, by its absolute
+// ID or by a fragment relative to the DID.
+func (d *Document) HasRelationship(purpose, vmID string) bool {
+	for _, id := range d.Relationship(purpose) {
+		if d.absoluteID(id) == vmID {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RotateKey retires every active #keys-N method from retireAt and adds pub as #keys-N,
+// valid from now. Old methods are kept so proofs created before retireAt still verify, and
+// keys added with AddKey are left alone. It returns the new method ID.
+func (d *Document) RotateKey(pub *PublicKey, now, retireAt time.Time) string {
+	next := d.NextKeyIndex()
+	for i := range d.VerificationMethod {
+		vm := &d.VerificationMethod[i]
+		if vm.Retired == "" && keyIndex(d.ID, vm.ID) > 0 {
+			vm.Retired = retireAt.UTC().Format(time.RFC3339)
+		}
+	}
+	vmID := fmt.Sprintf("%s#keys-%d", d.ID, next)
+	d.VerificationMethod = append(d.VerificationMethod, VerificationMethod{
+		ID:              vmID,
+		Type:            pub.Type.VerificationMethodType(),
+		Controller:      d.ID,
+		PublicKeyBase58: base58.Encode(pub.Raw),
+		Created:         now.UTC().Format(time.RFC3339),
+	})
+	d.Authentication = append(d.Authentication, vmID)
+	d.AssertionMethod = append(d.AssertionMethod, vmID)
+	return vmID
+}
+
+// AddKey adds a multikey-encoded public key for purpose and returns its method ID, which is
+// the DID followed by the multikey as fragment. authentication and assertionMethod take
+// signing keys; keyAgreement takes X25519 keys. Added keys never become CurrentMethod.
+func (d *Document) AddKey(multikey, purpose string, now time.Time) (string, error) {
+	vm := VerificationMethod{
+		ID:         d.ID + "#" + multikey,
+		Controller: d.ID,
+		Created:    now.UTC().Format(time.RFC3339),
+	}
+	if _, err := d.Method(vm.ID); err == nil {
+		return "", fmt.Errorf("%s is already in %s", multikey, d.ID)
+	}
+	switch purpose {
+	case PurposeAuthentication, PurposeAssertionMethod:
+		pub, err := ParseMultikey(multikey)
+		if err != nil {
+			return "", fmt.Errorf("invalid key %q: %w", multikey, err)
+		}
+		vm.Type = pub.Type.VerificationMethodType()
+		vm.PublicKeyBase58 = base58.Encode(pub.Raw)
+		if purpose == PurposeAuthentication {
+			d.Authentication = append(d.Authentication, vm.ID)
+		} else {
+			d.AssertionMethod = append(d.AssertionMethod, vm.ID)
+		}
+	case PurposeKeyAgreement:
+		raw, err := decodeMultikey(multikey)
+		if err != nil || !bytes.HasPrefix(raw, x25519Multicodec) {
+			return "", fmt.Errorf("key agreement keys must be X25519 multikeys")
+		}
+		if _, err := ecdh.X25519().NewPublicKey(raw[len(x25519Multicodec):]); err != nil {
+			return "", fmt.Errorf("invalid key agreement key: %w", err)
+		}
+		vm.Type = X25519KeyAgreementType
+		vm.PublicKeyBase58 = base58.Encode(raw[len(x25519Multicodec):])
+		d.KeyAgreement = append(d.KeyAgreement, vm.ID)
+	default:
+		return "", fmt.Errorf("unknown purpose %q; use %s, %s or %s", purpose, PurposeAssertionMethod, PurposeAuthentication, PurposeKeyAgreement)
+	}
+	d.VerificationMethod = append(d.VerificationMethod, vm)
+	return vm.ID, nil
+}
+
+// AddController makes did a controller of the document. The first controller added also
+// lists the document's own DID, so the subject keeps control of it.
+func (d *Document) AddController(did string) error {
+	if !strings.HasPrefix(did, "did:") {
+		return fmt.Errorf("controller %q is not a DID", did)
+	}
+	if len(d.Controller) == 0 && did != d.ID {
+		d.Controller = Controllers{d.ID}
+	}
+	if contains(d.Controller, did) {
+		return fmt.Errorf("%s already controls %s", did, d.ID)
+	}
+	d.Controller = append(d.Controller, did)
+	return nil
+}
+
+// AddService adds svc to the document. An ID starting with '#' is relative to the DID.
+func (d *Document) AddService(svc Service) error {
+	if svc.Type == "" || svc.ServiceEndpoint == nil || svc.ServiceEndpoint == "" {
+		return fmt.Errorf("a service needs a type and an endpoint")
+	}
+	svc.ID = d.absoluteID(svc.ID)
+	if !strings.Contains(svc.ID, "#") {
+		return fmt.Errorf("service id %q must be a fragment such as #messaging", svc.ID)
+	}
+	for _, s := range d.Service {
+		if s.ID == svc.ID {
+			return fmt.Errorf("service %s already exists", svc.ID)
+		}
+	}
+	d.Service = append(d.Service, svc)
+	return nil
+}
+
+// RemoveService removes the service with the given ID, absolute or relative to the DID.
+func (d *Document) RemoveService(id string) error {
+	id = d.absoluteID(id)
+	for i, s := range d.Service {
+		if s.ID == id {
+			d.Service = append(d.Service[:i], d.Service[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("service %s not found in %s", id, d.ID)
+}
+
+func (d *Document) absoluteID(id string) string {
+	if strings.HasPrefix(id, "#") {
+		return d.ID + id
+	}
+	return id
+}
+
+// NextKeyIndex returns the N that RotateKey will assign to the next #keys-N method.
+func (d *Document) NextKeyIndex() int {
+	next := 1
+	for _, vm := range d.VerificationMethod {
+		if n := keyIndex(d.ID, vm.ID); n >= next {
+			next = n + 1
+		}
+	}
+	return next
+}
+
+// keyIndex extracts N from "<did>#keys-N", or 0 if id has another form.
+func keyIndex(did, id string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(id, did+"#keys-"))
+	if err != nil {
+		return 0
+	}
+	return n
+}
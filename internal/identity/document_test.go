@@ -0,0 +1,185 @@
+package identity
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mr-tron/base58"
+)
+
+func TestRotateKey(t *testing.T) {
+	pub1 := mustGenerate(t, KeyTypeEd25519).Public()
+	pub2 := mustGenerate(t, KeyTypeEd25519).Public()
+	did := "did:web:example.com"
+	doc := NewDocument(did, pub1)
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	vmID := doc.RotateKey(pub2, now, now)
+	if vmID != did+"#keys-2" {
+		t.Errorf("new method ID = %s; want #keys-2", vmID)
+	}
+	if len(doc.VerificationMethod) != 2 || len(doc.Authentication) != 2 {
+		t.Fatalf("old method must be kept: %+v", doc)
+	}
+	cur, err := doc.CurrentMethod()
+	if err != nil || cur.ID != vmID {
+		t.Fatalf("CurrentMethod = %v, %v; want %s", cur, err, vmID)
+	}
+
+	old, _ := doc.Method(did + "#keys-1")
+	if err := old.CheckValidAt(now.Add(-time.Minute)); err != nil {
+		t.Errorf("old key should be valid before retirement: %v", err)
+	}
+	if err := old.CheckValidAt(now); err == nil {
+		t.Error("old key should be retired at rotation time")
+	}
+	if err := cur.CheckValidAt(now.Add(-time.Minute)); err == nil {
+		t.Error("new key should not be valid before its creation")
+	}
+
+	// a second rotation continues the numbering
+	pub3 := mustGenerate(t, KeyTypeEd25519).Public()
+	if id := doc.RotateKey(pub3, now.Add(time.Hour), now.Add(time.Hour)); id != did+"#keys-3" {
+		t.Errorf("third method ID = %s; want #keys-3", id)
+	}
+}
+
+func TestParseDIDDocumentRoundTrip(t *testing.T) {
+	pub := mustGenerate(t, KeyTypeEd25519).Public()
+	did := GenerateDIDForKey(pub)
+	data, err := BuildDIDDocument(did, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ParseDIDDocument(data)
+	if err != nil {
+		t.Fatalf("ParseDIDDocument failed: %v", err)
+	}
+	vm, err := doc.Method(did + "#keys-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := vm.PublicKey()
+	if err != nil || !got.Equal(pub) {
+		t.Errorf("public key round trip failed: %v", err)
+	}
+}
+
+func TestDocumentEdits(t *testing.T) {
+	own := mustGenerate(t, KeyTypeEd25519).Public()
+	did := "did:web:example.com"
+	doc := NewDocument(did, own)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	device := mustGenerate(t, KeyTypeP256).Public()
+	vmID, err := doc.AddKey(device.Multikey(), PurposeAssertionMethod, now)
+	if err != nil {
+		t.Fatalf("AddKey: %v", err)
+	}
+	if vmID != did+"#"+device.Multikey() || !doc.HasRelationship(PurposeAssertionMethod, vmID) || doc.HasRelationship(PurposeAuthentication, vmID) {
+		t.Errorf("assertion key not added: %s, %v", vmID, doc.AssertionMethod)
+	}
+	vm, _ := doc.Method(vmID)
+	if pub, err := vm.PublicKey(); err != nil || !pub.Equal(device) {
+		t.Errorf("added key does not decode: %v", err)
+	}
+	if _, err := doc.AddKey(device.Multikey(), PurposeAuthentication, now); err == nil {
+		t.Error("adding the same key twice must fail")
+	}
+	if _, err := doc.AddKey(device.Multikey(), PurposeKeyAgreement, now); err == nil {
+		t.Error("a signing key must not be accepted for keyAgreement")
+	}
+	if _, err := doc.AddKey(device.Multikey(), "capabilityInvocation", now); err == nil {
+		t.Error("unknown purposes must be rejected")
+	}
+	x, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	xKey := "z" + base58.Encode(append(append([]byte(nil), x25519Multicodec...), x.PublicKey().Bytes()...))
+	if _, err := doc.AddKey(xKey, PurposeKeyAgreement, now); err != nil || len(doc.KeyAgreement) != 1 {
+		t.Errorf("AddKey keyAgreement: %v", err)
+	}
+
+	// added keys never take over signing, and rotation leaves them alone
+	if cur, _ := doc.CurrentMethod(); cur.ID != did+"#keys-1" {
+		t.Errorf("CurrentMethod = %s; want #keys-1", cur.ID)
+	}
+	doc.RotateKey(mustGenerate(t, KeyTypeEd25519).Public(), now, now)
+	if cur, _ := doc.CurrentMethod(); cur.ID != did+"#keys-2" {
+		t.Errorf("CurrentMethod after rotation = %s; want #keys-2", cur.ID)
+	}
+	if vm, _ := doc.Method(vmID); vm.Retired != "" {
+		t.Error("rotation retired an added key")
+	}
+
+	if err := doc.AddService(Service{ID: "#messaging", Type: DIDCommMessaging, ServiceEndpoint: "https://example.com/didcomm"}); err != nil {
+		t.Fatalf("AddService: %v", err)
+	}
+	if doc.Service[0].ID != did+"#messaging" {
+		t.Errorf("service id = %s", doc.Service[0].ID)
+	}
+	if err := doc.AddService(Service{ID: did + "#messaging", Type: "LinkedDomains", ServiceEndpoint: "https://example.com"}); err == nil {
+		t.Error("duplicate service ids must be rejected")
+	}
+	if err := doc.RemoveService("#messaging"); err != nil || len(doc.Service) != 0 {
+		t.Errorf("RemoveService: %v", err)
+	}
+	if err := doc.RemoveService("#messaging"); err == nil {
+		t.Error("removing a missing service must fail")
+	}
+
+	if err := doc.AddController("did:web:admin.example"); err != nil {
+		t.Fatalf("AddController: %v", err)
+	}
+	if len(doc.Controller) != 2 || doc.Controller[0] != did {
+		t.Errorf("controllers = %v; the subject must keep control", doc.Controller)
+	}
+	if err := doc.AddController("admin"); err == nil {
+		t.Error("a controller that is not a DID must be rejected")
+	}
+}
+
+func TestControllersJSON(t *testing.T) {
+	var doc Document
+	if err := json.Unmarshal([]byte(`{"id":"did:web:a","controller":"did:web:b"}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Controller) != 1 || doc.Controller[0] != "did:web:b" {
+		t.Errorf("string controller = %v", doc.Controller)
+	}
+	data, _ := json.Marshal(doc.Controller)
+	if string(data) != `"did:web:b"` {
+		t.Errorf("single controller marshals as %s", data)
+	}
+	if err := json.Unmarshal([]byte(`{"id":"did:web:a","controller":["did:web:a","did:web:b"]}`), &doc); err != nil || len(doc.Controller) != 2 {
+		t.Errorf("controller set = %v, %v", doc.Controller, err)
+	}
+	if data, _ := json.Marshal(&Document{ID: "did:web:a"}); strings.Contains(string(data), "controller") {
+		t.Errorf("empty controller must be omitted: %s", data)
+	}
+}
+
+func TestMethodFor(t *testing.T) {
+	pub := mustGenerate(t, KeyTypeEd25519).Public()
+	did, err := GeneratePeerDID2(&PeerKeys{Authentication: []*PublicKey{pub}, Assertion: []*PublicKey{pub}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ParsePeerDID(did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for purpose, want := range map[string]string{PurposeAuthentication: did + "#key-1", PurposeAssertionMethod: did + "#key-2"} {
+		if vm, err := doc.MethodFor(purpose); err != nil || vm.ID != want {
+			t.Errorf("MethodFor(%s) = %v, %v; want %s", purpose, vm, err, want)
+		}
+	}
+
+	legacy := NewDocument("did:web:example.com", pub)
+	legacy.AssertionMethod = nil
+	if _, err := legacy.MethodFor(PurposeAssertionMethod); err == nil {
+		t.Error("a document without assertionMethod has no method to issue with")
+	}
+}
@@ -1,41 +1,68 @@
 package identity
 
 import (
+	"bytes"
 	"crypto/ed25519"
-	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/mr-tron/base58"
 )
 
+const didKeyPrefix = "did:key:z"
+
 func GenerateKeyPair() (ed25519.PublicKey, ed25519.PrivateKey, error) {
 	return ed25519.GenerateKey(nil)
 }
 
 func GenerateDID(pub ed25519.PublicKey) string {
-	prefix := []byte{0xED, 0x01}
-	data := append(prefix, pub...)
-	enc := base58.Encode(data)
-	return fmt.Sprintf("did:key:z%s", enc)
+	return GenerateDIDForKey(&PublicKey{Type: KeyTypeEd25519, Raw: pub})
+}
+
+// GenerateDIDForKey encodes pub as a did:key, prefixed with its multicodec.
+func GenerateDIDForKey(pub *PublicKey) string {
+	return "did:key:" + pub.Multikey()
+}
+
+// ParseDIDKey decodes the public key embedded in a did:key identifier.
+func ParseDIDKey(did string) (*PublicKey, error) {
+	if !strings.HasPrefix(did, didKeyPrefix) {
+		return nil, fmt.Errorf("unsupported DID method")
+	}
+	return ParseMultikey(did[len("did:key:"):])
+}
+
+// Multikey returns pub with its multicodec prefix as a base58btc multibase string, the
+// form embedded in did:key and did:peer identifiers.
+func (p *PublicKey) Multikey() string {
+	return "z" + base58.Encode(append(append([]byte(nil), keyTypes[p.Type].multicodec...), p.Raw...))
+}
+
+// ParseMultikey decodes a key encoded by Multikey.
+func ParseMultikey(s string) (*PublicKey, error) {
+	raw, err := decodeMultikey(s)
+	if err != nil {
+		return nil, err
+	}
+	for kt, info := range keyTypes {
+		if bytes.HasPrefix(raw, info.multicodec) {
+			return ParsePublicKey(kt, raw[len(info.multicodec):])
+		}
+	}
+	return nil, fmt.Errorf("invalid multicodec prefix")
+}
+
+func decodeMultikey(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "z") {
+		return nil, fmt.Errorf("unsupported multibase encoding")
+	}
+	return base58.Decode(s[1:])
 }
 
 func EncodePrivateKey(priv ed25519.PrivateKey) string {
 	return base58.Encode(priv)
 }
 
-func BuildDIDDocument(did string, pub ed25519.PublicKey) ([]byte, error) {
-	vmID := did + "#keys-1"
-	pubBase58 := base58.Encode(pub)
-	doc := map[string]interface{}{
-		"@context": []interface{}{"https://www.w3.org/ns/did/v1"},
-		"id":       did,
-		"verificationMethod": []map[string]interface{}{{
-			"id":              vmID,
-			"type":            "Ed25519VerificationKey2018",
-			"controller":      did,
-			"publicKeyBase58": pubBase58,
-		}},
-		"authentication": []string{vmID},
-	}
-	return json.MarshalIndent(doc, "", "  ")
+func BuildDIDDocument(did string, pub *PublicKey) ([]byte, error) {
+	return NewDocument(did, pub).Marshal()
 }
@@ -0,0 +1,118 @@
+package identity
+
+import (
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+const (
+	didJWKPrefix = "did:jwk:"
+
+	// JWKVerificationMethodType is the verification method type of keys given as publicKeyJwk.
+	JWKVerificationMethodType = "JsonWebKey2020"
+)
+
+// JWK is the public JSON Web Key form of a key, as embedded in did:jwk identifiers and
+// JsonWebKey2020 verification methods.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWK returns the JSON Web Key of p. Ed25519 keys are OKP keys; ECDSA keys are EC keys
+// with their uncompressed coordinates.
+func (p *PublicKey) JWK() (*JWK, error) {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch p.Type {
+	case KeyTypeEd25519:
+		return &JWK{Kty: "OKP", Crv: "Ed25519", X: b64(p.Raw)}, nil
+	case KeyTypeSecp256k1:
+		pub, err := secp256k1.ParsePubKey(p.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secp256k1 public key: %w", err)
+		}
+		xy := pub.SerializeUncompressed()[1:]
+		return &JWK{Kty: "EC", Crv: "secp256k1", X: b64(xy[:32]), Y: b64(xy[32:])}, nil
+	case KeyTypeP256:
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), p.Raw)
+		if x == nil {
+			return nil, fmt.Errorf("invalid P-256 public key")
+		}
+		return &JWK{Kty: "EC", Crv: "P-256", X: b64(x.FillBytes(make([]byte, 32))), Y: b64(y.FillBytes(make([]byte, 32)))}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", p.Type)
+}
+
+// PublicKey decodes the key described by j.
+func (j *JWK) PublicKey() (*PublicKey, error) {
+	x, err := base64.RawURLEncoding.DecodeString(j.X)
+	if err != nil {
+		return nil, fmt.Errorf("decode JWK x: %w", err)
+	}
+	switch {
+	case j.Kty == "OKP" && j.Crv == "Ed25519":
+		return ParsePublicKey(KeyTypeEd25519, x)
+	case j.Kty == "EC" && (j.Crv == "secp256k1" || j.Crv == "P-256"):
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decode JWK y: %w", err)
+		}
+		if len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("invalid %s JWK coordinates", j.Crv)
+		}
+		uncompressed := append(append([]byte{4}, x...), y...)
+		if j.Crv == "secp256k1" {
+			pub, err := secp256k1.ParsePubKey(uncompressed)
+			if err != nil {
+				return nil, fmt.Errorf("invalid secp256k1 JWK: %w", err)
+			}
+			return &PublicKey{Type: KeyTypeSecp256k1, Raw: pub.SerializeCompressed()}, nil
+		}
+		// NewPublicKey checks that the point is on the curve
+		if _, err := ecdh.P256().NewPublicKey(uncompressed); err != nil {
+			return nil, fmt.Errorf("invalid P-256 JWK: %w", err)
+		}
+		raw := elliptic.MarshalCompressed(elliptic.P256(), new(big.Int).SetBytes(x), new(big.Int).SetBytes(y))
+		return &PublicKey{Type: KeyTypeP256, Raw: raw}, nil
+	}
+	return nil, fmt.Errorf("unsupported JWK kty %q crv %q", j.Kty, j.Crv)
+}
+
+// GenerateDIDJWK encodes pub as a did:jwk.
+func GenerateDIDJWK(pub *PublicKey) (string, error) {
+	jwk, err := pub.JWK()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(jwk)
+	if err != nil {
+		return "", err
+	}
+	return didJWKPrefix + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// ParseDIDJWK decodes the key embedded in a did:jwk identifier.
+func ParseDIDJWK(did string) (*PublicKey, error) {
+	enc, ok := strings.CutPrefix(did, didJWKPrefix)
+	if !ok {
+		return nil, fmt.Errorf("unsupported DID method")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil {
+		return nil, fmt.Errorf("decode did:jwk: %w", err)
+	}
+	var jwk JWK
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, fmt.Errorf("parse did:jwk: %w", err)
+	}
+	return jwk.PublicKey()
+}
@@ -0,0 +1,49 @@
+package identity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDIDJWKRoundTrip(t *testing.T) {
+	for _, kt := range []KeyType{KeyTypeEd25519, KeyTypeSecp256k1, KeyTypeP256} {
+		pub := mustGenerate(t, kt).Public()
+		did, err := GenerateDIDJWK(pub)
+		if err != nil {
+			t.Fatalf("%s: GenerateDIDJWK: %v", kt, err)
+		}
+		if !strings.HasPrefix(did, "did:jwk:ey") {
+			t.Errorf("%s: unexpected DID %s", kt, did)
+		}
+		got, err := ParseDIDJWK(did)
+		if err != nil || !got.Equal(pub) {
+			t.Errorf("%s: ParseDIDJWK = %v, %v", kt, got, err)
+		}
+	}
+}
+
+func TestParseDIDJWKSpecExample(t *testing.T) {
+	// P-256 example from the did:jwk specification
+	did := "did:jwk:eyJjcnYiOiJQLTI1NiIsImt0eSI6IkVDIiwieCI6ImFjYklRaXVNczNpOF91c3pFakoydHBUdFJNNEVVM3l6OTFQSDZDZEgyVjAiLCJ5IjoiX0tjeUxqOXZXTXB0bm1LdG00NkdxRHo4d2Y3NEk1TEtncmwyR3pIM25TRSJ9"
+	pub, err := ParseDIDJWK(did)
+	if err != nil {
+		t.Fatalf("ParseDIDJWK: %v", err)
+	}
+	jwk, _ := pub.JWK()
+	if pub.Type != KeyTypeP256 || jwk.X != "acbIQiuMs3i8_uszEjJ2tpTtRM4EU3yz91PH6CdH2V0" {
+		t.Errorf("unexpected key %+v", jwk)
+	}
+}
+
+func TestParseDIDJWKRejectsBadKeys(t *testing.T) {
+	for _, did := range []string{
+		"did:key:z6Mk",
+		"did:jwk:not-base64!",
+		"did:jwk:eyJrdHkiOiJSU0EifQ", // {"kty":"RSA"}
+		"did:jwk:eyJrdHkiOiJFQyIsImNydiI6IlAtMjU2IiwieCI6IkFBQUEiLCJ5IjoiQUFBQSJ9", // short coordinates
+	} {
+		if _, err := ParseDIDJWK(did); err == nil {
+			t.Errorf("ParseDIDJWK(%s) should fail", did)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package identity
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// KeyType names a supported signature key algorithm.
+type KeyType string
+
+const (
+	KeyTypeEd25519   KeyType = "Ed25519"
+	KeyTypeSecp256k1 KeyType = "secp256k1"
+	KeyTypeP256      KeyType = "P-256"
+)
+
+// keyTypeInfo describes how a key type appears in did:key identifiers, DID Documents and proofs.
+type keyTypeInfo struct {
+	multicodec []byte // varint-encoded multicodec prefix of the public key
+	vmType     string
+	suite      string
+	jwsAlg     string
+}
+
+var keyTypes = map[KeyType]keyTypeInfo{
+	KeyTypeEd25519:   {[]byte{0xed, 0x01}, "Ed25519VerificationKey2018", "Ed25519Signature2018", "EdDSA"},
+	KeyTypeSecp256k1: {[]byte{0xe7, 0x01}, "EcdsaSecp256k1VerificationKey2019", "EcdsaSecp256k1Signature2019", "ES256K"},
+	KeyTypeP256:      {[]byte{0x80, 0x24}, "EcdsaSecp256r1VerificationKey2019", "EcdsaSecp256r1Signature2019", "ES256"},
+}
+
+// ParseKeyType accepts the canonical names plus common aliases (ed25519, es256k, p256, es256).
+func ParseKeyType(s string) (KeyType, error) {
+	switch strings.ToLower(s) {
+	case "", "ed25519", "eddsa":
+		return KeyTypeEd25519, nil
+	case "secp256k1", "es256k":
+		return KeyTypeSecp256k1, nil
+	case "p-256", "p256", "secp256r1", "es256":
+		return KeyTypeP256, nil
+	}
+	return "", fmt.Errorf("unsupported key type %q", s)
+}
+
+func (kt KeyType) info() (keyTypeInfo, error) {
+	info, ok := keyTypes[kt]
+	if !ok {
+		return keyTypeInfo{}, fmt.Errorf("unsupported key type %q", kt)
+	}
+	return info, nil
+}
+
+// VerificationMethodType returns the DID Document verification method type for kt.
+func (kt KeyType) VerificationMethodType() string { return keyTypes[kt].vmType }
+
+// SignatureSuite returns the proof type produced by keys of type kt.
+func (kt KeyType) SignatureSuite() string { return keyTypes[kt].suite }
+
+// JWSAlgorithm returns the JOSE "alg" for kt.
+func (kt KeyType) JWSAlgorithm() string { return keyTypes[kt].jwsAlg }
+
+// keyTypeForVerificationMethod maps a verification method type back to its key type.
+func keyTypeForVerificationMethod(vmType string) (KeyType, error) {
+	for kt, info := range keyTypes {
+		if info.vmType == vmType {
+			return kt, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported verification method type %q", vmType)
+}
+
+// PrivateKey is a signing key of any supported type.
+// Raw holds the 64-byte Ed25519 private key or the 32-byte ECDSA scalar.
+type PrivateKey struct {
+	Type KeyType
+	Raw  []byte
+}
+
+// PublicKey is a verification key of any supported type.
+// Raw holds the 32-byte Ed25519 key or the 33-byte compressed ECDSA point.
+type PublicKey struct {
+	Type KeyType
+	Raw  []byte
+}
+
+// GenerateKey creates a new private key of type kt.
+func GenerateKey(kt KeyType) (*PrivateKey, error) {
+	switch kt {
+	case KeyTypeEd25519:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return &PrivateKey{Type: kt, Raw: priv}, nil
+	case KeyTypeSecp256k1:
+		priv, err := secp256k1.GeneratePrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		return &PrivateKey{Type: kt, Raw: priv.Serialize()}, nil
+	case KeyTypeP256:
+		priv, err := ecdh.P256().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return &PrivateKey{Type: kt, Raw: priv.Bytes()}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", kt)
+}
+
+// ParsePrivateKey validates raw as a private key of type kt.
+func ParsePrivateKey(kt KeyType, raw []byte) (*PrivateKey, error) {
+	switch kt {
+	case KeyTypeEd25519:
+		if len(raw) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 private key length %d", len(raw))
+		}
+	case KeyTypeSecp256k1, KeyTypeP256:
+		if len(raw) != 32 {
+			return nil, fmt.Errorf("invalid %s private key length %d", kt, len(raw))
+		}
+		if kt == KeyTypeP256 {
+			if _, err := ecdh.P256().NewPrivateKey(raw); err != nil {
+				return nil, fmt.Errorf("invalid P-256 private key: %w", err)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", kt)
+	}
+	return &PrivateKey{Type: kt, Raw: append([]byte(nil), raw...)}, nil
+}
+
+// FromEd25519 wraps an Ed25519 private key.
+func FromEd25519(priv ed25519.PrivateKey) *PrivateKey {
+	return &PrivateKey{Type: KeyTypeEd25519, Raw: priv}
+}
+
+// Public returns the public half of k.
+func (k *PrivateKey) Public() *PublicKey {
+	switch k.Type {
+	case KeyTypeEd25519:
+		return &PublicKey{Type: k.Type, Raw: []byte(ed25519.PrivateKey(k.Raw).Public().(ed25519.PublicKey))}
+	case KeyTypeSecp256k1:
+		return &PublicKey{Type: k.Type, Raw: secp256k1.PrivKeyFromBytes(k.Raw).PubKey().SerializeCompressed()}
+	case KeyTypeP256:
+		priv, err := ecdh.P256().NewPrivateKey(k.Raw)
+		if err != nil {
+			return nil
+		}
+		uncompressed := priv.PublicKey().Bytes()
+		x, y := new(big.Int).SetBytes(uncompressed[1:33]), new(big.Int).SetBytes(uncompressed[33:])
+		return &PublicKey{Type: k.Type, Raw: elliptic.MarshalCompressed(elliptic.P256(), x, y)}
+	}
+	return nil
+}
+
+// Sign signs data. Ed25519 signs the message directly; ECDSA keys sign its SHA-256
+// digest and return the 64-byte r||s encoding used by JOSE.
+func (k *PrivateKey) Sign(data []byte) ([]byte, error) {
+	switch k.Type {
+	case KeyTypeEd25519:
+		return ed25519.Sign(ed25519.PrivateKey(k.Raw), data), nil
+	case KeyTypeSecp256k1:
+		digest := sha256.Sum256(data)
+		sig := secpecdsa.Sign(secp256k1.PrivKeyFromBytes(k.Raw), digest[:])
+		r, s := sig.R(), sig.S()
+		out := make([]byte, 64)
+		r.PutBytesUnchecked(out[:32])
+		s.PutBytesUnchecked(out[32:])
+		return out, nil
+	case KeyTypeP256:
+		pub := k.Public()
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), pub.Raw)
+		priv := &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y},
+			D:         new(big.Int).SetBytes(k.Raw),
+		}
+		digest := sha256.Sum256(data)
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, 64)
+		r.FillBytes(out[:32])
+		s.FillBytes(out[32:])
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Type)
+}
+
+// Wipe overwrites the key material in memory.
+func (k *PrivateKey) Wipe() {
+	for i := range k.Raw {
+		k.Raw[i] = 0
+	}
+	k.Raw = nil
+}
+
+// ParsePublicKey validates raw as a public key of type kt.
+func ParsePublicKey(kt KeyType, raw []byte) (*PublicKey, error) {
+	switch kt {
+	case KeyTypeEd25519:
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 public key length %d", len(raw))
+		}
+	case KeyTypeSecp256k1:
+		if _, err := secp256k1.ParsePubKey(raw); err != nil {
+			return nil, fmt.Errorf("invalid secp256k1 public key: %w", err)
+		}
+	case KeyTypeP256:
+		if x, _ := elliptic.UnmarshalCompressed(elliptic.P256(), raw); x == nil {
+			return nil, fmt.Errorf("invalid P-256 public key")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", kt)
+	}
+	return &PublicKey{Type: kt, Raw: append([]byte(nil), raw...)}, nil
+}
+
+// Equal reports whether p and o are the same key.
+func (p *PublicKey) Equal(o *PublicKey) bool {
+	return o != nil && p.Type == o.Type && bytes.Equal(p.Raw, o.Raw)
+}
+
+// Verify checks sig over data as produced by PrivateKey.Sign.
+func (p *PublicKey) Verify(data, sig []byte) bool {
+	switch p.Type {
+	case KeyTypeEd25519:
+		return len(p.Raw) == ed25519.PublicKeySize && ed25519.Verify(ed25519.PublicKey(p.Raw), data, sig)
+	case KeyTypeSecp256k1:
+		if len(sig) != 64 {
+			return false
+		}
+		pub, err := secp256k1.ParsePubKey(p.Raw)
+		if err != nil {
+			return false
+		}
+		var r, s secp256k1.ModNScalar
+		if r.SetByteSlice(sig[:32]) || s.SetByteSlice(sig[32:]) {
+			return false
+		}
+		digest := sha256.Sum256(data)
+		return secpecdsa.NewSignature(&r, &s).Verify(digest[:], pub)
+	case KeyTypeP256:
+		if len(sig) != 64 {
+			return false
+		}
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), p.Raw)
+		if x == nil {
+			return false
+		}
+		digest := sha256.Sum256(data)
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		return ecdsa.Verify(pub, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:]))
+	}
+	return false
+}
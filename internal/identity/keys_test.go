@@ -0,0 +1,113 @@
+package identity
+
+import (
+	"strings"
+	"testing"
+)
+
+var allKeyTypes = []KeyType{KeyTypeEd25519, KeyTypeSecp256k1, KeyTypeP256}
+
+func mustGenerate(t *testing.T, kt KeyType) *PrivateKey {
+	t.Helper()
+	priv, err := GenerateKey(kt)
+	if err != nil {
+		t.Fatalf("GenerateKey(%s): %v", kt, err)
+	}
+	return priv
+}
+
+func TestSignVerify(t *testing.T) {
+	msg := []byte("hello minervaid")
+	for _, kt := range allKeyTypes {
+		t.Run(string(kt), func(t *testing.T) {
+			priv := mustGenerate(t, kt)
+			sig, err := priv.Sign(msg)
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			pub := priv.Public()
+			if !pub.Verify(msg, sig) {
+				t.Error("signature did not verify")
+			}
+			if pub.Verify([]byte("tampered"), sig) {
+				t.Error("signature verified over different data")
+			}
+			other := mustGenerate(t, kt).Public()
+			if other.Verify(msg, sig) {
+				t.Error("signature verified with another key")
+			}
+		})
+	}
+}
+
+func TestParsePrivateKeyRoundTrip(t *testing.T) {
+	for _, kt := range allKeyTypes {
+		priv := mustGenerate(t, kt)
+		got, err := ParsePrivateKey(kt, priv.Raw)
+		if err != nil {
+			t.Fatalf("ParsePrivateKey(%s): %v", kt, err)
+		}
+		if !got.Public().Equal(priv.Public()) {
+			t.Errorf("%s: public key differs after round trip", kt)
+		}
+	}
+	if _, err := ParsePrivateKey(KeyTypeSecp256k1, []byte{1, 2, 3}); err == nil {
+		t.Error("expected error for short secp256k1 key")
+	}
+}
+
+func TestDIDKeyRoundTrip(t *testing.T) {
+	prefixes := map[KeyType]string{
+		KeyTypeEd25519:   "did:key:z6Mk",
+		KeyTypeSecp256k1: "did:key:zQ3s",
+		KeyTypeP256:      "did:key:zDn",
+	}
+	for _, kt := range allKeyTypes {
+		pub := mustGenerate(t, kt).Public()
+		did := GenerateDIDForKey(pub)
+		if !strings.HasPrefix(did, prefixes[kt]) {
+			t.Errorf("%s DID %s does not start with %s", kt, did, prefixes[kt])
+		}
+		got, err := ParseDIDKey(did)
+		if err != nil {
+			t.Fatalf("ParseDIDKey(%s): %v", did, err)
+		}
+		if !got.Equal(pub) {
+			t.Errorf("%s: key differs after did:key round trip", kt)
+		}
+	}
+}
+
+func TestVerificationMethodKeyType(t *testing.T) {
+	for _, kt := range allKeyTypes {
+		pub := mustGenerate(t, kt).Public()
+		doc := NewDocument(GenerateDIDForKey(pub), pub)
+		vm, _ := doc.CurrentMethod()
+		if vm.Type != kt.VerificationMethodType() {
+			t.Errorf("%s: method type = %s", kt, vm.Type)
+		}
+		got, err := vm.PublicKey()
+		if err != nil || !got.Equal(pub) {
+			t.Errorf("%s: PublicKey() = %v, %v", kt, got, err)
+		}
+	}
+}
+
+func TestParseKeyType(t *testing.T) {
+	cases := map[string]KeyType{
+		"":          KeyTypeEd25519,
+		"ed25519":   KeyTypeEd25519,
+		"secp256k1": KeyTypeSecp256k1,
+		"ES256K":    KeyTypeSecp256k1,
+		"P-256":     KeyTypeP256,
+		"p256":      KeyTypeP256,
+	}
+	for in, want := range cases {
+		if got, err := ParseKeyType(in); err != nil || got != want {
+			t.Errorf("ParseKeyType(%q) = %s, %v; want %s", in, got, err, want)
+		}
+	}
+	if _, err := ParseKeyType("rsa"); err == nil {
+		t.Error("expected error for unsupported key type")
+	}
+}
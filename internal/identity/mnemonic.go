@@ -0,0 +1,77 @@
+package identity
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39"
+)
+
+// mnemonicEntropyBits gives a 24-word BIP-39 mnemonic.
+const mnemonicEntropyBits = 256
+
+// mnemonicPurpose is the first hardened level of the derivation path m/<purpose>'/<index>'.
+const mnemonicPurpose = 0x45474f // "EGO"
+
+const hardenedOffset = 0x80000000
+
+// ErrInvalidMnemonic is returned for word lists that fail the BIP-39 checksum.
+var ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+// NewMnemonic returns a fresh 24-word BIP-39 mnemonic.
+func NewMnemonic() (string, error) {
+	entropy, err := bip39.NewEntropy(mnemonicEntropyBits)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// NormalizeMnemonic collapses whitespace and case so words read back from a file match.
+func NormalizeMnemonic(mnemonic string) string {
+	return strings.ToLower(strings.Join(strings.Fields(mnemonic), " "))
+}
+
+// DeriveKey derives the Ed25519 key at index from mnemonic using SLIP-0010 hardened
+// derivation along m/<purpose>'/<index>'. The same mnemonic and index always yield the same
+// key, so index 0 can restore a vault and higher indexes can feed rotation or pairwise DIDs.
+func DeriveKey(mnemonic string, index uint32) (*PrivateKey, error) {
+	mnemonic = NormalizeMnemonic(mnemonic)
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, ErrInvalidMnemonic
+	}
+	if index >= hardenedOffset {
+		return nil, fmt.Errorf("key index %d out of range", index)
+	}
+	seed := bip39.NewSeed(mnemonic, "")
+	return FromEd25519(ed25519.NewKeyFromSeed(deriveSeedKey(seed, mnemonicPurpose, index))), nil
+}
+
+// deriveSeedKey returns the SLIP-0010 Ed25519 private key of seed at the hardened path.
+func deriveSeedKey(seed []byte, path ...uint32) []byte {
+	mac := hmac.New(sha512.New, []byte("ed25519 seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, chain := sum[:32], sum[32:]
+	for _, i := range path {
+		key, chain = deriveHardened(key, chain, i)
+	}
+	return key
+}
+
+// deriveHardened computes the SLIP-0010 hardened child i of an Ed25519 node.
+func deriveHardened(key, chain []byte, i uint32) ([]byte, []byte) {
+	data := make([]byte, 0, 37)
+	data = append(data, 0)
+	data = append(data, key...)
+	data = binary.BigEndian.AppendUint32(data, i|hardenedOffset)
+	mac := hmac.New(sha512.New, chain)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	return sum[:32], sum[32:]
+}
@@ -0,0 +1,55 @@
+package identity
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon " +
+	"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art"
+
+func TestDeriveSeedKeySLIP10(t *testing.T) {
+	// SLIP-0010 test vector 1 for ed25519
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	cases := []struct {
+		path []uint32
+		want string
+	}{
+		{nil, "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7"},
+		{[]uint32{0}, "68e0fe46dfb67e368c75379acec591dad19df3cde26e63b93a8e704f1dade7a3"},
+		{[]uint32{0, 1}, "b1d0bad404bf35da785a64ca1ac54b2617211d2777696fbffaf208f746ae84f2"},
+	}
+	for _, c := range cases {
+		if got := hex.EncodeToString(deriveSeedKey(seed, c.path...)); got != c.want {
+			t.Errorf("path %v: got %s; want %s", c.path, got, c.want)
+		}
+	}
+}
+
+func TestDeriveKeyDeterministic(t *testing.T) {
+	k0, err := DeriveKey(testMnemonic, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := DeriveKey("  ABANDON "+testMnemonic[len("abandon "):]+"\n", 0)
+	if !k0.Public().Equal(again.Public()) {
+		t.Error("same mnemonic and index gave different keys")
+	}
+	k1, _ := DeriveKey(testMnemonic, 1)
+	if k0.Public().Equal(k1.Public()) {
+		t.Error("different indexes gave the same key")
+	}
+	if _, err := DeriveKey("abandon abandon abandon", 0); err != ErrInvalidMnemonic {
+		t.Errorf("expected ErrInvalidMnemonic, got %v", err)
+	}
+}
+
+func TestNewMnemonic(t *testing.T) {
+	m, err := NewMnemonic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DeriveKey(m, 0); err != nil {
+		t.Errorf("fresh mnemonic does not derive: %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package identity
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mr-tron/base58"
+)
+
+const (
+	peerPrefix = "did:peer:"
+
+	// X25519KeyAgreementType is the verification method type of X25519 key agreement keys.
+	X25519KeyAgreementType = "X25519KeyAgreementKey2019"
+
+	// DIDCommMessaging is the service type of DIDComm endpoints.
+	DIDCommMessaging = "DIDCommMessaging"
+)
+
+var x25519Multicodec = []byte{0xec, 0x01}
+
+// ErrPeerNumalgo is returned for did:peer variants other than numalgo 0 and 2.
+var ErrPeerNumalgo = errors.New("unsupported did:peer numalgo")
+
+// PeerKeys are the keys and services encoded in a numalgo 2 did:peer.
+type PeerKeys struct {
+	// Authentication keys are encoded with purpose V.
+	Authentication []*PublicKey
+	// Assertion keys are encoded with purpose A.
+	Assertion []*PublicKey
+	// KeyAgreement X25519 keys are encoded with purpose E.
+	KeyAgreement []*ecdh.PublicKey
+	// Services are encoded with purpose S; their IDs are assigned on resolution.
+	Services []Service
+}
+
+// GeneratePeerDID0 returns the numalgo 0 did:peer of pub, which carries the key alone.
+func GeneratePeerDID0(pub *PublicKey) string {
+	return peerPrefix + "0" + pub.Multikey()
+}
+
+// GeneratePeerDID2 returns the numalgo 2 did:peer carrying keys. In the document, keys
+// become #key-1, #key-2, ... in the order authentication, assertion, key agreement, and
+// services become #service, #service-1, ...
+func GeneratePeerDID2(keys *PeerKeys) (string, error) {
+	if len(keys.Authentication) == 0 {
+		return "", fmt.Errorf("did:peer needs an authentication key")
+	}
+	var b strings.Builder
+	b.WriteString(peerPrefix + "2")
+	for _, pub := range keys.Authentication {
+		b.WriteString(".V" + pub.Multikey())
+	}
+	for _, pub := range keys.Assertion {
+		b.WriteString(".A" + pub.Multikey())
+	}
+	for _, pub := range keys.KeyAgreement {
+		if pub.Curve() != ecdh.X25519() {
+			return "", fmt.Errorf("key agreement keys must be X25519")
+		}
+		b.WriteString(".Ez" + base58.Encode(append(append([]byte(nil), x25519Multicodec...), pub.Bytes()...)))
+	}
+	for _, svc := range keys.Services {
+		enc, err := encodePeerService(svc)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(".S" + enc)
+	}
+	return b.String(), nil
+}
+
+// ParsePeerDID builds the DID Document of a numalgo 0 or 2 did:peer from the identifier.
+func ParsePeerDID(did string) (*Document, error) {
+	id, ok := strings.CutPrefix(did, peerPrefix)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("unsupported DID method")
+	}
+	switch id[0] {
+	case '0':
+		pub, err := ParseMultikey(id[1:])
+		if err != nil {
+			return nil, err
+		}
+		return NewDocument(did, pub), nil
+	case '2':
+		return parsePeerDID2(did, id[1:])
+	}
+	return nil, fmt.Errorf("%w %c", ErrPeerNumalgo, id[0])
+}
+
+func parsePeerDID2(did, elements string) (*Document, error) {
+	doc := &Document{Context: []interface{}{didContext}, ID: did, Authentication: []string{}}
+	if !strings.HasPrefix(elements, ".") {
+		return nil, fmt.Errorf("invalid did:peer numalgo 2")
+	}
+	for _, el := range strings.Split(elements[1:], ".") {
+		if len(el) < 2 {
+			return nil, fmt.Errorf("invalid did:peer element %q", el)
+		}
+		purpose, value := el[0], el[1:]
+		if purpose == 'S' {
+			svc, err := decodePeerService(value)
+			if err != nil {
+				return nil, err
+			}
+			svc.ID = did + "#service"
+			if n := len(doc.Service); n > 0 {
+				svc.ID = fmt.Sprintf("%s#service-%d", did, n)
+			}
+			doc.Service = append(doc.Service, *svc)
+			continue
+		}
+		vm := VerificationMethod{
+			ID:         fmt.Sprintf("%s#key-%d", did, len(doc.VerificationMethod)+1),
+			Controller: did,
+		}
+		switch purpose {
+		case 'E':
+			raw, err := decodeMultikey(value)
+			if err != nil || !bytes.HasPrefix(raw, x25519Multicodec) {
+				return nil, fmt.Errorf("invalid did:peer key agreement key %q", value)
+			}
+			if _, err := ecdh.X25519().NewPublicKey(raw[len(x25519Multicodec):]); err != nil {
+				return nil, fmt.Errorf("invalid did:peer key agreement key: %w", err)
+			}
+			vm.Type = X25519KeyAgreementType
+			vm.PublicKeyBase58 = base58.Encode(raw[len(x25519Multicodec):])
+			doc.KeyAgreement = append(doc.KeyAgreement, vm.ID)
+		case 'V', 'A':
+			pub, err := ParseMultikey(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid did:peer key %q: %w", value, err)
+			}
+			vm.Type = pub.Type.VerificationMethodType()
+			vm.PublicKeyBase58 = base58.Encode(pub.Raw)
+			if purpose == 'V' {
+				doc.Authentication = append(doc.Authentication, vm.ID)
+			} else {
+				doc.AssertionMethod = append(doc.AssertionMethod, vm.ID)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported did:peer purpose %c", purpose)
+		}
+		doc.VerificationMethod = append(doc.VerificationMethod, vm)
+	}
+	if len(doc.VerificationMethod) == 0 {
+		return nil, fmt.Errorf("did:peer has no keys")
+	}
+	return doc, nil
+}
+
+// peerAbbreviations shortens service keys and values in numalgo 2 identifiers.
+var peerAbbreviations = map[string]string{
+	"type":            "t",
+	"serviceEndpoint": "s",
+	"routingKeys":     "r",
+	"accept":          "a",
+	DIDCommMessaging:  "dm",
+}
+
+func encodePeerService(svc Service) (string, error) {
+	svc.ID = ""
+	data, err := json.Marshal(svc)
+	if err != nil {
+		return "", err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return "", err
+	}
+	delete(m, "id")
+	data, err = json.Marshal(abbreviate(m, peerAbbreviations))
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodePeerService(s string) (*Service, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, fmt.Errorf("decode did:peer service: %w", err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse did:peer service: %w", err)
+	}
+	expand := make(map[string]string, len(peerAbbreviations))
+	for long, short := range peerAbbreviations {
+		expand[short] = long
+	}
+	if data, err = json.Marshal(abbreviate(m, expand)); err != nil {
+		return nil, err
+	}
+	var svc Service
+	if err := json.Unmarshal(data, &svc); err != nil {
+		return nil, fmt.Errorf("parse did:peer service: %w", err)
+	}
+	return &svc, nil
+}
+
+// abbreviate replaces the keys of m, and the values of its "type"/"t" entries, that
+// appear in table, descending into nested maps. Endpoint URLs are left alone.
+func abbreviate(m map[string]interface{}, table map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			v = abbreviate(nested, table)
+		}
+		if s, ok := v.(string); ok && (k == "type" || k == "t") {
+			if r, ok := table[s]; ok {
+				v = r
+			}
+		}
+		if r, ok := table[k]; ok {
+			k = r
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package identity
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestParsePeerDID2SpecExample(t *testing.T) {
+	// numalgo 2 example from the did:peer specification
+	did := "did:peer:2.Ez6LSbysY2xFMRpGMhb7tFTLMpeuPRaqaWM1yECx2AtzE3KCc.Vz6MkqRYqQiSgvZQdnBytw86Qbs2ZWUkGv22od935YF4s8M7V.Vz6MkgoLTnTypo3tDRwCkZXSccTPHRLhF4ZnjhueYAFpEX6vg.SeyJ0IjoiZG0iLCJzIjoiaHR0cHM6Ly9leGFtcGxlLmNvbS9lbmRwb2ludCIsInIiOlsiZGlkOmV4YW1wbGU6c29tZW1lZGlhdG9yI3NvbWVrZXkiXSwiYSI6WyJkaWRjb21tL3YyIiwiZGlkY29tbS9haXAyO2Vudj1yZmM1ODciXX0"
+	doc, err := ParsePeerDID(did)
+	if err != nil {
+		t.Fatalf("ParsePeerDID: %v", err)
+	}
+	if len(doc.VerificationMethod) != 3 {
+		t.Fatalf("expected 3 keys, got %+v", doc.VerificationMethod)
+	}
+	if len(doc.KeyAgreement) != 1 || doc.KeyAgreement[0] != did+"#key-1" || doc.VerificationMethod[0].Type != X25519KeyAgreementType {
+		t.Errorf("unexpected key agreement %v", doc.KeyAgreement)
+	}
+	if strings.Join(doc.Authentication, " ") != did+"#key-2 "+did+"#key-3" {
+		t.Errorf("unexpected authentication %v", doc.Authentication)
+	}
+	if len(doc.Service) != 1 {
+		t.Fatalf("expected one service, got %+v", doc.Service)
+	}
+	svc := doc.Service[0]
+	if svc.ID != did+"#service" || svc.Type != DIDCommMessaging || svc.ServiceEndpoint != "https://example.com/endpoint" {
+		t.Errorf("unexpected service %+v", svc)
+	}
+	if len(svc.RoutingKeys) != 1 || len(svc.Accept) != 2 {
+		t.Errorf("routing keys or accept lost: %+v", svc)
+	}
+	if vm, err := doc.CurrentMethod(); err != nil || vm.ID != did+"#key-3" {
+		t.Errorf("CurrentMethod = %v, %v; want the last signing key", vm, err)
+	}
+}
+
+func TestPeerDIDRoundTrip(t *testing.T) {
+	sign := mustGenerate(t, KeyTypeEd25519).Public()
+	agree, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	did, err := GeneratePeerDID2(&PeerKeys{
+		Authentication: []*PublicKey{sign},
+		Assertion:      []*PublicKey{sign},
+		KeyAgreement:   []*ecdh.PublicKey{agree.PublicKey()},
+		Services: []Service{
+			{Type: DIDCommMessaging, ServiceEndpoint: "https://alice.example/didcomm"},
+			{Type: "LinkedDomains", ServiceEndpoint: "https://alice.example"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("GeneratePeerDID2: %v", err)
+	}
+	doc, err := ParsePeerDID(did)
+	if err != nil {
+		t.Fatalf("ParsePeerDID(%s): %v", did, err)
+	}
+	for _, id := range []string{doc.Authentication[0], doc.AssertionMethod[0]} {
+		vm, err := doc.Method(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pub, err := vm.PublicKey(); err != nil || !pub.Equal(sign) {
+			t.Errorf("%s does not hold the signing key: %v", id, err)
+		}
+	}
+	if doc.KeyAgreement[0] != did+"#key-3" {
+		t.Errorf("unexpected key agreement ids %v", doc.KeyAgreement)
+	}
+	if len(doc.Service) != 2 || doc.Service[1].ID != did+"#service-1" || doc.Service[0].Type != DIDCommMessaging {
+		t.Errorf("unexpected services %+v", doc.Service)
+	}
+
+	did0 := GeneratePeerDID0(sign)
+	doc, err = ParsePeerDID(did0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vm, _ := doc.CurrentMethod(); vm == nil {
+		t.Error("numalgo 0 document has no key")
+	} else if pub, _ := vm.PublicKey(); !pub.Equal(sign) {
+		t.Error("numalgo 0 document holds the wrong key")
+	}
+
+	for _, bad := range []string{"did:peer:1zQm", "did:peer:2", "did:peer:2.Xz6Mk", "did:peer:2.Vz6Mk", "did:peer:2.Sbm90LWpzb24"} {
+		if _, err := ParsePeerDID(bad); err == nil {
+			t.Errorf("ParsePeerDID(%s) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package jsonld
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxNDegreeCalls bounds the work spent on blank nodes that cannot be told apart by
+// their own statements, so a crafted dataset cannot make canonicalization run for hours.
+const maxNDegreeCalls = 10000
+
+// Canonicalize expands doc, converts it to RDF and returns the dataset in canonical
+// N-Quads, following RDF Dataset Canonicalization (RDFC-1.0) with SHA-256.
+func Canonicalize(doc interface{}, loader Loader) (string, error) {
+	quads, err := ToRDF(doc, loader)
+	if err != nil {
+		return "", err
+	}
+	return CanonicalizeQuads(quads)
+}
+
+// CanonicalizeQuads relabels the blank nodes of quads with canonical identifiers and
+// returns the dataset as sorted canonical N-Quads.
+func CanonicalizeQuads(quads []Quad) (string, error) {
+	c := &canonicalizer{
+		quads:     make(map[string][]Quad),
+		canonical: newIssuer("_:c14n"),
+	}
+	quads = c.index(quads)
+	blanks := make([]string, 0, len(c.quads))
+	for id := range c.quads {
+		blanks = append(blanks, id)
+	}
+	sort.Strings(blanks)
+
+	byHash := make(map[string][]string)
+	for _, id := range blanks {
+		h := c.hashFirstDegree(id)
+		byHash[h] = append(byHash[h], id)
+	}
+	hashes := make([]string, 0, len(byHash))
+	for h := range byHash {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+	var shared []string
+	for _, h := range hashes {
+		if ids := byHash[h]; len(ids) == 1 {
+			c.canonical.id(ids[0])
+		} else {
+			shared = append(shared, h)
+		}
+	}
+	for _, h := range shared {
+		type result struct {
+			hash   string
+			issuer *issuer
+		}
+		var results []result
+		for _, id := range byHash[h] {
+			if c.canonical.has(id) {
+				continue
+			}
+			tmp := newIssuer("_:b")
+			tmp.id(id)
+			hash, iss, err := c.hashNDegree(id, tmp)
+			if err != nil {
+				return "", err
+			}
+			results = append(results, result{hash, iss})
+		}
+		sort.SliceStable(results, func(i, j int) bool { return results[i].hash < results[j].hash })
+		for _, r := range results {
+			for _, id := range r.issuer.order {
+				c.canonical.id(id)
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(quads))
+	for _, q := range quads {
+		q.Subject = c.relabel(q.Subject)
+		q.Object = c.relabel(q.Object)
+		q.Graph = c.relabel(q.Graph)
+		lines = append(lines, nquad(q))
+	}
+	sort.Strings(lines)
+	// a dataset is a set: drop repeated statements
+	out := lines[:0]
+	for i, l := range lines {
+		if i == 0 || l != lines[i-1] {
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, ""), nil
+}
+
+type canonicalizer struct {
+	// quads maps each blank node to the statements it appears in.
+	quads     map[string][]Quad
+	canonical *issuer
+	calls     int
+}
+
+// index drops repeated statements from quads, which form a set, and records the
+// statements of every blank node, each once even when the node appears in it twice. It
+// returns the remaining statements.
+func (c *canonicalizer) index(quads []Quad) []Quad {
+	seen := make(map[Quad]bool, len(quads))
+	out := make([]Quad, 0, len(quads))
+	for _, q := range quads {
+		if seen[q] {
+			continue
+		}
+		seen[q] = true
+		out = append(out, q)
+		added := map[string]bool{}
+		for _, n := range []Node{q.Subject, q.Object, q.Graph} {
+			if n.Kind == BlankNode && n.Value != "" && !added[n.Value] {
+				added[n.Value] = true
+				c.quads[n.Value] = append(c.quads[n.Value], q)
+			}
+		}
+	}
+	return out
+}
+
+func (c *canonicalizer) relabel(n Node) Node {
+	if n.Kind == BlankNode && n.Value != "" {
+		n.Value = c.canonical.issued[n.Value]
+	}
+	return n
+}
+
+// hashFirstDegree is the Hash First Degree Quads algorithm: it hashes the statements of
+// id with id written as _:a and every other blank node as _:z.
+func (c *canonicalizer) hashFirstDegree(id string) string {
+	lines := make([]string, 0, len(c.quads[id]))
+	for _, q := range c.quads[id] {
+		mask := func(n Node) Node {
+			if n.Kind == BlankNode && n.Value != "" {
+				if n.Value == id {
+					n.Value = "_:a"
+				} else {
+					n.Value = "_:z"
+				}
+			}
+			return n
+		}
+		q.Subject, q.Object, q.Graph = mask(q.Subject), mask(q.Object), mask(q.Graph)
+		lines = append(lines, nquad(q))
+	}
+	sort.Strings(lines)
+	return hashString(strings.Join(lines, ""))
+}
+
+// hashRelated is the Hash Related Blank Node algorithm.
+func (c *canonicalizer) hashRelated(related string, q Quad, iss *issuer, position string) string {
+	var id string
+	switch {
+	case c.canonical.has(related):
+		id = c.canonical.issued[related]
+	case iss.has(related):
+		id = iss.issued[related]
+	default:
+		id = c.hashFirstDegree(related)
+	}
+	input := position
+	if position != "g" {
+		input += "<" + q.Predicate.Value + ">"
+	}
+	return hashString(input + id)
+}
+
+// hashNDegree is the Hash N-Degree Quads algorithm.
+func (c *canonicalizer) hashNDegree(id string, iss *issuer) (string, *issuer, error) {
+	c.calls++
+	if c.calls > maxNDegreeCalls {
+		return "", nil, fmt.Errorf("%w: too many indistinguishable blank nodes", ErrInvalidDocument)
+	}
+	related := make(map[string][]string)
+	for _, q := range c.quads[id] {
+		for _, comp := range []struct {
+			n   Node
+			pos string
+		}{{q.Subject, "s"}, {q.Object, "o"}, {q.Graph, "g"}} {
+			if comp.n.Kind != BlankNode || comp.n.Value == "" || comp.n.Value == id {
+				continue
+			}
+			h := c.hashRelated(comp.n.Value, q, iss, comp.pos)
+			related[h] = append(related[h], comp.n.Value)
+		}
+	}
+	hashes := make([]string, 0, len(related))
+	for h := range related {
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+
+	var data strings.Builder
+	for _, h := range hashes {
+		data.WriteString(h)
+		var chosenPath string
+		var chosenIssuer *issuer
+		var err error
+		permute(related[h], func(perm []string) bool {
+			issCopy := iss.clone()
+			var path strings.Builder
+			var recursion []string
+			worse := func() bool {
+				return chosenIssuer != nil && path.Len() >= len(chosenPath) && path.String() > chosenPath
+			}
+			for _, r := range perm {
+				if c.canonical.has(r) {
+					path.WriteString(c.canonical.issued[r])
+				} else {
+					if !issCopy.has(r) {
+						recursion = append(recursion, r)
+					}
+					path.WriteString(issCopy.id(r))
+				}
+				if worse() {
+					return true
+				}
+			}
+			for _, r := range recursion {
+				var hash string
+				var result *issuer
+				hash, result, err = c.hashNDegree(r, issCopy)
+				if err != nil {
+					return false
+				}
+				path.WriteString(result.id(r))
+				path.WriteString("<" + hash + ">")
+				issCopy = result
+				if worse() {
+					return true
+				}
+			}
+			if chosenIssuer == nil || path.String() < chosenPath {
+				chosenPath, chosenIssuer = path.String(), issCopy
+			}
+			return true
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		data.WriteString(chosenPath)
+		iss = chosenIssuer
+	}
+	return hashString(data.String()), iss, nil
+}
+
+// permute calls fn with every permutation of items, in lexicographic order, until fn
+// returns false.
+func permute(items []string, fn func([]string) bool) {
+	perm := append([]string(nil), items...)
+	sort.Strings(perm)
+	for {
+		if !fn(append([]string(nil), perm...)) {
+			return
+		}
+		i := len(perm) - 2
+		for i >= 0 && perm[i] >= perm[i+1] {
+			i--
+		}
+		if i < 0 {
+			return
+		}
+		j := len(perm) - 1
+		for perm[j] <= perm[i] {
+			j--
+		}
+		perm[i], perm[j] = perm[j], perm[i]
+		for l, r := i+1, len(perm)-1; l < r; l, r = l+1, r-1 {
+			perm[l], perm[r] = perm[r], perm[l]
+		}
+	}
+}
+
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
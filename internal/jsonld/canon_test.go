@@ -0,0 +1,185 @@
+package jsonld
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestCanonicalizeSuite runs the RDF Dataset Canonicalization tests in testdata/rdfc10.
+func TestCanonicalizeSuite(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "rdfc10", "*-in.nq"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no tests found: %v", err)
+	}
+	for _, in := range inputs {
+		name := strings.TrimSuffix(filepath.Base(in), "-in.nq")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join("testdata", "rdfc10", name+"-urdna2015.nq"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := CanonicalizeQuads(parseNQuads(t, string(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeRepeatedBlankNode(t *testing.T) {
+	// a statement that uses a blank node twice is one of its statements, not two
+	self := "_:a <http://example.com/p> _:a .\n"
+	c := &canonicalizer{quads: make(map[string][]Quad)}
+	c.index(parseNQuads(t, self+self+"_:a <http://example.com/q> _:b .\n"))
+	if n := len(c.quads["_:a"]); n != 2 {
+		t.Errorf("_:a has %d statements; want 2", n)
+	}
+	got, err := CanonicalizeQuads(parseNQuads(t, self+self))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "_:c14n0 <http://example.com/p> _:c14n0 .\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// parseNQuads parses the N-Quads of the test suite: one statement per line, with IRIs,
+// blank nodes and literals with escapes, language tags or datatypes.
+func parseNQuads(t *testing.T, data string) []Quad {
+	t.Helper()
+	var quads []Quad
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		var nodes []Node
+		rest := strings.TrimSpace(strings.TrimSuffix(line, "."))
+		for rest != "" {
+			var n Node
+			var err error
+			n, rest, err = parseTerm(rest)
+			if err != nil {
+				t.Fatalf("%q: %v", line, err)
+			}
+			nodes = append(nodes, n)
+			rest = strings.TrimLeft(rest, " \t")
+		}
+		if len(nodes) != 3 && len(nodes) != 4 {
+			t.Fatalf("bad quad %q", line)
+		}
+		q := Quad{Subject: nodes[0], Predicate: nodes[1], Object: nodes[2]}
+		if len(nodes) == 4 {
+			q.Graph = nodes[3]
+		}
+		quads = append(quads, q)
+	}
+	return quads
+}
+
+func parseTerm(s string) (Node, string, error) {
+	switch s[0] {
+	case '<':
+		i := strings.IndexByte(s, '>')
+		if i < 0 {
+			return Node{}, "", strconv.ErrSyntax
+		}
+		iri, err := unescapeNQuads(s[1:i])
+		return Node{Kind: IRI, Value: iri}, s[i+1:], err
+	case '"':
+		i := 1
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' {
+				i++
+			}
+		}
+		if i >= len(s) {
+			return Node{}, "", strconv.ErrSyntax
+		}
+		value, err := unescapeNQuads(s[1:i])
+		if err != nil {
+			return Node{}, "", err
+		}
+		n, rest := Node{Kind: Literal, Value: value, Datatype: xsdString}, s[i+1:]
+		switch {
+		case strings.HasPrefix(rest, "@"):
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			n.Datatype, n.Language, rest = rdfLangString, rest[1:end], rest[end:]
+		case strings.HasPrefix(rest, "^^<"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return Node{}, "", strconv.ErrSyntax
+			}
+			n.Datatype, rest = rest[3:end], rest[end+1:]
+		}
+		return n, rest, nil
+	default:
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		return Node{Kind: BlankNode, Value: s[:end]}, s[end:], nil
+	}
+}
+
+// unescapeNQuads resolves the ECHAR and UCHAR escapes of N-Quads.
+func unescapeNQuads(s string) (string, error) {
+	if !strings.ContainsRune(s, '\\') {
+		return s, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return "", strconv.ErrSyntax
+		}
+		switch c := s[i]; c {
+		case 't':
+			sb.WriteByte('\t')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		case '"', '\'', '\\':
+			sb.WriteByte(c)
+		case 'u', 'U':
+			size := 4
+			if c == 'U' {
+				size = 8
+			}
+			if i+size >= len(s) {
+				return "", strconv.ErrSyntax
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(rune(r))
+			i += size
+		default:
+			return "", strconv.ErrSyntax
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,561 @@
+package jsonld
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxRemoteContexts bounds the remote contexts one document may pull in, which also
+// stops contexts that include each other.
+const maxRemoteContexts = 32
+
+var (
+	keywords = map[string]bool{
+		"@base": true, "@container": true, "@context": true, "@default": true, "@direction": true,
+		"@embed": true, "@explicit": true, "@graph": true, "@id": true, "@import": true,
+		"@included": true, "@index": true, "@json": true, "@language": true, "@list": true,
+		"@nest": true, "@none": true, "@omitDefault": true, "@prefix": true, "@preserve": true,
+		"@propagate": true, "@protected": true, "@requireAll": true, "@reverse": true,
+		"@set": true, "@type": true, "@value": true, "@version": true, "@vocab": true,
+	}
+	keywordForm = regexp.MustCompile(`^@[a-zA-Z]+$`)
+	schemeForm  = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// term is a term definition of an active context.
+type term struct {
+	iri       string // "" when the term maps to null
+	reverse   bool
+	typ       string // @id, @vocab, @json, @none or a datatype IRI
+	container map[string]bool
+	context   interface{}
+	hasCtx    bool
+	language  string
+	hasLang   bool
+	direction string
+	hasDir    bool
+	nest      string
+	prefix    bool
+	protected bool
+}
+
+// sameAs reports whether t and o are the same definition apart from protected.
+func (t *term) sameAs(o *term) bool {
+	a, b := *t, *o
+	a.protected, b.protected = false, false
+	return reflect.DeepEqual(a, b)
+}
+
+// activeContext is the state that context processing builds and expansion consults.
+type activeContext struct {
+	terms     map[string]*term
+	base      string
+	vocab     string
+	hasVocab  bool
+	language  string
+	direction string
+	// previous is the context to revert to in new node objects when a type-scoped
+	// context, which does not propagate, was applied.
+	previous *activeContext
+}
+
+func newActiveContext() *activeContext {
+	return &activeContext{terms: make(map[string]*term)}
+}
+
+func (c *activeContext) clone() *activeContext {
+	n := *c
+	n.terms = make(map[string]*term, len(c.terms))
+	for k, v := range c.terms {
+		n.terms[k] = v
+	}
+	return &n
+}
+
+func (c *activeContext) hasProtected() bool {
+	for _, t := range c.terms {
+		if t.protected {
+			return true
+		}
+	}
+	return false
+}
+
+// process is the JSON-LD 1.1 Context Processing algorithm.
+func (p *processor) process(active *activeContext, local interface{}, remote []string, overrideProtected, propagate bool) (*activeContext, error) {
+	result := active.clone()
+	if m, ok := local.(map[string]interface{}); ok {
+		if v, ok := m["@propagate"]; ok {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%w: @propagate must be a boolean", ErrInvalidContext)
+			}
+			propagate = b
+		}
+	}
+	if !propagate && result.previous == nil {
+		result.previous = active
+	}
+	for _, ctx := range asArray(local) {
+		switch c := ctx.(type) {
+		case nil:
+			if !overrideProtected && result.hasProtected() {
+				return nil, fmt.Errorf("%w: cannot nullify a context with protected terms", ErrInvalidContext)
+			}
+			prev := result
+			result = newActiveContext()
+			if !propagate {
+				result.previous = prev
+			}
+			continue
+		case string:
+			iri := p.resolve(active.base, c)
+			for _, r := range remote {
+				if r == iri {
+					return nil, fmt.Errorf("%w: recursive context inclusion of %s", ErrInvalidContext, iri)
+				}
+			}
+			if len(remote) >= maxRemoteContexts {
+				return nil, fmt.Errorf("%w: too many remote contexts", ErrInvalidContext)
+			}
+			doc, err := p.loader.LoadContext(iri)
+			if err != nil {
+				return nil, err
+			}
+			m, ok := doc.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%w: %s is not a context document", ErrInvalidContext, iri)
+			}
+			inner, ok := m["@context"]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s has no @context", ErrInvalidContext, iri)
+			}
+			next := append(append([]string(nil), remote...), iri)
+			if result, err = p.process(result, inner, next, overrideProtected, true); err != nil {
+				return nil, err
+			}
+			continue
+		case map[string]interface{}:
+			var err error
+			if result, err = p.processLocal(result, c, remote, overrideProtected); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%w: context must be an object, a string or null", ErrInvalidContext)
+		}
+	}
+	return result, nil
+}
+
+// processLocal applies one context object to result.
+func (p *processor) processLocal(result *activeContext, c map[string]interface{}, remote []string, overrideProtected bool) (*activeContext, error) {
+	if v, ok := c["@version"]; ok && v != 1.1 {
+		return nil, fmt.Errorf("%w: unsupported @version %v", ErrInvalidContext, v)
+	}
+	if v, ok := c["@import"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: @import must be a string", ErrInvalidContext)
+		}
+		doc, err := p.loader.LoadContext(p.resolve(result.base, s))
+		if err != nil {
+			return nil, err
+		}
+		m, _ := doc.(map[string]interface{})
+		imported, ok := m["@context"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: imported context %s is not an object", ErrInvalidContext, s)
+		}
+		if _, ok := imported["@import"]; ok {
+			return nil, fmt.Errorf("%w: imported context %s imports another", ErrInvalidContext, s)
+		}
+		merged := make(map[string]interface{}, len(imported)+len(c))
+		for k, v := range imported {
+			merged[k] = v
+		}
+		for k, v := range c {
+			if k != "@import" {
+				merged[k] = v
+			}
+		}
+		c = merged
+	}
+	if v, ok := c["@base"]; ok && len(remote) == 0 {
+		switch b := v.(type) {
+		case nil:
+			result.base = ""
+		case string:
+			result.base = p.resolve(result.base, b)
+		default:
+			return nil, fmt.Errorf("%w: invalid @base", ErrInvalidContext)
+		}
+	}
+	if v, ok := c["@vocab"]; ok {
+		switch s := v.(type) {
+		case nil:
+			result.vocab, result.hasVocab = "", false
+		case string:
+			iri, err := p.expandIRI(result, s, true, true, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			result.vocab, result.hasVocab = iri, true
+		default:
+			return nil, fmt.Errorf("%w: invalid @vocab", ErrInvalidContext)
+		}
+	}
+	if v, ok := c["@language"]; ok {
+		switch s := v.(type) {
+		case nil:
+			result.language = ""
+		case string:
+			result.language = strings.ToLower(s)
+		default:
+			return nil, fmt.Errorf("%w: invalid @language", ErrInvalidContext)
+		}
+	}
+	if v, ok := c["@direction"]; ok {
+		switch s := v.(type) {
+		case nil:
+			result.direction = ""
+		case string:
+			if s != "ltr" && s != "rtl" {
+				return nil, fmt.Errorf("%w: invalid @direction %q", ErrInvalidContext, s)
+			}
+			result.direction = s
+		default:
+			return nil, fmt.Errorf("%w: invalid @direction", ErrInvalidContext)
+		}
+	}
+	protected := false
+	if v, ok := c["@protected"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: @protected must be a boolean", ErrInvalidContext)
+		}
+		protected = b
+	}
+	defined := make(map[string]bool)
+	for _, k := range sortedKeys(c) {
+		switch k {
+		case "@base", "@direction", "@import", "@language", "@propagate", "@protected", "@version", "@vocab":
+			continue
+		}
+		if err := p.define(result, c, k, defined, protected, overrideProtected); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// define is the Create Term Definition algorithm.
+func (p *processor) define(active *activeContext, local map[string]interface{}, name string, defined map[string]bool, protected, overrideProtected bool) error {
+	if done, ok := defined[name]; ok {
+		if done {
+			return nil
+		}
+		return fmt.Errorf("%w: cyclic definition of %q", ErrInvalidContext, name)
+	}
+	if name == "" {
+		return fmt.Errorf("%w: empty term", ErrInvalidContext)
+	}
+	defined[name] = false
+	value := local[name]
+	if name == "@type" {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%w: @type can only be given a @container of @set", ErrInvalidContext)
+		}
+		for k, v := range m {
+			if !(k == "@container" && v == "@set") && k != "@protected" {
+				return fmt.Errorf("%w: @type can only be given a @container of @set", ErrInvalidContext)
+			}
+		}
+	} else if keywords[name] {
+		return fmt.Errorf("%w: keyword %s cannot be redefined", ErrInvalidContext, name)
+	} else if keywordForm.MatchString(name) {
+		// reserved for future keywords; ignored
+		defined[name] = true
+		return nil
+	}
+	previous := active.terms[name]
+	delete(active.terms, name)
+
+	simple := false
+	var m map[string]interface{}
+	switch v := value.(type) {
+	case nil:
+		m = map[string]interface{}{"@id": nil}
+	case string:
+		m = map[string]interface{}{"@id": v}
+		simple = true
+	case map[string]interface{}:
+		m = v
+	default:
+		return fmt.Errorf("%w: definition of %q must be a string or an object", ErrInvalidContext, name)
+	}
+	def := &term{protected: protected}
+	for k := range m {
+		switch k {
+		case "@id", "@reverse", "@type", "@container", "@context", "@language", "@direction", "@index", "@nest", "@prefix", "@protected":
+		default:
+			return fmt.Errorf("%w: %q has unknown entry %s", ErrInvalidContext, name, k)
+		}
+	}
+	if v, ok := m["@protected"]; ok {
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("%w: @protected of %q must be a boolean", ErrInvalidContext, name)
+		}
+		def.protected = b
+	}
+	if v, ok := m["@type"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%w: @type of %q must be a string", ErrInvalidContext, name)
+		}
+		typ, err := p.expandIRI(active, s, false, true, local, defined)
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case "@id", "@vocab", "@json", "@none":
+		default:
+			if !isAbsoluteIRI(typ) {
+				return fmt.Errorf("%w: @type of %q is %q", ErrInvalidContext, name, typ)
+			}
+		}
+		def.typ = typ
+	}
+	if v, ok := m["@reverse"]; ok {
+		if _, ok := m["@id"]; ok {
+			return fmt.Errorf("%w: %q has both @reverse and @id", ErrInvalidContext, name)
+		}
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%w: @reverse of %q must be a string", ErrInvalidContext, name)
+		}
+		iri, err := p.expandIRI(active, s, false, true, local, defined)
+		if err != nil {
+			return err
+		}
+		if !isAbsoluteIRI(iri) && !isBlankNode(iri) {
+			return fmt.Errorf("%w: @reverse of %q is %q", ErrInvalidContext, name, iri)
+		}
+		def.iri, def.reverse = iri, true
+	} else if v, ok := m["@id"]; ok && v != name {
+		switch s := v.(type) {
+		case nil:
+			// the term maps to null and its values are dropped
+		case string:
+			if !keywords[s] && keywordForm.MatchString(s) {
+				defined[name] = true
+				return nil
+			}
+			iri, err := p.expandIRI(active, s, false, true, local, defined)
+			if err != nil {
+				return err
+			}
+			if !keywords[iri] && !isAbsoluteIRI(iri) && !isBlankNode(iri) {
+				return fmt.Errorf("%w: %q maps to %q, which is not an IRI", ErrInvalidContext, name, iri)
+			}
+			if iri == "@context" {
+				return fmt.Errorf("%w: %q cannot alias @context", ErrInvalidContext, name)
+			}
+			def.iri = iri
+			if simple && !strings.ContainsAny(name, ":/") && (strings.ContainsAny(iri[len(iri)-1:], ":/?#[]@") || isBlankNode(iri)) {
+				def.prefix = true
+			}
+		default:
+			return fmt.Errorf("%w: @id of %q must be a string", ErrInvalidContext, name)
+		}
+	} else if i := strings.Index(name[1:], ":"); i >= 0 {
+		prefix, suffix := name[:i+1], name[i+2:]
+		if _, ok := local[prefix]; ok {
+			if err := p.define(active, local, prefix, defined, protected, overrideProtected); err != nil {
+				return err
+			}
+		}
+		if t, ok := active.terms[prefix]; ok && t.iri != "" {
+			def.iri = t.iri + suffix
+		} else {
+			def.iri = name
+		}
+	} else if strings.Contains(name, "/") {
+		def.iri = p.resolve(active.base, name)
+		if !isAbsoluteIRI(def.iri) {
+			return fmt.Errorf("%w: %q is not an IRI", ErrInvalidContext, name)
+		}
+	} else if name == "@type" {
+		def.iri = "@type"
+	} else if active.hasVocab {
+		def.iri = active.vocab + name
+	} else {
+		return fmt.Errorf("%w: %q has no IRI and there is no @vocab", ErrInvalidContext, name)
+	}
+	if v, ok := m["@container"]; ok {
+		def.container = make(map[string]bool)
+		for _, c := range asArray(v) {
+			s, ok := c.(string)
+			if !ok {
+				return fmt.Errorf("%w: invalid @container of %q", ErrInvalidContext, name)
+			}
+			switch s {
+			case "@graph", "@id", "@index", "@language", "@list", "@set", "@type":
+				def.container[s] = true
+			default:
+				return fmt.Errorf("%w: invalid @container %s of %q", ErrInvalidContext, s, name)
+			}
+		}
+		if def.reverse {
+			for c := range def.container {
+				if c != "@set" && c != "@index" {
+					return fmt.Errorf("%w: reverse term %q can only be a set or index", ErrInvalidContext, name)
+				}
+			}
+		}
+	}
+	if v, ok := m["@context"]; ok {
+		def.context, def.hasCtx = v, true
+	}
+	if v, ok := m["@language"]; ok {
+		if _, typed := m["@type"]; !typed {
+			switch s := v.(type) {
+			case nil:
+			case string:
+				def.language = strings.ToLower(s)
+			default:
+				return fmt.Errorf("%w: invalid @language of %q", ErrInvalidContext, name)
+			}
+			def.hasLang = true
+		}
+	}
+	if v, ok := m["@direction"]; ok {
+		if _, typed := m["@type"]; !typed {
+			switch s := v.(type) {
+			case nil:
+			case string:
+				if s != "ltr" && s != "rtl" {
+					return fmt.Errorf("%w: invalid @direction of %q", ErrInvalidContext, name)
+				}
+				def.direction = s
+			default:
+				return fmt.Errorf("%w: invalid @direction of %q", ErrInvalidContext, name)
+			}
+			def.hasDir = true
+		}
+	}
+	if v, ok := m["@nest"]; ok {
+		s, ok := v.(string)
+		if !ok || (keywords[s] && s != "@nest") {
+			return fmt.Errorf("%w: invalid @nest of %q", ErrInvalidContext, name)
+		}
+		def.nest = s
+	}
+	if v, ok := m["@prefix"]; ok {
+		b, ok := v.(bool)
+		if !ok || strings.ContainsAny(name, ":/") {
+			return fmt.Errorf("%w: invalid @prefix of %q", ErrInvalidContext, name)
+		}
+		def.prefix = b
+	}
+	if !overrideProtected && previous != nil && previous.protected {
+		if !def.sameAs(previous) {
+			return fmt.Errorf("%w: %q", ErrProtectedTerm, name)
+		}
+		def = previous
+	}
+	active.terms[name] = def
+	defined[name] = true
+	return nil
+}
+
+// expandIRI is the IRI Expansion algorithm. It returns "" for values that map to null.
+func (p *processor) expandIRI(active *activeContext, value string, documentRelative, vocab bool, local map[string]interface{}, defined map[string]bool) (string, error) {
+	if keywords[value] {
+		return value, nil
+	}
+	if keywordForm.MatchString(value) {
+		return "", nil
+	}
+	if local != nil {
+		if _, ok := local[value]; ok && !defined[value] {
+			if err := p.define(active, local, value, defined, false, false); err != nil {
+				return "", err
+			}
+		}
+	}
+	if t, ok := active.terms[value]; ok && (vocab || keywords[t.iri]) {
+		return t.iri, nil
+	}
+	if i := strings.Index(value, ":"); i > 0 {
+		prefix, suffix := value[:i], value[i+1:]
+		if prefix == "_" || strings.HasPrefix(suffix, "//") {
+			return value, nil
+		}
+		if local != nil {
+			if _, ok := local[prefix]; ok && !defined[prefix] {
+				if err := p.define(active, local, prefix, defined, false, false); err != nil {
+					return "", err
+				}
+			}
+		}
+		if t, ok := active.terms[prefix]; ok && t.iri != "" && t.prefix {
+			return t.iri + suffix, nil
+		}
+		if isAbsoluteIRI(value) {
+			return value, nil
+		}
+	}
+	if vocab && active.hasVocab {
+		return active.vocab + value, nil
+	}
+	if documentRelative {
+		return p.resolve(active.base, value), nil
+	}
+	return value, nil
+}
+
+// resolve resolves ref against base; without a base ref is returned unchanged.
+func (p *processor) resolve(base, ref string) string {
+	if base == "" || isAbsoluteIRI(ref) {
+		return ref
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
+
+func isAbsoluteIRI(s string) bool {
+	return schemeForm.MatchString(s) && !strings.ContainsAny(s, " <>\"{}|\\^`")
+}
+
+func isBlankNode(s string) bool {
+	return strings.HasPrefix(s, "_:")
+}
+
+func asArray(v interface{}) []interface{} {
+	if a, ok := v.([]interface{}); ok {
+		return a
+	}
+	return []interface{}{v}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,579 @@
+package jsonld
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// processor holds the settings of one expansion.
+type processor struct {
+	loader Loader
+}
+
+// Expand is the JSON-LD 1.1 Expansion algorithm. The document is parsed JSON, as
+// produced by encoding/json. Unlike a lenient processor, expansion fails on properties
+// and types that the contexts do not define instead of dropping them, so nothing in a
+// signed document can escape its signature.
+func Expand(doc interface{}, loader Loader) ([]interface{}, error) {
+	p := &processor{loader: loader}
+	out, err := p.expand(newActiveContext(), "", doc, false)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := out.(map[string]interface{}); ok && len(m) == 1 {
+		if g, ok := m["@graph"]; ok {
+			out = g
+		}
+	}
+	if out == nil {
+		return []interface{}{}, nil
+	}
+	return asArray(out), nil
+}
+
+func (p *processor) expand(active *activeContext, prop string, element interface{}, fromMap bool) (interface{}, error) {
+	if element == nil {
+		return nil, nil
+	}
+	def := active.terms[prop]
+	switch e := element.(type) {
+	case []interface{}:
+		var out []interface{}
+		for _, item := range e {
+			v, err := p.expand(active, prop, item, fromMap)
+			if err != nil {
+				return nil, err
+			}
+			if def != nil && def.container["@list"] {
+				if a, ok := v.([]interface{}); ok {
+					v = map[string]interface{}{"@list": a}
+				}
+			}
+			switch x := v.(type) {
+			case nil:
+			case []interface{}:
+				out = append(out, x...)
+			default:
+				out = append(out, x)
+			}
+		}
+		if out == nil {
+			out = []interface{}{}
+		}
+		return out, nil
+	case map[string]interface{}:
+		return p.expandObject(active, prop, e, fromMap)
+	default:
+		if prop == "" || prop == "@graph" {
+			// free-floating values are dropped by JSON-LD; refuse them instead
+			return nil, fmt.Errorf("%w: value %v outside of a property", ErrUndefinedTerm, e)
+		}
+		if def != nil && def.hasCtx {
+			var err error
+			if active, err = p.process(active, def.context, nil, true, true); err != nil {
+				return nil, err
+			}
+		}
+		return p.expandValue(active, prop, element)
+	}
+}
+
+func (p *processor) expandObject(active *activeContext, prop string, element map[string]interface{}, fromMap bool) (interface{}, error) {
+	def := active.terms[prop]
+	if active.previous != nil && !fromMap && !p.keepsScope(active, element) {
+		active = active.previous
+	}
+	var err error
+	if def != nil && def.hasCtx {
+		if active, err = p.process(active, def.context, nil, true, true); err != nil {
+			return nil, err
+		}
+	}
+	if c, ok := element["@context"]; ok {
+		if active, err = p.process(active, c, nil, false, true); err != nil {
+			return nil, err
+		}
+	}
+	typeScoped := active
+	var inputType string
+	for _, k := range sortedKeys(element) {
+		iri, err := p.expandIRI(active, k, false, true, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if iri != "@type" {
+			continue
+		}
+		var types []string
+		for _, v := range asArray(element[k]) {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+		sort.Strings(types)
+		for _, t := range types {
+			if td, ok := typeScoped.terms[t]; ok && td.hasCtx {
+				if active, err = p.process(active, td.context, nil, false, false); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if len(types) > 0 {
+			last := asArray(element[k])
+			if s, ok := last[len(last)-1].(string); ok {
+				if inputType, err = p.expandIRI(active, s, true, true, nil, nil); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	result := make(map[string]interface{})
+	if err := p.expandEntries(active, typeScoped, prop, element, result, inputType); err != nil {
+		return nil, err
+	}
+
+	if v, ok := result["@value"]; ok {
+		for k := range result {
+			switch k {
+			case "@direction", "@index", "@language", "@type", "@value":
+			default:
+				return nil, fmt.Errorf("%w: value object with %s", ErrInvalidDocument, k)
+			}
+		}
+		_, lang := result["@language"]
+		_, dir := result["@direction"]
+		t, typed := result["@type"]
+		if typed && (lang || dir) {
+			return nil, fmt.Errorf("%w: value object with both a type and a language", ErrInvalidDocument)
+		}
+		if t == "@json" {
+			return result, nil
+		}
+		if v == nil {
+			return nil, nil
+		}
+		if _, ok := v.(string); !ok && lang {
+			return nil, fmt.Errorf("%w: language-tagged value %v is not a string", ErrInvalidDocument, v)
+		}
+		if typed {
+			if s, ok := t.(string); !ok || !isAbsoluteIRI(s) {
+				return nil, fmt.Errorf("%w: value type %v is not an IRI", ErrInvalidDocument, t)
+			}
+		}
+		return result, nil
+	}
+	if t, ok := result["@type"]; ok {
+		result["@type"] = asArray(t)
+	}
+	if _, set := result["@set"]; set || result["@list"] != nil {
+		for k := range result {
+			if k != "@set" && k != "@list" && k != "@index" {
+				return nil, fmt.Errorf("%w: list or set object with %s", ErrInvalidDocument, k)
+			}
+		}
+		if s, ok := result["@set"]; ok {
+			return s, nil
+		}
+	}
+	if _, ok := result["@language"]; ok && len(result) == 1 {
+		return nil, nil
+	}
+	if prop == "" || prop == "@graph" {
+		_, hasValue := result["@value"]
+		_, hasList := result["@list"]
+		if len(result) == 0 || hasValue || hasList {
+			return nil, nil
+		}
+		if _, ok := result["@id"]; ok && len(result) == 1 {
+			return nil, nil
+		}
+	}
+	return result, nil
+}
+
+// keepsScope reports whether element, a value object or a bare reference, keeps the
+// type-scoped context of its parent.
+func (p *processor) keepsScope(active *activeContext, element map[string]interface{}) bool {
+	onlyID := len(element) == 1
+	for k := range element {
+		iri, _ := p.expandIRI(active, k, false, true, nil, nil)
+		if iri == "@value" {
+			return true
+		}
+		if iri != "@id" {
+			onlyID = false
+		}
+	}
+	return onlyID
+}
+
+func (p *processor) expandEntries(active, typeScoped *activeContext, prop string, element, result map[string]interface{}, inputType string) error {
+	var nests []string
+	for _, key := range sortedKeys(element) {
+		value := element[key]
+		if key == "@context" {
+			continue
+		}
+		eprop, err := p.expandIRI(active, key, false, true, nil, nil)
+		if err != nil {
+			return err
+		}
+		if eprop == "" || (!strings.Contains(eprop, ":") && !keywords[eprop]) {
+			return fmt.Errorf("%w: %q", ErrUndefinedTerm, key)
+		}
+		if keywords[eprop] {
+			if prop == "@reverse" {
+				return fmt.Errorf("%w: keyword %s in a reverse map", ErrInvalidDocument, key)
+			}
+			if _, dup := result[eprop]; dup && eprop != "@included" && eprop != "@type" {
+				return fmt.Errorf("%w: colliding keywords %s", ErrInvalidDocument, eprop)
+			}
+			var ev interface{}
+			switch eprop {
+			case "@id":
+				s, ok := value.(string)
+				if !ok {
+					return fmt.Errorf("%w: @id must be a string", ErrInvalidDocument)
+				}
+				if ev, err = p.expandIRI(active, s, true, false, nil, nil); err != nil {
+					return err
+				}
+				if !isAbsoluteIRI(ev.(string)) && !isBlankNode(ev.(string)) {
+					return fmt.Errorf("%w: relative @id %q", ErrUndefinedTerm, s)
+				}
+			case "@type":
+				var types []interface{}
+				for _, v := range asArray(value) {
+					s, ok := v.(string)
+					if !ok {
+						return fmt.Errorf("%w: @type values must be strings", ErrInvalidDocument)
+					}
+					iri, err := p.expandIRI(typeScoped, s, true, true, nil, nil)
+					if err != nil {
+						return err
+					}
+					if !isAbsoluteIRI(iri) && !isBlankNode(iri) && iri != "@json" {
+						return fmt.Errorf("%w: type %q", ErrUndefinedTerm, s)
+					}
+					types = append(types, iri)
+				}
+				if prev, ok := result["@type"]; ok {
+					types = append(asArray(prev), types...)
+				}
+				if _, isArray := value.([]interface{}); !isArray && len(types) == 1 {
+					ev = types[0]
+				} else {
+					ev = types
+				}
+			case "@graph":
+				v, err := p.expand(active, "@graph", value, false)
+				if err != nil {
+					return err
+				}
+				ev = asArray(v)
+			case "@included":
+				v, err := p.expand(active, "", value, false)
+				if err != nil {
+					return err
+				}
+				items := asArray(v)
+				if prev, ok := result["@included"]; ok {
+					items = append(asArray(prev), items...)
+				}
+				ev = items
+			case "@value":
+				if inputType != "@json" {
+					switch value.(type) {
+					case nil, string, bool:
+					default:
+						if _, ok := toNumber(value); !ok {
+							return fmt.Errorf("%w: invalid @value", ErrInvalidDocument)
+						}
+					}
+				}
+				result["@value"] = value
+				continue
+			case "@language":
+				s, ok := value.(string)
+				if !ok {
+					return fmt.Errorf("%w: @language must be a string", ErrInvalidDocument)
+				}
+				ev = strings.ToLower(s)
+			case "@direction":
+				if value != "ltr" && value != "rtl" {
+					return fmt.Errorf("%w: invalid @direction", ErrInvalidDocument)
+				}
+				ev = value
+			case "@index":
+				if _, ok := value.(string); !ok {
+					return fmt.Errorf("%w: @index must be a string", ErrInvalidDocument)
+				}
+				ev = value
+			case "@list":
+				if prop == "" || prop == "@graph" {
+					continue
+				}
+				v, err := p.expand(active, prop, value, false)
+				if err != nil {
+					return err
+				}
+				ev = asArray(v)
+			case "@set":
+				if ev, err = p.expand(active, prop, value, false); err != nil {
+					return err
+				}
+			case "@reverse":
+				m, ok := value.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("%w: @reverse must be an object", ErrInvalidDocument)
+				}
+				v, err := p.expand(active, "@reverse", m, false)
+				if err != nil {
+					return err
+				}
+				if err := mergeReverse(result, v); err != nil {
+					return err
+				}
+				continue
+			case "@nest":
+				nests = append(nests, key)
+				continue
+			default:
+				// framing keywords have no meaning here
+				continue
+			}
+			if ev != nil {
+				result[eprop] = ev
+			}
+			continue
+		}
+
+		def := active.terms[key]
+		var ev interface{}
+		switch {
+		case def != nil && def.typ == "@json":
+			ev = map[string]interface{}{"@value": value, "@type": "@json"}
+		case def != nil && def.container["@language"] && isMap(value):
+			if ev, err = p.expandLanguageMap(active, def, value.(map[string]interface{})); err != nil {
+				return err
+			}
+		case def != nil && def.container["@index"] && isMap(value):
+			var items []interface{}
+			m := value.(map[string]interface{})
+			for _, idx := range sortedKeys(m) {
+				v, err := p.expand(active, key, asArray(m[idx]), true)
+				if err != nil {
+					return err
+				}
+				for _, item := range asArray(v) {
+					if def.container["@graph"] && !isGraph(item) {
+						item = map[string]interface{}{"@graph": asArray(item)}
+					}
+					if o, ok := item.(map[string]interface{}); ok && idx != "@none" {
+						if _, has := o["@index"]; !has {
+							o["@index"] = idx
+						}
+					}
+					items = append(items, item)
+				}
+			}
+			ev = items
+		case def != nil && (def.container["@id"] || def.container["@type"]) && isMap(value):
+			return fmt.Errorf("%w: %s maps of %q", ErrUnsupported, containerName(def), key)
+		default:
+			if ev, err = p.expand(active, key, value, false); err != nil {
+				return err
+			}
+		}
+		if ev == nil {
+			continue
+		}
+		if def != nil && def.container["@list"] && !isList(ev) {
+			ev = map[string]interface{}{"@list": asArray(ev)}
+		}
+		if def != nil && def.container["@graph"] && !def.container["@id"] && !def.container["@index"] {
+			var graphs []interface{}
+			for _, item := range asArray(ev) {
+				graphs = append(graphs, map[string]interface{}{"@graph": asArray(item)})
+			}
+			ev = graphs
+		}
+		if def != nil && def.reverse {
+			rev, _ := result["@reverse"].(map[string]interface{})
+			if rev == nil {
+				rev = make(map[string]interface{})
+				result["@reverse"] = rev
+			}
+			for _, item := range asArray(ev) {
+				if isValue(item) || isList(item) {
+					return fmt.Errorf("%w: reverse property %q has a value", ErrInvalidDocument, key)
+				}
+				rev[eprop] = append(asArrayOrEmpty(rev[eprop]), item)
+			}
+			continue
+		}
+		result[eprop] = append(asArrayOrEmpty(result[eprop]), asArray(ev)...)
+	}
+	for _, key := range nests {
+		for _, nv := range asArray(element[key]) {
+			nested, ok := nv.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%w: @nest value must be an object", ErrInvalidDocument)
+			}
+			for k := range nested {
+				if iri, _ := p.expandIRI(active, k, false, true, nil, nil); iri == "@value" {
+					return fmt.Errorf("%w: @nest value is a value object", ErrInvalidDocument)
+				}
+			}
+			if err := p.expandEntries(active, typeScoped, prop, nested, result, inputType); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *processor) expandLanguageMap(active *activeContext, def *term, m map[string]interface{}) (interface{}, error) {
+	var items []interface{}
+	dir := active.direction
+	if def.hasDir {
+		dir = def.direction
+	}
+	for _, lang := range sortedKeys(m) {
+		for _, item := range asArray(m[lang]) {
+			if item == nil {
+				continue
+			}
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: language map value %v is not a string", ErrInvalidDocument, item)
+			}
+			v := map[string]interface{}{"@value": s}
+			if iri, _ := p.expandIRI(active, lang, false, true, nil, nil); iri != "@none" {
+				v["@language"] = strings.ToLower(lang)
+			}
+			if dir != "" {
+				v["@direction"] = dir
+			}
+			items = append(items, v)
+		}
+	}
+	return items, nil
+}
+
+// expandValue is the Value Expansion algorithm.
+func (p *processor) expandValue(active *activeContext, prop string, value interface{}) (interface{}, error) {
+	def := active.terms[prop]
+	if s, ok := value.(string); ok && def != nil && (def.typ == "@id" || def.typ == "@vocab") {
+		iri, err := p.expandIRI(active, s, true, def.typ == "@vocab", nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !isAbsoluteIRI(iri) && !isBlankNode(iri) {
+			return nil, fmt.Errorf("%w: relative IRI %q in %q", ErrUndefinedTerm, s, prop)
+		}
+		return map[string]interface{}{"@id": iri}, nil
+	}
+	result := map[string]interface{}{"@value": value}
+	if def != nil && def.typ != "" && def.typ != "@id" && def.typ != "@vocab" && def.typ != "@none" {
+		result["@type"] = def.typ
+	} else if _, ok := value.(string); ok {
+		lang, dir := active.language, active.direction
+		if def != nil && def.hasLang {
+			lang = def.language
+		}
+		if def != nil && def.hasDir {
+			dir = def.direction
+		}
+		if lang != "" {
+			result["@language"] = lang
+		}
+		if dir != "" {
+			result["@direction"] = dir
+		}
+	}
+	return result, nil
+}
+
+func mergeReverse(result map[string]interface{}, v interface{}) error {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for k, items := range m {
+		if k == "@reverse" {
+			inner, _ := items.(map[string]interface{})
+			for ik, iv := range inner {
+				result[ik] = append(asArrayOrEmpty(result[ik]), asArray(iv)...)
+			}
+			continue
+		}
+		rev, _ := result["@reverse"].(map[string]interface{})
+		if rev == nil {
+			rev = make(map[string]interface{})
+			result["@reverse"] = rev
+		}
+		for _, item := range asArray(items) {
+			if isValue(item) || isList(item) {
+				return fmt.Errorf("%w: reverse property %s has a value", ErrInvalidDocument, k)
+			}
+			rev[k] = append(asArrayOrEmpty(rev[k]), item)
+		}
+	}
+	return nil
+}
+
+func containerName(def *term) string {
+	if def.container["@id"] {
+		return "@id"
+	}
+	return "@type"
+}
+
+func asArrayOrEmpty(v interface{}) []interface{} {
+	if v == nil {
+		return nil
+	}
+	return asArray(v)
+}
+
+func isMap(v interface{}) bool {
+	_, ok := v.(map[string]interface{})
+	return ok
+}
+
+func isValue(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = m["@value"]
+	return ok
+}
+
+func isList(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = m["@list"]
+	return ok
+}
+
+func isGraph(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	if _, ok := m["@graph"]; !ok {
+		return false
+	}
+	for k := range m {
+		if k != "@graph" && k != "@id" && k != "@index" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,24 @@
+// Package jsonld implements the parts of JSON-LD 1.1 and RDF Dataset Canonicalization
+// (RDFC-1.0) that Data Integrity proofs need: expanding a document with its contexts,
+// converting it to RDF and writing the dataset in canonical N-Quads.
+//
+// Remote contexts come from a Loader; OfflineLoader serves the W3C credentials, DID and
+// security contexts bundled with the package. Expansion runs in what other processors
+// call safe mode: terms that no context defines and relative IRIs are errors.
+package jsonld
+
+import "errors"
+
+var (
+	// ErrInvalidContext is returned for contexts that cannot be processed.
+	ErrInvalidContext = errors.New("invalid JSON-LD context")
+	// ErrProtectedTerm is returned when a context redefines a protected term.
+	ErrProtectedTerm = errors.New("protected term redefinition")
+	// ErrUndefinedTerm is returned for properties, types and IRIs that the document's
+	// contexts do not map to absolute IRIs, which a lenient processor would drop.
+	ErrUndefinedTerm = errors.New("term not defined by the JSON-LD context")
+	// ErrInvalidDocument is returned for documents that are not valid JSON-LD.
+	ErrInvalidDocument = errors.New("invalid JSON-LD document")
+	// ErrUnsupported is returned for JSON-LD features the package does not implement.
+	ErrUnsupported = errors.New("unsupported JSON-LD feature")
+)
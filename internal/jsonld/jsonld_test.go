@@ -0,0 +1,267 @@
+package jsonld
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// alumniCredential is the unsigned credential of the eddsa-rdfc-2022 test vector in
+// the W3C Data Integrity EdDSA Cryptosuites specification.
+const alumniCredential = `{
+  "@context": [
+    "https://www.w3.org/ns/credentials/v2",
+    "https://www.w3.org/ns/credentials/examples/v2"
+  ],
+  "id": "urn:uuid:58172aac-d8ba-11ed-83dd-0b3aef56cc33",
+  "type": ["VerifiableCredential", "AlumniCredential"],
+  "name": "Alumni Credential",
+  "description": "A minimum viable example of an Alumni Credential.",
+  "issuer": "https://vc.example/issuers/5678",
+  "validFrom": "2023-01-01T00:00:00Z",
+  "credentialSubject": {
+    "id": "did:example:abcdefgh",
+    "alumniOf": "The School of Examples"
+  }
+}`
+
+const alumniCanonical = `<did:example:abcdefgh> <https://www.w3.org/ns/credentials/examples#alumniOf> "The School of Examples" .
+<urn:uuid:58172aac-d8ba-11ed-83dd-0b3aef56cc33> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <https://www.w3.org/2018/credentials#VerifiableCredential> .
+<urn:uuid:58172aac-d8ba-11ed-83dd-0b3aef56cc33> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <https://www.w3.org/ns/credentials/examples#AlumniCredential> .
+<urn:uuid:58172aac-d8ba-11ed-83dd-0b3aef56cc33> <https://schema.org/description> "A minimum viable example of an Alumni Credential." .
+<urn:uuid:58172aac-d8ba-11ed-83dd-0b3aef56cc33> <https://schema.org/name> "Alumni Credential" .
+<urn:uuid:58172aac-d8ba-11ed-83dd-0b3aef56cc33> <https://www.w3.org/2018/credentials#credentialSubject> <did:example:abcdefgh> .
+<urn:uuid:58172aac-d8ba-11ed-83dd-0b3aef56cc33> <https://www.w3.org/2018/credentials#issuer> <https://vc.example/issuers/5678> .
+<urn:uuid:58172aac-d8ba-11ed-83dd-0b3aef56cc33> <https://www.w3.org/2018/credentials#validFrom> "2023-01-01T00:00:00Z"^^<http://www.w3.org/2001/XMLSchema#dateTime> .
+`
+
+const alumniProofConfig = `{
+  "@context": [
+    "https://www.w3.org/ns/credentials/v2",
+    "https://www.w3.org/ns/credentials/examples/v2"
+  ],
+  "type": "DataIntegrityProof",
+  "cryptosuite": "eddsa-rdfc-2022",
+  "created": "2023-02-24T23:36:38Z",
+  "verificationMethod": "did:key:z6MkrJVnaZkeFzdQyMZu1cgjg7k1pZZ6pvBQ7XJPt4swbTQ2#z6MkrJVnaZkeFzdQyMZu1cgjg7k1pZZ6pvBQ7XJPt4swbTQ2",
+  "proofPurpose": "assertionMethod"
+}`
+
+const alumniProofCanonical = `_:c14n0 <http://purl.org/dc/terms/created> "2023-02-24T23:36:38Z"^^<http://www.w3.org/2001/XMLSchema#dateTime> .
+_:c14n0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <https://w3id.org/security#DataIntegrityProof> .
+_:c14n0 <https://w3id.org/security#cryptosuite> "eddsa-rdfc-2022"^^<https://w3id.org/security#cryptosuiteString> .
+_:c14n0 <https://w3id.org/security#proofPurpose> <https://w3id.org/security#assertionMethod> .
+_:c14n0 <https://w3id.org/security#verificationMethod> <did:key:z6MkrJVnaZkeFzdQyMZu1cgjg7k1pZZ6pvBQ7XJPt4swbTQ2#z6MkrJVnaZkeFzdQyMZu1cgjg7k1pZZ6pvBQ7XJPt4swbTQ2> .
+`
+
+func parse(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCanonicalizeTestVector(t *testing.T) {
+	loader := NewOfflineLoader()
+	cases := []struct {
+		name, doc, want, hash string
+	}{
+		{"credential", alumniCredential, alumniCanonical, "517744132ae165a5349155bef0bb0cf2258fff99dfe1dbd914b938d775a36017"},
+		{"proof config", alumniProofConfig, alumniProofCanonical, "bea7b7acfbad0126b135104024a5f1733e705108f42d59668b05c0c50004c6b0"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := Canonicalize(parse(t, c.doc), loader)
+			if err != nil {
+				t.Fatalf("Canonicalize: %v", err)
+			}
+			if got != c.want {
+				t.Fatalf("canonical form:\n%s\nwant:\n%s", got, c.want)
+			}
+			if h := digest(got); h != c.hash {
+				t.Errorf("hash %s, want %s", h, c.hash)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeCredentialV1(t *testing.T) {
+	doc := `{
+	  "@context": [
+	    "https://www.w3.org/2018/credentials/v1",
+	    "https://w3id.org/security/data-integrity/v2",
+	    {"@vocab": "https://www.w3.org/ns/credentials/issuer-dependent#"}
+	  ],
+	  "id": "urn:uuid:1",
+	  "type": ["VerifiableCredential"],
+	  "issuer": "did:key:z6Mk",
+	  "issuanceDate": "2024-01-01T00:00:00Z",
+	  "credentialSubject": {"name": "Ada", "age": 36, "score": 1.5, "member": true},
+	  "proof": [{"type": "DataIntegrityProof", "cryptosuite": "eddsa-rdfc-2022", "proofPurpose": "assertionMethod", "verificationMethod": "did:key:z6Mk#keys-1", "proofValue": "z1"}]
+	}`
+	got, err := Canonicalize(parse(t, doc), NewOfflineLoader())
+	if err != nil {
+		t.Fatalf("Canonicalize: %v", err)
+	}
+	// the proof is a node in a graph of its own, named by a blank node
+	want := `<urn:uuid:1> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <https://www.w3.org/2018/credentials#VerifiableCredential> .
+<urn:uuid:1> <https://w3id.org/security#proof> _:c14n1 .
+<urn:uuid:1> <https://www.w3.org/2018/credentials#credentialSubject> _:c14n0 .
+<urn:uuid:1> <https://www.w3.org/2018/credentials#issuanceDate> "2024-01-01T00:00:00Z"^^<http://www.w3.org/2001/XMLSchema#dateTime> .
+<urn:uuid:1> <https://www.w3.org/2018/credentials#issuer> <did:key:z6Mk> .
+_:c14n0 <https://www.w3.org/ns/credentials/issuer-dependent#age> "36"^^<http://www.w3.org/2001/XMLSchema#integer> .
+_:c14n0 <https://www.w3.org/ns/credentials/issuer-dependent#member> "true"^^<http://www.w3.org/2001/XMLSchema#boolean> .
+_:c14n0 <https://www.w3.org/ns/credentials/issuer-dependent#name> "Ada" .
+_:c14n0 <https://www.w3.org/ns/credentials/issuer-dependent#score> "1.5E0"^^<http://www.w3.org/2001/XMLSchema#double> .
+_:c14n2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <https://w3id.org/security#DataIntegrityProof> _:c14n1 .
+_:c14n2 <https://w3id.org/security#cryptosuite> "eddsa-rdfc-2022"^^<https://w3id.org/security#cryptosuiteString> _:c14n1 .
+_:c14n2 <https://w3id.org/security#proofPurpose> <https://w3id.org/security#assertionMethod> _:c14n1 .
+_:c14n2 <https://w3id.org/security#proofValue> "z1"^^<https://w3id.org/security#multibase> _:c14n1 .
+_:c14n2 <https://w3id.org/security#verificationMethod> <did:key:z6Mk#keys-1> _:c14n1 .
+`
+	if got != want {
+		t.Errorf("canonical form:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestExpandRejectsUndefinedTerms(t *testing.T) {
+	loader := NewOfflineLoader()
+	cases := map[string]string{
+		"property": `{"@context": ["https://www.w3.org/2018/credentials/v1"], "type": "VerifiableCredential", "credentialSubject": {"name": "Ada"}}`,
+		"type":     `{"@context": ["https://www.w3.org/2018/credentials/v1"], "type": ["VerifiableCredential", "Custom"]}`,
+		"id":       `{"@context": ["https://www.w3.org/2018/credentials/v1"], "id": "relative", "type": "VerifiableCredential"}`,
+	}
+	for name, doc := range cases {
+		if _, err := Canonicalize(parse(t, doc), loader); !errors.Is(err, ErrUndefinedTerm) {
+			t.Errorf("%s: expected ErrUndefinedTerm, got %v", name, err)
+		}
+	}
+}
+
+func TestContextErrors(t *testing.T) {
+	loader := NewOfflineLoader()
+	redefine := `{"@context": ["https://www.w3.org/ns/credentials/v2", {"name": "https://example.com/name"}], "type": "VerifiableCredential"}`
+	if _, err := Canonicalize(parse(t, redefine), loader); !errors.Is(err, ErrProtectedTerm) {
+		t.Errorf("expected ErrProtectedTerm, got %v", err)
+	}
+	remote := `{"@context": "https://example.com/context.jsonld", "name": "x"}`
+	if _, err := Canonicalize(parse(t, remote), loader); !errors.Is(err, ErrContextNotFound) {
+		t.Errorf("expected ErrContextNotFound, got %v", err)
+	}
+	if err := loader.Add("https://example.com/context.jsonld", []byte(`{"@context": {"name": "https://schema.org/name"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Canonicalize(parse(t, remote), loader); err != nil {
+		t.Errorf("added context: %v", err)
+	}
+}
+
+func TestCanonicalizeBlankNodes(t *testing.T) {
+	p := "<http://example.com/p>"
+	// a cycle of four blank nodes, where every node has the same first-degree hash
+	a := "_:a " + p + " _:b .\n_:b " + p + " _:c .\n_:c " + p + " _:d .\n_:d " + p + " _:a .\n_:a <http://example.com/q> \"x\" .\n"
+	b := "_:w " + p + " _:x .\n_:x " + p + " _:y .\n_:y " + p + " _:z .\n_:z " + p + " _:w .\n_:y <http://example.com/q> \"x\" .\n"
+	ca, err := CanonicalizeQuads(quadsOf(t, a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := CanonicalizeQuads(quadsOf(t, reverse(quadsOf(t, b))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ca != cb {
+		t.Errorf("isomorphic datasets differ:\n%s\n%s", ca, cb)
+	}
+	if !containsLine(ca, `_:c14n0 <http://example.com/q> "x" .`) {
+		t.Errorf("unexpected labels:\n%s", ca)
+	}
+	other := "_:w " + p + " _:x .\n_:x " + p + " _:y .\n_:y " + p + " _:z .\n_:z " + p + " _:w .\n_:y <http://example.com/q> \"y\" .\n"
+	if co, _ := CanonicalizeQuads(quadsOf(t, other)); co == ca {
+		t.Error("different datasets have the same canonical form")
+	}
+}
+
+func TestLiteralEscaping(t *testing.T) {
+	q := Quad{
+		Subject:   Node{Kind: IRI, Value: "http://example.com/s"},
+		Predicate: Node{Kind: IRI, Value: "http://example.com/p"},
+		Object:    Node{Kind: Literal, Value: "a\"b\\c\nd\te\x01", Datatype: xsdString},
+	}
+	want := `<http://example.com/s> <http://example.com/p> "a\"b\\c\nd\te\u0001" .` + "\n"
+	if got := NQuads([]Quad{q}); got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+}
+
+func splitLines(s string) []string {
+	var lines []string
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '\n')
+		lines = append(lines, s[:i])
+		s = s[i+1:]
+	}
+	return lines
+}
+
+func containsLine(s, line string) bool {
+	for _, l := range splitLines(s) {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+// quadsOf parses the simple N-Quads used in tests: IRIs, blank nodes and plain literals.
+func quadsOf(t *testing.T, s string) []Quad {
+	t.Helper()
+	var quads []Quad
+	for _, line := range splitLines(s) {
+		var nodes []Node
+		for rest := strings.TrimSuffix(line, " ."); rest != ""; rest = strings.TrimPrefix(rest, " ") {
+			var n Node
+			switch rest[0] {
+			case '<':
+				i := strings.IndexByte(rest, '>')
+				n, rest = Node{Kind: IRI, Value: rest[1:i]}, rest[i+1:]
+			case '"':
+				i := strings.IndexByte(rest[1:], '"') + 1
+				n, rest = Node{Kind: Literal, Value: rest[1:i], Datatype: xsdString}, rest[i+1:]
+			default:
+				i := strings.IndexByte(rest, ' ')
+				if i < 0 {
+					i = len(rest)
+				}
+				n, rest = Node{Kind: BlankNode, Value: rest[:i]}, rest[i:]
+			}
+			nodes = append(nodes, n)
+		}
+		if len(nodes) < 3 {
+			t.Fatalf("bad quad %q", line)
+		}
+		q := Quad{Subject: nodes[0], Predicate: nodes[1], Object: nodes[2]}
+		if len(nodes) == 4 {
+			q.Graph = nodes[3]
+		}
+		quads = append(quads, q)
+	}
+	return quads
+}
+
+func reverse(quads []Quad) string {
+	var out []Quad
+	for i := len(quads) - 1; i >= 0; i-- {
+		out = append(out, quads[i])
+	}
+	return NQuads(out)
+}
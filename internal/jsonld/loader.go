@@ -0,0 +1,83 @@
+package jsonld
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrContextNotFound is returned by OfflineLoader for contexts it does not hold.
+var ErrContextNotFound = errors.New("JSON-LD context not available offline")
+
+// Loader retrieves the remote contexts that documents refer to by URL.
+type Loader interface {
+	// LoadContext returns the parsed JSON document at url.
+	LoadContext(url string) (interface{}, error)
+}
+
+//go:embed contexts/*.jsonld
+var bundled embed.FS
+
+// bundledContexts maps the URLs of the contexts shipped with the package to their file.
+var bundledContexts = map[string]string{
+	"https://www.w3.org/2018/credentials/v1":           "credentials-v1.jsonld",
+	"https://www.w3.org/ns/credentials/v2":             "credentials-v2.jsonld",
+	"https://www.w3.org/ns/credentials/examples/v2":    "credentials-examples-v2.jsonld",
+	"https://www.w3.org/ns/did/v1":                     "did-v1.jsonld",
+	"https://w3id.org/did/v1":                          "did-v1.jsonld",
+	"https://w3id.org/security/data-integrity/v1":      "data-integrity-v1.jsonld",
+	"https://w3id.org/security/data-integrity/v2":      "data-integrity-v2.jsonld",
+	"https://w3id.org/security/suites/ed25519-2020/v1": "ed25519-2020-v1.jsonld",
+	"https://w3id.org/security/multikey/v1":            "multikey-v1.jsonld",
+}
+
+// OfflineLoader serves the W3C credentials, DID and security contexts bundled with the
+// package, and any context added with Add. It never uses the network, so processing a
+// document cannot leak what is being verified or be steered by a changed context.
+type OfflineLoader struct {
+	mu   sync.RWMutex
+	docs map[string]interface{}
+}
+
+// NewOfflineLoader returns a loader holding the bundled contexts.
+func NewOfflineLoader() *OfflineLoader {
+	l := &OfflineLoader{docs: make(map[string]interface{})}
+	for url, name := range bundledContexts {
+		data, err := bundled.ReadFile("contexts/" + name)
+		if err != nil {
+			panic(err)
+		}
+		if err := l.Add(url, data); err != nil {
+			panic(fmt.Sprintf("bundled context %s: %v", name, err))
+		}
+	}
+	return l
+}
+
+// Add makes the JSON-LD context document data available as url.
+func (l *OfflineLoader) Add(url string, data []byte) error {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse context %s: %w", url, err)
+	}
+	if _, ok := doc["@context"]; !ok {
+		return fmt.Errorf("context %s has no @context", url)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.docs[url] = doc
+	return nil
+}
+
+// LoadContext returns the context document added as url.
+func (l *OfflineLoader) LoadContext(url string) (interface{}, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	doc, ok := l.docs[url]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrContextNotFound, url)
+	}
+	return doc, nil
+}
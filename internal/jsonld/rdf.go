@@ -0,0 +1,513 @@
+package jsonld
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gowebpki/jcs"
+)
+
+// Well-known IRIs of RDF literals.
+const (
+	rdfType       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+	rdfFirst      = "http://www.w3.org/1999/02/22-rdf-syntax-ns#first"
+	rdfRest       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#rest"
+	rdfNil        = "http://www.w3.org/1999/02/22-rdf-syntax-ns#nil"
+	rdfJSON       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#JSON"
+	rdfLangString = "http://www.w3.org/1999/02/22-rdf-syntax-ns#langString"
+	xsdBoolean    = "http://www.w3.org/2001/XMLSchema#boolean"
+	xsdDouble     = "http://www.w3.org/2001/XMLSchema#double"
+	xsdInteger    = "http://www.w3.org/2001/XMLSchema#integer"
+	xsdString     = "http://www.w3.org/2001/XMLSchema#string"
+)
+
+// NodeKind tells IRIs, blank nodes and literals apart.
+type NodeKind int
+
+const (
+	IRI NodeKind = iota
+	BlankNode
+	Literal
+)
+
+// Node is an RDF term. Blank node values keep their "_:" prefix. A zero Node as the
+// graph of a Quad stands for the default graph.
+type Node struct {
+	Kind     NodeKind
+	Value    string
+	Datatype string
+	Language string
+}
+
+// Quad is an RDF statement in a named or the default graph.
+type Quad struct {
+	Subject, Predicate, Object, Graph Node
+}
+
+// ToRDF expands doc and deserializes it into an RDF dataset.
+func ToRDF(doc interface{}, loader Loader) ([]Quad, error) {
+	expanded, err := Expand(doc, loader)
+	if err != nil {
+		return nil, err
+	}
+	b := &rdfBuilder{issuer: newIssuer("_:b"), graphs: map[string]map[string]map[string]interface{}{"@default": {}}}
+	if err := b.nodeMap(expanded, "@default", "", nil, "", nil); err != nil {
+		return nil, err
+	}
+	return b.quads()
+}
+
+type rdfBuilder struct {
+	issuer *issuer
+	// graphs maps graph names to subjects to flattened node objects.
+	graphs map[string]map[string]map[string]interface{}
+}
+
+// nodeMap is the Node Map Generation algorithm. list, when not nil, collects the
+// items of the list object being flattened.
+func (b *rdfBuilder) nodeMap(element interface{}, graph, subject string, reverseSubject map[string]interface{}, prop string, list *[]interface{}) error {
+	if a, ok := element.([]interface{}); ok {
+		for _, item := range a {
+			if err := b.nodeMap(item, graph, subject, reverseSubject, prop, list); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	e, ok := element.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%w: unexpected %v in expanded document", ErrInvalidDocument, element)
+	}
+	nodes := b.graphs[graph]
+	if nodes == nil {
+		nodes = make(map[string]map[string]interface{})
+		b.graphs[graph] = nodes
+	}
+	var subjectNode map[string]interface{}
+	if subject != "" {
+		subjectNode = nodes[subject]
+	}
+	if types, ok := e["@type"]; ok && !isValue(e) {
+		var relabeled []interface{}
+		for _, t := range asArray(types) {
+			if s, _ := t.(string); isBlankNode(s) {
+				t = b.issuer.id(s)
+			}
+			relabeled = append(relabeled, t)
+		}
+		e["@type"] = relabeled
+	}
+	switch {
+	case isValue(e):
+		if list != nil {
+			*list = append(*list, e)
+		} else {
+			addUnique(subjectNode, prop, e)
+		}
+	case isList(e):
+		var items []interface{}
+		if err := b.nodeMap(e["@list"], graph, subject, reverseSubject, prop, &items); err != nil {
+			return err
+		}
+		l := map[string]interface{}{"@list": items}
+		if list != nil {
+			*list = append(*list, l)
+		} else {
+			subjectNode[prop] = append(asArrayOrEmpty(subjectNode[prop]), l)
+		}
+	default:
+		id, _ := e["@id"].(string)
+		if id == "" || isBlankNode(id) {
+			id = b.issuer.id(id)
+		}
+		node := nodes[id]
+		if node == nil {
+			node = map[string]interface{}{"@id": id}
+			nodes[id] = node
+		}
+		ref := map[string]interface{}{"@id": id}
+		switch {
+		case reverseSubject != nil:
+			addUnique(node, prop, reverseSubject)
+		case prop != "":
+			if list != nil {
+				*list = append(*list, ref)
+			} else {
+				addUnique(subjectNode, prop, ref)
+			}
+		}
+		if types, ok := e["@type"]; ok {
+			for _, t := range asArray(types) {
+				addUnique(node, "@type", t)
+			}
+		}
+		if rev, ok := e["@reverse"].(map[string]interface{}); ok {
+			for _, rp := range sortedKeys(rev) {
+				for _, v := range asArray(rev[rp]) {
+					if err := b.nodeMap(v, graph, "", ref, rp, nil); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if g, ok := e["@graph"]; ok {
+			if err := b.nodeMap(g, id, "", nil, "", nil); err != nil {
+				return err
+			}
+		}
+		if inc, ok := e["@included"]; ok {
+			if err := b.nodeMap(inc, graph, "", nil, "", nil); err != nil {
+				return err
+			}
+		}
+		for _, p := range sortedKeys(e) {
+			if keywords[p] {
+				continue
+			}
+			np := p
+			if isBlankNode(p) {
+				np = b.issuer.id(p)
+			}
+			if _, ok := node[np]; !ok {
+				node[np] = []interface{}{}
+			}
+			if err := b.nodeMap(e[p], graph, id, nil, np, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addUnique adds v to the values of prop in node unless an equal value is there.
+func addUnique(node map[string]interface{}, prop string, v interface{}) {
+	values := asArrayOrEmpty(node[prop])
+	for _, existing := range values {
+		if reflect.DeepEqual(existing, v) {
+			return
+		}
+	}
+	node[prop] = append(values, v)
+}
+
+// quads is the Deserialize JSON-LD to RDF algorithm, applied to the node map.
+func (b *rdfBuilder) quads() ([]Quad, error) {
+	var out []Quad
+	for _, name := range sortedGraphNames(b.graphs) {
+		var graph Node
+		if name != "@default" {
+			g, ok := termFor(name)
+			if !ok {
+				continue
+			}
+			graph = g
+		}
+		nodes := b.graphs[name]
+		subjects := make([]string, 0, len(nodes))
+		for s := range nodes {
+			subjects = append(subjects, s)
+		}
+		sort.Strings(subjects)
+		for _, s := range subjects {
+			subject, ok := termFor(s)
+			if !ok {
+				return nil, fmt.Errorf("%w: relative subject %q", ErrUndefinedTerm, s)
+			}
+			node := nodes[s]
+			props := make([]string, 0, len(node))
+			for p := range node {
+				props = append(props, p)
+			}
+			sort.Strings(props)
+			for _, p := range props {
+				switch {
+				case p == "@type":
+					for _, t := range asArray(node[p]) {
+						obj, ok := termFor(t.(string))
+						if !ok {
+							return nil, fmt.Errorf("%w: relative type %q", ErrUndefinedTerm, t)
+						}
+						out = append(out, Quad{subject, Node{Kind: IRI, Value: rdfType}, obj, graph})
+					}
+					continue
+				case keywords[p]:
+					continue
+				case isBlankNode(p):
+					// generalized RDF is not produced
+					continue
+				}
+				pred, ok := termFor(p)
+				if !ok {
+					return nil, fmt.Errorf("%w: relative property %q", ErrUndefinedTerm, p)
+				}
+				for _, item := range asArrayOrEmpty(node[p]) {
+					var obj Node
+					var err error
+					if isList(item) {
+						obj, out, err = b.list(item.(map[string]interface{})["@list"].([]interface{}), graph, out)
+					} else {
+						obj, out, err = b.object(item, graph, out)
+					}
+					if err != nil {
+						return nil, err
+					}
+					out = append(out, Quad{subject, pred, obj, graph})
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// list turns the items of a list object into rdf:first/rdf:rest statements and
+// returns the head of the list.
+func (b *rdfBuilder) list(items []interface{}, graph Node, out []Quad) (Node, []Quad, error) {
+	if len(items) == 0 {
+		return Node{Kind: IRI, Value: rdfNil}, out, nil
+	}
+	nodes := make([]Node, len(items))
+	for i := range items {
+		nodes[i] = Node{Kind: BlankNode, Value: b.issuer.id("")}
+	}
+	for i, item := range items {
+		var obj Node
+		var err error
+		if isList(item) {
+			obj, out, err = b.list(item.(map[string]interface{})["@list"].([]interface{}), graph, out)
+		} else {
+			obj, out, err = b.object(item, graph, out)
+		}
+		if err != nil {
+			return Node{}, nil, err
+		}
+		out = append(out, Quad{nodes[i], Node{Kind: IRI, Value: rdfFirst}, obj, graph})
+		rest := Node{Kind: IRI, Value: rdfNil}
+		if i+1 < len(nodes) {
+			rest = nodes[i+1]
+		}
+		out = append(out, Quad{nodes[i], Node{Kind: IRI, Value: rdfRest}, rest, graph})
+	}
+	return nodes[0], out, nil
+}
+
+// object is the Object to RDF Conversion algorithm.
+func (b *rdfBuilder) object(item interface{}, graph Node, out []Quad) (Node, []Quad, error) {
+	m := item.(map[string]interface{})
+	if !isValue(m) {
+		id, _ := m["@id"].(string)
+		n, ok := termFor(id)
+		if !ok {
+			return Node{}, nil, fmt.Errorf("%w: relative IRI %q", ErrUndefinedTerm, id)
+		}
+		return n, out, nil
+	}
+	value := m["@value"]
+	datatype, _ := m["@type"].(string)
+	lang, _ := m["@language"].(string)
+	var lexical string
+	switch v := value.(type) {
+	case string:
+		lexical = v
+	case bool:
+		lexical = strconv.FormatBool(v)
+		if datatype == "" {
+			datatype = xsdBoolean
+		}
+	default:
+		if datatype == "@json" {
+			break
+		}
+		f, ok := toNumber(v)
+		if !ok {
+			return Node{}, nil, fmt.Errorf("%w: invalid value %v", ErrInvalidDocument, v)
+		}
+		if f != math.Trunc(f) || math.Abs(f) >= 1e21 || datatype == xsdDouble {
+			lexical = canonicalDouble(f)
+			if datatype == "" {
+				datatype = xsdDouble
+			}
+		} else {
+			lexical = strconv.FormatFloat(f, 'f', -1, 64)
+			if datatype == "" {
+				datatype = xsdInteger
+			}
+		}
+	}
+	if datatype == "@json" {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return Node{}, nil, err
+		}
+		canon, err := jcs.Transform(raw)
+		if err != nil {
+			return Node{}, nil, err
+		}
+		lexical, datatype = string(canon), rdfJSON
+	}
+	switch {
+	case lang != "":
+		datatype = rdfLangString
+	case datatype == "":
+		datatype = xsdString
+	}
+	return Node{Kind: Literal, Value: lexical, Datatype: datatype, Language: lang}, out, nil
+}
+
+// canonicalDouble formats f as the XSD canonical form of a double, such as 1.1E0.
+func canonicalDouble(f float64) string {
+	s := strconv.FormatFloat(f, 'E', 15, 64)
+	mantissa, exp, _ := strings.Cut(s, "E")
+	mantissa = strings.TrimRight(mantissa, "0")
+	if strings.HasSuffix(mantissa, ".") {
+		mantissa += "0"
+	}
+	e, _ := strconv.Atoi(exp)
+	return mantissa + "E" + strconv.Itoa(e)
+}
+
+// toNumber returns the value of a JSON number as decoded by encoding/json.
+func toNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// termFor returns the IRI or blank node for id, or false for a relative IRI.
+func termFor(id string) (Node, bool) {
+	switch {
+	case isBlankNode(id):
+		return Node{Kind: BlankNode, Value: id}, true
+	case isAbsoluteIRI(id):
+		return Node{Kind: IRI, Value: id}, true
+	}
+	return Node{}, false
+}
+
+func sortedGraphNames(graphs map[string]map[string]map[string]interface{}) []string {
+	names := make([]string, 0, len(graphs))
+	for n := range graphs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// issuer is the Identifier Issuer of the canonicalization algorithm, which the node map
+// also uses to relabel blank nodes.
+type issuer struct {
+	prefix  string
+	counter int
+	issued  map[string]string
+	order   []string
+}
+
+func newIssuer(prefix string) *issuer {
+	return &issuer{prefix: prefix, issued: make(map[string]string)}
+}
+
+// id returns the identifier issued for existing, issuing a new one if needed. An empty
+// existing always gets a fresh identifier.
+func (i *issuer) id(existing string) string {
+	if id, ok := i.issued[existing]; ok && existing != "" {
+		return id
+	}
+	id := i.prefix + strconv.Itoa(i.counter)
+	i.counter++
+	if existing != "" {
+		i.issued[existing] = id
+		i.order = append(i.order, existing)
+	}
+	return id
+}
+
+func (i *issuer) has(existing string) bool {
+	_, ok := i.issued[existing]
+	return ok
+}
+
+func (i *issuer) clone() *issuer {
+	n := &issuer{prefix: i.prefix, counter: i.counter, issued: make(map[string]string, len(i.issued))}
+	for k, v := range i.issued {
+		n.issued[k] = v
+	}
+	n.order = append([]string(nil), i.order...)
+	return n
+}
+
+// NQuads serializes quads in canonical N-Quads, one statement per line, in the given order.
+func NQuads(quads []Quad) string {
+	var sb strings.Builder
+	for _, q := range quads {
+		sb.WriteString(nquad(q))
+	}
+	return sb.String()
+}
+
+func nquad(q Quad) string {
+	var sb strings.Builder
+	writeNode(&sb, q.Subject)
+	sb.WriteByte(' ')
+	writeNode(&sb, q.Predicate)
+	sb.WriteByte(' ')
+	writeNode(&sb, q.Object)
+	if q.Graph.Value != "" {
+		sb.WriteByte(' ')
+		writeNode(&sb, q.Graph)
+	}
+	sb.WriteString(" .\n")
+	return sb.String()
+}
+
+func writeNode(sb *strings.Builder, n Node) {
+	switch n.Kind {
+	case IRI:
+		sb.WriteString("<" + n.Value + ">")
+	case BlankNode:
+		sb.WriteString(n.Value)
+	case Literal:
+		sb.WriteByte('"')
+		for _, r := range n.Value {
+			switch {
+			case r == '"':
+				sb.WriteString(`\"`)
+			case r == '\\':
+				sb.WriteString(`\\`)
+			case r == '\n':
+				sb.WriteString(`\n`)
+			case r == '\r':
+				sb.WriteString(`\r`)
+			case r == '\b':
+				sb.WriteString(`\b`)
+			case r == '\t':
+				sb.WriteString(`\t`)
+			case r == '\f':
+				sb.WriteString(`\f`)
+			case r < 0x20 || r == 0x7f:
+				fmt.Fprintf(sb, `\u%04X`, r)
+			default:
+				sb.WriteRune(r)
+			}
+		}
+		sb.WriteByte('"')
+		switch {
+		case n.Datatype == rdfLangString:
+			sb.WriteString("@" + n.Language)
+		case n.Datatype != xsdString:
+			sb.WriteString("^^<" + n.Datatype + ">")
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package resolver
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long Cache keeps a resolved document when no TTL is given.
+const DefaultTTL = 5 * time.Minute
+
+type cacheEntry struct {
+	res     *Resolution
+	expires time.Time
+}
+
+// Cache keeps successful resolutions of another resolver for a fixed time. Failures are
+// not cached, so a document that was missing is fetched again on the next call.
+type Cache struct {
+	next Resolver
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// NewCache caches the resolutions of next for ttl, or DefaultTTL when ttl is not positive.
+func NewCache(next Resolver, ttl time.Duration) *Cache {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Cache{next: next, ttl: ttl, now: time.Now, entries: map[string]cacheEntry{}}
+}
+
+// Resolve returns the cached resolution of did, resolving it again once it has expired.
+// Cached results have ResolutionMetadata.Cached set.
+func (c *Cache) Resolve(ctx context.Context, did string) (*Resolution, error) {
+	now := c.now()
+	c.mu.Lock()
+	e, ok := c.entries[did]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		res := *e.res
+		res.ResolutionMetadata.Cached = true
+		return &res, nil
+	}
+	res, err := c.next.Resolve(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	// drop expired entries so a long-running process does not keep every DID it saw
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[did] = cacheEntry{res: res, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return res, nil
+}
+
+// Forget drops did from the cache, or every entry when did is empty.
+func (c *Cache) Forget(did string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if did == "" {
+		c.entries = map[string]cacheEntry{}
+		return
+	}
+	delete(c.entries, did)
+}
@@ -0,0 +1,66 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+func TestCache(t *testing.T) {
+	calls := 0
+	fail := false
+	next := Func(func(ctx context.Context, did string) (*Resolution, error) {
+		calls++
+		if fail {
+			return nil, ErrNotFound
+		}
+		return &Resolution{Document: &identity.Document{ID: did}}, nil
+	})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewCache(next, time.Minute)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	first, err := c.Resolve(ctx, "did:example:a")
+	if err != nil || first.ResolutionMetadata.Cached {
+		t.Fatalf("first resolution: %+v, %v", first, err)
+	}
+	second, err := c.Resolve(ctx, "did:example:a")
+	if err != nil || !second.ResolutionMetadata.Cached || calls != 1 {
+		t.Errorf("second resolution not cached: %+v, %v, %d calls", second, err, calls)
+	}
+	if first.ResolutionMetadata.Cached {
+		t.Error("marking a hit as cached must not change earlier results")
+	}
+
+	now = now.Add(time.Minute)
+	if res, _ := c.Resolve(ctx, "did:example:a"); res.ResolutionMetadata.Cached || calls != 2 {
+		t.Errorf("expired entry served from cache (%d calls)", calls)
+	}
+
+	c.Forget("did:example:a")
+	c.Resolve(ctx, "did:example:a")
+	if calls != 3 {
+		t.Errorf("forgotten entry served from cache (%d calls)", calls)
+	}
+
+	// failures are not cached
+	fail = true
+	if _, err := c.Resolve(ctx, "did:example:b"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	fail = false
+	if _, err := c.Resolve(ctx, "did:example:b"); err != nil || calls != 5 {
+		t.Errorf("failure was cached: %v, %d calls", err, calls)
+	}
+
+	// expired entries are evicted, not just ignored
+	now = now.Add(time.Minute)
+	c.Resolve(ctx, "did:example:c")
+	if _, ok := c.entries["did:example:a"]; ok || len(c.entries) != 1 {
+		t.Errorf("expired entries kept: %d entries", len(c.entries))
+	}
+}
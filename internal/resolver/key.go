@@ -0,0 +1,57 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+// Key resolves did:key DIDs, whose document is derived from the key in the identifier.
+type Key struct{}
+
+// Resolve builds the document of did with its key as #keys-1. The key is listed a second
+// time with the multikey itself as fragment, the method id that the did:key
+// specification gives it and that other implementations sign with.
+func (Key) Resolve(ctx context.Context, did string) (*Resolution, error) {
+	pub, err := identity.ParseDIDKey(did)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidDID, did, err)
+	}
+	doc := identity.NewDocument(did, pub)
+	vm := doc.VerificationMethod[0]
+	vm.ID = did + "#" + strings.TrimPrefix(did, "did:key:")
+	doc.VerificationMethod = append(doc.VerificationMethod, vm)
+	doc.Authentication = append(doc.Authentication, vm.ID)
+	doc.AssertionMethod = append(doc.AssertionMethod, vm.ID)
+	return derived(doc), nil
+}
+
+// JWK resolves did:jwk DIDs, whose identifier is the base64url encoding of a public JWK.
+type JWK struct{}
+
+// Resolve builds the document of did with its key as the JsonWebKey2020 method #0.
+func (JWK) Resolve(ctx context.Context, did string) (*Resolution, error) {
+	pub, err := identity.ParseDIDJWK(did)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidDID, did, err)
+	}
+	jwk, err := pub.JWK()
+	if err != nil {
+		return nil, err
+	}
+	vmID := did + "#0"
+	return derived(&identity.Document{
+		Context: []interface{}{"https://www.w3.org/ns/did/v1", "https://w3id.org/security/suites/jws-2020/v1"},
+		ID:      did,
+		VerificationMethod: []identity.VerificationMethod{{
+			ID:           vmID,
+			Type:         identity.JWKVerificationMethodType,
+			Controller:   did,
+			PublicKeyJwk: jwk,
+		}},
+		Authentication:  []string{vmID},
+		AssertionMethod: []string{vmID},
+	}), nil
+}
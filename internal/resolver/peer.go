@@ -0,0 +1,25 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+// Peer resolves numalgo 0 and 2 did:peer DIDs, whose keys and services are encoded in the
+// identifier.
+type Peer struct{}
+
+// Resolve builds the document of did from the identifier.
+func (Peer) Resolve(ctx context.Context, did string) (*Resolution, error) {
+	doc, err := identity.ParsePeerDID(did)
+	if err != nil {
+		if errors.Is(err, identity.ErrPeerNumalgo) {
+			return nil, fmt.Errorf("%w: %s: %v", ErrMethodNotSupported, did, err)
+		}
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidDID, did, err)
+	}
+	return derived(doc), nil
+}
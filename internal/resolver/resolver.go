@@ -0,0 +1,131 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+var (
+	// ErrMethodNotSupported is returned for DIDs of methods without a registered resolver.
+	ErrMethodNotSupported = errors.New("DID method not supported")
+
+	// ErrNotFound is returned when the document of a DID does not exist.
+	ErrNotFound = errors.New("DID document not found")
+
+	// ErrDeactivated is returned for DIDs whose controller has deactivated them.
+	ErrDeactivated = errors.New("DID has been deactivated")
+)
+
+// ResolutionMetadata describes how a document was resolved.
+type ResolutionMetadata struct {
+	ContentType string    `json:"contentType,omitempty"`
+	Retrieved   time.Time `json:"retrieved"`
+	Cached      bool      `json:"cached,omitempty"`
+}
+
+// DocumentMetadata holds the DID Core metadata about the resolved document itself.
+type DocumentMetadata struct {
+	Created     string `json:"created,omitempty"`
+	Updated     string `json:"updated,omitempty"`
+	VersionID   string `json:"versionId,omitempty"`
+	Deactivated bool   `json:"deactivated,omitempty"`
+}
+
+// Resolution is the result of resolving a DID, serialized in the DID resolution format.
+type Resolution struct {
+	Document           *identity.Document `json:"didDocument"`
+	ResolutionMetadata ResolutionMetadata `json:"didResolutionMetadata"`
+	DocumentMetadata   DocumentMetadata   `json:"didDocumentMetadata"`
+}
+
+// Resolver resolves a DID to its document. Callers must not modify the returned document.
+type Resolver interface {
+	Resolve(ctx context.Context, did string) (*Resolution, error)
+}
+
+// Func adapts a function to a Resolver.
+type Func func(ctx context.Context, did string) (*Resolution, error)
+
+// Resolve calls f.
+func (f Func) Resolve(ctx context.Context, did string) (*Resolution, error) {
+	return f(ctx, did)
+}
+
+// Method returns the method name of did, such as "key" for did:key:z6Mk....
+func Method(did string) (string, error) {
+	rest, ok := strings.CutPrefix(did, "did:")
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrInvalidDID, did)
+	}
+	method, id, ok := strings.Cut(rest, ":")
+	if !ok || method == "" || id == "" {
+		return "", fmt.Errorf("%w: %s", ErrInvalidDID, did)
+	}
+	return method, nil
+}
+
+// Registry dispatches resolution to the resolver registered for each DID method.
+type Registry struct {
+	methods map[string]Resolver
+}
+
+// NewRegistry returns a registry for did:key, did:web, did:jwk and did:peer. did:web
+// documents are fetched with client, or a client with DefaultWebTimeout when it is nil.
+func NewRegistry(client *http.Client) *Registry {
+	r := &Registry{methods: map[string]Resolver{}}
+	r.Register("key", Key{})
+	r.Register("web", NewWeb(client))
+	r.Register("jwk", JWK{})
+	r.Register("peer", Peer{})
+	return r
+}
+
+// Register makes res the resolver of method, replacing any previous one.
+func (r *Registry) Register(method string, res Resolver) {
+	r.methods[method] = res
+}
+
+// Methods lists the registered method names.
+func (r *Registry) Methods() []string {
+	names := make([]string, 0, len(r.methods))
+	for name := range r.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve resolves did with the resolver of its method.
+func (r *Registry) Resolve(ctx context.Context, did string) (*Resolution, error) {
+	method, err := Method(did)
+	if err != nil {
+		return nil, err
+	}
+	res, ok := r.methods[method]
+	if !ok {
+		return nil, fmt.Errorf("%w: did:%s", ErrMethodNotSupported, method)
+	}
+	out, err := res.Resolve(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	if out.ResolutionMetadata.Retrieved.IsZero() {
+		out.ResolutionMetadata.Retrieved = time.Now().UTC()
+	}
+	return out, nil
+}
+
+// derived returns the resolution of a document computed from the DID itself.
+func derived(doc *identity.Document) *Resolution {
+	return &Resolution{
+		Document:           doc,
+		ResolutionMetadata: ResolutionMetadata{ContentType: "application/did+json"},
+	}
+}
@@ -0,0 +1,87 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+func TestRegistryDerivedMethods(t *testing.T) {
+	reg := NewRegistry(nil)
+	if got := strings.Join(reg.Methods(), ","); got != "jwk,key,peer,web" {
+		t.Errorf("Methods = %s", got)
+	}
+	for _, kt := range []identity.KeyType{identity.KeyTypeEd25519, identity.KeyTypeSecp256k1, identity.KeyTypeP256} {
+		priv, err := identity.GenerateKey(kt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub := priv.Public()
+		didKey := identity.GenerateDIDForKey(pub)
+		didJWK, err := identity.GenerateDIDJWK(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for did, vmID := range map[string]string{
+			didKey: didKey + "#keys-1",
+			didJWK: didJWK + "#0",
+			"did:peer:0" + strings.TrimPrefix(didKey, "did:key:"): "did:peer:0" + strings.TrimPrefix(didKey, "did:key:") + "#keys-1",
+		} {
+			res, err := reg.Resolve(context.Background(), did)
+			if err != nil {
+				t.Fatalf("%s: Resolve(%s): %v", kt, did, err)
+			}
+			if res.Document.ID != did || res.ResolutionMetadata.Retrieved.IsZero() {
+				t.Errorf("%s: unexpected resolution %+v", kt, res)
+			}
+			vm, err := res.Document.Method(vmID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := vm.PublicKey(); err != nil || !got.Equal(pub) {
+				t.Errorf("%s: %s resolves to the wrong key: %v", kt, did, err)
+			}
+		}
+
+		// did:key also lists the key under its multikey fragment
+		res, err := reg.Resolve(context.Background(), didKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vmID := didKey + "#" + strings.TrimPrefix(didKey, "did:key:")
+		if _, err := res.Document.Method(vmID); err != nil {
+			t.Errorf("%s: %v", kt, err)
+		}
+		if !res.Document.HasRelationship(identity.PurposeAssertionMethod, vmID) {
+			t.Errorf("%s: %s is not an assertion method", kt, vmID)
+		}
+	}
+}
+
+func TestRegistryErrors(t *testing.T) {
+	reg := NewRegistry(nil)
+	for did, want := range map[string]error{
+		"did:example:123": ErrMethodNotSupported,
+		"did:peer:4zQm":   ErrMethodNotSupported,
+		"did:peer:2Ez6L":  ErrInvalidDID,
+		"not-a-did":       ErrInvalidDID,
+		"did:key:":        ErrInvalidDID,
+		"did:key:zabc":    ErrInvalidDID,
+		"did:jwk:e30":     ErrInvalidDID,
+	} {
+		if _, err := reg.Resolve(context.Background(), did); !errors.Is(err, want) {
+			t.Errorf("Resolve(%s) = %v; want %v", did, err, want)
+		}
+	}
+
+	// a registered resolver replaces the built-in one
+	reg.Register("example", Func(func(ctx context.Context, did string) (*Resolution, error) {
+		return &Resolution{Document: &identity.Document{ID: did}}, nil
+	}))
+	if res, err := reg.Resolve(context.Background(), "did:example:123"); err != nil || res.Document.ID != "did:example:123" {
+		t.Errorf("custom resolver: %v, %v", res, err)
+	}
+}
@@ -0,0 +1,158 @@
+// Package resolver resolves DIDs to their DID Documents. A Registry dispatches on the DID
+// method; did:key, did:jwk and did:peer documents are derived from the identifier, and
+// did:web documents are fetched over HTTPS. Cache keeps resolutions for a fixed time.
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+const (
+	webPrefix = "did:web:"
+
+	// maxDocumentSize bounds the DID documents read from the network.
+	maxDocumentSize = 1 << 20
+
+	// DefaultWebTimeout bounds a did:web fetch when the client has no timeout of its own.
+	DefaultWebTimeout = 10 * time.Second
+)
+
+// ErrInvalidDID is returned for identifiers that are not valid for the method.
+var ErrInvalidDID = errors.New("invalid DID")
+
+// WebURL returns the HTTPS URL of the DID document of a did:web DID. did:web:example.com
+// maps to https://example.com/.well-known/did.json and did:web:example.com:users:alice to
+// https://example.com/users/alice/did.json. A port is written percent-encoded, as in
+// did:web:localhost%3A8443.
+func WebURL(did string) (string, error) {
+	id, ok := strings.CutPrefix(did, webPrefix)
+	if !ok || id == "" {
+		return "", fmt.Errorf("%w: %s is not a did:web", ErrInvalidDID, did)
+	}
+	if i := strings.IndexAny(id, "?#"); i >= 0 {
+		id = id[:i]
+	}
+	parts := strings.Split(id, ":")
+	host, err := url.PathUnescape(parts[0])
+	if err != nil || host == "" || strings.ContainsAny(host, "/@") {
+		return "", fmt.Errorf("%w: bad domain in %s", ErrInvalidDID, did)
+	}
+	path := "/.well-known"
+	if len(parts) > 1 {
+		segs := make([]string, len(parts)-1)
+		for i, p := range parts[1:] {
+			seg, err := url.PathUnescape(p)
+			if err != nil || seg == "" || seg == "." || seg == ".." || strings.Contains(seg, "/") {
+				return "", fmt.Errorf("%w: bad path in %s", ErrInvalidDID, did)
+			}
+			segs[i] = url.PathEscape(seg)
+		}
+		path = "/" + strings.Join(segs, "/")
+	}
+	return "https://" + host + path + "/did.json", nil
+}
+
+// WebDID returns the did:web identifier for a domain, with an optional port and path,
+// such as "example.com", "localhost:8443" or "example.com/users/alice".
+func WebDID(domain string) string {
+	domain = strings.Trim(domain, "/")
+	host, path, _ := strings.Cut(domain, "/")
+	did := webPrefix + strings.ReplaceAll(host, ":", "%3A")
+	if path != "" {
+		did += ":" + strings.ReplaceAll(path, "/", ":")
+	}
+	return did
+}
+
+// Web resolves did:web DIDs by fetching their documents over HTTPS. Redirects are never
+// followed: the document must be served at the URL the DID names, and a redirect could
+// otherwise send the fetch to another host or downgrade it to plain HTTP.
+type Web struct {
+	// Client fetches documents; nil means a client with DefaultWebTimeout. Its
+	// CheckRedirect is replaced so that redirects are refused.
+	Client *http.Client
+}
+
+// NewWeb returns a did:web resolver that fetches documents with client.
+func NewWeb(client *http.Client) *Web {
+	return &Web{Client: client}
+}
+
+// Resolve fetches and parses the document of did. The document's id must be did.
+func (w *Web) Resolve(ctx context.Context, did string) (*Resolution, error) {
+	u, err := WebURL(did)
+	if err != nil {
+		return nil, err
+	}
+	client := w.httpClient()
+	if client.Timeout == 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultWebTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/did+json, application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusGone {
+		// 410 Gone is how a did:web host signals deactivation
+		return nil, fmt.Errorf("%w: %s", ErrDeactivated, did)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: fetch %s: %s", ErrNotFound, u, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: %s", u, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", u, err)
+	}
+	if len(data) > maxDocumentSize {
+		return nil, fmt.Errorf("fetch %s: document larger than %d bytes", u, maxDocumentSize)
+	}
+	doc, err := identity.ParseDIDDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if doc.ID != did {
+		return nil, fmt.Errorf("document at %s is for %s, not %s", u, doc.ID, did)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mt
+	}
+	return &Resolution{
+		Document:           doc,
+		ResolutionMetadata: ResolutionMetadata{ContentType: contentType, Retrieved: time.Now().UTC()},
+	}, nil
+}
+
+// httpClient returns a copy of w.Client, or a client with DefaultWebTimeout, that refuses
+// redirects.
+func (w *Web) httpClient() *http.Client {
+	c := http.Client{Timeout: DefaultWebTimeout}
+	if w.Client != nil {
+		c = *w.Client
+	}
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return fmt.Errorf("redirected to %s; did:web documents must be served without redirects", req.URL.Redacted())
+	}
+	return &c
+}
@@ -0,0 +1,160 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+func TestWebURL(t *testing.T) {
+	cases := map[string]string{
+		"did:web:example.com":                  "https://example.com/.well-known/did.json",
+		"did:web:example.com:users:alice":      "https://example.com/users/alice/did.json",
+		"did:web:localhost%3A8443":             "https://localhost:8443/.well-known/did.json",
+		"did:web:example.com#keys-1":           "https://example.com/.well-known/did.json",
+		"did:web:w3c-ccg.github.io:user:alice": "https://w3c-ccg.github.io/user/alice/did.json",
+	}
+	for did, want := range cases {
+		got, err := WebURL(did)
+		if err != nil || got != want {
+			t.Errorf("WebURL(%s) = %q, %v; want %q", did, got, err, want)
+		}
+	}
+	for _, did := range []string{"did:key:z6Mk", "did:web:", "did:web:example.com:..:etc", "did:web:a%2Fb", "did:web:example.com::x"} {
+		if _, err := WebURL(did); err == nil {
+			t.Errorf("WebURL(%s) should fail", did)
+		}
+	}
+}
+
+func TestWebDID(t *testing.T) {
+	cases := map[string]string{
+		"example.com":             "did:web:example.com",
+		"localhost:8443":          "did:web:localhost%3A8443",
+		"example.com/users/alice": "did:web:example.com:users:alice",
+	}
+	for domain, want := range cases {
+		if got := WebDID(domain); got != want {
+			t.Errorf("WebDID(%s) = %s; want %s", domain, got, want)
+		}
+		if _, err := WebURL(want); err != nil {
+			t.Errorf("WebURL(%s): %v", want, err)
+		}
+	}
+}
+
+// serveDocument starts a TLS server that serves doc at path and returns the did:web of
+// the server with pathParts appended.
+func serveDocument(t *testing.T, path string, doc func(did string) []byte, pathParts ...string) (*httptest.Server, string) {
+	t.Helper()
+	var did string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/did+json")
+		w.Write(doc(did))
+	}))
+	t.Cleanup(srv.Close)
+	did = WebDID(strings.TrimPrefix(srv.URL, "https://"))
+	for _, p := range pathParts {
+		did += ":" + p
+	}
+	return srv, did
+}
+
+func TestWebResolve(t *testing.T) {
+	key, err := identity.GenerateKey(identity.KeyTypeEd25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	document := func(did string) []byte {
+		data, _ := identity.NewDocument(did, key.Public()).Marshal()
+		return data
+	}
+
+	for name, tc := range map[string]struct {
+		path  string
+		parts []string
+	}{
+		"well-known": {"/.well-known/did.json", nil},
+		"path":       {"/users/alice/did.json", []string{"users", "alice"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv, did := serveDocument(t, tc.path, document, tc.parts...)
+			res, err := NewWeb(srv.Client()).Resolve(context.Background(), did)
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if res.ResolutionMetadata.ContentType != "application/did+json" {
+				t.Errorf("content type = %q", res.ResolutionMetadata.ContentType)
+			}
+			vm, err := res.Document.Method(did + "#keys-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			pub, err := vm.PublicKey()
+			if err != nil || !pub.Equal(key.Public()) {
+				t.Errorf("resolved key does not match: %v", err)
+			}
+		})
+	}
+}
+
+func TestWebResolveErrors(t *testing.T) {
+	key, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+
+	srv, did := serveDocument(t, "/.well-known/did.json", func(string) []byte {
+		data, _ := identity.NewDocument("did:web:other.example", key.Public()).Marshal()
+		return data
+	})
+	if _, err := NewWeb(srv.Client()).Resolve(context.Background(), did); err == nil {
+		t.Error("a document for another DID must be rejected")
+	}
+
+	srv, did = serveDocument(t, "/.well-known/did.json", func(string) []byte { return []byte("{") })
+	if _, err := NewWeb(srv.Client()).Resolve(context.Background(), did); err == nil {
+		t.Error("an invalid document must be rejected")
+	}
+
+	srv, did = serveDocument(t, "/elsewhere/did.json", func(string) []byte { return nil })
+	if _, err := NewWeb(srv.Client()).Resolve(context.Background(), did); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	gone := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer gone.Close()
+	did = WebDID(strings.TrimPrefix(gone.URL, "https://"))
+	if _, err := NewWeb(gone.Client()).Resolve(context.Background(), did); !errors.Is(err, ErrDeactivated) {
+		t.Errorf("expected ErrDeactivated for 410 Gone, got %v", err)
+	}
+
+	// the default client does not trust the test server's certificate
+	srv, did = serveDocument(t, "/.well-known/did.json", func(d string) []byte {
+		data, _ := identity.NewDocument(d, key.Public()).Marshal()
+		return data
+	})
+	if _, err := NewWeb(nil).Resolve(context.Background(), did); err == nil {
+		t.Error("untrusted certificate must be rejected")
+	}
+
+	// redirects are refused, even to the same host
+	moved := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, srv.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer moved.Close()
+	did = WebDID(strings.TrimPrefix(moved.URL, "https://"))
+	client := srv.Client()
+	client.CheckRedirect = nil
+	if _, err := NewWeb(client).Resolve(context.Background(), did); err == nil || !strings.Contains(err.Error(), "redirect") {
+		t.Errorf("expected a redirect to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+// Package shamir implements Shamir's secret sharing over GF(2^8), splitting each byte of
+// the secret with an independent random polynomial.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Share is one point of every polynomial; X is never zero.
+type Share struct {
+	X    byte
+	Data []byte
+}
+
+var (
+	// ErrTooFewShares is returned when combining fewer shares than the threshold used to split.
+	ErrTooFewShares = errors.New("not enough shares")
+	// ErrDuplicateShare is returned when two shares have the same X coordinate.
+	ErrDuplicateShare = errors.New("duplicate share")
+)
+
+// Split divides secret into n shares, any threshold of which recover it.
+func Split(secret []byte, n, threshold int) ([]Share, error) {
+	if threshold < 2 || threshold > n {
+		return nil, fmt.Errorf("threshold must be between 2 and the number of shares, got %d of %d", threshold, n)
+	}
+	if n > 255 {
+		return nil, fmt.Errorf("at most 255 shares, got %d", n)
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("empty secret")
+	}
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{X: byte(i + 1), Data: make([]byte, len(secret))}
+	}
+	coeffs := make([]byte, threshold)
+	defer wipe(coeffs)
+	for b, s := range secret {
+		coeffs[0] = s
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			shares[i].Data[b] = evaluate(coeffs, shares[i].X)
+		}
+	}
+	return shares, nil
+}
+
+// Combine recovers the secret from shares by Lagrange interpolation at zero. It cannot
+// tell a wrong result from a right one: callers must check the secret themselves.
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, ErrTooFewShares
+	}
+	size := len(shares[0].Data)
+	seen := make(map[byte]bool, len(shares))
+	for _, s := range shares {
+		if s.X == 0 {
+			return nil, errors.New("invalid share with x = 0")
+		}
+		if seen[s.X] {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateShare, s.X)
+		}
+		seen[s.X] = true
+		if len(s.Data) != size {
+			return nil, errors.New("shares have different lengths")
+		}
+	}
+	secret := make([]byte, size)
+	for i, si := range shares {
+		// Lagrange basis polynomial for si evaluated at 0
+		basis := byte(1)
+		for j, sj := range shares {
+			if i != j {
+				basis = mul(basis, div(sj.X, sj.X^si.X))
+			}
+		}
+		for b := range secret {
+			secret[b] ^= mul(si.Data[b], basis)
+		}
+	}
+	return secret, nil
+}
+
+// evaluate computes the polynomial with coefficients coeffs (constant first) at x.
+func evaluate(coeffs []byte, x byte) byte {
+	var y byte
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coeffs[i]
+	}
+	return y
+}
+
+var expTable, logTable [256]byte
+
+func init() {
+	// generator 3 over the AES polynomial x^8 + x^4 + x^3 + x + 1
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = x
+		logTable[x] = byte(i)
+		x ^= xtime(x)
+	}
+	expTable[255] = expTable[0]
+}
+
+func xtime(a byte) byte {
+	if a&0x80 != 0 {
+		return a<<1 ^ 0x1b
+	}
+	return a << 1
+}
+
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])+int(logTable[b]))%255]
+}
+
+func div(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return expTable[(int(logTable[a])-int(logTable[b])+255)%255]
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
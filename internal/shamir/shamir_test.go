@@ -0,0 +1,53 @@
+package shamir
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("correct horse battery staple, 32b")
+	shares, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		var picked []Share
+		for _, i := range subset {
+			picked = append(picked, shares[i])
+		}
+		got, err := Combine(picked)
+		if err != nil {
+			t.Fatalf("Combine%v: %v", subset, err)
+		}
+		if !bytes.Equal(got, secret) {
+			t.Errorf("Combine%v recovered %x", subset, got)
+		}
+	}
+	got, _ := Combine(shares[:2])
+	if bytes.Equal(got, secret) {
+		t.Error("two shares of a 3-of-5 split recovered the secret")
+	}
+}
+
+func TestCombineErrors(t *testing.T) {
+	shares, _ := Split([]byte("secret"), 3, 2)
+	if _, err := Combine(shares[:1]); !errors.Is(err, ErrTooFewShares) {
+		t.Errorf("expected ErrTooFewShares, got %v", err)
+	}
+	if _, err := Combine([]Share{shares[0], shares[0]}); !errors.Is(err, ErrDuplicateShare) {
+		t.Errorf("expected ErrDuplicateShare, got %v", err)
+	}
+	if _, err := Split([]byte("secret"), 3, 4); err == nil {
+		t.Error("expected error for threshold above share count")
+	}
+}
+
+func TestFieldInverse(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		if got := mul(byte(a), div(1, byte(a))); got != 1 {
+			t.Fatalf("a * a^-1 = %d for a = %d", got, a)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+// Operations understood by an external signer process.
+const (
+	OpPublicKey = "public-key"
+	OpSign      = "sign"
+)
+
+// ProcessRequest is written as a single JSON object to the signer process's stdin.
+type ProcessRequest struct {
+	Op   string `json:"op"`
+	Data []byte `json:"data,omitempty"` // base64 in JSON
+}
+
+// ProcessResponse is read as a single JSON object from the signer process's stdout.
+type ProcessResponse struct {
+	KeyType   identity.KeyType `json:"keyType,omitempty"`
+	PublicKey []byte           `json:"publicKey,omitempty"` // raw key, base64 in JSON
+	Signature []byte           `json:"signature,omitempty"` // base64 in JSON
+	Error     string           `json:"error,omitempty"`
+}
+
+// Process delegates signing to an external command, such as a shim in front of an HSM or
+// cloud KMS. The command is started once per request, reads one ProcessRequest on stdin
+// and answers with one ProcessResponse on stdout. Signatures must use the encoding of
+// identity.PrivateKey.Sign (raw Ed25519, or r||s over SHA-256 for ECDSA).
+type Process struct {
+	argv []string
+	pub  *identity.PublicKey
+}
+
+// NewProcess starts argv once to fetch its public key and returns a Signer for it.
+func NewProcess(ctx context.Context, argv []string) (*Process, error) {
+	if len(argv) == 0 {
+		return nil, errors.New("empty signer command")
+	}
+	p := &Process{argv: argv}
+	resp, err := p.call(ctx, &ProcessRequest{Op: OpPublicKey})
+	if err != nil {
+		return nil, err
+	}
+	if p.pub, err = identity.ParsePublicKey(resp.KeyType, resp.PublicKey); err != nil {
+		return nil, fmt.Errorf("signer %s: %w", argv[0], err)
+	}
+	return p, nil
+}
+
+func (p *Process) PublicKey() *identity.PublicKey { return p.pub }
+
+func (p *Process) Algorithm() string { return p.pub.Type.JWSAlgorithm() }
+
+// Sign sends data to the process and checks the returned signature against its public key.
+func (p *Process) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	resp, err := p.call(ctx, &ProcessRequest{Op: OpSign, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	if !p.pub.Verify(data, resp.Signature) {
+		return nil, fmt.Errorf("signer %s returned an invalid signature", p.argv[0])
+	}
+	return resp.Signature, nil
+}
+
+func (p *Process) call(ctx context.Context, req *ProcessRequest) (*ProcessResponse, error) {
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, p.argv[0], p.argv[1:]...)
+	c.Stdin = bytes.NewReader(in)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("signer %s: %w: %s", p.argv[0], err, msg)
+		}
+		return nil, fmt.Errorf("signer %s: %w", p.argv[0], err)
+	}
+	var resp ProcessResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("signer %s: invalid response: %w", p.argv[0], err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("signer %s: %s", p.argv[0], resp.Error)
+	}
+	return &resp, nil
+}
+
+// ServeProcess answers a single ProcessRequest from r on w using s. It is the other half
+// of Process, for writing signer shims in Go.
+func ServeProcess(ctx context.Context, s Signer, r io.Reader, w io.Writer) error {
+	var req ProcessRequest
+	resp := &ProcessResponse{}
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+		return json.NewEncoder(w).Encode(resp)
+	}
+	switch req.Op {
+	case OpPublicKey:
+		pub := s.PublicKey()
+		resp.KeyType, resp.PublicKey = pub.Type, pub.Raw
+	case OpSign:
+		sig, err := s.Sign(ctx, req.Data)
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		resp.Signature = sig
+	default:
+		resp.Error = fmt.Sprintf("unknown operation %q", req.Op)
+	}
+	return json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,41 @@
+// Package signer abstracts over where signing keys live. Credentials, presentations and
+// authentication responses are signed through a Signer, so the private key can stay in a
+// vault file, an agent, or an external HSM/KMS process instead of in our memory.
+package signer
+
+import (
+	"context"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+// Signer produces signatures for a single key.
+type Signer interface {
+	// PublicKey returns the verification key matching the signatures.
+	PublicKey() *identity.PublicKey
+	// Algorithm returns the JOSE "alg" of the signatures, e.g. EdDSA or ES256K.
+	Algorithm() string
+	// Sign signs data as identity.PrivateKey.Sign does.
+	Sign(ctx context.Context, data []byte) ([]byte, error)
+}
+
+// Memory signs with a private key held in process memory.
+type Memory struct {
+	key *identity.PrivateKey
+}
+
+// NewMemory returns a Signer for priv.
+func NewMemory(priv *identity.PrivateKey) *Memory {
+	return &Memory{key: priv}
+}
+
+func (m *Memory) PublicKey() *identity.PublicKey { return m.key.Public() }
+
+func (m *Memory) Algorithm() string { return m.key.Type.JWSAlgorithm() }
+
+func (m *Memory) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.key.Sign(data)
+}
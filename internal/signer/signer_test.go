@@ -0,0 +1,141 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/signer/signertest"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+	"github.com/mr-tron/base58"
+)
+
+// helperKeyEnv makes the test binary act as an external signer holding the given key.
+const helperKeyEnv = "SIGNER_TEST_HELPER_KEY"
+
+func TestMain(m *testing.M) {
+	if enc := os.Getenv(helperKeyEnv); enc != "" {
+		raw, _ := base58.Decode(enc)
+		priv, err := identity.ParsePrivateKey(identity.KeyType(os.Getenv(helperKeyEnv+"_TYPE")), raw)
+		if err != nil {
+			os.Exit(2)
+		}
+		fake := &signertest.Fake{Key: priv}
+		if os.Getenv(helperKeyEnv+"_FAIL") != "" {
+			fake.Err = errors.New("token not present")
+		}
+		ServeProcess(context.Background(), fake, os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestMemorySigner(t *testing.T) {
+	priv, _ := identity.GenerateKey(identity.KeyTypeP256)
+	s := NewMemory(priv)
+	if s.Algorithm() != "ES256" {
+		t.Errorf("Algorithm = %s; want ES256", s.Algorithm())
+	}
+	sig, err := s.Sign(context.Background(), []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.PublicKey().Verify([]byte("data"), sig) {
+		t.Error("signature did not verify")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.Sign(ctx, []byte("data")); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestVaultFileSigner(t *testing.T) {
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+	doc, _ := identity.BuildDIDDocument(did, priv.Public())
+	v := vault.NewVault(filepath.Join(t.TempDir(), "vault"))
+	v.KDF = vault.KDFParams{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	if err := v.Init(doc, priv, []byte("pass")); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewVaultFile(v, []byte("pass"))
+	if err != nil {
+		t.Fatalf("NewVaultFile: %v", err)
+	}
+	if !s.PublicKey().Equal(priv.Public()) {
+		t.Error("public key does not match the vault key")
+	}
+	sig, err := s.Sign(context.Background(), []byte("data"))
+	if err != nil || !priv.Public().Verify([]byte("data"), sig) {
+		t.Fatalf("Sign = %v", err)
+	}
+
+	// the key stays unsealed until Close
+	ks := filepath.Join(v.BaseDir, "keystore.json")
+	if err := os.Rename(ks, ks+".away"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Sign(context.Background(), []byte("more")); err != nil {
+		t.Errorf("second Sign unsealed the keystore again: %v", err)
+	}
+	s.Close()
+	if s.priv != nil {
+		t.Error("Close did not drop the key")
+	}
+	if err := os.Rename(ks+".away", ks); err != nil {
+		t.Fatal(err)
+	}
+
+	wrong, _ := NewVaultFile(v, []byte("nope"))
+	if _, err := wrong.Sign(context.Background(), []byte("data")); !errors.Is(err, vault.ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+}
+
+// helperCommand returns a command line that runs this test binary as a signer for priv.
+func helperCommand(t *testing.T, priv *identity.PrivateKey, fail bool) []string {
+	t.Helper()
+	t.Setenv(helperKeyEnv, base58.Encode(priv.Raw))
+	t.Setenv(helperKeyEnv+"_TYPE", string(priv.Type))
+	if fail {
+		t.Setenv(helperKeyEnv+"_FAIL", "1")
+	}
+	return []string{os.Args[0]}
+}
+
+func TestProcessSigner(t *testing.T) {
+	priv, _ := identity.GenerateKey(identity.KeyTypeSecp256k1)
+	s, err := NewProcess(context.Background(), helperCommand(t, priv, false))
+	if err != nil {
+		t.Fatalf("NewProcess: %v", err)
+	}
+	if !s.PublicKey().Equal(priv.Public()) || s.Algorithm() != "ES256K" {
+		t.Fatalf("unexpected key %v / %s", s.PublicKey(), s.Algorithm())
+	}
+	sig, err := s.Sign(context.Background(), []byte("data"))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if !priv.Public().Verify([]byte("data"), sig) {
+		t.Error("signature did not verify")
+	}
+}
+
+func TestProcessSignerErrors(t *testing.T) {
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	s, err := NewProcess(context.Background(), helperCommand(t, priv, true))
+	if err != nil {
+		t.Fatalf("NewProcess: %v", err)
+	}
+	if _, err := s.Sign(context.Background(), []byte("data")); err == nil {
+		t.Error("expected the signer's error to be returned")
+	}
+	if _, err := NewProcess(context.Background(), []string{filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("expected error for a missing signer command")
+	}
+}
@@ -0,0 +1,53 @@
+// Package signertest provides a fake signer.Signer for tests.
+package signertest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+// Fake is an in-memory Signer that records what it signed and can be told to fail,
+// standing in for HSM or KMS backed signers.
+type Fake struct {
+	Key *identity.PrivateKey
+	// Err, if set, is returned by Sign instead of a signature.
+	Err error
+
+	mu     sync.Mutex
+	signed [][]byte
+}
+
+// New returns a Fake with a fresh key of type kt.
+func New(kt identity.KeyType) (*Fake, error) {
+	priv, err := identity.GenerateKey(kt)
+	if err != nil {
+		return nil, err
+	}
+	return &Fake{Key: priv}, nil
+}
+
+func (f *Fake) PublicKey() *identity.PublicKey { return f.Key.Public() }
+
+func (f *Fake) Algorithm() string { return f.Key.Type.JWSAlgorithm() }
+
+func (f *Fake) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	f.mu.Lock()
+	f.signed = append(f.signed, append([]byte(nil), data...))
+	f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return f.Key.Sign(data)
+}
+
+// Signed returns the payloads passed to Sign, in order.
+func (f *Fake) Signed() [][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]byte(nil), f.signed...)
+}
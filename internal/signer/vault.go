@@ -0,0 +1,78 @@
+package signer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/vault"
+)
+
+// VaultFile signs with the key sealed in a vault's keystore.json. The keystore is
+// decrypted on the first signature, which is the costly part, and the key is kept in
+// memory until Close wipes it.
+type VaultFile struct {
+	vault      *vault.Vault
+	passphrase []byte
+	pub        *identity.PublicKey
+
+	mu   sync.Mutex
+	priv *identity.PrivateKey
+}
+
+// NewVaultFile returns a Signer for the current key of v. The public key is read from
+// did.json; the passphrase is only checked when signing. Deactivated vaults do not sign.
+func NewVaultFile(v *vault.Vault, passphrase []byte) (*VaultFile, error) {
+	if err := v.CheckActive(); err != nil {
+		return nil, err
+	}
+	doc, err := v.Document()
+	if err != nil {
+		return nil, err
+	}
+	vm, err := doc.CurrentMethod()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := vm.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return &VaultFile{vault: v, passphrase: passphrase, pub: pub}, nil
+}
+
+func (f *VaultFile) PublicKey() *identity.PublicKey { return f.pub }
+
+func (f *VaultFile) Algorithm() string { return f.pub.Type.JWSAlgorithm() }
+
+func (f *VaultFile) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.priv == nil {
+		_, priv, err := f.vault.Load(f.passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if !priv.Public().Equal(f.pub) {
+			priv.Wipe()
+			return nil, fmt.Errorf("keystore.json does not hold the key of the current verification method")
+		}
+		f.priv = priv
+	}
+	return f.priv.Sign(data)
+}
+
+// Close wipes the unsealed key. Signing afterwards unseals it again.
+func (f *VaultFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.priv != nil {
+		f.priv.Wipe()
+		f.priv = nil
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/mr-tron/base58"
+)
+
+const keystoreVersion = 1
+
+// DefaultPurposes are the verification relationships a new key is registered for.
+var DefaultPurposes = []string{"assertionMethod", "authentication"}
+
+var (
+	// ErrUnknownDID is returned when a DID has no entry in the keystore.
+	ErrUnknownDID = errors.New("unknown DID")
+	// ErrDuplicateDID is returned when adding a DID that is already stored.
+	ErrDuplicateDID = errors.New("DID already in keystore")
+	// ErrPurpose is returned when a key is used for a purpose it is not registered for.
+	ErrPurpose = errors.New("key not registered for purpose")
+)
+
+// ParsePurposes splits a comma-separated list of verification relationships,
+// rejecting any that a DID Document does not define.
+func ParsePurposes(list string) ([]string, error) {
+	var purposes []string
+	for _, p := range strings.Split(list, ",") {
+		switch p = strings.TrimSpace(p); p {
+		case identity.PurposeAuthentication, identity.PurposeAssertionMethod, identity.PurposeKeyAgreement:
+			purposes = append(purposes, p)
+		default:
+			return nil, fmt.Errorf("unknown key purpose %q: want %s, %s or %s", p,
+				identity.PurposeAuthentication, identity.PurposeAssertionMethod, identity.PurposeKeyAgreement)
+		}
+	}
+	return purposes, nil
+}
+
+// Entry is the key material and metadata stored for a single DID.
+type Entry struct {
+	KeyType    identity.KeyType `json:"keyType"`
+	PrivateKey string           `json:"privateKey"` // base58
+	Created    time.Time        `json:"created"`
+	Label      string           `json:"label,omitempty"`
+	Purpose    []string         `json:"purpose,omitempty"`
+}
+
+// Key decodes the entry's private key.
+func (e *Entry) Key() (*identity.PrivateKey, error) {
+	raw, err := base58.Decode(e.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("decoding private key: %w", err)
+	}
+	return identity.ParsePrivateKey(e.KeyType, raw)
+}
+
+// Allows reports whether the key is registered for purpose. An entry without
+// purposes has the defaults.
+func (e *Entry) Allows(purpose string) bool {
+	purposes := e.Purpose
+	if len(purposes) == 0 {
+		purposes = DefaultPurposes
+	}
+	for _, p := range purposes {
+		if p == purpose {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyStore is the multi-DID keystore persisted in <store>/keystore.json.
+type KeyStore struct {
+	path    string
+	entries map[string]*Entry
+}
+
+type keystoreFile struct {
+	Version int               `json:"version"`
+	Keys    map[string]*Entry `json:"keys"`
+}
+
+// Open reads the keystore at path. A missing file yields an empty keystore.
+// The legacy flat {"<did>": "<base58 key>"} layout is converted on load.
+func Open(path string) (*KeyStore, error) {
+	ks := &KeyStore{path: path, entries: make(map[string]*Entry)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ks, nil
+		}
+		return nil, fmt.Errorf("read keystore: %w", err)
+	}
+	if len(data) == 0 {
+		return ks, nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse keystore: %w", err)
+	}
+	if _, ok := raw["version"]; ok {
+		var f keystoreFile
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("parse keystore: %w", err)
+		}
+		if f.Version != keystoreVersion {
+			return nil, fmt.Errorf("unsupported keystore version %d", f.Version)
+		}
+		for did, e := range f.Keys {
+			if e == nil {
+				return nil, fmt.Errorf("keystore entry for %s is empty", did)
+			}
+			ks.entries[did] = e
+		}
+		return ks, nil
+	}
+
+	// Legacy layout
+	for did, v := range raw {
+		var enc string
+		if err := json.Unmarshal(v, &enc); err != nil {
+			return nil, fmt.Errorf("parse legacy keystore entry %s: %w", did, err)
+		}
+		ks.entries[did] = &Entry{
+			KeyType:    identity.KeyTypeEd25519,
+			PrivateKey: enc,
+			Purpose:    append([]string(nil), DefaultPurposes...),
+		}
+	}
+	return ks, nil
+}
+
+// Save atomically writes the keystore back to disk with 0600 permissions.
+func (ks *KeyStore) Save() error {
+	data, err := json.MarshalIndent(keystoreFile{Version: keystoreVersion, Keys: ks.entries}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal keystore: %w", err)
+	}
+	if err := fsutil.WriteFile(ks.path, data, 0600); err != nil {
+		return fmt.Errorf("write keystore: %w", err)
+	}
+	return nil
+}
+
+// Add stores a new entry for did. Missing metadata is filled with defaults.
+func (ks *KeyStore) Add(did string, e Entry) error {
+	if _, ok := ks.entries[did]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateDID, did)
+	}
+	if e.KeyType == "" {
+		e.KeyType = identity.KeyTypeEd25519
+	}
+	if e.Created.IsZero() {
+		e.Created = time.Now().UTC()
+	}
+	if len(e.Purpose) == 0 {
+		e.Purpose = append([]string(nil), DefaultPurposes...)
+	}
+	ks.entries[did] = &e
+	return nil
+}
+
+// Get returns the entry for did.
+func (ks *KeyStore) Get(did string) (*Entry, error) {
+	e, ok := ks.entries[did]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDID, did)
+	}
+	return e, nil
+}
+
+// Delete removes did from the keystore.
+func (ks *KeyStore) Delete(did string) error {
+	if _, ok := ks.entries[did]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownDID, did)
+	}
+	delete(ks.entries, did)
+	return nil
+}
+
+// Rename changes the human-readable label of did. The DID itself is derived from its key
+// and cannot change.
+func (ks *KeyStore) Rename(did, label string) error {
+	e, err := ks.Get(did)
+	if err != nil {
+		return err
+	}
+	e.Label = label
+	return nil
+}
+
+// DIDs returns all stored DIDs in sorted order.
+func (ks *KeyStore) DIDs() []string {
+	dids := make([]string, 0, len(ks.entries))
+	for did := range ks.entries {
+		dids = append(dids, did)
+	}
+	sort.Strings(dids)
+	return dids
+}
+
+// LoadKey opens the keystore at path and returns the private key for did, which
+// must be registered for purpose.
+func LoadKey(path, did, purpose string) (*identity.PrivateKey, error) {
+	ks, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	e, err := ks.Get(did)
+	if err != nil {
+		return nil, err
+	}
+	if !e.Allows(purpose) {
+		return nil, fmt.Errorf("%w %s: %s", ErrPurpose, purpose, did)
+	}
+	return e.Key()
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/mr-tron/base58"
+)
+
+func TestKeyStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	ks, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open on missing file: %v", err)
+	}
+	priv, _ := identity.GenerateKey(identity.KeyTypeSecp256k1)
+	did := identity.GenerateDIDForKey(priv.Public())
+	if err := ks.Add(did, Entry{KeyType: priv.Type, PrivateKey: base58.Encode(priv.Raw), Label: "main"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := ks.Add(did, Entry{}); !errors.Is(err, ErrDuplicateDID) {
+		t.Errorf("expected ErrDuplicateDID, got %v", err)
+	}
+	if err := ks.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("keystore permissions = %o; want 600", info.Mode().Perm())
+	}
+
+	got, err := LoadKey(path, did, identity.PurposeAssertionMethod)
+	if err != nil {
+		t.Fatalf("LoadKey failed: %v", err)
+	}
+	if got.Type != priv.Type || !bytes.Equal(got.Raw, priv.Raw) {
+		t.Error("loaded key differs from original")
+	}
+	ks2, _ := Open(path)
+	e, _ := ks2.Get(did)
+	if e.Label != "main" || e.KeyType != identity.KeyTypeSecp256k1 || e.Created.IsZero() || len(e.Purpose) == 0 {
+		t.Errorf("metadata not persisted: %+v", e)
+	}
+}
+
+func TestKeyStoreDeleteRename(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	ks, _ := Open(path)
+	ks.Add("did:example:a", Entry{PrivateKey: "x"})
+	if err := ks.Rename("did:example:a", "renamed"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if e, _ := ks.Get("did:example:a"); e.Label != "renamed" {
+		t.Errorf("label = %q; want renamed", e.Label)
+	}
+	if err := ks.Delete("did:example:a"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := ks.Delete("did:example:a"); !errors.Is(err, ErrUnknownDID) {
+		t.Errorf("expected ErrUnknownDID, got %v", err)
+	}
+	if _, err := ks.Get("did:example:a"); !errors.Is(err, ErrUnknownDID) {
+		t.Errorf("expected ErrUnknownDID, got %v", err)
+	}
+}
+
+func TestKeyPurposes(t *testing.T) {
+	if _, err := ParsePurposes("authentication,signing"); err == nil {
+		t.Error("ParsePurposes accepted an unknown purpose")
+	}
+	purposes, err := ParsePurposes("authentication, keyAgreement")
+	if err != nil || len(purposes) != 2 || purposes[1] != identity.PurposeKeyAgreement {
+		t.Fatalf("ParsePurposes = %v, %v", purposes, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	ks, _ := Open(path)
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+	if err := ks.Add(did, Entry{PrivateKey: base58.Encode(priv.Raw), Purpose: purposes}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKey(path, did, identity.PurposeAuthentication); err != nil {
+		t.Errorf("LoadKey for authentication: %v", err)
+	}
+	if _, err := LoadKey(path, did, identity.PurposeAssertionMethod); !errors.Is(err, ErrPurpose) {
+		t.Errorf("expected ErrPurpose, got %v", err)
+	}
+	if !(&Entry{}).Allows(identity.PurposeAssertionMethod) {
+		t.Error("an entry without purposes should have the defaults")
+	}
+}
+
+func TestOpenLegacyKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	_, priv, _ := identity.GenerateKeyPair()
+	legacy := `{"did:key:zLegacy": "` + identity.EncodePrivateKey(priv) + `"}`
+	os.WriteFile(path, []byte(legacy), 0600)
+
+	ks, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open legacy failed: %v", err)
+	}
+	if dids := ks.DIDs(); len(dids) != 1 || dids[0] != "did:key:zLegacy" {
+		t.Fatalf("unexpected DIDs: %v", dids)
+	}
+	e, _ := ks.Get("did:key:zLegacy")
+	if k, err := e.Key(); err != nil || !bytes.Equal(k.Raw, priv) {
+		t.Errorf("legacy key not decoded: %v", err)
+	}
+}
+
+func TestOpenCorruptKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	os.WriteFile(path, []byte("{not json"), 0600)
+	if _, err := Open(path); err == nil {
+		t.Error("expected error on corrupt keystore")
+	}
+}
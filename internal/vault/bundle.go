@@ -0,0 +1,320 @@
+package vault
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	bundleFormat       = "ego-vault-bundle"
+	bundleVersion      = 1
+	bundleManifestName = "manifest.json"
+)
+
+// ErrBundleCorrupt is returned when a bundle fails its integrity checks.
+var ErrBundleCorrupt = errors.New("vault bundle is corrupted")
+
+// BundleManifest describes the contents of an exported vault.
+type BundleManifest struct {
+	Version int          `json:"version"`
+	Name    string       `json:"name"`
+	DID     string       `json:"did"`
+	Created time.Time    `json:"created"`
+	Files   []BundleFile `json:"files"`
+}
+
+// BundleFile is a vault file recorded in the manifest, with its path relative to the vault.
+type BundleFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// bundleFile is the on-disk layout of an exported vault: a gzipped tar of the vault plus
+// manifest.json, sealed with a passphrase like keystore.json.
+type bundleFile struct {
+	Format  string       `json:"format"`
+	Version int          `json:"version"`
+	Crypto  keystoreSeal `json:"crypto"`
+}
+
+// Bundle is a decrypted and verified vault export.
+type Bundle struct {
+	Manifest BundleManifest
+	files    map[string][]byte
+}
+
+// Export writes every file of the vault, with all its identities, under name as an
+// encrypted bundle to w. The manifest records the DID of the active identity. The vault
+// and each identity stay locked while they are read, so the bundle is a consistent
+// snapshot.
+func (v *Vault) Export(w io.Writer, name string, passphrase []byte) (*BundleManifest, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrEmptyPassphrase
+	}
+	lock, err := fsutil.LockDir(v.BaseDir)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	ix, err := OpenIndex(v.BaseDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ix.Identities {
+		// an identity in the single-identity layout lives at the vault root, locked above
+		if d := ix.Dir(id.Name); d != v.BaseDir {
+			l, err := fsutil.LockDir(d)
+			if err != nil {
+				return nil, err
+			}
+			defer l.Unlock()
+		}
+	}
+	dir, err := IdentityDir(v.BaseDir, "")
+	if err != nil {
+		return nil, err
+	}
+	did, err := NewVault(dir).DID()
+	if err != nil {
+		return nil, err
+	}
+	manifest := &BundleManifest{Version: bundleVersion, Name: name, DID: did, Created: time.Now().UTC()}
+	files := make(map[string][]byte)
+	err = filepath.WalkDir(v.BaseDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() || d.Name() == fsutil.LockFilename {
+			return err
+		}
+		rel, err := filepath.Rel(v.BaseDir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		sum := sha256.Sum256(data)
+		files[rel] = data
+		manifest.Files = append(manifest.Files, BundleFile{Path: rel, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read vault: %w", err)
+	}
+
+	archive, err := writeBundleArchive(manifest, files)
+	if err != nil {
+		return nil, err
+	}
+	params := v.KDF
+	params.Salt = make([]byte, 16)
+	if _, err := rand.Read(params.Salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(deriveKey(passphrase, params))
+	if err != nil {
+		return nil, fmt.Errorf("init cipher: %w", err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	out := bundleFile{
+		Format:  bundleFormat,
+		Version: bundleVersion,
+		Crypto: keystoreSeal{
+			Cipher:     cipherXChaCha,
+			Nonce:      nonce,
+			Ciphertext: aead.Seal(nil, nonce, archive, []byte(bundleFormat)),
+			KDF:        kdfArgon2id,
+			KDFParams:  params,
+		},
+	}
+	if err := json.NewEncoder(w).Encode(out); err != nil {
+		return nil, fmt.Errorf("write bundle: %w", err)
+	}
+	return manifest, nil
+}
+
+// OpenBundle decrypts a bundle written by Export and checks every file against the manifest.
+func OpenBundle(r io.Reader, passphrase []byte) (*Bundle, error) {
+	var bf bundleFile
+	if err := json.NewDecoder(r).Decode(&bf); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBundleCorrupt, err)
+	}
+	if bf.Format != bundleFormat {
+		return nil, fmt.Errorf("not a vault bundle")
+	}
+	if bf.Version != bundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d", bf.Version)
+	}
+	seal := bf.Crypto
+	if seal.KDF != kdfArgon2id || seal.Cipher != cipherXChaCha {
+		return nil, fmt.Errorf("unsupported bundle encryption %s/%s", seal.KDF, seal.Cipher)
+	}
+	// the parameters are not authenticated until the key they derive opens the bundle
+	if err := seal.KDFParams.check(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBundleCorrupt, err)
+	}
+	aead, err := chacha20poly1305.NewX(deriveKey(passphrase, seal.KDFParams))
+	if err != nil {
+		return nil, fmt.Errorf("init cipher: %w", err)
+	}
+	if len(seal.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce", ErrBundleCorrupt)
+	}
+	archive, err := aead.Open(nil, seal.Nonce, seal.Ciphertext, []byte(bundleFormat))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return readBundleArchive(archive)
+}
+
+// Extract writes the bundle's files into dir, which must not exist yet. Files are first
+// written to a temporary sibling directory so a failed import leaves nothing behind.
+func (b *Bundle) Extract(dir string) error {
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("%s already exists", dir)
+	}
+	parent := filepath.Dir(dir)
+	if err := os.MkdirAll(parent, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(parent, "."+filepath.Base(dir)+".import-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	for _, f := range b.Manifest.Files {
+		p := filepath.Join(tmp, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p, b.files[f.Path], 0600); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp, dir)
+}
+
+func writeBundleArchive(manifest *BundleManifest, files map[string][]byte) ([]byte, error) {
+	mdata, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: manifest.Created}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	if err := add(bundleManifestName, mdata); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := add("vault/"+name, files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func readBundleArchive(archive []byte) (*Bundle, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBundleCorrupt, err)
+	}
+	tr := tar.NewReader(gz)
+	b := &Bundle{files: make(map[string][]byte)}
+	var manifest []byte
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBundleCorrupt, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("%w: unexpected entry %s", ErrBundleCorrupt, hdr.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBundleCorrupt, err)
+		}
+		if hdr.Name == bundleManifestName {
+			manifest = data
+			continue
+		}
+		name, ok := strings.CutPrefix(hdr.Name, "vault/")
+		if !ok || !safeBundlePath(name) {
+			return nil, fmt.Errorf("%w: unsafe path %s", ErrBundleCorrupt, hdr.Name)
+		}
+		b.files[name] = data
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: missing manifest", ErrBundleCorrupt)
+	}
+	if err := json.Unmarshal(manifest, &b.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBundleCorrupt, err)
+	}
+	if len(b.Manifest.Files) != len(b.files) {
+		return nil, fmt.Errorf("%w: manifest lists %d files, archive holds %d", ErrBundleCorrupt, len(b.Manifest.Files), len(b.files))
+	}
+	for _, f := range b.Manifest.Files {
+		data, ok := b.files[f.Path]
+		sum := sha256.Sum256(data)
+		if !ok || int64(len(data)) != f.Size || hex.EncodeToString(sum[:]) != f.SHA256 {
+			return nil, fmt.Errorf("%w: %s does not match the manifest", ErrBundleCorrupt, f.Path)
+		}
+	}
+	_, hasIndex := b.files[indexFilename]
+	if _, ok := b.files[didFilename]; !ok && !hasIndex {
+		return nil, fmt.Errorf("%w: missing %s", ErrBundleCorrupt, indexFilename)
+	}
+	return b, nil
+}
+
+// safeBundlePath rejects absolute paths and paths that escape the vault directory.
+func safeBundlePath(name string) bool {
+	if name == "" || strings.HasPrefix(name, "/") || strings.Contains(name, "\\") {
+		return false
+	}
+	clean := path.Clean(name)
+	return clean == name && clean != ".." && !strings.HasPrefix(clean, "../")
+}
@@ -0,0 +1,158 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+func TestExportOpenBundle(t *testing.T) {
+	v := newTestVault(t)
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+	doc, _ := identity.BuildDIDDocument(did, priv.Public())
+	if err := v.Init(doc, priv, []byte("vault-pass")); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(v.BaseDir, "credentials"), 0700)
+	os.WriteFile(filepath.Join(v.BaseDir, "credentials", "vc1.json"), []byte(`{"id":"vc1"}`), 0600)
+	os.WriteFile(filepath.Join(v.BaseDir, "revocations.json"), []byte(`[]`), 0600)
+
+	// a single-identity vault is exported in its own layout
+	var buf bytes.Buffer
+	m, err := v.Export(&buf, "alice", []byte("bundle-pass"))
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if m.DID != did || len(m.Files) != 5 {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("vc1")) {
+		t.Error("bundle contains plaintext vault data")
+	}
+
+	if _, err := OpenBundle(bytes.NewReader(buf.Bytes()), []byte("wrong")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+	b, err := OpenBundle(bytes.NewReader(buf.Bytes()), []byte("bundle-pass"))
+	if err != nil {
+		t.Fatalf("OpenBundle: %v", err)
+	}
+	if b.Manifest.Name != "alice" {
+		t.Errorf("name = %q", b.Manifest.Name)
+	}
+
+	dest := filepath.Join(t.TempDir(), "imported")
+	if err := b.Extract(dest); err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	id, err := IdentityDir(dest, "")
+	if err != nil {
+		t.Fatalf("IdentityDir: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(id, "credentials", "vc1.json")); string(data) != `{"id":"vc1"}` {
+		t.Errorf("credential not restored: %q", data)
+	}
+	if _, got, err := NewVault(id).Load([]byte("vault-pass")); err != nil || !got.Public().Equal(priv.Public()) {
+		t.Errorf("imported vault does not unlock: %v", err)
+	}
+	if err := b.Extract(dest); err == nil {
+		t.Error("expected Extract to refuse an existing directory")
+	}
+}
+
+func TestExportWaitsForLock(t *testing.T) {
+	v := newTestVault(t)
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	doc, _ := identity.BuildDIDDocument(identity.GenerateDIDForKey(priv.Public()), priv.Public())
+	if err := v.Init(doc, priv, []byte("vault-pass")); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := v.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := v.Export(io.Discard, "alice", []byte("bundle-pass"))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("Export ran while the vault was locked: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	lock.Unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+}
+
+func TestOpenBundleTampered(t *testing.T) {
+	v := newTestVault(t)
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	doc, _ := identity.BuildDIDDocument(identity.GenerateDIDForKey(priv.Public()), priv.Public())
+	v.Init(doc, priv, []byte("p"))
+	var buf bytes.Buffer
+	if _, err := v.Export(&buf, "x", []byte("bundle-pass")); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// flip a character inside the base64 ciphertext
+	i := bytes.Index(data, []byte(`"ciphertext":"`)) + len(`"ciphertext":"`) + 10
+	if data[i] == 'A' {
+		data[i] = 'B'
+	} else {
+		data[i] = 'A'
+	}
+	if _, err := OpenBundle(bytes.NewReader(data), []byte("bundle-pass")); err == nil {
+		t.Error("expected tampered bundle to be rejected")
+	}
+}
+
+func TestSafeBundlePath(t *testing.T) {
+	for p, want := range map[string]bool{
+		"did.json":             true,
+		"credentials/a.json":   true,
+		"../etc/passwd":        false,
+		"/abs":                 false,
+		"a/../../b":            false,
+		"credentials/./a.json": false,
+	} {
+		if got := safeBundlePath(p); got != want {
+			t.Errorf("safeBundlePath(%q) = %v; want %v", p, got, want)
+		}
+	}
+}
+
+func TestOpenBundleCorruptedKDFParams(t *testing.T) {
+	v := newTestVault(t)
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	doc, _ := identity.BuildDIDDocument(identity.GenerateDIDForKey(priv.Public()), priv.Public())
+	v.Init(doc, priv, []byte("p"))
+	var buf bytes.Buffer
+	if _, err := v.Export(&buf, "x", []byte("bundle-pass")); err != nil {
+		t.Fatal(err)
+	}
+	for name, corrupt := range map[string]func(*KDFParams){
+		"no rounds":   func(p *KDFParams) { p.Time = 0 },
+		"no threads":  func(p *KDFParams) { p.Threads = 0 },
+		"huge memory": func(p *KDFParams) { p.Memory = 1 << 31 },
+		"long key":    func(p *KDFParams) { p.KeyLen = 64 },
+	} {
+		var bf bundleFile
+		json.Unmarshal(buf.Bytes(), &bf)
+		corrupt(&bf.Crypto.KDFParams)
+		data, _ := json.Marshal(bf)
+		if _, err := OpenBundle(bytes.NewReader(data), []byte("bundle-pass")); !errors.Is(err, ErrBundleCorrupt) {
+			t.Errorf("%s: got %v, want ErrBundleCorrupt", name, err)
+		}
+	}
+}
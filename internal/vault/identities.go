@@ -0,0 +1,306 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
+)
+
+const (
+	indexFilename = "identities.json"
+	identitiesDir = "identities"
+	indexVersion  = 1
+
+	// DefaultIdentity names the first identity of a vault, and the identity that a
+	// single-identity vault is migrated into.
+	DefaultIdentity = "default"
+)
+
+var (
+	// ErrNoIdentity is returned for identity names that are not in the vault.
+	ErrNoIdentity = errors.New("no such identity")
+
+	identityNameRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// IdentityEntry describes one identity held by a vault.
+type IdentityEntry struct {
+	Name    string    `json:"name"`
+	DID     string    `json:"did"`
+	Created time.Time `json:"created"`
+}
+
+// Index is the vault-level list of identities kept in identities.json. Each identity lives
+// in identities/<name>/ with its own did.json, keystore.json, attributes and credentials,
+// and is opened with NewVault(ix.Dir(name)).
+type Index struct {
+	Version    int              `json:"version"`
+	Active     string           `json:"active"`
+	Identities []*IdentityEntry `json:"identities"`
+
+	root string
+	// legacy is set for a vault in the single-identity layout, whose only identity lives
+	// at root
+	legacy bool
+	// removed lists identities whose directories are deleted once the index is saved
+	removed []string
+}
+
+// OpenIndex reads the identity index of the vault at root without changing anything. A
+// vault in the older single-identity layout, with did.json at its root, reads as the
+// single identity DefaultIdentity kept at root until Migrate moves it. A directory that
+// holds no vault yet has an empty index.
+func OpenIndex(root string) (*Index, error) {
+	ix, err := readIndex(root)
+	if err != nil {
+		return nil, err
+	}
+	didDoc, err := os.ReadFile(filepath.Join(root, didFilename))
+	if os.IsNotExist(err) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(ix.Identities) > 0 {
+		return nil, fmt.Errorf("the migration of %s into %s/ was interrupted; migrate it again to finish", root, identitiesDir)
+	}
+	did, err := DIDFromDocument(didDoc)
+	if err != nil {
+		return nil, err
+	}
+	ix.Identities = []*IdentityEntry{{Name: DefaultIdentity, DID: did}}
+	ix.Active, ix.legacy = DefaultIdentity, true
+	return ix, nil
+}
+
+// Migrate moves a vault in the single-identity layout into the identity DefaultIdentity
+// and writes its index, holding the vault lock. Other vaults are left as they are.
+func Migrate(root string) error {
+	lock, err := fsutil.LockDir(root)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	if err := migrateSingleIdentity(root); err != nil {
+		return fmt.Errorf("migrate vault: %w", err)
+	}
+	return nil
+}
+
+// UpdateIndex applies fn to the index of the vault at root and saves it, holding the vault
+// lock throughout. A vault in the single-identity layout is migrated first. The
+// directories of identities removed by fn are deleted once the index no longer lists them.
+func UpdateIndex(root string, fn func(ix *Index) error) error {
+	lock, err := fsutil.LockDir(root)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	if err := migrateSingleIdentity(root); err != nil {
+		return fmt.Errorf("migrate vault: %w", err)
+	}
+	ix, err := readIndex(root)
+	if err != nil {
+		return err
+	}
+	if err := fn(ix); err != nil {
+		return err
+	}
+	if err := ix.save(); err != nil {
+		return err
+	}
+	for _, name := range ix.removed {
+		if err := os.RemoveAll(ix.Dir(name)); err != nil {
+			return fmt.Errorf("remove identity %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// IdentityDir returns the directory of identity name in the vault at root, or of the
+// active identity when name is empty.
+func IdentityDir(root, name string) (string, error) {
+	ix, err := OpenIndex(root)
+	if err != nil {
+		return "", err
+	}
+	if len(ix.Identities) == 0 {
+		return "", fmt.Errorf("%s is not a vault", root)
+	}
+	if name == "" {
+		name = ix.Active
+	}
+	if _, ok := ix.Get(name); !ok {
+		return "", fmt.Errorf("%w: %q", ErrNoIdentity, name)
+	}
+	return ix.Dir(name), nil
+}
+
+// IsVault reports whether dir holds a vault in either layout.
+func IsVault(dir string) bool {
+	for _, name := range []string{indexFilename, didFilename} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Dir returns the directory of the named identity.
+func (ix *Index) Dir(name string) string {
+	if ix.legacy && name == DefaultIdentity {
+		return ix.root
+	}
+	return filepath.Join(ix.root, identitiesDir, name)
+}
+
+// Get returns the entry of the named identity.
+func (ix *Index) Get(name string) (*IdentityEntry, bool) {
+	for _, e := range ix.Identities {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// Add records a new identity whose files are already in Dir(name). The first identity
+// becomes the active one.
+func (ix *Index) Add(name, did string) error {
+	if err := ValidateIdentityName(name); err != nil {
+		return err
+	}
+	if _, ok := ix.Get(name); ok {
+		return fmt.Errorf("identity %q already exists", name)
+	}
+	ix.Identities = append(ix.Identities, &IdentityEntry{Name: name, DID: did, Created: time.Now().UTC()})
+	sort.Slice(ix.Identities, func(i, j int) bool { return ix.Identities[i].Name < ix.Identities[j].Name })
+	if ix.Active == "" {
+		ix.Active = name
+	}
+	return nil
+}
+
+// Remove drops the named identity from the index. UpdateIndex deletes its directory after
+// saving the index, so an interrupted removal never leaves the index listing an identity
+// whose files are gone. The active identity cannot be removed; switch to another one first.
+func (ix *Index) Remove(name string) error {
+	if _, ok := ix.Get(name); !ok {
+		return fmt.Errorf("%w: %q", ErrNoIdentity, name)
+	}
+	if name == ix.Active {
+		return fmt.Errorf("identity %q is active; switch to another identity first", name)
+	}
+	kept := ix.Identities[:0]
+	for _, e := range ix.Identities {
+		if e.Name != name {
+			kept = append(kept, e)
+		}
+	}
+	ix.Identities = kept
+	ix.removed = append(ix.removed, name)
+	return nil
+}
+
+// Switch makes the named identity the active one.
+func (ix *Index) Switch(name string) error {
+	if _, ok := ix.Get(name); !ok {
+		return fmt.Errorf("%w: %q", ErrNoIdentity, name)
+	}
+	ix.Active = name
+	return nil
+}
+
+// ValidateIdentityName rejects names that are not usable as a directory name.
+func ValidateIdentityName(name string) error {
+	if !identityNameRE.MatchString(name) {
+		return fmt.Errorf("invalid identity name %q", name)
+	}
+	return nil
+}
+
+func (ix *Index) save() error {
+	data, err := json.MarshalIndent(ix, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fsutil.WriteFile(filepath.Join(ix.root, indexFilename), data, 0600); err != nil {
+		return fmt.Errorf("write %s: %w", indexFilename, err)
+	}
+	return nil
+}
+
+func readIndex(root string) (*Index, error) {
+	ix := &Index{Version: indexVersion, root: root}
+	data, err := os.ReadFile(filepath.Join(root, indexFilename))
+	if os.IsNotExist(err) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", indexFilename, err)
+	}
+	if err := json.Unmarshal(data, ix); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", indexFilename, err)
+	}
+	if ix.Version != indexVersion {
+		return nil, fmt.Errorf("unsupported %s version %d", indexFilename, ix.Version)
+	}
+	return ix, nil
+}
+
+// migrateSingleIdentity moves the files of a single-identity vault into
+// identities/default and writes the index; the vault lock must be held. did.json is moved
+// last, so an interrupted migration is picked up again on the next run.
+func migrateSingleIdentity(root string) error {
+	didDoc, err := os.ReadFile(filepath.Join(root, didFilename))
+	if os.IsNotExist(err) {
+		// not a single-identity vault, or another process migrated it first
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	did, err := DIDFromDocument(didDoc)
+	if err != nil {
+		return err
+	}
+	ix, err := readIndex(root)
+	if err != nil {
+		return err
+	}
+
+	target := ix.Dir(DefaultIdentity)
+	if err := os.MkdirAll(target, 0700); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		switch e.Name() {
+		case fsutil.LockFilename, identitiesDir, indexFilename, didFilename:
+			continue
+		}
+		if err := os.Rename(filepath.Join(root, e.Name()), filepath.Join(target, e.Name())); err != nil {
+			return err
+		}
+	}
+	if _, ok := ix.Get(DefaultIdentity); !ok {
+		if err := ix.Add(DefaultIdentity, did); err != nil {
+			return err
+		}
+	}
+	if err := ix.save(); err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(root, didFilename), filepath.Join(target, didFilename))
+}
@@ -0,0 +1,130 @@
+package vault
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+func initIdentity(t *testing.T, dir string) string {
+	t.Helper()
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+	doc, _ := identity.BuildDIDDocument(did, priv.Public())
+	v := NewVault(dir)
+	v.KDF = testKDF
+	if err := v.Init(doc, priv, []byte("pass")); err != nil {
+		t.Fatal(err)
+	}
+	return did
+}
+
+func TestMigrateSingleIdentity(t *testing.T) {
+	root := t.TempDir()
+	did := initIdentity(t, root)
+	os.MkdirAll(filepath.Join(root, "credentials"), 0700)
+	os.WriteFile(filepath.Join(root, "credentials", "vc1.json"), []byte(`{}`), 0600)
+	os.WriteFile(filepath.Join(root, "attributes.json"), []byte(`{"name":"Ada"}`), 0600)
+
+	// reading the vault leaves it in place
+	dir, err := IdentityDir(root, "")
+	if err != nil {
+		t.Fatalf("IdentityDir: %v", err)
+	}
+	if dir != root {
+		t.Errorf("legacy dir = %s", dir)
+	}
+	if _, err := os.Stat(filepath.Join(root, indexFilename)); !os.IsNotExist(err) {
+		t.Error("reading the index migrated the vault")
+	}
+
+	if err := Migrate(root); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if dir, err = IdentityDir(root, ""); err != nil {
+		t.Fatalf("IdentityDir: %v", err)
+	}
+	if dir != filepath.Join(root, "identities", DefaultIdentity) {
+		t.Errorf("dir = %s", dir)
+	}
+	if got, _ := NewVault(dir).DID(); got != did {
+		t.Errorf("migrated DID = %s; want %s", got, did)
+	}
+	for _, name := range []string{"keystore.json", "attributes.json", "credentials/vc1.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not migrated: %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+			t.Errorf("%s left at the vault root", name)
+		}
+	}
+	ix, err := OpenIndex(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ix.Active != DefaultIdentity || len(ix.Identities) != 1 || ix.Identities[0].DID != did {
+		t.Errorf("unexpected index %+v", ix)
+	}
+}
+
+func TestIndexAddSwitchRemove(t *testing.T) {
+	root := t.TempDir()
+	if _, err := IdentityDir(root, ""); err == nil {
+		t.Error("expected an error for an empty directory")
+	}
+	ix, _ := OpenIndex(root)
+	aliceDID := initIdentity(t, ix.Dir("alice"))
+	bobDID := initIdentity(t, ix.Dir("bob"))
+	err := UpdateIndex(root, func(ix *Index) error {
+		if err := ix.Add("alice", aliceDID); err != nil {
+			return err
+		}
+		return ix.Add("bob", bobDID)
+	})
+	if err != nil {
+		t.Fatalf("UpdateIndex: %v", err)
+	}
+	if dir, _ := IdentityDir(root, ""); dir != ix.Dir("alice") {
+		t.Errorf("active identity dir = %s; want alice", dir)
+	}
+	if dir, _ := IdentityDir(root, "bob"); dir != ix.Dir("bob") {
+		t.Errorf("bob dir = %s", dir)
+	}
+	if _, err := IdentityDir(root, "carol"); !errors.Is(err, ErrNoIdentity) {
+		t.Errorf("expected ErrNoIdentity, got %v", err)
+	}
+
+	if err := UpdateIndex(root, func(ix *Index) error { return ix.Add("alice", aliceDID) }); err == nil {
+		t.Error("expected duplicate identity to be refused")
+	}
+	if err := UpdateIndex(root, func(ix *Index) error { return ix.Add("../x", aliceDID) }); err == nil {
+		t.Error("expected invalid name to be refused")
+	}
+	if err := UpdateIndex(root, func(ix *Index) error { return ix.Remove("alice") }); err == nil {
+		t.Error("expected removing the active identity to be refused")
+	}
+	if err := UpdateIndex(root, func(ix *Index) error { return ix.Switch("bob") }); err != nil {
+		t.Fatal(err)
+	}
+	// nothing is deleted unless the index is saved
+	failed := errors.New("fail")
+	if err := UpdateIndex(root, func(ix *Index) error { ix.Remove("alice"); return failed }); !errors.Is(err, failed) {
+		t.Fatalf("got %v", err)
+	}
+	if _, err := os.Stat(ix.Dir("alice")); err != nil {
+		t.Errorf("identity deleted by a failed update: %v", err)
+	}
+	if err := UpdateIndex(root, func(ix *Index) error { return ix.Remove("alice") }); err != nil {
+		t.Fatal(err)
+	}
+	ix, _ = OpenIndex(root)
+	if ix.Active != "bob" || len(ix.Identities) != 1 {
+		t.Errorf("unexpected index %+v", ix)
+	}
+	if _, err := os.Stat(ix.Dir("alice")); !os.IsNotExist(err) {
+		t.Error("removed identity directory still exists")
+	}
+}
@@ -0,0 +1,204 @@
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	keystoreVersion = 1
+	kdfArgon2id     = "argon2id"
+	cipherXChaCha   = "xchacha20-poly1305"
+)
+
+var (
+	// ErrWrongPassphrase is returned when a keystore cannot be opened with the given passphrase.
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupted keystore")
+	// ErrEmptyPassphrase is returned when sealing a keystore with an empty passphrase.
+	ErrEmptyPassphrase = errors.New("passphrase must not be empty")
+)
+
+// KDFParams are the Argon2id parameters used to derive the keystore encryption key.
+// They are recorded in keystore.json so they can be raised later without breaking old vaults.
+type KDFParams struct {
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"` // KiB
+	Threads uint8  `json:"threads"`
+	KeyLen  uint32 `json:"keyLen"`
+}
+
+// Bounds on the KDF parameters read from keystore.json and bundles, which are checked
+// before deriving a key: Argon2id panics with no rounds or threads, and a forged memory
+// cost would exhaust memory instead of failing.
+const (
+	maxKDFTime   = 64
+	maxKDFMemory = 1 << 20 // KiB, 1 GiB
+)
+
+// DefaultKDFParams are applied to newly sealed keystores (salt is generated per keystore).
+var DefaultKDFParams = KDFParams{
+	Time:    3,
+	Memory:  64 * 1024,
+	Threads: 4,
+	KeyLen:  chacha20poly1305.KeySize,
+}
+
+// encryptedKeystore is the on-disk layout of an encrypted keystore.json.
+type encryptedKeystore struct {
+	Version int              `json:"version"`
+	KeyType identity.KeyType `json:"keyType,omitempty"` // empty means Ed25519
+	Crypto  keystoreSeal     `json:"crypto"`
+	// Pairwise is the pairwise seed, sealed with the key that seals Crypto.
+	Pairwise *sealedSecret `json:"pairwise,omitempty"`
+}
+
+// sealedSecret is a further secret of the keystore, encrypted with the keystore's cipher
+// and derived key under its own nonce.
+type sealedSecret struct {
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+type keystoreSeal struct {
+	Cipher     string    `json:"cipher"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext"`
+	KDF        string    `json:"kdf"`
+	KDFParams  KDFParams `json:"kdfparams"`
+}
+
+// pairwiseSeedSize is the length of the seed pairwise keys are derived from.
+const pairwiseSeedSize = 32
+
+// pairwiseAAD binds the sealed pairwise seed to the keystore format version.
+var pairwiseAAD = []byte(fmt.Sprintf("ego-keystore-v%d:pairwise", keystoreVersion))
+
+// newPairwiseSeed returns a random pairwise seed.
+func newPairwiseSeed() ([]byte, error) {
+	seed := make([]byte, pairwiseSeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("generate pairwise seed: %w", err)
+	}
+	return seed, nil
+}
+
+// sealKey encrypts key and the pairwise seed, if any, with a key derived from passphrase
+// and returns the keystore JSON.
+func sealKey(key *identity.PrivateKey, seed, passphrase []byte, params KDFParams) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrEmptyPassphrase
+	}
+	params.Salt = make([]byte, 16)
+	if _, err := rand.Read(params.Salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(deriveKey(passphrase, params))
+	if err != nil {
+		return nil, fmt.Errorf("init cipher: %w", err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	ks := encryptedKeystore{
+		Version: keystoreVersion,
+		KeyType: key.Type,
+		Crypto: keystoreSeal{
+			Cipher:     cipherXChaCha,
+			Nonce:      nonce,
+			Ciphertext: aead.Seal(nil, nonce, key.Raw, keystoreAAD(key.Type)),
+			KDF:        kdfArgon2id,
+			KDFParams:  params,
+		},
+	}
+	if seed != nil {
+		seedNonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(seedNonce); err != nil {
+			return nil, fmt.Errorf("generate nonce: %w", err)
+		}
+		ks.Pairwise = &sealedSecret{Nonce: seedNonce, Ciphertext: aead.Seal(nil, seedNonce, seed, pairwiseAAD)}
+	}
+	return json.MarshalIndent(ks, "", "  ")
+}
+
+// openKey decrypts an encrypted keystore with passphrase. It returns the private key and
+// the pairwise seed, which is nil for keystores sealed before seeds were added.
+func openKey(ks *encryptedKeystore, passphrase []byte) (*identity.PrivateKey, []byte, error) {
+	if ks.Version != keystoreVersion {
+		return nil, nil, fmt.Errorf("unsupported keystore version %d", ks.Version)
+	}
+	if ks.Crypto.KDF != kdfArgon2id {
+		return nil, nil, fmt.Errorf("unsupported kdf %q", ks.Crypto.KDF)
+	}
+	if ks.Crypto.Cipher != cipherXChaCha {
+		return nil, nil, fmt.Errorf("unsupported cipher %q", ks.Crypto.Cipher)
+	}
+	if err := ks.Crypto.KDFParams.check(); err != nil {
+		return nil, nil, err
+	}
+	aead, err := chacha20poly1305.NewX(deriveKey(passphrase, ks.Crypto.KDFParams))
+	if err != nil {
+		return nil, nil, fmt.Errorf("init cipher: %w", err)
+	}
+	if len(ks.Crypto.Nonce) != aead.NonceSize() {
+		return nil, nil, fmt.Errorf("invalid nonce length %d", len(ks.Crypto.Nonce))
+	}
+	kt := ks.KeyType
+	if kt == "" {
+		kt = identity.KeyTypeEd25519
+	}
+	secret, err := aead.Open(nil, ks.Crypto.Nonce, ks.Crypto.Ciphertext, keystoreAAD(kt))
+	if err != nil {
+		return nil, nil, ErrWrongPassphrase
+	}
+	key, err := identity.ParsePrivateKey(kt, secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ks.Pairwise == nil {
+		return key, nil, nil
+	}
+	if len(ks.Pairwise.Nonce) != aead.NonceSize() {
+		return nil, nil, fmt.Errorf("invalid nonce length %d", len(ks.Pairwise.Nonce))
+	}
+	seed, err := aead.Open(nil, ks.Pairwise.Nonce, ks.Pairwise.Ciphertext, pairwiseAAD)
+	if err != nil || len(seed) != pairwiseSeedSize {
+		return nil, nil, ErrWrongPassphrase
+	}
+	return key, seed, nil
+}
+
+// check rejects parameters that Argon2id cannot or should not be run with.
+func (p KDFParams) check() error {
+	switch {
+	case p.KeyLen != chacha20poly1305.KeySize:
+		return fmt.Errorf("invalid kdf key length %d", p.KeyLen)
+	case p.Time < 1 || p.Time > maxKDFTime:
+		return fmt.Errorf("invalid kdf time cost %d", p.Time)
+	case p.Threads < 1:
+		return fmt.Errorf("invalid kdf threads %d", p.Threads)
+	case p.Memory < 8*uint32(p.Threads) || p.Memory > maxKDFMemory:
+		return fmt.Errorf("invalid kdf memory cost %d KiB", p.Memory)
+	}
+	return nil
+}
+
+func deriveKey(passphrase []byte, p KDFParams) []byte {
+	return argon2.IDKey(passphrase, p.Salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+}
+
+// keystoreAAD binds the ciphertext to the keystore format version and key type.
+func keystoreAAD(kt identity.KeyType) []byte {
+	aad := fmt.Sprintf("ego-keystore-v%d", keystoreVersion)
+	if kt != identity.KeyTypeEd25519 {
+		aad += ":" + string(kt)
+	}
+	return []byte(aad)
+}
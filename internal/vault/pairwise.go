@@ -0,0 +1,161 @@
+package vault
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+const (
+	pairwiseFilename = "pairwise.json"
+	pairwiseInfo     = "ego-pairwise-v1"
+)
+
+// PairwiseEntry records the DID presented to one relying party.
+type PairwiseEntry struct {
+	Domain   string    `json:"domain"`
+	DID      string    `json:"did"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+type pairwiseFile struct {
+	Version int                       `json:"version"`
+	Parties map[string]*PairwiseEntry `json:"parties"`
+}
+
+// NormalizeDomain reduces an audience to the relying party's host name. It accepts a bare
+// domain, an origin or URL, which stands for its own host, or an OIDC4VP authorization
+// request (openid4vp://), whose redirect_uri, response_uri or client_id names the relying
+// party. It returns "" for a request that names no relying party.
+func NormalizeDomain(audience string) string {
+	audience = strings.TrimSpace(audience)
+	u, err := url.Parse(audience)
+	if err == nil && strings.EqualFold(u.Scheme, openID4VPScheme) {
+		return requestDomain(u.Query())
+	}
+	if err == nil && u.Host != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	return strings.ToLower(strings.TrimSuffix(audience, "."))
+}
+
+// openID4VPScheme is the scheme of OIDC4VP authorization requests sent to a wallet.
+const openID4VPScheme = "openid4vp"
+
+// requestDomain returns the host of the relying party that an OIDC4VP request names.
+func requestDomain(q url.Values) string {
+	for _, param := range []string{"redirect_uri", "response_uri", "client_id"} {
+		v := q.Get(param)
+		// client_id may carry a client identifier scheme
+		if dns, ok := strings.CutPrefix(v, "x509_san_dns:"); ok && param == "client_id" {
+			return strings.ToLower(strings.TrimSuffix(dns, "."))
+		}
+		v = strings.TrimPrefix(v, "redirect_uri:")
+		if r, err := url.Parse(v); err == nil && r.Host != "" && (r.Scheme == "https" || r.Scheme == "http") {
+			return strings.ToLower(r.Hostname())
+		}
+	}
+	return ""
+}
+
+// PairwiseKey derives the Ed25519 key used with domain from the vault's pairwise seed. The
+// same seed and domain always give the same key; different domains give unlinkable keys.
+func PairwiseKey(seed []byte, domain string) *identity.PrivateKey {
+	mac := hmac.New(sha256.New, seed)
+	mac.Write([]byte(pairwiseInfo))
+	mac.Write([]byte{0})
+	mac.Write([]byte(NormalizeDomain(domain)))
+	return identity.FromEd25519(ed25519.NewKeyFromSeed(mac.Sum(nil)))
+}
+
+// PairwiseDID returns the did:key derived for domain.
+func PairwiseDID(seed []byte, domain string) string {
+	return identity.GenerateDIDForKey(PairwiseKey(seed, domain).Public())
+}
+
+// PairwiseRegistry returns the recorded relying parties sorted by domain.
+func (v *Vault) PairwiseRegistry() ([]*PairwiseEntry, error) {
+	f, err := v.readPairwise()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*PairwiseEntry, 0, len(f.Parties))
+	for _, e := range f.Parties {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Domain < entries[j].Domain })
+	return entries, nil
+}
+
+// ErrPairwiseChanged is returned when a relying party would be given a DID other than the
+// one recorded for it, which only happens when the pairwise seed was replaced.
+var ErrPairwiseChanged = errors.New("pairwise DID changed")
+
+// RecordPairwise notes that did was presented to domain. A relying party keeps the DID it
+// was first given, and a different one fails with ErrPairwiseChanged. A peer contact may
+// be given a new did:peer, with other endpoints; the previous DID is then returned.
+func (v *Vault) RecordPairwise(domain, did string) (string, error) {
+	lock, err := v.Lock()
+	if err != nil {
+		return "", err
+	}
+	defer lock.Unlock()
+	f, err := v.readPairwise()
+	if err != nil {
+		return "", err
+	}
+	domain = NormalizeDomain(domain)
+	now := time.Now().UTC()
+	var previous string
+	e, ok := f.Parties[domain]
+	if !ok {
+		e = &PairwiseEntry{Domain: domain, Created: now}
+		f.Parties[domain] = e
+	} else if e.DID != did {
+		if !IsPeerContact(domain) {
+			return "", fmt.Errorf("%w: %s was given %s, not %s", ErrPairwiseChanged, domain, e.DID, did)
+		}
+		previous = e.DID
+	}
+	e.DID = did
+	e.LastUsed = now
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := fsutil.WriteFile(filepath.Join(v.BaseDir, pairwiseFilename), data, 0600); err != nil {
+		return "", fmt.Errorf("write %s: %w", pairwiseFilename, err)
+	}
+	return previous, nil
+}
+
+func (v *Vault) readPairwise() (*pairwiseFile, error) {
+	f := &pairwiseFile{Version: 1, Parties: make(map[string]*PairwiseEntry)}
+	data, err := os.ReadFile(filepath.Join(v.BaseDir, pairwiseFilename))
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", pairwiseFilename, err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", pairwiseFilename, err)
+	}
+	if f.Parties == nil {
+		f.Parties = make(map[string]*PairwiseEntry)
+	}
+	return f, nil
+}
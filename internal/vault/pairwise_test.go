@@ -0,0 +1,110 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+func TestNormalizeDomain(t *testing.T) {
+	cases := map[string]string{
+		"Verifier.Example":                   "verifier.example",
+		"verifier.example.":                  "verifier.example",
+		"https://verifier.example:8443/path": "verifier.example",
+		"openid4vp://?client_id=x&redirect_uri=https%3A%2F%2Frp.example%2Fcb": "rp.example",
+		"openid4vp://authorize?response_uri=https%3A%2F%2FRP.example%2Fpost":  "rp.example",
+		"openid4vp://?client_id=x509_san_dns%3Arp.example":                    "rp.example",
+		"openid4vp://?client_id=redirect_uri%3Ahttps%3A%2F%2Frp.example%2Fcb": "rp.example",
+		"openid4vp://?client_id=not-a-host":                                   "",
+		// any other URL is its own relying party, whatever its query says
+		"https://evil.example/?redirect_uri=https%3A%2F%2Fbank.example":         "evil.example",
+		"https://wallet.example/authorize?client_id=https%3A%2F%2Fbank.example": "wallet.example",
+	}
+	for in, want := range cases {
+		if got := NormalizeDomain(in); got != want {
+			t.Errorf("NormalizeDomain(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
+
+func TestPairwiseDID(t *testing.T) {
+	seed, _ := newPairwiseSeed()
+	a := PairwiseDID(seed, "a.example")
+	if a != PairwiseDID(seed, "https://A.example/login") {
+		t.Error("pairwise DID is not stable for the same domain")
+	}
+	if a == PairwiseDID(seed, "b.example") {
+		t.Error("different domains share a pairwise DID")
+	}
+	if PairwiseDID(seed, "https://evil.example/?redirect_uri=https://a.example") == a {
+		t.Error("a page got the pairwise DID of the site its query names")
+	}
+	other, _ := newPairwiseSeed()
+	if a == PairwiseDID(other, "a.example") {
+		t.Error("different seeds share a pairwise DID")
+	}
+}
+
+func TestPairwiseSeedSurvivesRotation(t *testing.T) {
+	pass := []byte("pass")
+	v := newTestVault(t)
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	doc, _ := identity.BuildDIDDocument("did:web:example.com", priv.Public())
+	if err := v.Init(doc, priv, pass); err != nil {
+		t.Fatal(err)
+	}
+	_, seed, err := v.LoadPairwise(pass)
+	if err != nil || len(seed) != pairwiseSeedSize {
+		t.Fatalf("LoadPairwise = %x, %v", seed, err)
+	}
+	if bytes.Equal(seed, priv.Raw) {
+		t.Error("pairwise seed is the vault key")
+	}
+
+	next, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	if _, err := v.Rotate(pass, func(doc *identity.Document) (*identity.PrivateKey, error) {
+		now := time.Now()
+		doc.RotateKey(next.Public(), now, now)
+		return next, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.ChangePassphrase(pass, []byte("other")); err != nil {
+		t.Fatal(err)
+	}
+	key, after, err := v.LoadPairwise([]byte("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Public().Equal(next.Public()) || !bytes.Equal(after, seed) {
+		t.Error("pairwise seed changed with the key or passphrase")
+	}
+}
+
+func TestRecordPairwise(t *testing.T) {
+	v := NewVault(t.TempDir())
+	if prev, err := v.RecordPairwise("a.example", "did:key:z1"); err != nil || prev != "" {
+		t.Fatalf("RecordPairwise = %q, %v", prev, err)
+	}
+	if prev, _ := v.RecordPairwise("b.example", "did:key:z2"); prev != "" {
+		t.Errorf("unexpected previous DID %q", prev)
+	}
+	if _, err := v.RecordPairwise("https://a.example", "did:key:z3"); !errors.Is(err, ErrPairwiseChanged) {
+		t.Errorf("expected ErrPairwiseChanged for a new DID, got %v", err)
+	}
+	// peer contacts may be given a new did:peer
+	v.RecordPairwise(PeerContact("bob"), "did:peer:2.Va")
+	if prev, err := v.RecordPairwise(PeerContact("bob"), "did:peer:2.Vb"); err != nil || prev != "did:peer:2.Va" {
+		t.Errorf("RecordPairwise = %q, %v; want the previous did:peer", prev, err)
+	}
+	entries, err := v.PairwiseRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Domain != "a.example" || entries[0].DID != "did:key:z1" || entries[1].DID != "did:key:z2" {
+		t.Errorf("unexpected registry %+v", entries)
+	}
+}
@@ -0,0 +1,146 @@
+package vault
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+const (
+	peerInfo         = "ego-peer-v1"
+	keyAgreementInfo = "ego-key-agreement-v1"
+
+	// PeerContactPrefix marks pairwise registry entries of peer contacts, so that a
+	// contact named like a domain does not share the domain's DID.
+	PeerContactPrefix = "peer:"
+)
+
+// derive returns HMAC-SHA256 of the vault key over info, label and name.
+func derive(secret []byte, info, label, name string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(info))
+	mac.Write([]byte{0})
+	mac.Write([]byte(label))
+	mac.Write([]byte{0})
+	mac.Write([]byte(name))
+	return mac.Sum(nil)
+}
+
+// KeyAgreementKey derives the X25519 key that the did:peer identity of priv publishes for
+// key agreement.
+func KeyAgreementKey(priv *identity.PrivateKey) (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().NewPrivateKey(derive(priv.Raw, keyAgreementInfo, "", ""))
+}
+
+// ErrNoPairwiseSeed is returned for pairwise DIDs of a vault whose keystore.json is not
+// encrypted, and so holds no pairwise seed.
+var ErrNoPairwiseSeed = errors.New("the vault has no pairwise seed; encrypt keystore.json with 'ego passwd'")
+
+// PeerDID returns the numalgo 2 did:peer of priv, with priv's key for authentication and
+// assertion, a derived X25519 key for key agreement, and a DIDComm service for each
+// endpoint.
+func PeerDID(priv *identity.PrivateKey, endpoints []string) (string, error) {
+	agree, err := KeyAgreementKey(priv)
+	if err != nil {
+		return "", err
+	}
+	return peerDID(priv.Public(), agree.PublicKey(), endpoints)
+}
+
+// PeerContact returns the pairwise registry name of contact.
+func PeerContact(contact string) string {
+	return NormalizeDomain(PeerContactPrefix + strings.TrimPrefix(strings.TrimSpace(contact), PeerContactPrefix))
+}
+
+// IsPeerContact reports whether a pairwise audience names a peer contact.
+func IsPeerContact(audience string) bool {
+	return strings.HasPrefix(NormalizeDomain(audience), PeerContactPrefix)
+}
+
+// PeerContactKeys derives the Ed25519 signing key and X25519 key agreement key used with
+// contact from the pairwise seed. Like pairwise keys, they are unlinkable across contacts.
+func PeerContactKeys(seed []byte, contact string) (*identity.PrivateKey, *ecdh.PrivateKey, error) {
+	contact = PeerContact(contact)
+	sign := identity.FromEd25519(ed25519.NewKeyFromSeed(derive(seed, peerInfo, "sign", contact)))
+	agree, err := ecdh.X25519().NewPrivateKey(derive(seed, peerInfo, "agree", contact))
+	if err != nil {
+		return nil, nil, err
+	}
+	return sign, agree, nil
+}
+
+// PeerContactDID returns the did:peer used with contact, with a DIDComm service for each
+// endpoint.
+func PeerContactDID(seed []byte, contact string, endpoints []string) (string, error) {
+	sign, agree, err := PeerContactKeys(seed, contact)
+	if err != nil {
+		return "", err
+	}
+	return peerDID(sign.Public(), agree.PublicKey(), endpoints)
+}
+
+func peerDID(sign *identity.PublicKey, agree *ecdh.PublicKey, endpoints []string) (string, error) {
+	keys := &identity.PeerKeys{
+		Authentication: []*identity.PublicKey{sign},
+		Assertion:      []*identity.PublicKey{sign},
+		KeyAgreement:   []*ecdh.PublicKey{agree},
+	}
+	for _, ep := range endpoints {
+		keys.Services = append(keys.Services, identity.Service{
+			Type:            identity.DIDCommMessaging,
+			ServiceEndpoint: ep,
+			Accept:          []string{"didcomm/v2"},
+		})
+	}
+	return identity.GeneratePeerDID2(keys)
+}
+
+// PairwiseIdentity returns the key, DID and verification method that the vault with
+// pairwise seed seed presents to audience. A peer contact uses the did:peer recorded by 'ego pairwise
+// peer'; any other audience uses its derived did:key.
+func (v *Vault) PairwiseIdentity(seed []byte, audience string) (*identity.PrivateKey, string, string, error) {
+	if seed == nil {
+		return nil, "", "", ErrNoPairwiseSeed
+	}
+	if !IsPeerContact(audience) {
+		pk := PairwiseKey(seed, audience)
+		did := identity.GenerateDIDForKey(pk.Public())
+		return pk, did, did + "#keys-1", nil
+	}
+	entries, err := v.PairwiseRegistry()
+	if err != nil {
+		return nil, "", "", err
+	}
+	contact := PeerContact(audience)
+	for _, e := range entries {
+		if e.Domain != contact {
+			continue
+		}
+		sign, _, err := PeerContactKeys(seed, contact)
+		if err != nil {
+			return nil, "", "", err
+		}
+		doc, err := identity.ParsePeerDID(e.DID)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("peer DID of %s: %w", contact, err)
+		}
+		if len(doc.Authentication) == 0 {
+			return nil, "", "", fmt.Errorf("peer DID of %s has no authentication key", contact)
+		}
+		vm, err := doc.Method(doc.Authentication[0])
+		if err != nil {
+			return nil, "", "", err
+		}
+		if pub, err := vm.PublicKey(); err != nil || !pub.Equal(sign.Public()) {
+			return nil, "", "", fmt.Errorf("peer DID of %s was not derived from this vault's pairwise seed", contact)
+		}
+		return sign, e.DID, vm.ID, nil
+	}
+	return nil, "", "", fmt.Errorf("no peer DID for %s; create one with 'ego pairwise peer'", contact)
+}
@@ -0,0 +1,84 @@
+package vault
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+)
+
+func TestPeerDID(t *testing.T) {
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did, err := PeerDID(priv, []string{"https://alice.example/didcomm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := identity.ParsePeerDID(did)
+	if err != nil {
+		t.Fatalf("ParsePeerDID: %v", err)
+	}
+	vm, err := doc.CurrentMethod()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub, _ := vm.PublicKey(); !pub.Equal(priv.Public()) {
+		t.Error("signing method does not hold the vault key")
+	}
+	if len(doc.KeyAgreement) != 1 || len(doc.Service) != 1 {
+		t.Errorf("expected one key agreement key and one service: %+v", doc)
+	}
+	if again, _ := PeerDID(priv, []string{"https://alice.example/didcomm"}); again != did {
+		t.Error("did:peer is not stable for the same key and endpoints")
+	}
+}
+
+func TestPeerContact(t *testing.T) {
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	seed, _ := newPairwiseSeed()
+	if PeerContact("Bob") != "peer:bob" || PeerContact("peer:bob") != "peer:bob" || !IsPeerContact("peer:Bob") {
+		t.Errorf("unexpected contact names %q, %q", PeerContact("Bob"), PeerContact("peer:bob"))
+	}
+	if IsPeerContact("bob.example") {
+		t.Error("a domain is not a peer contact")
+	}
+	bob, err := PeerContactDID(seed, "bob", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	carol, _ := PeerContactDID(seed, "carol", nil)
+	own, _ := PeerDID(priv, nil)
+	if bob == carol || bob == own {
+		t.Error("contacts share a did:peer")
+	}
+
+	v := NewVault(t.TempDir())
+	if _, _, _, err := v.PairwiseIdentity(seed, "peer:bob"); err == nil || !strings.Contains(err.Error(), "ego pairwise peer") {
+		t.Errorf("expected an error for an unknown contact, got %v", err)
+	}
+	v.RecordPairwise(PeerContact("bob"), bob)
+	key, did, vm, err := v.PairwiseIdentity(seed, "peer:Bob")
+	if err != nil {
+		t.Fatalf("PairwiseIdentity: %v", err)
+	}
+	sign, _, _ := PeerContactKeys(seed, "bob")
+	if did != bob || vm != bob+"#key-1" || !key.Public().Equal(sign.Public()) {
+		t.Errorf("PairwiseIdentity = %s, %s", did, vm)
+	}
+
+	// an edited registry with a did:peer lacking a V key is an error, not a panic
+	agreementOnly := "did:peer:2" + bob[strings.Index(bob, ".E"):]
+	v.RecordPairwise(PeerContact("dave"), agreementOnly)
+	if _, _, _, err := v.PairwiseIdentity(seed, "peer:dave"); err == nil {
+		t.Error("expected an error for a did:peer without an authentication key")
+	}
+
+	if _, _, _, err := v.PairwiseIdentity(nil, "shop.example"); !errors.Is(err, ErrNoPairwiseSeed) {
+		t.Errorf("expected ErrNoPairwiseSeed without a seed, got %v", err)
+	}
+
+	// a domain keeps using its did:key
+	if _, did, vm, _ := v.PairwiseIdentity(seed, "shop.example"); did != PairwiseDID(seed, "shop.example") || vm != did+"#keys-1" {
+		t.Errorf("domain pairwise identity = %s, %s", did, vm)
+	}
+}
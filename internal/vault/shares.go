@@ -0,0 +1,138 @@
+package vault
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/shamir"
+)
+
+const keyShareVersion = 1
+
+// ErrShareMismatch is returned when key shares do not belong to the same vault key and split.
+var ErrShareMismatch = errors.New("key shares do not match")
+
+// KeyShare is one Shamir share of a vault key, written as a small JSON file.
+// Fingerprint identifies the public key the share belongs to; SetID identifies the split,
+// so shares from two different splits of the same key are not combined.
+type KeyShare struct {
+	Version     int              `json:"version"`
+	DID         string           `json:"did"`
+	Fingerprint string           `json:"fingerprint"`
+	SetID       string           `json:"setId"`
+	KeyType     identity.KeyType `json:"keyType"`
+	Threshold   int              `json:"threshold"`
+	Total       int              `json:"total"`
+	Index       int              `json:"index"`
+	Share       []byte           `json:"share"`
+}
+
+// KeyFingerprint is a short hex digest of a public key.
+func KeyFingerprint(pub *identity.PublicKey) string {
+	sum := sha256.Sum256(append([]byte(pub.Type+":"), pub.Raw...))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SplitKey splits priv, the key of did, into n shares of which threshold recover it.
+func SplitKey(did string, priv *identity.PrivateKey, n, threshold int) ([]*KeyShare, error) {
+	parts, err := shamir.Split(priv.Raw, n, threshold)
+	if err != nil {
+		return nil, err
+	}
+	setID := make([]byte, 8)
+	if _, err := rand.Read(setID); err != nil {
+		return nil, err
+	}
+	fp := KeyFingerprint(priv.Public())
+	shares := make([]*KeyShare, len(parts))
+	for i, p := range parts {
+		shares[i] = &KeyShare{
+			Version:     keyShareVersion,
+			DID:         did,
+			Fingerprint: fp,
+			SetID:       hex.EncodeToString(setID),
+			KeyType:     priv.Type,
+			Threshold:   threshold,
+			Total:       n,
+			Index:       int(p.X),
+			Share:       p.Data,
+		}
+	}
+	return shares, nil
+}
+
+// CombineKeyShares rebuilds the private key from shares and checks it against pub.
+func CombineKeyShares(shares []*KeyShare, pub *identity.PublicKey) (*identity.PrivateKey, error) {
+	if len(shares) == 0 {
+		return nil, shamir.ErrTooFewShares
+	}
+	first := shares[0]
+	fp := KeyFingerprint(pub)
+	parts := make([]shamir.Share, len(shares))
+	for i, s := range shares {
+		if s.Version != keyShareVersion {
+			return nil, fmt.Errorf("unsupported key share version %d", s.Version)
+		}
+		if s.Fingerprint != fp {
+			return nil, fmt.Errorf("%w: share %d is for key %s, the vault key is %s", ErrShareMismatch, s.Index, s.Fingerprint, fp)
+		}
+		if s.SetID != first.SetID || s.Threshold != first.Threshold || s.KeyType != first.KeyType {
+			return nil, fmt.Errorf("%w: shares %d and %d come from different splits", ErrShareMismatch, first.Index, s.Index)
+		}
+		if s.Index < 1 || s.Index > 255 {
+			return nil, fmt.Errorf("invalid share index %d", s.Index)
+		}
+		parts[i] = shamir.Share{X: byte(s.Index), Data: s.Share}
+	}
+	if len(shares) < first.Threshold {
+		return nil, fmt.Errorf("%w: have %d, need %d", shamir.ErrTooFewShares, len(shares), first.Threshold)
+	}
+	secret, err := shamir.Combine(parts)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := identity.ParsePrivateKey(first.KeyType, secret)
+	if err != nil {
+		return nil, fmt.Errorf("recovered key is invalid: %w", err)
+	}
+	if !priv.Public().Equal(pub) {
+		priv.Wipe()
+		return nil, fmt.Errorf("recovered key does not match the public key in did.json")
+	}
+	return priv, nil
+}
+
+// RecoverKey writes a new keystore.json for priv, which must be the current key in did.json.
+// The pairwise seed is not part of the key shares, so a new one is created: relying parties
+// see new pairwise DIDs after a recovery.
+func (v *Vault) RecoverKey(priv *identity.PrivateKey, passphrase []byte) error {
+	lock, err := v.Lock()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	doc, err := v.Document()
+	if err != nil {
+		return err
+	}
+	vm, err := doc.CurrentMethod()
+	if err != nil {
+		return err
+	}
+	pub, err := vm.PublicKey()
+	if err != nil {
+		return err
+	}
+	if !priv.Public().Equal(pub) {
+		return fmt.Errorf("key does not match %s", vm.ID)
+	}
+	seed, err := newPairwiseSeed()
+	if err != nil {
+		return err
+	}
+	return v.writeKeystore(priv, seed, passphrase)
+}
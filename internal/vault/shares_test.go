@@ -0,0 +1,61 @@
+package vault
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/shamir"
+)
+
+func TestSplitCombineKeyShares(t *testing.T) {
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	did := identity.GenerateDIDForKey(priv.Public())
+	shares, err := SplitKey(did, priv, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := CombineKeyShares([]*KeyShare{shares[4], shares[1], shares[2]}, priv.Public())
+	if err != nil {
+		t.Fatalf("CombineKeyShares: %v", err)
+	}
+	if !got.Public().Equal(priv.Public()) {
+		t.Error("recovered key differs")
+	}
+
+	if _, err := CombineKeyShares(shares[:2], priv.Public()); !errors.Is(err, shamir.ErrTooFewShares) {
+		t.Errorf("expected ErrTooFewShares, got %v", err)
+	}
+
+	// shares of another split of the same key are rejected
+	other, _ := SplitKey(did, priv, 5, 3)
+	if _, err := CombineKeyShares([]*KeyShare{shares[0], shares[1], other[2]}, priv.Public()); !errors.Is(err, ErrShareMismatch) {
+		t.Errorf("expected ErrShareMismatch for mixed splits, got %v", err)
+	}
+
+	// shares of another vault are rejected
+	stranger, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	if _, err := CombineKeyShares(shares[:3], stranger.Public()); !errors.Is(err, ErrShareMismatch) {
+		t.Errorf("expected ErrShareMismatch for another key, got %v", err)
+	}
+}
+
+func TestRecoverKey(t *testing.T) {
+	v := newTestVault(t)
+	priv, _ := identity.GenerateKey(identity.KeyTypeP256)
+	did := identity.GenerateDIDForKey(priv.Public())
+	doc, _ := identity.BuildDIDDocument(did, priv.Public())
+	if err := v.Init(doc, priv, []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	other, _ := identity.GenerateKey(identity.KeyTypeP256)
+	if err := v.RecoverKey(other, []byte("new")); err == nil {
+		t.Error("expected RecoverKey to reject a foreign key")
+	}
+	if err := v.RecoverKey(priv, []byte("new")); err != nil {
+		t.Fatalf("RecoverKey: %v", err)
+	}
+	if _, _, err := v.Load([]byte("new")); err != nil {
+		t.Errorf("Load after recovery: %v", err)
+	}
+}
@@ -1,14 +1,16 @@
 package vault
 
 import (
-	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
 	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/resolver"
 	"github.com/mr-tron/base58"
 )
 
@@ -19,73 +21,313 @@ const (
 
 type Vault struct {
 	BaseDir string
+	// KDF holds the parameters used when (re)sealing the keystore.
+	KDF KDFParams
 }
 
 // NewVault prepares a Vault rooted at baseDir (does not create files yet)
 func NewVault(baseDir string) *Vault {
-	return &Vault{BaseDir: baseDir}
+	return &Vault{BaseDir: baseDir, KDF: DefaultKDFParams}
 }
 
-// Init writes a new DID Document and encodes the private key in base58, saving both to disk.
-func (v *Vault) Init(didDoc []byte, privKey ed25519.PrivateKey) error {
+// Lock takes the vault's advisory lock, which serialises ego processes that read and
+// rewrite the same vault files. Callers must Unlock it before locking the vault again.
+func (v *Vault) Lock() (*fsutil.Lock, error) {
+	return fsutil.LockDir(v.BaseDir)
+}
+
+// Init writes a new DID Document and seals the private key with passphrase, saving both to disk.
+func (v *Vault) Init(didDoc []byte, privKey *identity.PrivateKey, passphrase []byte) error {
+	if len(passphrase) == 0 {
+		return ErrEmptyPassphrase
+	}
 	if err := os.MkdirAll(v.BaseDir, fs.ModePerm); err != nil {
 		return fmt.Errorf("create vault dir: %w", err)
 	}
 
 	// Write DID document
-	if err := os.WriteFile(
+	if err := fsutil.WriteFile(
 		filepath.Join(v.BaseDir, didFilename),
 		didDoc,
 		0600,
 	); err != nil {
 		return fmt.Errorf("write did.json: %w", err)
 	}
+	meta := resolver.DocumentMetadata{Created: time.Now().UTC().Format(time.RFC3339), VersionID: "1"}
+	if err := v.writeMetadata(meta); err != nil {
+		return err
+	}
+
+	seed, err := newPairwiseSeed()
+	if err != nil {
+		return err
+	}
+	return v.writeKeystore(privKey, seed, passphrase)
+}
+
+// Load reads did.json and keystore.json, unseals the private key with passphrase, and returns the contents.
+// Legacy plaintext keystores are still readable; the passphrase is ignored for them.
+func (v *Vault) Load(passphrase []byte) ([]byte, *identity.PrivateKey, error) {
+	// Read DID document
+	didDoc, err := os.ReadFile(filepath.Join(v.BaseDir, didFilename))
+	if err != nil {
+		return nil, nil, fmt.Errorf("read did.json: %w", err)
+	}
+
+	key, _, err := v.openKeystore(passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	return didDoc, key, nil
+}
+
+// LoadPairwise unseals the private key and the pairwise seed that pairwise keys are
+// derived from. The seed is created and stored in keystore.json the first time it is
+// needed, and keeps the vault's pairwise DIDs when the key is rotated. Legacy plaintext
+// keystores cannot hold a seed, and their seed is nil until ChangePassphrase seals them.
+func (v *Vault) LoadPairwise(passphrase []byte) (*identity.PrivateKey, []byte, error) {
+	enc, legacy, err := v.readKeystore()
+	if err != nil {
+		return nil, nil, err
+	}
+	if enc == nil {
+		return legacy, nil, nil
+	}
+	key, seed, err := openKey(enc, passphrase)
+	if err != nil || seed != nil {
+		return key, seed, err
+	}
+	key.Wipe()
+	lock, err := v.Lock()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer lock.Unlock()
+	// another process may have created the seed meanwhile
+	if key, seed, err = v.openKeystore(passphrase); err != nil || seed != nil {
+		return key, seed, err
+	}
+	if seed, err = newPairwiseSeed(); err != nil {
+		return nil, nil, err
+	}
+	if err := v.writeKeystore(key, seed, passphrase); err != nil {
+		return nil, nil, err
+	}
+	return key, seed, nil
+}
+
+// openKeystore unseals keystore.json with passphrase. Legacy plaintext keystores have no
+// pairwise seed.
+func (v *Vault) openKeystore(passphrase []byte) (*identity.PrivateKey, []byte, error) {
+	enc, legacy, err := v.readKeystore()
+	if err != nil {
+		return nil, nil, err
+	}
+	if enc == nil {
+		return legacy, nil, nil
+	}
+	return openKey(enc, passphrase)
+}
 
-	// Encode and write private key
-	enc := identity.EncodePrivateKey(privKey)
-	k := map[string]string{"privateKey": enc}
-	ksBytes, err := json.MarshalIndent(k, "", "  ")
+// IsEncrypted reports whether keystore.json is sealed with a passphrase.
+func (v *Vault) IsEncrypted() (bool, error) {
+	enc, _, err := v.readKeystore()
+	if err != nil {
+		return false, err
+	}
+	return enc != nil, nil
+}
+
+// ChangePassphrase re-seals the private key under newPass.
+// For a legacy plaintext keystore oldPass is ignored and the keystore is upgraded in place.
+func (v *Vault) ChangePassphrase(oldPass, newPass []byte) error {
+	if len(newPass) == 0 {
+		return ErrEmptyPassphrase
+	}
+	lock, err := v.Lock()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	priv, seed, err := v.openKeystore(oldPass)
 	if err != nil {
-		return fmt.Errorf("marshal keystore: %w", err)
+		return err
 	}
-	if err := os.WriteFile(
+	if seed == nil {
+		if seed, err = newPairwiseSeed(); err != nil {
+			return err
+		}
+	}
+	return v.writeKeystore(priv, seed, newPass)
+}
+
+func (v *Vault) writeKeystore(privKey *identity.PrivateKey, seed, passphrase []byte) error {
+	ksBytes, err := sealKey(privKey, seed, passphrase, v.KDF)
+	if err != nil {
+		return fmt.Errorf("seal keystore: %w", err)
+	}
+	if err := fsutil.WriteFile(
 		filepath.Join(v.BaseDir, keystoreFilename),
 		ksBytes,
 		0600,
 	); err != nil {
 		return fmt.Errorf("write keystore.json: %w", err)
 	}
-
 	return nil
 }
 
-// Load reads did.json and keystore.json, decodes the private key from base58, and returns the contents.
-func (v *Vault) Load() ([]byte, ed25519.PrivateKey, error) {
-	// Read DID document
-	didDoc, err := os.ReadFile(filepath.Join(v.BaseDir, didFilename))
-	if err != nil {
-		return nil, nil, fmt.Errorf("read did.json: %w", err)
-	}
-
-	// Read keystore entry
+// readKeystore returns either the encrypted keystore or, for legacy vaults, the plaintext private key.
+func (v *Vault) readKeystore() (*encryptedKeystore, *identity.PrivateKey, error) {
 	ksBytes, err := os.ReadFile(filepath.Join(v.BaseDir, keystoreFilename))
 	if err != nil {
 		return nil, nil, fmt.Errorf("read keystore.json: %w", err)
 	}
-	var k map[string]string
+	var k map[string]json.RawMessage
 	if err := json.Unmarshal(ksBytes, &k); err != nil {
 		return nil, nil, fmt.Errorf("unmarshal keystore: %w", err)
 	}
-	enc, ok := k["privateKey"]
+	if _, ok := k["crypto"]; ok {
+		var enc encryptedKeystore
+		if err := json.Unmarshal(ksBytes, &enc); err != nil {
+			return nil, nil, fmt.Errorf("unmarshal keystore: %w", err)
+		}
+		return &enc, nil, nil
+	}
+
+	// Legacy plaintext keystore: {"privateKey": "<base58>"}
+	rawEnc, ok := k["privateKey"]
 	if !ok {
 		return nil, nil, fmt.Errorf("keystore missing 'privateKey'")
 	}
-
-	// Decode private key
-	rawPriv, err := base58.Decode(enc)
+	var encKey string
+	if err := json.Unmarshal(rawEnc, &encKey); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal keystore: %w", err)
+	}
+	rawPriv, err := base58.Decode(encKey)
 	if err != nil {
 		return nil, nil, fmt.Errorf("decode private key: %w", err)
 	}
+	key, err := identity.ParsePrivateKey(identity.KeyTypeEd25519, rawPriv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, key, nil
+}
+
+// DID reads did.json and returns the document's "id"; the keystore is not touched.
+func (v *Vault) DID() (string, error) {
+	didDoc, err := os.ReadFile(filepath.Join(v.BaseDir, didFilename))
+	if err != nil {
+		return "", fmt.Errorf("read did.json: %w", err)
+	}
+	return DIDFromDocument(didDoc)
+}
 
-	return didDoc, ed25519.PrivateKey(rawPriv), nil
+// DIDFromDocument extracts the "id" of a serialized DID Document.
+func DIDFromDocument(didDoc []byte) (string, error) {
+	var doc struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(didDoc, &doc); err != nil {
+		return "", fmt.Errorf("parse did.json: %w", err)
+	}
+	if doc.ID == "" {
+		return "", fmt.Errorf("did.json missing 'id'")
+	}
+	return doc.ID, nil
+}
+
+// Document reads and parses did.json.
+func (v *Vault) Document() (*identity.Document, error) {
+	didDoc, err := os.ReadFile(filepath.Join(v.BaseDir, didFilename))
+	if err != nil {
+		return nil, fmt.Errorf("read did.json: %w", err)
+	}
+	return identity.ParseDIDDocument(didDoc)
+}
+
+// UpdateDocument applies fn to the DID Document and writes it back as a new version,
+// holding the vault lock. Deactivated documents cannot be updated.
+func (v *Vault) UpdateDocument(fn func(doc *identity.Document) error) error {
+	lock, err := v.Lock()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	doc, err := v.Document()
+	if err != nil {
+		return err
+	}
+	if err := fn(doc); err != nil {
+		return err
+	}
+	data, err := doc.Marshal()
+	if err != nil {
+		return err
+	}
+	return v.writeDocument(data, false)
+}
+
+// VerificationMethod returns the ID of the key that currently signs for the vault.
+func (v *Vault) VerificationMethod() (string, error) {
+	doc, err := v.Document()
+	if err != nil {
+		return "", err
+	}
+	vm, err := doc.CurrentMethod()
+	if err != nil {
+		return "", err
+	}
+	return vm.ID, nil
+}
+
+// Rotate replaces the vault key while holding the vault lock. rotate is given the current
+// DID Document and returns the new key, after adding it to the document. The document is
+// written as a new version before keystore.json is replaced, and the new keystore is
+// staged next to it first, so a failure leaves the vault with its old key and a document
+// that lists it. The passphrase must open the current keystore and seals the new one,
+// with the current pairwise seed. Rotate returns the written document.
+func (v *Vault) Rotate(passphrase []byte, rotate func(doc *identity.Document) (*identity.PrivateKey, error)) ([]byte, error) {
+	lock, err := v.Lock()
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+	oldKey, seed, err := v.openKeystore(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	oldKey.Wipe()
+	if err := v.CheckActive(); err != nil {
+		return nil, err
+	}
+	doc, err := v.Document()
+	if err != nil {
+		return nil, err
+	}
+	newKey, err := rotate(doc)
+	if err != nil {
+		return nil, err
+	}
+	didDoc, err := doc.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	ksBytes, err := sealKey(newKey, seed, passphrase, v.KDF)
+	if err != nil {
+		return nil, fmt.Errorf("seal keystore: %w", err)
+	}
+	ksPath := filepath.Join(v.BaseDir, keystoreFilename)
+	staged := ksPath + ".rotate"
+	if err := fsutil.WriteFile(staged, ksBytes, 0600); err != nil {
+		return nil, fmt.Errorf("write keystore.json: %w", err)
+	}
+	defer os.Remove(staged) // no-op once renamed
+	if err := v.writeDocument(didDoc, false); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(staged, ksPath); err != nil {
+		return nil, fmt.Errorf("write keystore.json: %w", err)
+	}
+	return didDoc, nil
 }
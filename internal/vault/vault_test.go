@@ -0,0 +1,134 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/mr-tron/base58"
+)
+
+// testKDF keeps Argon2id cheap in tests.
+var testKDF = KDFParams{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+
+func newTestVault(t *testing.T) *Vault {
+	v := NewVault(t.TempDir())
+	v.KDF = testKDF
+	return v
+}
+
+func TestInitLoadEncrypted(t *testing.T) {
+	v := newTestVault(t)
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	if err := v.Init([]byte(`{"id":"did:example:1"}`), priv, []byte("s3cret")); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	// keystore must not contain the plaintext key
+	data, err := os.ReadFile(filepath.Join(v.BaseDir, keystoreFilename))
+	if err != nil {
+		t.Fatalf("read keystore: %v", err)
+	}
+	if bytes.Contains(data, []byte(base58.Encode(priv.Raw))) {
+		t.Error("keystore.json contains plaintext private key")
+	}
+	var ks encryptedKeystore
+	if err := json.Unmarshal(data, &ks); err != nil {
+		t.Fatalf("invalid keystore JSON: %v", err)
+	}
+	if ks.Crypto.KDF != kdfArgon2id || ks.Crypto.KDFParams.Memory != testKDF.Memory {
+		t.Errorf("kdf params not recorded: %+v", ks.Crypto)
+	}
+
+	_, got, err := v.Load([]byte("s3cret"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !bytes.Equal(got.Raw, priv.Raw) {
+		t.Error("loaded key differs from original")
+	}
+	if _, _, err := v.Load([]byte("wrong")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+}
+
+func TestInitEmptyPassphrase(t *testing.T) {
+	v := newTestVault(t)
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	if err := v.Init([]byte(`{}`), priv, nil); !errors.Is(err, ErrEmptyPassphrase) {
+		t.Errorf("expected ErrEmptyPassphrase, got %v", err)
+	}
+}
+
+func TestUpgradeLegacyKeystore(t *testing.T) {
+	v := newTestVault(t)
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	os.WriteFile(filepath.Join(v.BaseDir, didFilename), []byte(`{}`), 0600)
+	legacy, _ := json.Marshal(map[string]string{"privateKey": base58.Encode(priv.Raw)})
+	os.WriteFile(filepath.Join(v.BaseDir, keystoreFilename), legacy, 0600)
+
+	if enc, err := v.IsEncrypted(); err != nil || enc {
+		t.Fatalf("expected plaintext keystore, got enc=%v err=%v", enc, err)
+	}
+	if _, got, err := v.Load(nil); err != nil || !bytes.Equal(got.Raw, priv.Raw) {
+		t.Fatalf("legacy Load failed: %v", err)
+	}
+
+	if err := v.ChangePassphrase(nil, []byte("new")); err != nil {
+		t.Fatalf("ChangePassphrase failed: %v", err)
+	}
+	if enc, _ := v.IsEncrypted(); !enc {
+		t.Fatal("keystore should be encrypted after upgrade")
+	}
+	if _, got, err := v.Load([]byte("new")); err != nil || !bytes.Equal(got.Raw, priv.Raw) {
+		t.Errorf("Load after upgrade failed: %v", err)
+	}
+}
+
+func TestChangePassphrase(t *testing.T) {
+	v := newTestVault(t)
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	v.Init([]byte(`{}`), priv, []byte("old"))
+
+	if err := v.ChangePassphrase([]byte("bad"), []byte("new")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("expected ErrWrongPassphrase, got %v", err)
+	}
+	if err := v.ChangePassphrase([]byte("old"), []byte("new")); err != nil {
+		t.Fatalf("ChangePassphrase failed: %v", err)
+	}
+	if _, _, err := v.Load([]byte("old")); err == nil {
+		t.Error("old passphrase should no longer work")
+	}
+	if _, got, err := v.Load([]byte("new")); err != nil || !bytes.Equal(got.Raw, priv.Raw) {
+		t.Errorf("Load with new passphrase failed: %v", err)
+	}
+}
+
+func TestLoadCorruptedKDFParams(t *testing.T) {
+	v := newTestVault(t)
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	if err := v.Init([]byte(`{}`), priv, []byte("s3cret")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(v.BaseDir, keystoreFilename)
+	orig, _ := os.ReadFile(path)
+	for name, corrupt := range map[string]func(*KDFParams){
+		"no rounds":   func(p *KDFParams) { p.Time = 0 },
+		"no threads":  func(p *KDFParams) { p.Threads = 0 },
+		"huge memory": func(p *KDFParams) { p.Memory = 1 << 31 },
+		"short key":   func(p *KDFParams) { p.KeyLen = 16 },
+	} {
+		var ks encryptedKeystore
+		json.Unmarshal(orig, &ks)
+		corrupt(&ks.Crypto.KDFParams)
+		data, _ := json.Marshal(ks)
+		os.WriteFile(path, data, 0600)
+		if _, _, err := v.Load([]byte("s3cret")); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/fsutil"
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/resolver"
+)
+
+const (
+	metadataFilename = "did-meta.json"
+	versionsDir      = "did-versions"
+)
+
+// Metadata returns the DID document metadata of the vault: when did.json was created and
+// last updated, its versionId and whether the DID is deactivated. Vaults created before
+// documents were versioned are at version 1.
+func (v *Vault) Metadata() (resolver.DocumentMetadata, error) {
+	meta := resolver.DocumentMetadata{VersionID: "1"}
+	data, err := os.ReadFile(filepath.Join(v.BaseDir, metadataFilename))
+	if os.IsNotExist(err) {
+		return meta, nil
+	}
+	if err != nil {
+		return meta, fmt.Errorf("read %s: %w", metadataFilename, err)
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, fmt.Errorf("parse %s: %w", metadataFilename, err)
+	}
+	return meta, nil
+}
+
+// CheckActive returns resolver.ErrDeactivated if the vault's DID has been deactivated.
+func (v *Vault) CheckActive() error {
+	meta, err := v.Metadata()
+	if err != nil {
+		return err
+	}
+	if meta.Deactivated {
+		did, _ := v.DID()
+		return fmt.Errorf("%w: %s", resolver.ErrDeactivated, did)
+	}
+	return nil
+}
+
+// DocumentVersion returns the DID Document as it was at versionID.
+func (v *Vault) DocumentVersion(versionID string) (*identity.Document, error) {
+	meta, err := v.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	if versionID == meta.VersionID {
+		return v.Document()
+	}
+	if n, err := strconv.Atoi(versionID); err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid version %q", versionID)
+	}
+	data, err := os.ReadFile(filepath.Join(v.BaseDir, versionsDir, versionID+".json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s has no version %s", didFilename, versionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return identity.ParseDIDDocument(data)
+}
+
+// Deactivate marks the vault's DID as deactivated. The document is kept, but resolution
+// reports it deactivated, the vault no longer signs and the document cannot be changed.
+func (v *Vault) Deactivate() error {
+	lock, err := v.Lock()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	data, err := os.ReadFile(filepath.Join(v.BaseDir, didFilename))
+	if err != nil {
+		return fmt.Errorf("read did.json: %w", err)
+	}
+	return v.writeDocument(data, true)
+}
+
+// writeDocument replaces did.json with data, keeping the previous version in
+// did-versions/<versionId>.json and bumping versionId and updated. The caller holds the
+// vault lock.
+func (v *Vault) writeDocument(data []byte, deactivate bool) error {
+	if err := v.CheckActive(); err != nil {
+		return err
+	}
+	meta, err := v.Metadata()
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(meta.VersionID)
+	if err != nil {
+		return fmt.Errorf("invalid versionId %q in %s", meta.VersionID, metadataFilename)
+	}
+	prev, err := os.ReadFile(filepath.Join(v.BaseDir, didFilename))
+	if err != nil {
+		return fmt.Errorf("read did.json: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(v.BaseDir, versionsDir), 0700); err != nil {
+		return err
+	}
+	if err := fsutil.WriteFile(filepath.Join(v.BaseDir, versionsDir, meta.VersionID+".json"), prev, 0600); err != nil {
+		return fmt.Errorf("keep did.json version %s: %w", meta.VersionID, err)
+	}
+	if err := fsutil.WriteFile(filepath.Join(v.BaseDir, didFilename), data, 0600); err != nil {
+		return fmt.Errorf("write did.json: %w", err)
+	}
+	meta.VersionID = strconv.Itoa(n + 1)
+	meta.Updated = time.Now().UTC().Format(time.RFC3339)
+	meta.Deactivated = deactivate
+	return v.writeMetadata(meta)
+}
+
+func (v *Vault) writeMetadata(meta resolver.DocumentMetadata) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fsutil.WriteFile(filepath.Join(v.BaseDir, metadataFilename), data, 0600); err != nil {
+		return fmt.Errorf("write %s: %w", metadataFilename, err)
+	}
+	return nil
+}
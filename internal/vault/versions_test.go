@@ -0,0 +1,115 @@
+package vault
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/juanpablocruz/minervaid/internal/identity"
+	"github.com/juanpablocruz/minervaid/internal/resolver"
+)
+
+func TestDocumentVersions(t *testing.T) {
+	v := newTestVault(t)
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	doc, _ := identity.BuildDIDDocument("did:web:example.com", priv.Public())
+	if err := v.Init(doc, priv, []byte("s3cret")); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := v.Metadata()
+	if err != nil || meta.VersionID != "1" || meta.Created == "" || meta.Updated != "" {
+		t.Fatalf("initial metadata = %+v, %v", meta, err)
+	}
+
+	for _, endpoint := range []string{"https://a.example", "https://b.example"} {
+		err := v.UpdateDocument(func(doc *identity.Document) error {
+			return doc.AddService(identity.Service{ID: "#svc-" + endpoint[8:9], Type: "LinkedDomains", ServiceEndpoint: endpoint})
+		})
+		if err != nil {
+			t.Fatalf("UpdateDocument: %v", err)
+		}
+	}
+	if meta, _ = v.Metadata(); meta.VersionID != "3" || meta.Updated == "" {
+		t.Errorf("metadata after two updates = %+v", meta)
+	}
+	for version, services := range map[string]int{"1": 0, "2": 1, "3": 2} {
+		doc, err := v.DocumentVersion(version)
+		if err != nil || len(doc.Service) != services {
+			t.Errorf("version %s: %v services, %v; want %d", version, doc, err, services)
+		}
+	}
+	for _, version := range []string{"4", "../did", "0"} {
+		if _, err := v.DocumentVersion(version); err == nil {
+			t.Errorf("DocumentVersion(%q) must fail", version)
+		}
+	}
+
+	if err := v.Deactivate(); err != nil {
+		t.Fatalf("Deactivate: %v", err)
+	}
+	if meta, _ = v.Metadata(); !meta.Deactivated || meta.VersionID != "4" {
+		t.Errorf("metadata after deactivation = %+v", meta)
+	}
+	if err := v.CheckActive(); !errors.Is(err, resolver.ErrDeactivated) {
+		t.Errorf("CheckActive = %v; want ErrDeactivated", err)
+	}
+	err = v.UpdateDocument(func(doc *identity.Document) error { return nil })
+	if !errors.Is(err, resolver.ErrDeactivated) {
+		t.Errorf("a deactivated document must not be updated, got %v", err)
+	}
+	if err := v.Deactivate(); err == nil {
+		t.Error("deactivating twice must fail")
+	}
+}
+
+func TestMetadataOfUnversionedVault(t *testing.T) {
+	v := newTestVault(t)
+	meta, err := v.Metadata()
+	if err != nil || meta.VersionID != "1" || meta.Deactivated {
+		t.Errorf("metadata without did-meta.json = %+v, %v", meta, err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	v := newTestVault(t)
+	priv, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	doc, _ := identity.BuildDIDDocument("did:web:example.com", priv.Public())
+	if err := v.Init(doc, priv, []byte("s3cret")); err != nil {
+		t.Fatal(err)
+	}
+	next, _ := identity.GenerateKey(identity.KeyTypeEd25519)
+	rotate := func(doc *identity.Document) (*identity.PrivateKey, error) {
+		now := time.Now()
+		doc.RotateKey(next.Public(), now, now)
+		return next, nil
+	}
+
+	// a document that cannot be written leaves the old key in place
+	versions := filepath.Join(v.BaseDir, versionsDir)
+	if err := os.WriteFile(versions, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Rotate([]byte("s3cret"), rotate); err == nil {
+		t.Fatal("expected Rotate to fail")
+	}
+	if _, got, err := v.Load([]byte("s3cret")); err != nil || !got.Public().Equal(priv.Public()) {
+		t.Fatalf("failed rotation replaced the key: %v", err)
+	}
+	os.Remove(versions)
+
+	if _, err := v.Rotate([]byte("wrong"), rotate); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("wrong passphrase: got %v", err)
+	}
+	if _, err := v.Rotate([]byte("s3cret"), rotate); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if _, got, err := v.Load([]byte("s3cret")); err != nil || !got.Public().Equal(next.Public()) {
+		t.Errorf("rotation did not replace the key: %v", err)
+	}
+	cur, err := v.VerificationMethod()
+	if err != nil || cur != "did:web:example.com#keys-2" {
+		t.Errorf("current method = %s, %v", cur, err)
+	}
+}